@@ -0,0 +1,255 @@
+package alarm
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrAlarmNotFound       = errs.New(errs.NotFound, "alarm not found")
+	ErrAlarmNotShelved     = errs.New(errs.FailedPrecondition, "alarm not shelved")
+	ErrAlreadyAcknowledged = errs.New(errs.FailedPrecondition, "alarm already acknowledged")
+	ErrInvalidDefinition   = errs.New(errs.InvalidArgument, "invalid alarm definition")
+)
+
+type AlarmType string
+
+const (
+	Limit        AlarmType = "limit"
+	Deviation    AlarmType = "deviation"
+	RateOfChange AlarmType = "rate_of_change"
+	Discrete     AlarmType = "discrete"
+)
+
+type Severity string
+
+const (
+	Low      Severity = "low"
+	Medium   Severity = "medium"
+	High     Severity = "high"
+	Critical Severity = "critical"
+)
+
+// Condition is the abnormal condition that caused an alarm to be active.
+type Condition string
+
+const (
+	Normal        Condition = ""
+	HiHi          Condition = "hihi"
+	Hi            Condition = "hi"
+	Lo            Condition = "lo"
+	LoLo          Condition = "lolo"
+	DeviationHigh Condition = "deviation_high"
+	DeviationLow  Condition = "deviation_low"
+	RateExceeded  Condition = "rate_exceeded"
+	DiscreteAlarm Condition = "discrete"
+)
+
+type Limits struct {
+	HiHi *float64 `json:"hihi,omitempty"`
+	Hi   *float64 `json:"hi,omitempty"`
+	Lo   *float64 `json:"lo,omitempty"`
+	LoLo *float64 `json:"lolo,omitempty"`
+}
+
+// Definition declares an alarm on a point.
+//
+// Limit alarms use Limits, deviation alarms compare the value against
+// Setpoint with Deviation as the allowed band, rate-of-change alarms compare
+// the change per second against Rate, and discrete alarms are active while
+// the value equals AlarmValue. Deadband applies to the return to normal of
+// analog alarms, while OnDelay and OffDelay require the condition to persist
+// before the alarm is raised or cleared.
+type Definition struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Source     machine.PointRef `json:"source"`
+	Type       AlarmType        `json:"type"`
+	Severity   Severity         `json:"severity"`
+	Message    string           `json:"message"`
+	Limits     *Limits          `json:"limits,omitempty"`
+	Setpoint   float64          `json:"setpoint,omitempty"`
+	Deviation  float64          `json:"deviation,omitempty"`
+	Rate       float64          `json:"rate,omitempty"`
+	AlarmValue any              `json:"alarm_value,omitempty"`
+	Deadband   float64          `json:"deadband,omitempty"`
+	OnDelay    machine.Duration `json:"on_delay,omitempty"`
+	OffDelay   machine.Duration `json:"off_delay,omitempty"`
+}
+
+func (def *Definition) Validate() error {
+	if def.ID == "" {
+		return errors.Join(ErrInvalidDefinition, errors.New("id is required"))
+	}
+
+	if def.Source.MachineID == "" || def.Source.Point == "" {
+		return errors.Join(ErrInvalidDefinition, errors.New("source is required"))
+	}
+
+	switch def.Type {
+	case Limit:
+		if def.Limits == nil {
+			return errors.Join(ErrInvalidDefinition, errors.New("limits are required"))
+		}
+
+	case Deviation:
+		if def.Deviation <= 0 {
+			return errors.Join(ErrInvalidDefinition, errors.New("deviation must be positive"))
+		}
+
+	case RateOfChange:
+		if def.Rate <= 0 {
+			return errors.Join(ErrInvalidDefinition, errors.New("rate must be positive"))
+		}
+
+	case Discrete:
+		if def.AlarmValue == nil {
+			return errors.Join(ErrInvalidDefinition, errors.New("alarm value is required"))
+		}
+
+	default:
+		return errors.Join(ErrInvalidDefinition, errors.New("unsupported alarm type"))
+	}
+
+	if def.Deadband < 0 {
+		return errors.Join(ErrInvalidDefinition, errors.New("deadband must not be negative"))
+	}
+
+	return nil
+}
+
+// LoadDefinitions reads the alarm definitions from the given JSON file.
+// A missing file yields an empty list.
+func LoadDefinitions(filename string) ([]*Definition, error) {
+	f, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make([]*Definition, 0), nil
+		}
+
+		return nil, err
+	}
+
+	var defs []*Definition
+	if err := json.Unmarshal(f, &defs); err != nil {
+		return nil, err
+	}
+
+	return defs, nil
+}
+
+// State is the lifecycle state of an alarm.
+type State string
+
+const (
+	Active       State = "active"
+	Acknowledged State = "acknowledged"
+	Cleared      State = "cleared"
+	Shelved      State = "shelved"
+)
+
+type Acknowledgement struct {
+	User    string    `json:"user"`
+	Comment string    `json:"comment"`
+	Time    time.Time `json:"time"`
+}
+
+type Shelve struct {
+	User    string    `json:"user"`
+	Comment string    `json:"comment"`
+	Time    time.Time `json:"time"`
+	Until   time.Time `json:"until"`
+}
+
+// Alarm is an occurrence of an alarm definition. It stays in the alarm list
+// until it has both returned to normal and been acknowledged.
+type Alarm struct {
+	ID              string           `json:"id"`
+	Name            string           `json:"name"`
+	Source          machine.PointRef `json:"source"`
+	Type            AlarmType        `json:"type"`
+	Severity        Severity         `json:"severity"`
+	Message         string           `json:"message"`
+	Condition       Condition        `json:"condition"`
+	State           State            `json:"state"`
+	Value           any              `json:"value"`
+	ActiveTime      time.Time        `json:"active_time"`
+	ClearedTime     *time.Time       `json:"cleared_time,omitempty"`
+	Acknowledgement *Acknowledgement `json:"acknowledgement,omitempty"`
+	Shelve          *Shelve          `json:"shelve,omitempty"`
+	UpdatedTime     time.Time        `json:"updated_time"`
+}
+
+func (a *Alarm) Active() bool {
+	return a.ClearedTime == nil
+}
+
+func (a *Alarm) Acknowledged() bool {
+	return a.Acknowledgement != nil
+}
+
+func (a *Alarm) refreshState() {
+	switch {
+	case a.Shelve != nil:
+		a.State = Shelved
+	case !a.Active():
+		a.State = Cleared
+	case a.Acknowledged():
+		a.State = Acknowledged
+	default:
+		a.State = Active
+	}
+}
+
+func (a *Alarm) clone() *Alarm {
+	alarm := *a
+	return &alarm
+}
+
+type EventType string
+
+const (
+	AlarmRaised       EventType = "raised"
+	AlarmReturned     EventType = "returned" // a cleared, unacknowledged alarm active again
+	AlarmChanged      EventType = "changed"
+	AlarmAcknowledged EventType = "acknowledged"
+	AlarmCleared      EventType = "cleared"
+	AlarmShelved      EventType = "shelved"
+	AlarmUnshelved    EventType = "unshelved"
+	AlarmNormal       EventType = "normal"
+)
+
+type Event struct {
+	Type  EventType `json:"type"`
+	Alarm *Alarm    `json:"alarm"`
+	Time  time.Time `json:"time"`
+}
+
+type EventHandler func(event *Event)
+
+type Filter struct {
+	MachineID machine.MachineID `json:"machine_id,omitempty" form:"machine_id"`
+	State     State             `json:"state,omitempty" form:"state"`
+	Severity  Severity          `json:"severity,omitempty" form:"severity"`
+}
+
+func (f Filter) Match(a *Alarm) bool {
+	if f.MachineID != "" && f.MachineID != a.Source.MachineID {
+		return false
+	}
+
+	if f.State != "" && f.State != a.State {
+		return false
+	}
+
+	if f.Severity != "" && f.Severity != a.Severity {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,465 @@
+package alarm
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+type Service interface {
+	// Evaluate evaluates the alarm definitions of the sampled point.
+	Evaluate(ctx context.Context, sample *machine.Sample)
+
+	// Alarms lists the alarms matching the filter, oldest first.
+	Alarms(filter Filter) []*Alarm
+
+	// Acknowledge acknowledges the alarm with the given ID.
+	Acknowledge(id string, user string, comment string) (*Alarm, error)
+
+	// Shelve suppresses the alarm with the given ID for the given duration.
+	Shelve(id string, user string, comment string, duration time.Duration) (*Alarm, error)
+
+	// Subscribe registers a handler that receives every alarm event.
+	Subscribe(handler EventHandler)
+}
+
+func NewService(defs []*Definition) (Service, error) {
+	svc := &service{
+		evaluators: make(map[string]*evaluator),
+		sources:    make(map[machine.PointRef][]*evaluator),
+		handlers:   make([]EventHandler, 0),
+		now:        time.Now,
+	}
+
+	for _, def := range defs {
+		if err := def.Validate(); err != nil {
+			return nil, err
+		}
+
+		ev := &evaluator{def: def}
+		svc.evaluators[def.ID] = ev
+		svc.sources[def.Source] = append(svc.sources[def.Source], ev)
+	}
+
+	return svc, nil
+}
+
+type service struct {
+	evaluators map[string]*evaluator
+	sources    map[machine.PointRef][]*evaluator
+	handlers   []EventHandler
+	now        func() time.Time
+	sync.RWMutex
+}
+
+func (svc *service) Evaluate(ctx context.Context, sample *machine.Sample) {
+	if sample == nil || sample.Value == nil {
+		return
+	}
+
+	svc.Lock()
+
+	events := svc.expireShelves()
+	for _, ev := range svc.sources[sample.PointRef] {
+		if event := ev.evaluate(sample.Value); event != nil {
+			events = append(events, event)
+		}
+	}
+
+	svc.Unlock()
+
+	svc.publish(events...)
+}
+
+func (svc *service) Alarms(filter Filter) []*Alarm {
+	svc.Lock()
+	events := svc.expireShelves()
+
+	alarms := make([]*Alarm, 0)
+	for _, ev := range svc.evaluators {
+		if ev.alarm == nil {
+			continue
+		}
+
+		if filter.Match(ev.alarm) {
+			alarms = append(alarms, ev.alarm.clone())
+		}
+	}
+	svc.Unlock()
+
+	svc.publish(events...)
+
+	sort.Slice(alarms, func(i, j int) bool {
+		return alarms[i].ActiveTime.Before(alarms[j].ActiveTime)
+	})
+
+	return alarms
+}
+
+func (svc *service) Acknowledge(id string, user string, comment string) (*Alarm, error) {
+	svc.Lock()
+
+	ev, ok := svc.evaluators[id]
+	if !ok || ev.alarm == nil {
+		svc.Unlock()
+		return nil, ErrAlarmNotFound
+	}
+
+	if ev.alarm.Acknowledged() {
+		svc.Unlock()
+		return nil, ErrAlreadyAcknowledged
+	}
+
+	now := svc.now()
+
+	alarm := ev.alarm
+	alarm.Acknowledgement = &Acknowledgement{
+		User:    user,
+		Comment: comment,
+		Time:    now,
+	}
+	alarm.UpdatedTime = now
+	alarm.refreshState()
+
+	events := []*Event{
+		newEvent(AlarmAcknowledged, alarm, now),
+	}
+
+	// An acknowledged alarm that has already cleared returns to normal.
+	if !alarm.Active() {
+		ev.alarm = nil
+		events = append(events, newEvent(AlarmNormal, alarm, now))
+	}
+
+	svc.Unlock()
+
+	svc.publish(events...)
+	return alarm.clone(), nil
+}
+
+func (svc *service) Shelve(id string, user string, comment string, duration time.Duration) (*Alarm, error) {
+	svc.Lock()
+
+	ev, ok := svc.evaluators[id]
+	if !ok || ev.alarm == nil {
+		svc.Unlock()
+		return nil, ErrAlarmNotFound
+	}
+
+	now := svc.now()
+
+	ev.shelve = &Shelve{
+		User:    user,
+		Comment: comment,
+		Time:    now,
+		Until:   now.Add(duration),
+	}
+
+	alarm := ev.alarm
+	alarm.Shelve = ev.shelve
+	alarm.UpdatedTime = now
+	alarm.refreshState()
+
+	event := newEvent(AlarmShelved, alarm, now)
+
+	svc.Unlock()
+
+	svc.publish(event)
+	return alarm.clone(), nil
+}
+
+func (svc *service) Subscribe(handler EventHandler) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	svc.handlers = append(svc.handlers, handler)
+}
+
+// expireShelves unshelves the alarms whose shelve duration has elapsed.
+// The caller must hold the lock.
+func (svc *service) expireShelves() []*Event {
+	now := svc.now()
+
+	events := make([]*Event, 0)
+	for _, ev := range svc.evaluators {
+		if ev.shelve == nil || now.Before(ev.shelve.Until) {
+			continue
+		}
+
+		ev.shelve = nil
+
+		if ev.alarm != nil {
+			ev.alarm.Shelve = nil
+			ev.alarm.UpdatedTime = now
+			ev.alarm.refreshState()
+
+			events = append(events, newEvent(AlarmUnshelved, ev.alarm, now))
+		}
+	}
+
+	return events
+}
+
+func (svc *service) publish(events ...*Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	svc.RLock()
+	handlers := svc.handlers
+	svc.RUnlock()
+
+	for _, event := range events {
+		for _, handler := range handlers {
+			handler(event)
+		}
+	}
+}
+
+func newEvent(eventType EventType, alarm *Alarm, t time.Time) *Event {
+	return &Event{
+		Type:  eventType,
+		Alarm: alarm.clone(),
+		Time:  t,
+	}
+}
+
+type evaluator struct {
+	def           *Definition
+	alarm         *Alarm
+	shelve        *Shelve
+	last          *machine.Value
+	pending       Condition
+	pendingSince  time.Time
+	clearingSince time.Time
+}
+
+// evaluate applies the value to the alarm state machine, honoring the
+// deadband and the on/off delays. Delays are measured between sample times,
+// so their resolution is bounded by the polling interval.
+func (ev *evaluator) evaluate(value *machine.Value) *Event {
+	current := Normal
+	if ev.alarm != nil && ev.alarm.Active() {
+		current = ev.alarm.Condition
+	}
+
+	cond, ok := ev.check(value, current)
+	if !ok {
+		return nil
+	}
+
+	t := value.Time
+
+	if cond == Normal {
+		ev.pending = Normal
+
+		if current == Normal {
+			return nil
+		}
+
+		if ev.clearingSince.IsZero() {
+			ev.clearingSince = t
+		}
+
+		if t.Sub(ev.clearingSince) < time.Duration(ev.def.OffDelay) {
+			return nil
+		}
+
+		return ev.clear(value, t)
+	}
+
+	ev.clearingSince = time.Time{}
+
+	if current != Normal {
+		ev.alarm.Value = value.Value
+		if cond == current {
+			return nil
+		}
+
+		ev.alarm.Condition = cond
+		ev.alarm.UpdatedTime = t
+		return ev.notify(AlarmChanged, t)
+	}
+
+	if ev.pending != cond {
+		ev.pending = cond
+		ev.pendingSince = t
+	}
+
+	if t.Sub(ev.pendingSince) < time.Duration(ev.def.OnDelay) {
+		return nil
+	}
+
+	return ev.raise(cond, value, t)
+}
+
+// raise activates the alarm. An alarm cleared but not yet acknowledged
+// returns to active unacknowledged, as in ISA-18.2, keeping the time it was
+// first raised and the acknowledgement it still waits for.
+func (ev *evaluator) raise(cond Condition, value *machine.Value, t time.Time) *Event {
+	def := ev.def
+
+	ev.pending = Normal
+
+	if alarm := ev.alarm; alarm != nil {
+		alarm.Condition = cond
+		alarm.Value = value.Value
+		alarm.ClearedTime = nil
+		alarm.UpdatedTime = t
+		alarm.refreshState()
+
+		return ev.notify(AlarmReturned, t)
+	}
+
+	ev.alarm = &Alarm{
+		ID:          def.ID,
+		Name:        def.Name,
+		Source:      def.Source,
+		Type:        def.Type,
+		Severity:    def.Severity,
+		Message:     def.Message,
+		Condition:   cond,
+		Value:       value.Value,
+		ActiveTime:  t,
+		Shelve:      ev.shelve,
+		UpdatedTime: t,
+	}
+	ev.alarm.refreshState()
+
+	return ev.notify(AlarmRaised, t)
+}
+
+func (ev *evaluator) clear(value *machine.Value, t time.Time) *Event {
+	ev.clearingSince = time.Time{}
+
+	alarm := ev.alarm
+	alarm.Value = value.Value
+	alarm.ClearedTime = &t
+	alarm.UpdatedTime = t
+	alarm.refreshState()
+
+	if alarm.Acknowledged() {
+		ev.alarm = nil
+		return ev.notifyAlarm(AlarmNormal, alarm, t)
+	}
+
+	return ev.notify(AlarmCleared, t)
+}
+
+func (ev *evaluator) notify(eventType EventType, t time.Time) *Event {
+	return ev.notifyAlarm(eventType, ev.alarm, t)
+}
+
+// notifyAlarm creates an event unless the alarm is shelved, in which case
+// the change is tracked without being annunciated.
+func (ev *evaluator) notifyAlarm(eventType EventType, alarm *Alarm, t time.Time) *Event {
+	if ev.shelve != nil {
+		return nil
+	}
+
+	return newEvent(eventType, alarm, t)
+}
+
+// check returns the condition of the value, taking the current condition
+// into account for the deadband. It returns false if the value cannot be
+// evaluated.
+func (ev *evaluator) check(value *machine.Value, current Condition) (Condition, bool) {
+	def := ev.def
+
+	if def.Type == Discrete {
+		if machine.Equal(value.Value, def.AlarmValue) {
+			return DiscreteAlarm, true
+		}
+
+		return Normal, true
+	}
+
+	v, ok := machine.ToFloat(value.Value)
+	if !ok {
+		return Normal, false
+	}
+
+	db := def.Deadband
+
+	switch def.Type {
+	case Limit:
+		l := def.Limits
+
+		high := func(limit *float64, conds ...Condition) bool {
+			if limit == nil {
+				return false
+			}
+
+			return v >= *limit || (contains(conds, current) && v > *limit-db)
+		}
+
+		low := func(limit *float64, conds ...Condition) bool {
+			if limit == nil {
+				return false
+			}
+
+			return v <= *limit || (contains(conds, current) && v < *limit+db)
+		}
+
+		switch {
+		case high(l.HiHi, HiHi):
+			return HiHi, true
+		case high(l.Hi, Hi, HiHi):
+			return Hi, true
+		case low(l.LoLo, LoLo):
+			return LoLo, true
+		case low(l.Lo, Lo, LoLo):
+			return Lo, true
+		}
+
+	case Deviation:
+		d := v - def.Setpoint
+		band := def.Deviation
+
+		switch {
+		case d >= band || (current == DeviationHigh && d > band-db):
+			return DeviationHigh, true
+		case d <= -band || (current == DeviationLow && d < -band+db):
+			return DeviationLow, true
+		}
+
+	case RateOfChange:
+		last := ev.last
+		ev.last = value
+
+		if last == nil {
+			return Normal, false
+		}
+
+		lv, ok := machine.ToFloat(last.Value)
+		if !ok {
+			return Normal, false
+		}
+
+		dt := value.Time.Sub(last.Time).Seconds()
+		if dt <= 0 {
+			return Normal, false
+		}
+
+		rate := math.Abs(v-lv) / dt
+		if rate >= def.Rate || (current == RateExceeded && rate > def.Rate-db) {
+			return RateExceeded, true
+		}
+	}
+
+	return Normal, true
+}
+
+func contains(conds []Condition, cond Condition) bool {
+	for _, c := range conds {
+		if c == cond {
+			return true
+		}
+	}
+
+	return false
+}
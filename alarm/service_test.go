@@ -0,0 +1,227 @@
+package alarm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+var source = machine.PointRef{
+	MachineID:    "M1",
+	ControllerID: "PLC1",
+	Point:        "temperature",
+}
+
+func sample(v any, t time.Time) *machine.Sample {
+	return &machine.Sample{
+		PointRef: source,
+		Value: &machine.Value{
+			Value: v,
+			Time:  t,
+		},
+	}
+}
+
+func newTestService(t *testing.T, def *Definition) (*service, *[]*Event) {
+	svc, err := NewService([]*Definition{def})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make([]*Event, 0)
+	svc.Subscribe(func(event *Event) {
+		events = append(events, event)
+	})
+
+	return svc.(*service), &events
+}
+
+func TestLimitAlarmWithDeadband(t *testing.T) {
+	assert := assert.New(t)
+
+	hi, hihi := 80.0, 90.0
+	svc, events := newTestService(t, &Definition{
+		ID:       "TEMP_HIGH",
+		Source:   source,
+		Type:     Limit,
+		Limits:   &Limits{Hi: &hi, HiHi: &hihi},
+		Deadband: 2,
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+
+	svc.Evaluate(ctx, sample(85.0, now))
+	svc.Evaluate(ctx, sample(95.0, now.Add(time.Second)))
+	svc.Evaluate(ctx, sample(79.0, now.Add(2*time.Second))) // within deadband of Hi
+
+	alarms := svc.Alarms(Filter{})
+	if !assert.Len(alarms, 1) {
+		return
+	}
+
+	assert.Equal(Hi, alarms[0].Condition)
+	assert.Equal(Active, alarms[0].State)
+
+	svc.Evaluate(ctx, sample(77.0, now.Add(3*time.Second)))
+
+	alarms = svc.Alarms(Filter{})
+	if !assert.Len(alarms, 1) {
+		return
+	}
+
+	assert.Equal(Cleared, alarms[0].State)
+
+	_, err := svc.Acknowledge("TEMP_HIGH", "operator", "checked cooling")
+	assert.NoError(err)
+	assert.Empty(svc.Alarms(Filter{}))
+
+	types := make([]EventType, len(*events))
+	for i, event := range *events {
+		types[i] = event.Type
+	}
+
+	assert.Equal([]EventType{
+		AlarmRaised, AlarmChanged, AlarmChanged, AlarmCleared, AlarmAcknowledged, AlarmNormal,
+	}, types)
+}
+
+func TestOnOffDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	svc, _ := newTestService(t, &Definition{
+		ID:         "DOOR_OPEN",
+		Source:     source,
+		Type:       Discrete,
+		AlarmValue: true,
+		OnDelay:    machine.Duration(5 * time.Second),
+		OffDelay:   machine.Duration(5 * time.Second),
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+
+	svc.Evaluate(ctx, sample(true, now))
+	svc.Evaluate(ctx, sample(true, now.Add(3*time.Second)))
+	assert.Empty(svc.Alarms(Filter{}))
+
+	svc.Evaluate(ctx, sample(true, now.Add(5*time.Second)))
+	assert.Len(svc.Alarms(Filter{State: Active}), 1)
+
+	_, err := svc.Acknowledge("DOOR_OPEN", "operator", "")
+	assert.NoError(err)
+
+	svc.Evaluate(ctx, sample(false, now.Add(6*time.Second)))
+	assert.Len(svc.Alarms(Filter{State: Acknowledged}), 1)
+
+	svc.Evaluate(ctx, sample(false, now.Add(11*time.Second)))
+	assert.Empty(svc.Alarms(Filter{}))
+}
+
+func TestRateOfChangeAlarm(t *testing.T) {
+	assert := assert.New(t)
+
+	svc, _ := newTestService(t, &Definition{
+		ID:     "TEMP_RATE",
+		Source: source,
+		Type:   RateOfChange,
+		Rate:   1, // units per second
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+
+	svc.Evaluate(ctx, sample(10.0, now))
+	svc.Evaluate(ctx, sample(15.0, now.Add(10*time.Second)))
+	assert.Empty(svc.Alarms(Filter{}))
+
+	svc.Evaluate(ctx, sample(30.0, now.Add(15*time.Second)))
+	assert.Len(svc.Alarms(Filter{}), 1)
+}
+
+func TestShelve(t *testing.T) {
+	assert := assert.New(t)
+
+	svc, events := newTestService(t, &Definition{
+		ID:        "FLOW_DEV",
+		Source:    source,
+		Type:      Deviation,
+		Setpoint:  50,
+		Deviation: 5,
+	})
+
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	svc.Evaluate(ctx, sample(40.0, now))
+
+	alarm, err := svc.Shelve("FLOW_DEV", "operator", "sensor under repair", time.Hour)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(Shelved, alarm.State)
+	assert.Equal(DeviationLow, alarm.Condition)
+
+	// Changes of shelved alarms are not annunciated.
+	svc.Evaluate(ctx, sample(60.0, now.Add(time.Second)))
+	assert.Len(*events, 2)
+
+	svc.now = func() time.Time { return now.Add(2 * time.Hour) }
+
+	alarms := svc.Alarms(Filter{})
+	if !assert.Len(alarms, 1) {
+		return
+	}
+
+	assert.Equal(Active, alarms[0].State)
+	assert.Equal(DeviationHigh, alarms[0].Condition)
+	assert.Equal(AlarmUnshelved, (*events)[2].Type)
+}
+
+func TestAlarmReturnsBeforeAcknowledgement(t *testing.T) {
+	assert := assert.New(t)
+
+	hi := 80.0
+	svc, events := newTestService(t, &Definition{
+		ID:     "TEMP_HIGH",
+		Source: source,
+		Type:   Limit,
+		Limits: &Limits{Hi: &hi},
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+
+	svc.Evaluate(ctx, sample(85.0, now))
+	svc.Evaluate(ctx, sample(70.0, now.Add(time.Second)))
+	svc.Evaluate(ctx, sample(86.0, now.Add(2*time.Second)))
+
+	// The occurrence returns to active, still waiting for acknowledgement.
+	alarms := svc.Alarms(Filter{})
+	if !assert.Len(alarms, 1) {
+		return
+	}
+
+	assert.Equal(Active, alarms[0].State)
+	assert.Equal(now, alarms[0].ActiveTime)
+	assert.Nil(alarms[0].ClearedTime)
+	assert.Equal(86.0, alarms[0].Value)
+
+	types := make([]EventType, len(*events))
+	for i, event := range *events {
+		types[i] = event.Type
+	}
+
+	assert.Equal([]EventType{AlarmRaised, AlarmCleared, AlarmReturned}, types)
+
+	_, err := svc.Acknowledge("TEMP_HIGH", "operator", "")
+	assert.NoError(err)
+	assert.Equal(Acknowledged, svc.Alarms(Filter{})[0].State)
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/xeipuuv/gojsonschema"
 
+	"github.com/flarexio/iiot/driver/sdk"
+	"github.com/flarexio/iiot/driver/tool"
 	"github.com/flarexio/iiot/driver/tool/example"
 	"github.com/flarexio/iiot/driver/tool/stdio"
 )
@@ -28,17 +30,17 @@ func (suite *exampleTestSuite) SetupTest() {
 	suite.ctx = ctx
 	suite.cancel = cancel
 
-	tool := example.NewTool()
-
 	in := new(bytes.Buffer)
 	out := new(bytes.Buffer)
 
 	suite.serverIn = in
 	suite.serverOut = out
 
-	server := stdio.NewStdioServer()
-	server.AddHandler("driver.schema", SchemaHandler(tool))
-	server.AddHandler("driver.readPoints", ReadPointsHandler(tool))
+	server, err := sdk.NewServer(tool.Info{Name: "example"}, example.NewTool())
+	if err != nil {
+		suite.FailNow(err.Error())
+	}
+
 	server.SetIO(in, out)
 	go server.Listen(ctx)
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+
+	"github.com/flarexio/iiot/config"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+// applyMachines applies the configurations pushed to the edge. They are
+// rolled back when a driver is missing, speaks another protocol or refuses
+// the request of a controller; a device failing to answer is reported as a
+// warning, as it does not stop the others from running.
+func applyMachines(drivers tool.Describer, machines machine.Service, poller machine.Poller) config.ApplyFunc {
+	return func(ctx context.Context, ms []*machine.Machine) ([]string, error) {
+		checked := make(map[string]struct{})
+		for _, m := range ms {
+			for _, c := range m.Controllers {
+				if _, ok := checked[c.Driver]; ok {
+					continue
+				}
+
+				info, err := drivers.Info(ctx, c.Driver)
+				if err != nil {
+					return nil, errs.From(err).WithDetail("driver", c.Driver)
+				}
+
+				if err := info.Check(); err != nil {
+					return nil, err
+				}
+
+				checked[c.Driver] = struct{}{}
+			}
+		}
+
+		if err := machines.Reload(ms); err != nil {
+			return nil, err
+		}
+
+		poller.SetMachines(ms)
+
+		err := poller.Poll(ctx)
+		if err == nil {
+			return nil, nil
+		}
+
+		failures := []error{err}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			failures = joined.Unwrap()
+		}
+
+		warnings := make([]string, 0, len(failures))
+		for _, failure := range failures {
+			if errs.CodeOf(failure) == errs.InvalidArgument {
+				return nil, failure
+			}
+
+			warnings = append(warnings, failure.Error())
+		}
+
+		return warnings, nil
+	}
+}
@@ -0,0 +1,11 @@
+//go:build example
+
+package main
+
+// The drivers compiled into the edge, registered in their init(). The
+// example driver is only compiled in with the example build tag:
+//
+//	go build -tags example ./cmd/iiot
+import (
+	_ "github.com/flarexio/iiot/driver/tool/example"
+)
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/driver/tool/grpc"
+	"github.com/flarexio/iiot/driver/tool/inproc"
+	"github.com/flarexio/iiot/driver/tool/stdio"
+	"github.com/flarexio/iiot/driver/tool/wasm"
+)
+
+// driversCommand manages the drivers of the edge from its host. A running
+// edge restarts the drivers whose binary is replaced.
+func driversCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "drivers",
+		Usage: "Manage the drivers of the edge",
+		Commands: []*cli.Command{
+			{
+				Name:   "list",
+				Usage:  "List the installed drivers",
+				Action: listDrivers,
+			},
+			{
+				Name:      "install",
+				Usage:     "Install or upgrade a driver from a package",
+				ArgsUsage: "<package.tar.gz>",
+				Action:    installDriver,
+			},
+			{
+				Name:      "uninstall",
+				Usage:     "Remove a driver",
+				ArgsUsage: "<driver>",
+				Action:    uninstallDriver,
+			},
+		},
+	}
+}
+
+// localService returns the service managing the drivers in the path.
+func localService(cmd *cli.Command) (iiot.Service, error) {
+	path := cmd.String("path")
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+
+		path = filepath.Join(homeDir, ".flarex", "iiot")
+	}
+
+	keys, err := install.LoadTrustedKeys(filepath.Join(path, "trusted_keys"))
+	if err != nil {
+		return nil, err
+	}
+
+	executor, err := newExecutor(path)
+	if err != nil {
+		return nil, err
+	}
+
+	client := stdio.NewStdioClient(executor)
+	installer := install.NewInstaller(filepath.Join(path, "drivers"), client, install.Trust{
+		Keys:          keys,
+		AllowUnsigned: cmd.Bool("drivers-allow-unsigned"),
+	})
+
+	router, _, err := newRouter(path, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return iiot.NewService(path, router, nil, nil, nil, nil, installer), nil
+}
+
+// newRouter returns the client of the drivers of the path: over gRPC for
+// the drivers configured in grpc_drivers.json, in process for the drivers
+// compiled in, in WebAssembly for the .wasm files, over stdio otherwise.
+func newRouter(path string, client stdio.StdioClient) (tool.Router, io.Closer, error) {
+	targets, err := grpc.LoadTargets(filepath.Join(path, "grpc_drivers.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limits, err := wasm.LoadLimits(filepath.Join(path, "wasm.json"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remote, err := grpc.NewGRPCClient(targets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	modules, err := wasm.NewWASMClient(filepath.Join(path, "drivers"), limits)
+	if err != nil {
+		remote.Close()
+		return nil, nil, err
+	}
+
+	// Drivers compiled in are preferred to their binaries and modules, and
+	// drivers served over gRPC to all of them.
+	local := inproc.NewInprocClient()
+
+	routes := make(map[string]tool.Client)
+	for _, driver := range modules.Drivers() {
+		routes[driver] = modules
+	}
+
+	for _, driver := range local.Drivers() {
+		routes[driver] = local
+	}
+
+	for _, driver := range remote.Drivers() {
+		routes[driver] = remote
+	}
+
+	return tool.NewRouter(client, routes), closers{remote, modules}, nil
+}
+
+// closers closes each of the closers, returning the first error.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var first error
+	for _, c := range cs {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+func listDrivers(ctx context.Context, cmd *cli.Command) error {
+	svc, err := localService(cmd)
+	if err != nil {
+		return err
+	}
+
+	drivers, err := svc.ListDrivers(ctx)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(drivers)
+}
+
+func installDriver(ctx context.Context, cmd *cli.Command) error {
+	file := cmd.Args().First()
+	if file == "" {
+		return errors.New("package file is required")
+	}
+
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	svc, err := localService(cmd)
+	if err != nil {
+		return err
+	}
+
+	driver, err := svc.InstallDriver(ctx, install.Request{File: file})
+	if err != nil {
+		return err
+	}
+
+	return printJSON(driver)
+}
+
+func uninstallDriver(ctx context.Context, cmd *cli.Command) error {
+	driver := cmd.Args().First()
+	if driver == "" {
+		return errors.New("driver is required")
+	}
+
+	svc, err := localService(cmd)
+	if err != nil {
+		return err
+	}
+
+	return svc.UninstallDriver(ctx, driver)
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
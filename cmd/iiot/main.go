@@ -2,22 +2,39 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/nats-io/nats.go/micro"
 	"github.com/urfave/cli/v3"
 	"go.uber.org/zap"
 
 	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/config"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/driver/sandbox"
+	"github.com/flarexio/iiot/driver/watch"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/fleet"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/production"
+	"github.com/flarexio/iiot/safety"
 	"github.com/flarexio/iiot/transport/http"
+	"github.com/flarexio/iiot/transport/mcp"
 	"github.com/flarexio/iiot/transport/pubsub"
 
 	tool "github.com/flarexio/iiot/driver/tool/stdio"
@@ -28,6 +45,9 @@ const (
 )
 
 func main() {
+	// Sandboxed drivers are started through this binary.
+	sandbox.Init()
+
 	cmd := &cli.Command{
 		Name:  "iiot",
 		Usage: "IIoT Service",
@@ -46,12 +66,58 @@ func main() {
 				Usage: "HTTP server port",
 				Value: 8080,
 			},
+			&cli.StringFlag{
+				Name:  "mcp-transport",
+				Usage: "Serve the MCP tools of the edge over stdio or http (default: disabled)",
+			},
+			&cli.IntFlag{
+				Name:  "mcp-port",
+				Usage: "MCP HTTP server port (loopback only without --mcp-token)",
+				Value: 8081,
+			},
+			&cli.StringSliceFlag{
+				Name:    "mcp-token",
+				Usage:   "Bearer token accepted by the MCP HTTP server, as name:token",
+				Sources: cli.EnvVars("MCP_TOKENS"),
+			},
+			&cli.StringSliceFlag{
+				Name:  "mcp-origin",
+				Usage: "Origin allowed to call the MCP HTTP server (default: local origins)",
+			},
 			&cli.StringFlag{
 				Name:    "nats",
 				Usage:   "NATS server URL",
 				Value:   "wss://nats.flarex.io",
 				Sources: cli.EnvVars("NATS_URL"),
 			},
+			&cli.DurationFlag{
+				Name:  "poll-interval",
+				Usage: "Interval for polling machine points",
+				Value: 1 * time.Second,
+			},
+			&cli.BoolFlag{
+				Name:  "commands-enable",
+				Usage: "Enable the durable command queue (requires JetStream)",
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:  "config-enable",
+				Usage: "Enable the configuration push from the cloud (requires JetStream)",
+				Value: false,
+			},
+			&cli.DurationFlag{
+				Name:  "heartbeat-interval",
+				Usage: "Interval for announcing the edge to the fleet",
+				Value: fleet.DefaultHeartbeatInterval,
+			},
+			&cli.BoolFlag{
+				Name:  "drivers-allow-unsigned",
+				Usage: "Install the driver packages without a signature when no key is trusted",
+				Value: false,
+			},
+		},
+		Commands: []*cli.Command{
+			driversCommand(),
 		},
 		Action: run,
 	}
@@ -81,21 +147,166 @@ func run(ctx context.Context, cmd *cli.Command) error {
 
 	zap.ReplaceGlobals(log) // Replace the global logger
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Initialize the tool client
 	driverPath := filepath.Join(path, "drivers")
-	executor := tool.NewCommandExecutor(driverPath)
+	executor, err := newExecutor(path)
+	if err != nil {
+		return err
+	}
+	defer executor.Close()
+
 	tool := tool.NewStdioClient(executor)
 
+	// Route the drivers served over gRPC, compiled in or to WebAssembly,
+	// the others over stdio
+	drivers, closer, err := newRouter(path, tool)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	// Restart the drivers whose binary is replaced
+	watcher := watch.NewWatcher(driverPath, tool)
+	watcher.Subscribe(func(event *watch.Event) {
+		log.Info("driver binary changed",
+			zap.String("driver", event.Driver),
+			zap.String("op", string(event.Op)),
+		)
+	})
+
+	// Load the machines and poll their points
+	machines, err := machine.LoadMachines(filepath.Join(path, "machines.json"))
+	if err != nil {
+		return err
+	}
+
+	poller := machine.NewPoller(machines, drivers, cmd.Duration("poll-interval"))
+
+	// Initialize the machine service to derive the machine status
+	machineSvc, err := machine.NewService(machines)
+	if err != nil {
+		return err
+	}
+
+	poller.Subscribe(machineSvc.Update)
+
+	// Initialize the alarm service
+	defs, err := alarm.LoadDefinitions(filepath.Join(path, "alarms.json"))
+	if err != nil {
+		return err
+	}
+
+	alarms, err := alarm.NewService(defs)
+	if err != nil {
+		return err
+	}
+
+	poller.Subscribe(alarms.Evaluate)
+
+	// Initialize the historian
+	historianCfg, err := historian.LoadConfig(filepath.Join(path, "historian.json"))
+	if err != nil {
+		return err
+	}
+
+	history, err := historian.NewService(filepath.Join(path, "historian"), historianCfg)
+	if err != nil {
+		return err
+	}
+	defer history.Close()
+
+	// The goroutines using the services are waited for before the services
+	// are closed; the failures returning early cancel them first.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	spawn := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	poller.Subscribe(history.Record)
+
+	spawn(func() { history.Run(ctx) })
+
+	// Initialize the production tracking
+	productionCfg, err := production.LoadConfig(filepath.Join(path, "production.json"))
+	if err != nil {
+		return err
+	}
+
+	productionSvc := production.NewService(productionCfg)
+
+	poller.Subscribe(productionSvc.Update)
+	machineSvc.Subscribe(productionSvc.UpdateStatus)
+
+	spawn(func() { productionSvc.Run(ctx) })
+
+	// Install the driver packages signed by the trusted keys; without any,
+	// only the unsigned ones if allowed
+	keys, err := install.LoadTrustedKeys(filepath.Join(path, "trusted_keys"))
+	if err != nil {
+		return err
+	}
+
+	installer := install.NewInstaller(driverPath, tool, install.Trust{
+		Keys:          keys,
+		AllowUnsigned: cmd.Bool("drivers-allow-unsigned"),
+	})
+
 	// Create a new IIoT service
-	svc := iiot.NewService(path, tool)
+	base := iiot.NewService(path, drivers, machineSvc, alarms, history, productionSvc, installer)
+
+	// Guard the point writes; without a policy, every write is denied
+	policy, err := safety.LoadPolicy(filepath.Join(path, "write_policy.json"))
+	if err != nil {
+		return err
+	}
+
+	var svc iiot.Service
+	svc = iiot.WriteGuardMiddleware(safety.NewGuard(policy))(base)
 	svc = iiot.LoggingMiddleware(log)(svc)
 
 	endpoints := iiot.EndpointSet{
 		CheckConnection: iiot.CheckConnectionEndpoint(svc),
 		ListDrivers:     iiot.ListDriversEndpoint(svc),
+		InstallDriver:   iiot.InstallDriverEndpoint(svc),
+		UninstallDriver: iiot.UninstallDriverEndpoint(svc),
 		Schema:          iiot.SchemaEndpoint(svc),
 		Instruction:     iiot.InstructionEndpoint(svc),
 		ReadPoints:      iiot.ReadPointsEndpoint(svc),
+
+		ListMachines:         iiot.ListMachinesEndpoint(svc),
+		MachineStatus:        iiot.MachineStatusEndpoint(svc),
+		MachineStatusHistory: iiot.MachineStatusHistoryEndpoint(svc),
+		PointValues:          iiot.PointValuesEndpoint(svc),
+		WritePoints:          iiot.WritePointsEndpoint(svc),
+		ProductionReport:     iiot.ProductionReportEndpoint(svc),
+		ProductionHistory:    iiot.ProductionHistoryEndpoint(svc),
+
+		ListAlarms:       iiot.ListAlarmsEndpoint(svc),
+		AcknowledgeAlarm: iiot.AcknowledgeAlarmEndpoint(svc),
+		ShelveAlarm:      iiot.ShelveAlarmEndpoint(svc),
+
+		ReadHistory: iiot.ReadHistoryEndpoint(svc),
+	}
+
+	// Add MCP Transport, bound to the local service
+	mcpTransport := cmd.String("mcp-transport")
+	switch mcpTransport {
+	case "", "http":
+	case "stdio":
+		// Stdout carries the MCP protocol.
+		gin.DefaultWriter = os.Stderr
+	default:
+		return errors.New("unknown mcp transport: " + mcpTransport)
 	}
 
 	// Add HTTP Transport
@@ -133,6 +344,9 @@ func run(ctx context.Context, cmd *cli.Command) error {
 		srv, err := micro.AddService(nc, micro.Config{
 			Name:    "iiot",
 			Version: Version,
+			Metadata: map[string]string{
+				pubsub.EdgeIDMetadata: edgeID,
+			},
 		})
 
 		if err != nil {
@@ -144,8 +358,129 @@ func run(ctx context.Context, cmd *cli.Command) error {
 
 		root := srv.AddGroup(topic)
 		pubsub.AddEndpoints(root, endpoints)
+
+		machineSvc.Subscribe(pubsub.StatusEventHandler(nc, topic+".machines.events"))
+		poller.Subscribe(pubsub.SamplePublisher(nc, topic+".machines.samples"))
+		alarms.Subscribe(pubsub.AlarmEventHandler(nc, topic+".alarms.events"))
+		watcher.Subscribe(pubsub.DriverEventHandler(nc, topic+".drivers.events"))
+		productionSvc.Subscribe(pubsub.ProductionReportPublisher(nc, topic+".production.reports"))
+
+		// Announce the edge to the fleet
+		source := heartbeatSource(edgeID, base, machineSvc)
+		spawn(func() {
+			fleet.Beat(ctx, cmd.Duration("heartbeat-interval"), source, pubsub.HeartbeatPublisher(nc, topic+".heartbeat"))
+		})
+
+		// Forward the recorded values, buffering them while the link is down
+		js, err := jetstream.New(nc)
+		if err != nil {
+			return err
+		}
+
+		spawn(func() { history.Forward(ctx, pubsub.HistoryPublisher(js, topic+".history")) })
+
+		// Install the driver packages uploaded to the object store
+		installer.SetObjectStore(pubsub.NewObjectStore(js))
+
+		// Run the commands queued while the edge was offline
+		if cmd.Bool("commands-enable") {
+			cc, err := pubsub.ConsumeCommands(ctx, js, edgeID, pubsub.CommandExecutor(nc, topic))
+			if err != nil {
+				return err
+			}
+			defer cc.Stop()
+		}
+
+		// Apply the configurations pushed from the cloud
+		if cmd.Bool("config-enable") {
+			agent, err := config.NewAgent(edgeID,
+				filepath.Join(path, "config"),
+				filepath.Join(path, "machines.json"),
+				config.Validators(
+					config.SchemaValidator(base.(config.RequestValidator)),
+					// The alarms and production tracking are not reloaded
+					// with the machines: keep the points they reference.
+					config.ReferenceValidator(references(defs, productionCfg)...),
+				),
+				applyMachines(drivers, machineSvc, poller),
+			)
+
+			if err != nil {
+				return err
+			}
+
+			configWatcher, err := pubsub.WatchConfig(ctx, js, edgeID, agent)
+			if err != nil {
+				return err
+			}
+			defer configWatcher.Stop()
+		}
+	}
+
+	if mcpTransport != "" {
+		// Notify the sessions of the updates of the resources they read
+		subs := mcp.NewSubscriptions()
+
+		hooks := new(server.Hooks)
+		subs.Register(hooks)
+
+		poller.Subscribe(func(ctx context.Context, sample *machine.Sample) {
+			subs.NotifySample("", sample)
+		})
+
+		machineSvc.Subscribe(func(event *machine.StatusEvent) {
+			subs.NotifyStatus("", event)
+		})
+
+		s := server.NewMCPServer("IIoT Service", Version,
+			server.WithResourceCapabilities(false, false),
+			server.WithPromptCapabilities(false),
+			server.WithHooks(hooks),
+		)
+
+		mcp.AddTools(s, endpoints, true)
+		mcp.AddResources(s, endpoints, true)
+		mcp.AddPrompts(s, endpoints, true)
+
+		switch mcpTransport {
+		case "stdio":
+			// The server reading stdin cannot be waited for: it returns at
+			// the next line read after the context is done.
+			go func() {
+				err := server.NewStdioServer(s).Listen(ctx, os.Stdin, os.Stdout)
+				if err != nil && !errors.Is(err, context.Canceled) {
+					log.Error("mcp stdio server stopped", zap.Error(err))
+				}
+			}()
+
+		case "http":
+			cfg := mcp.HTTPConfig{
+				Tokens:         mcp.ParseTokens(cmd.StringSlice("mcp-token")),
+				AllowedOrigins: cmd.StringSlice("mcp-origin"),
+			}
+
+			if len(cfg.Tokens) == 0 {
+				log.Warn("the MCP HTTP server accepts every request, serving on loopback only; set --mcp-token to serve others")
+			}
+
+			addr := cfg.ListenAddr(cmd.Int("mcp-port"))
+
+			spawn(func() {
+				if err := mcp.ServeHTTP(ctx, addr, s, cfg); err != nil {
+					log.Error("mcp http server stopped", zap.Error(err))
+				}
+			})
+		}
 	}
 
+	spawn(func() {
+		if err := watcher.Run(ctx); err != nil {
+			log.Error("driver watcher stopped", zap.Error(err))
+		}
+	})
+
+	spawn(func() { poller.Run(ctx) })
+
 	// Setup signal handling for graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -153,5 +488,88 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	sign := <-quit // Wait for a termination signal
 
 	log.Info("graceful shutdown", zap.String("signal", sign.String()))
+
+	cancel() // Stop polling and forwarding before closing the historian
 	return nil
 }
+
+// newExecutor returns the executor of the drivers of the path, sandboxed
+// when the sandbox is configured.
+func newExecutor(path string) (tool.Executor, error) {
+	driverPath := filepath.Join(path, "drivers")
+
+	cfg, err := sandbox.LoadConfig(filepath.Join(path, "sandbox.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		return tool.NewCommandExecutor(driverPath), nil
+	}
+
+	if cfg.ScratchDir == "" {
+		cfg.ScratchDir = filepath.Join(path, "scratch")
+	}
+
+	return tool.NewSandboxedExecutor(driverPath, cfg), nil
+}
+
+func heartbeatSource(edgeID string, svc iiot.Service, machines machine.Service) fleet.HeartbeatSource {
+	return func(ctx context.Context) (*fleet.Heartbeat, error) {
+		drivers, err := svc.ListDrivers(ctx)
+		if err != nil && errs.CodeOf(err) != errs.NotFound {
+			return nil, err
+		}
+
+		// Drivers the edge refuses are not reported as available.
+		names := make([]string, 0, len(drivers))
+		for _, driver := range drivers {
+			if driver.Error == "" {
+				names = append(names, driver.Name)
+			}
+		}
+
+		summaries := make([]*fleet.MachineSummary, 0)
+		for _, m := range machines.Machines() {
+			summaries = append(summaries, &fleet.MachineSummary{
+				MachineID: m.MachineID,
+				Status:    m.Status,
+			})
+		}
+
+		return &fleet.Heartbeat{
+			EdgeID:   edgeID,
+			Version:  Version,
+			Time:     time.Now(),
+			Drivers:  names,
+			Machines: summaries,
+		}, nil
+	}
+}
+
+// references lists the machines and points the alarms and production
+// tracking of the edge rely on.
+func references(defs []*alarm.Definition, cfg *production.Config) []machine.PointRef {
+	refs := make([]machine.PointRef, 0, len(defs))
+	for _, def := range defs {
+		refs = append(refs, def.Source)
+	}
+
+	for _, m := range cfg.Machines {
+		refs = append(refs, machine.PointRef{MachineID: m.MachineID})
+
+		for _, counter := range []*production.Counter{m.TotalCounter, m.GoodCounter, m.RejectCounter} {
+			if counter == nil {
+				continue
+			}
+
+			refs = append(refs, machine.PointRef{
+				MachineID:    m.MachineID,
+				ControllerID: counter.ControllerID,
+				Point:        counter.Point,
+			})
+		}
+	}
+
+	return refs
+}
@@ -2,14 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 
+	"github.com/gin-gonic/gin"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/urfave/cli/v3"
 
 	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/command"
+	"github.com/flarexio/iiot/config"
+	"github.com/flarexio/iiot/fleet"
+	"github.com/flarexio/iiot/transport/http"
 	"github.com/flarexio/iiot/transport/mcp"
 	"github.com/flarexio/iiot/transport/pubsub"
 )
@@ -34,6 +44,45 @@ func main() {
 				Usage:   "NATS user credentials file",
 				Sources: cli.EnvVars("NATS_CREDS"),
 			},
+			&cli.StringFlag{
+				Name:  "transport",
+				Usage: "MCP transport: stdio, or http for streamable HTTP and SSE",
+				Value: "stdio",
+			},
+			&cli.IntFlag{
+				Name:  "mcp-port",
+				Usage: "MCP HTTP server port (loopback only without --mcp-token)",
+				Value: 8081,
+			},
+			&cli.StringSliceFlag{
+				Name:    "mcp-token",
+				Usage:   "Bearer token accepted by the MCP HTTP server, as name:token",
+				Sources: cli.EnvVars("MCP_TOKENS"),
+			},
+			&cli.StringSliceFlag{
+				Name:  "mcp-origin",
+				Usage: "Origin allowed to call the MCP HTTP server (default: local origins)",
+			},
+			&cli.BoolFlag{
+				Name:  "commands-enable",
+				Usage: "Enable the durable command queue (requires JetStream)",
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:  "config-enable",
+				Usage: "Enable the configuration push to the edges (requires JetStream)",
+				Value: false,
+			},
+			&cli.BoolFlag{
+				Name:  "http-enable",
+				Usage: "Enable HTTP transport of the fleet",
+				Value: false,
+			},
+			&cli.IntFlag{
+				Name:  "port",
+				Usage: "HTTP server port",
+				Value: 8080,
+			},
 		},
 		Action: run,
 	}
@@ -65,51 +114,176 @@ func run(ctx context.Context, cmd *cli.Command) error {
 	var svc iiot.Service
 	svc = iiot.ProxyMiddleware(endpoints)(svc)
 
+	// Notify the sessions of the updates of the resources they read
+	subs := mcp.NewSubscriptions()
+
+	hooks := new(server.Hooks)
+	subs.Register(hooks)
+
 	s := server.NewMCPServer(
 		"IIoT Service",
 		Version,
 		server.WithToolHandlerMiddleware(mcp.InjectContextMiddleware()),
+		server.WithResourceCapabilities(false, false),
+		server.WithPromptCapabilities(false),
+		server.WithHooks(hooks),
 	)
 
-	// Add CheckConnection tool
-	{
-		endpoint := iiot.CheckConnectionEndpoint(svc)
-		handler := mcp.CheckConnectionHandler(endpoint)
-		tool := mcp.CheckConnectionTool()
-		s.AddTool(tool, handler)
+	// Discover the edges of the fleet
+	fleetSvc := fleet.NewService(pubsub.NewDiscoverer(nc, "iiot", 0), 0, svc)
+
+	sub, err := pubsub.SubscribeHeartbeats(nc, "edges.*.iiot.heartbeat", fleetSvc.Observe)
+	if err != nil {
+		return err
 	}
+	defer sub.Unsubscribe()
 
-	// Add ListDrivers tool
+	// Add ListEdges tool
 	{
-		endpoint := iiot.ListDriversEndpoint(svc)
-		handler := mcp.ListDriversHandler(endpoint)
-		tool := mcp.ListDriversTool()
+		endpoint := fleet.ListEdgesEndpoint(fleetSvc)
+		handler := mcp.ListEdgesHandler(endpoint)
+		tool := mcp.ListEdgesTool()
 		s.AddTool(tool, handler)
 	}
 
-	// Add Schema tool
+	// Add FanOut tool
 	{
-		endpoint := iiot.SchemaEndpoint(svc)
-		handler := mcp.SchemaHandler(endpoint)
-		tool := mcp.SchemaTool()
+		endpoint := fleet.FanOutEndpoint(fleetSvc)
+		handler := mcp.FanOutHandler(endpoint)
+		tool := mcp.FanOutTool()
 		s.AddTool(tool, handler)
 	}
 
-	// Add Instruction tool
-	{
-		endpoint := iiot.InstructionEndpoint(svc)
-		handler := mcp.InstructionHandler(endpoint)
-		tool := mcp.InstructionTool()
-		s.AddTool(tool, handler)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return err
 	}
 
-	// Add ReadPoints tool
-	{
-		endpoint := iiot.ReadPointsEndpoint(svc)
-		handler := mcp.ReadPointsHandler(endpoint)
-		tool := mcp.ReadPointsTool()
-		s.AddTool(tool, handler)
+	// Queue commands for offline edges
+	var commandSvc command.Service
+	if cmd.Bool("commands-enable") {
+		queue, err := pubsub.NewCommandQueue(ctx, js)
+		if err != nil {
+			return err
+		}
+
+		commandSvc = command.NewService(queue)
+
+		// Add SubmitCommand tool
+		{
+			endpoint := command.SubmitEndpoint(commandSvc)
+			handler := mcp.SubmitCommandHandler(endpoint)
+			tool := mcp.SubmitCommandTool()
+			s.AddTool(tool, handler)
+		}
+
+		// Add CommandResult tool
+		{
+			endpoint := command.ResultEndpoint(commandSvc)
+			handler := mcp.CommandResultHandler(endpoint)
+			tool := mcp.CommandResultTool()
+			s.AddTool(tool, handler)
+		}
 	}
 
-	return server.ServeStdio(s)
+	// Push configurations to the edges
+	var configSvc config.Service
+	if cmd.Bool("config-enable") {
+		store, err := pubsub.NewConfigStore(ctx, js)
+		if err != nil {
+			return err
+		}
+
+		configSvc = config.NewService(store)
+
+		// Add PushConfig tool
+		{
+			endpoint := config.PushEndpoint(configSvc)
+			handler := mcp.PushConfigHandler(endpoint)
+			tool := mcp.PushConfigTool()
+			s.AddTool(tool, handler)
+		}
+
+		// Add RollbackConfig tool
+		{
+			endpoint := config.RollbackEndpoint(configSvc)
+			handler := mcp.RollbackConfigHandler(endpoint)
+			tool := mcp.RollbackConfigTool()
+			s.AddTool(tool, handler)
+		}
+
+		// Add ConfigState tool
+		{
+			endpoint := config.StateEndpoint(configSvc)
+			handler := mcp.ConfigStateHandler(endpoint)
+			tool := mcp.ConfigStateTool()
+			s.AddTool(tool, handler)
+		}
+	}
+
+	// Add HTTP Transport
+	if cmd.Bool("http-enable") {
+		port := cmd.Int("port")
+
+		// Stdout carries the MCP protocol.
+		gin.DefaultWriter = os.Stderr
+
+		r := gin.Default()
+		http.AddFleetRouters(r, fleetSvc)
+
+		if commandSvc != nil {
+			http.AddCommandRouters(r, commandSvc)
+		}
+
+		if configSvc != nil {
+			http.AddConfigRouters(r, configSvc)
+		}
+
+		go r.Run(":" + strconv.Itoa(port))
+	}
+
+	// Add the tools of the edges
+	mcp.AddTools(s, *endpoints, false)
+
+	// Add the driver, machine and point resources
+	mcp.AddResources(s, *endpoints, false)
+
+	// Add the guided workflows
+	mcp.AddPrompts(s, *endpoints, false)
+
+	samples, err := pubsub.SubscribeSamples(nc, "edges.*.iiot.machines.samples.*", subs.NotifySample)
+	if err != nil {
+		return err
+	}
+	defer samples.Unsubscribe()
+
+	events, err := pubsub.SubscribeStatusEvents(nc, "edges.*.iiot.machines.events.*", subs.NotifyStatus)
+	if err != nil {
+		return err
+	}
+	defer events.Unsubscribe()
+
+	switch transport := cmd.String("transport"); transport {
+	case "stdio":
+		return server.ServeStdio(s)
+
+	case "http":
+		cfg := mcp.HTTPConfig{
+			Tokens:         mcp.ParseTokens(cmd.StringSlice("mcp-token")),
+			AllowedOrigins: cmd.StringSlice("mcp-origin"),
+		}
+
+		if len(cfg.Tokens) == 0 {
+			log.Println("warning: the MCP HTTP server accepts every request, serving on loopback only; set --mcp-token to serve others")
+		}
+
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		addr := cfg.ListenAddr(cmd.Int("mcp-port"))
+		return mcp.ServeHTTP(ctx, addr, s, cfg)
+
+	default:
+		return errors.New("unknown transport: " + transport)
+	}
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/driver/tool"
 	"github.com/flarexio/iiot/transport/pubsub"
 
 	mcptransport "github.com/flarexio/iiot/transport/mcp"
@@ -51,8 +52,7 @@ func TestMCPToolCall(t *testing.T) {
 
 	endpoint := iiot.ListDriversEndpoint(svc)
 	handler := mcptransport.ListDriversHandler(endpoint)
-	tool := mcptransport.ListDriversTool()
-	s.AddTool(tool, handler)
+	s.AddTool(mcptransport.ListDriversTool(), handler)
 
 	req := mcp.JSONRPCRequest{
 		JSONRPC: mcp.JSONRPC_VERSION,
@@ -102,12 +102,17 @@ func TestMCPToolCall(t *testing.T) {
 		return
 	}
 
-	var drivers []string
+	var drivers []*tool.Descriptor
 	if err := json.Unmarshal([]byte(content.Text), &drivers); err != nil {
 		assert.Fail(err.Error())
 		return
 	}
 
-	assert.Contains(drivers, "example")
-	assert.Contains(drivers, "modbus")
+	names := make([]string, len(drivers))
+	for i, driver := range drivers {
+		names[i] = driver.Name
+	}
+
+	assert.Contains(names, "example")
+	assert.Contains(names, "modbus")
 }
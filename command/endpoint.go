@@ -0,0 +1,31 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func SubmitEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(SubmitRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.Submit(ctx, req)
+	}
+}
+
+func ResultEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(ResultRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.Result(ctx, req.ID, time.Duration(req.Wait))
+	}
+}
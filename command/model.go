@@ -0,0 +1,153 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrCommandNotFound = errs.New(errs.NotFound, "command not found")
+	ErrInvalidCommand  = errs.New(errs.InvalidArgument, "invalid command")
+)
+
+// Status is the state of a command. A command is pending until the edge
+// has run it, or until it expires.
+type Status string
+
+const (
+	Pending   Status = "pending"
+	Succeeded Status = "succeeded"
+	Failed    Status = "failed"
+	Expired   Status = "expired"
+)
+
+// Done reports whether the status is final.
+func (s Status) Done() bool {
+	return s == Succeeded || s == Failed || s == Expired
+}
+
+// Command is a request to an endpoint of an edge (e.g., "alarms.acknowledge"
+// for edges.<edge_id>.iiot.alarms.acknowledge), queued until the edge is
+// online. The ID deduplicates the command: submitting the same ID twice runs
+// it once.
+type Command struct {
+	ID          string          `json:"id"`
+	EdgeID      string          `json:"edge_id"`
+	Endpoint    string          `json:"endpoint"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	TraceID     string          `json:"trace_id,omitempty"`
+	CreatedTime time.Time       `json:"created_time"`
+	ExpiresTime *time.Time      `json:"expires_time,omitempty"`
+}
+
+// Expired reports whether the command can no longer run at the given time.
+func (cmd *Command) Expired(now time.Time) bool {
+	return cmd.ExpiresTime != nil && !now.Before(*cmd.ExpiresTime)
+}
+
+var (
+	idPattern       = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+	endpointPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+	// The edge ID is a single token of the stream name and the subject.
+	edgeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+)
+
+func (cmd *Command) Validate() error {
+	if !idPattern.MatchString(cmd.ID) {
+		return ErrInvalidCommand.WithDetail("id", cmd.ID)
+	}
+
+	if !edgeIDPattern.MatchString(cmd.EdgeID) {
+		return ErrInvalidCommand.WithDetail("edge_id", cmd.EdgeID)
+	}
+
+	if !endpointPattern.MatchString(cmd.Endpoint) {
+		return ErrInvalidCommand.WithDetail("endpoint", cmd.Endpoint)
+	}
+
+	if len(cmd.Payload) > 0 && !json.Valid(cmd.Payload) {
+		return ErrInvalidCommand.WithDetail("payload", "invalid json")
+	}
+
+	return nil
+}
+
+// Result is the state of a command, and once it has run, the response or
+// error of the endpoint.
+type Result struct {
+	ID            string          `json:"id"`
+	EdgeID        string          `json:"edge_id"`
+	Endpoint      string          `json:"endpoint"`
+	Status        Status          `json:"status"`
+	Response      json.RawMessage `json:"response,omitempty"`
+	Error         *errs.Error     `json:"error,omitempty"`
+	Attempts      int             `json:"attempts"`
+	CreatedTime   time.Time       `json:"created_time"`
+	CompletedTime *time.Time      `json:"completed_time,omitempty"`
+}
+
+// NewResult returns the pending result of the command.
+func NewResult(cmd *Command) *Result {
+	return &Result{
+		ID:          cmd.ID,
+		EdgeID:      cmd.EdgeID,
+		Endpoint:    cmd.Endpoint,
+		Status:      Pending,
+		CreatedTime: cmd.CreatedTime,
+	}
+}
+
+// Complete records the outcome of the command.
+func (r *Result) Complete(response json.RawMessage, err error, now time.Time) {
+	r.Status = Succeeded
+	r.Response = response
+	r.CompletedTime = &now
+
+	if err != nil {
+		r.Status = Failed
+		r.Response = nil
+		r.Error = errs.From(err)
+	}
+}
+
+// Queue is the durable store of the commands and their results.
+type Queue interface {
+	// Enqueue stores the command along with its pending result. A command
+	// whose ID is already known is not queued again; its current result is
+	// returned instead.
+	Enqueue(ctx context.Context, cmd *Command) (*Result, error)
+
+	// Result returns the current result of the command.
+	Result(ctx context.Context, id string) (*Result, error)
+
+	// Watch sends the result of the command on every change until the
+	// context is done.
+	Watch(ctx context.Context, id string) (<-chan *Result, error)
+}
+
+// Executor runs a command on the edge and returns the response of its
+// endpoint.
+type Executor func(ctx context.Context, cmd *Command) (json.RawMessage, error)
+
+// SubmitRequest queues a command for an edge. The ID is generated when
+// empty; the TTL bounds how long the command waits for the edge.
+type SubmitRequest struct {
+	ID       string           `json:"id,omitempty"`
+	EdgeID   string           `json:"edge_id"`
+	Endpoint string           `json:"endpoint"`
+	Payload  json.RawMessage  `json:"payload,omitempty"`
+	TTL      machine.Duration `json:"ttl,omitempty"`
+}
+
+// ResultRequest gets the result of a command, waiting up to Wait for it to
+// complete.
+type ResultRequest struct {
+	ID   string           `json:"id"`
+	Wait machine.Duration `json:"wait,omitempty"`
+}
@@ -0,0 +1,119 @@
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/metadata"
+)
+
+// MaxWait bounds how long a result request waits for the command.
+var MaxWait = 5 * time.Minute
+
+type Service interface {
+	// Submit queues a command for an edge, which runs it once online.
+	//
+	// Args:
+	//   - req: the edge, the endpoint and payload, and optionally the ID and TTL
+	//
+	// Returns:
+	//   - the pending result, or the current result of a known ID
+	Submit(ctx context.Context, req SubmitRequest) (*Result, error)
+
+	// Result returns the result of a command, waiting for it to complete.
+	//
+	// Args:
+	//   - id: the command ID
+	//   - wait: how long to wait for completion; zero returns at once
+	//
+	// Returns:
+	//   - the result, pending if the command did not complete in time
+	Result(ctx context.Context, id string, wait time.Duration) (*Result, error)
+}
+
+func NewService(queue Queue) Service {
+	return &service{queue}
+}
+
+type service struct {
+	queue Queue
+}
+
+func (svc *service) Submit(ctx context.Context, req SubmitRequest) (*Result, error) {
+	if req.TTL < 0 {
+		return nil, errs.New(errs.InvalidArgument, "ttl must not be negative")
+	}
+
+	now := time.Now()
+
+	cmd := &Command{
+		ID:          req.ID,
+		EdgeID:      req.EdgeID,
+		Endpoint:    req.Endpoint,
+		Payload:     req.Payload,
+		TraceID:     metadata.TraceID(ctx),
+		CreatedTime: now,
+	}
+
+	if cmd.ID == "" {
+		cmd.ID = metadata.NewTraceID()
+	}
+
+	if req.TTL > 0 {
+		expires := now.Add(time.Duration(req.TTL))
+		cmd.ExpiresTime = &expires
+	}
+
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
+
+	return svc.queue.Enqueue(ctx, cmd)
+}
+
+func (svc *service) Result(ctx context.Context, id string, wait time.Duration) (*Result, error) {
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id is required")
+	}
+
+	if wait <= 0 {
+		return svc.queue.Result(ctx, id)
+	}
+
+	wait = min(wait, MaxWait)
+
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	results, err := svc.queue.Watch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var last *Result
+	for {
+		select {
+		case <-ctx.Done():
+			if last == nil {
+				return svc.queue.Result(context.WithoutCancel(ctx), id)
+			}
+
+			return last, nil
+
+		case result, ok := <-results:
+			if !ok {
+				if last == nil {
+					return nil, ErrCommandNotFound
+				}
+
+				return last, nil
+			}
+
+			last = result
+			if result.Status.Done() {
+				return result, nil
+			}
+		}
+	}
+}
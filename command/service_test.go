@@ -0,0 +1,162 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+// memoryQueue is an in-memory queue whose results change through complete.
+type memoryQueue struct {
+	results  map[string]*Result
+	watchers map[string][]chan *Result
+	sync.Mutex
+}
+
+func newMemoryQueue() *memoryQueue {
+	return &memoryQueue{
+		results:  make(map[string]*Result),
+		watchers: make(map[string][]chan *Result),
+	}
+}
+
+func (q *memoryQueue) Enqueue(ctx context.Context, cmd *Command) (*Result, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	if result, ok := q.results[cmd.ID]; ok {
+		return result, nil
+	}
+
+	result := NewResult(cmd)
+	q.results[cmd.ID] = result
+	return result, nil
+}
+
+func (q *memoryQueue) Result(ctx context.Context, id string) (*Result, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	result, ok := q.results[id]
+	if !ok {
+		return nil, ErrCommandNotFound
+	}
+
+	return result, nil
+}
+
+func (q *memoryQueue) Watch(ctx context.Context, id string) (<-chan *Result, error) {
+	q.Lock()
+	defer q.Unlock()
+
+	ch := make(chan *Result, 2)
+	if result, ok := q.results[id]; ok {
+		ch <- result
+	}
+
+	q.watchers[id] = append(q.watchers[id], ch)
+	return ch, nil
+}
+
+func (q *memoryQueue) complete(id string, response json.RawMessage, err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	result := *q.results[id]
+	result.Complete(response, err, time.Now())
+	q.results[id] = &result
+
+	for _, ch := range q.watchers[id] {
+		ch <- &result
+	}
+}
+
+func TestSubmit(t *testing.T) {
+	assert := assert.New(t)
+
+	queue := newMemoryQueue()
+	svc := NewService(queue)
+
+	ctx := context.Background()
+
+	result, err := svc.Submit(ctx, SubmitRequest{
+		EdgeID:   "edge-1",
+		Endpoint: "alarms.acknowledge",
+		Payload:  json.RawMessage(`{"id":"A1"}`),
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.NotEmpty(result.ID)
+	assert.Equal(Pending, result.Status)
+
+	// The same ID is deduplicated.
+	again, err := svc.Submit(ctx, SubmitRequest{
+		ID:       result.ID,
+		EdgeID:   "edge-1",
+		Endpoint: "alarms.acknowledge",
+	})
+	if assert.NoError(err) {
+		assert.Same(result, again)
+	}
+
+	_, err = svc.Submit(ctx, SubmitRequest{EdgeID: "edge-1", Endpoint: "alarms.>"})
+	assert.Equal(errs.InvalidArgument, errs.CodeOf(err))
+
+	_, err = svc.Submit(ctx, SubmitRequest{ID: "a b", EdgeID: "edge-1", Endpoint: "drivers"})
+	assert.ErrorIs(err, ErrInvalidCommand)
+
+	// The edge ID is a single token of the subject.
+	for _, edgeID := range []string{"", "edge.*", "edges.>", "edge 1"} {
+		_, err = svc.Submit(ctx, SubmitRequest{EdgeID: edgeID, Endpoint: "drivers"})
+		if assert.ErrorIs(err, ErrInvalidCommand) {
+			assert.Equal(edgeID, errs.From(err).Details["edge_id"])
+		}
+	}
+}
+
+func TestResultWait(t *testing.T) {
+	assert := assert.New(t)
+
+	queue := newMemoryQueue()
+	svc := NewService(queue)
+
+	ctx := context.Background()
+
+	submitted, err := svc.Submit(ctx, SubmitRequest{
+		ID:       "cmd-1",
+		EdgeID:   "edge-1",
+		Endpoint: "drivers",
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	// Still pending once the wait is over.
+	result, err := svc.Result(ctx, submitted.ID, 10*time.Millisecond)
+	if assert.NoError(err) {
+		assert.Equal(Pending, result.Status)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		queue.complete("cmd-1", json.RawMessage(`["modbus"]`), nil)
+	}()
+
+	result, err = svc.Result(ctx, submitted.ID, time.Second)
+	if assert.NoError(err) {
+		assert.Equal(Succeeded, result.Status)
+		assert.JSONEq(`["modbus"]`, string(result.Response))
+		assert.NotNil(result.CompletedTime)
+	}
+
+	_, err = svc.Result(ctx, "unknown", 0)
+	assert.ErrorIs(err, ErrCommandNotFound)
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+// Agent applies the configurations pushed to the edge.
+type Agent interface {
+	// Current returns the configuration running on the edge.
+	Current() *Document
+
+	// Apply validates and applies the document, restoring the current
+	// configuration if it fails once applied. Documents at the current
+	// version are applied already; older ones are rejected.
+	Apply(ctx context.Context, doc *Document) *Report
+}
+
+// NewAgent returns the agent of the edge. The running document and the one
+// before it are kept in dir; the machines of the running document are also
+// written to machinesFile, which the edge loads at startup.
+func NewAgent(edgeID string, dir string, machinesFile string, validate Validator, apply ApplyFunc) (Agent, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	agent := &agent{
+		edgeID:       edgeID,
+		dir:          dir,
+		machinesFile: machinesFile,
+		validate:     validate,
+		apply:        apply,
+	}
+
+	current, err := readDocument(filepath.Join(dir, "current.json"))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		// Not configured remotely yet: start from the local machines.
+		machines, err := machine.LoadMachines(machinesFile)
+		if err != nil {
+			return nil, err
+		}
+
+		current = &Document{Machines: machines}
+	}
+
+	previous, err := readDocument(filepath.Join(dir, "previous.json"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	agent.current = current
+	agent.previous = previous
+
+	return agent, nil
+}
+
+type agent struct {
+	edgeID       string
+	dir          string
+	machinesFile string
+	validate     Validator
+	apply        ApplyFunc
+
+	current  *Document
+	previous *Document
+	sync.Mutex
+}
+
+func (a *agent) Current() *Document {
+	a.Lock()
+	defer a.Unlock()
+
+	return a.current
+}
+
+func (a *agent) Apply(ctx context.Context, doc *Document) *Report {
+	a.Lock()
+	defer a.Unlock()
+
+	current := a.current
+
+	switch {
+	case doc.Version == current.Version:
+		return a.report(doc, Applied, nil, nil)
+
+	case doc.Version < current.Version:
+		err := ErrStaleVersion.WithDetail("current_version", current.Version)
+		return a.report(doc, Rejected, nil, err)
+	}
+
+	if err := Validate(doc.Machines); err != nil {
+		return a.report(doc, Rejected, nil, err)
+	}
+
+	if a.validate != nil {
+		if err := a.validate(ctx, doc.Machines); err != nil {
+			return a.report(doc, Rejected, nil, err)
+		}
+	}
+
+	diff := NewDiff(current.Machines, doc.Machines)
+
+	warnings, err := a.run(ctx, doc.Machines)
+	if err == nil {
+		err = a.persist(doc, current)
+	}
+
+	if err != nil {
+		// Best effort: the edge must restart with the current machines.
+		writeJSON(a.machinesFile, current.Machines)
+
+		if _, rerr := a.run(ctx, current.Machines); rerr != nil {
+			return a.report(doc, Failed, diff, errors.Join(err, rerr))
+		}
+
+		return a.report(doc, RolledBack, diff, err)
+	}
+
+	a.previous = current
+	a.current = doc
+
+	report := a.report(doc, Applied, diff, nil)
+	report.Warnings = warnings
+	return report
+}
+
+// report describes the outcome of the document against the configuration
+// now running. The caller must hold the lock.
+func (a *agent) report(doc *Document, status Status, diff *Diff, err error) *Report {
+	report := &Report{
+		EdgeID:           a.edgeID,
+		Status:           status,
+		RequestedVersion: doc.Version,
+		AppliedVersion:   a.current.Version,
+		Diff:             diff,
+		Error:            errs.From(err),
+		Time:             time.Now(),
+	}
+
+	if a.previous != nil {
+		report.PreviousVersion = a.previous.Version
+	}
+
+	return report
+}
+
+// run applies a copy of the machines, as the services of the edge fill in
+// their runtime state, keeping the documents as pushed.
+func (a *agent) run(ctx context.Context, machines []*machine.Machine) ([]string, error) {
+	data, err := json.Marshal(machines)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied []*machine.Machine
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+
+	return a.apply(ctx, copied)
+}
+
+// persist writes the document as the running configuration, so that the
+// edge restarts with it.
+func (a *agent) persist(doc *Document, previous *Document) error {
+	if err := writeJSON(a.machinesFile, doc.Machines); err != nil {
+		return err
+	}
+
+	if err := writeJSON(filepath.Join(a.dir, "previous.json"), previous); err != nil {
+		return err
+	}
+
+	return writeJSON(filepath.Join(a.dir, "current.json"), doc)
+}
+
+func readDocument(filename string) (*Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// writeJSON replaces the file atomically.
+func writeJSON(filename string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filename)
+}
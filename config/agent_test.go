@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+func machines(ids ...machine.MachineID) []*machine.Machine {
+	ms := make([]*machine.Machine, len(ids))
+	for i, id := range ids {
+		ms[i] = &machine.Machine{
+			MachineID: id,
+			Controllers: []*machine.Controller{
+				{ControllerID: "PLC", Driver: "modbus", Address: "10.0.0.1:502"},
+			},
+		}
+	}
+
+	return ms
+}
+
+func TestAgent(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	machinesFile := filepath.Join(dir, "machines.json")
+
+	var running []*machine.Machine
+	apply := func(ctx context.Context, ms []*machine.Machine) ([]string, error) {
+		var warnings []string
+		for _, m := range ms {
+			switch m.MachineID {
+			case "BROKEN":
+				return nil, errors.New("driver refused")
+			case "OFFLINE":
+				warnings = append(warnings, "OFFLINE/PLC: device offline")
+			}
+		}
+
+		running = ms
+		return warnings, nil
+	}
+
+	agent, err := NewAgent("edge-1", filepath.Join(dir, "config"), machinesFile, nil, apply)
+	if !assert.NoError(err) {
+		return
+	}
+
+	ctx := context.Background()
+
+	report := agent.Apply(ctx, &Document{Version: 1, Machines: machines("CNC01", "CNC02")})
+	assert.Equal(Applied, report.Status)
+	assert.Equal(int64(1), report.AppliedVersion)
+	assert.Equal([]machine.MachineID{"CNC01", "CNC02"}, report.Diff.Added)
+	assert.Len(running, 2)
+
+	changed := machines("CNC01", "CNC03")
+	changed[0].Controllers[0].Address = "10.0.0.2:502"
+
+	report = agent.Apply(ctx, &Document{Version: 2, Machines: changed})
+	assert.Equal(Applied, report.Status)
+	assert.Equal(int64(1), report.PreviousVersion)
+	assert.Equal([]machine.MachineID{"CNC03"}, report.Diff.Added)
+	assert.Equal([]machine.MachineID{"CNC02"}, report.Diff.Removed)
+	assert.Equal([]machine.MachineID{"CNC01"}, report.Diff.Changed)
+
+	// Fails once applied: version 2 is restored.
+	report = agent.Apply(ctx, &Document{Version: 3, Machines: machines("BROKEN")})
+	assert.Equal(RolledBack, report.Status)
+	assert.Equal(int64(2), report.AppliedVersion)
+	assert.Equal(machine.MachineID("CNC03"), running[1].MachineID)
+
+	// A device offline does not hold back the configuration.
+	report = agent.Apply(ctx, &Document{Version: 4, Machines: machines("CNC01", "CNC03", "OFFLINE")})
+	assert.Equal(Applied, report.Status)
+	assert.Equal([]string{"OFFLINE/PLC: device offline"}, report.Warnings)
+
+	report = agent.Apply(ctx, &Document{Version: 5, Machines: changed})
+	assert.Equal(Applied, report.Status)
+	assert.Empty(report.Warnings)
+
+	// Invalid: nothing is applied.
+	report = agent.Apply(ctx, &Document{Version: 6, Machines: machines("CNC01", "CNC01")})
+	assert.Equal(Rejected, report.Status)
+	assert.ErrorIs(report.Error, ErrInvalidConfig)
+
+	report = agent.Apply(ctx, &Document{Version: 1, Machines: machines("CNC01")})
+	assert.Equal(Rejected, report.Status)
+	assert.ErrorIs(report.Error, ErrStaleVersion)
+
+	// The edge restarts with the applied configuration.
+	loaded, err := machine.LoadMachines(machinesFile)
+	if assert.NoError(err) {
+		assert.Len(loaded, 2)
+	}
+
+	restarted, err := NewAgent("edge-1", filepath.Join(dir, "config"), machinesFile, nil, apply)
+	if assert.NoError(err) {
+		assert.Equal(int64(5), restarted.Current().Version)
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "config", "previous.json"))
+	assert.NoError(err)
+}
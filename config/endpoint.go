@@ -0,0 +1,41 @@
+package config
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func PushEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(PushRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.Push(ctx, req)
+	}
+}
+
+func RollbackEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		edgeID, ok := request.(string)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.Rollback(ctx, edgeID)
+	}
+}
+
+func StateEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		edgeID, ok := request.(string)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.State(ctx, edgeID)
+	}
+}
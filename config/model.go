@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrInvalidConfig   = errs.New(errs.InvalidArgument, "invalid configuration")
+	ErrStaleVersion    = errs.New(errs.FailedPrecondition, "stale configuration version")
+	ErrVersionConflict = errs.New(errs.FailedPrecondition, "configuration version conflict")
+	ErrNoConfig        = errs.New(errs.NotFound, "no configuration")
+	ErrNoPrevious      = errs.New(errs.NotFound, "no previous configuration")
+)
+
+// Document is a versioned machine and driver configuration of an edge. The
+// version increases with every push, rollbacks included.
+type Document struct {
+	Version     int64              `json:"version"`
+	Machines    []*machine.Machine `json:"machines"`
+	Comment     string             `json:"comment,omitempty"`
+	RollbackOf  int64              `json:"rollback_of,omitempty"`
+	CreatedTime time.Time          `json:"created_time"`
+}
+
+// Validate checks the structure of the machines; the drivers check their
+// own options on the edge.
+func Validate(machines []*machine.Machine) error {
+	ids := make(map[machine.MachineID]struct{}, len(machines))
+
+	for _, m := range machines {
+		if m == nil || m.MachineID == "" {
+			return ErrInvalidConfig.WithDetail("reason", "machine id is required")
+		}
+
+		if _, ok := ids[m.MachineID]; ok {
+			return ErrInvalidConfig.WithDetail("reason", "duplicate machine id: "+string(m.MachineID))
+		}
+
+		ids[m.MachineID] = struct{}{}
+
+		controllers := make(map[string]struct{}, len(m.Controllers))
+		for _, c := range m.Controllers {
+			if c.ControllerID == "" || c.Driver == "" {
+				return ErrInvalidConfig.WithDetail("reason", "controller id and driver are required: "+string(m.MachineID))
+			}
+
+			if _, ok := controllers[c.ControllerID]; ok {
+				return ErrInvalidConfig.WithDetail("reason", "duplicate controller id: "+string(m.MachineID)+"/"+c.ControllerID)
+			}
+
+			controllers[c.ControllerID] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// Diff lists the machines changed by a configuration.
+type Diff struct {
+	Added   []machine.MachineID `json:"added"`
+	Removed []machine.MachineID `json:"removed"`
+	Changed []machine.MachineID `json:"changed"`
+}
+
+// NewDiff compares the configuration of the machines, ignoring their
+// runtime status.
+func NewDiff(from []*machine.Machine, to []*machine.Machine) *Diff {
+	diff := &Diff{
+		Added:   make([]machine.MachineID, 0),
+		Removed: make([]machine.MachineID, 0),
+		Changed: make([]machine.MachineID, 0),
+	}
+
+	old := make(map[machine.MachineID]any, len(from))
+	for _, m := range from {
+		old[m.MachineID] = normalize(m)
+	}
+
+	seen := make(map[machine.MachineID]struct{}, len(to))
+	for _, m := range to {
+		seen[m.MachineID] = struct{}{}
+
+		prev, ok := old[m.MachineID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, m.MachineID)
+		case !reflect.DeepEqual(prev, normalize(m)):
+			diff.Changed = append(diff.Changed, m.MachineID)
+		}
+	}
+
+	for _, m := range from {
+		if _, ok := seen[m.MachineID]; !ok {
+			diff.Removed = append(diff.Removed, m.MachineID)
+		}
+	}
+
+	return diff
+}
+
+// normalize returns the configuration of the machine as generic JSON.
+func normalize(m *machine.Machine) any {
+	copied := *m
+	copied.Status = ""
+
+	data, _ := json.Marshal(&copied)
+
+	var v any
+	json.Unmarshal(data, &v)
+	return v
+}
+
+// Status is the outcome of a configuration on the edge.
+type Status string
+
+const (
+	// Applied: the configuration is running.
+	Applied Status = "applied"
+
+	// Rejected: the configuration is invalid; nothing changed.
+	Rejected Status = "rejected"
+
+	// RolledBack: the configuration failed once applied, and the previous
+	// configuration was restored.
+	RolledBack Status = "rolled_back"
+
+	// Failed: the configuration failed, and so did the rollback.
+	Failed Status = "failed"
+)
+
+// Report is published by the edge after each configuration it receives.
+type Report struct {
+	EdgeID           string      `json:"edge_id"`
+	Status           Status      `json:"status"`
+	RequestedVersion int64       `json:"requested_version"`
+	AppliedVersion   int64       `json:"applied_version"`
+	PreviousVersion  int64       `json:"previous_version,omitempty"`
+	Diff             *Diff       `json:"diff,omitempty"`
+	Warnings         []string    `json:"warnings,omitempty"`
+	Error            *errs.Error `json:"error,omitempty"`
+	Time             time.Time   `json:"time"`
+}
+
+// State is the configuration of an edge as seen from the cloud.
+type State struct {
+	EdgeID         string    `json:"edge_id"`
+	DesiredVersion int64     `json:"desired_version"`
+	Reported       *Report   `json:"reported,omitempty"`
+	InSync         bool      `json:"in_sync"`
+	Desired        *Document `json:"desired,omitempty"`
+}
+
+// Validator checks the machines against the drivers of the edge.
+type Validator func(ctx context.Context, machines []*machine.Machine) error
+
+// ApplyFunc applies the machines to the running edge. It returns an error
+// when they cannot run, e.g. when a driver refuses them, which rolls the
+// configuration back. The problems that leave the configuration running,
+// such as a device offline, are returned as warnings.
+type ApplyFunc func(ctx context.Context, machines []*machine.Machine) (warnings []string, err error)
+
+type ReportHandler func(report *Report)
+
+// Store keeps the documents pushed to the edges and their reports.
+type Store interface {
+	// Documents returns the documents kept for the edge, oldest first.
+	Documents(ctx context.Context, edgeID string) ([]*Document, error)
+
+	// Push stores the document as the desired configuration of the edge.
+	// It fails with ErrVersionConflict if another version was pushed since
+	// the given one.
+	Push(ctx context.Context, edgeID string, doc *Document, since int64) error
+
+	// Report returns the last report of the edge.
+	Report(ctx context.Context, edgeID string) (*Report, error)
+}
+
+type PushRequest struct {
+	EdgeID   string             `json:"edge_id"`
+	Machines []*machine.Machine `json:"machines"`
+	Comment  string             `json:"comment,omitempty"`
+}
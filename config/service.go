@@ -0,0 +1,155 @@
+package config
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+type Service interface {
+	// Push publishes a new version of the configuration of an edge, which
+	// the edge validates and applies.
+	//
+	// Args:
+	//   - req: the edge, its machines with their controllers and points, and a comment
+	//
+	// Returns:
+	//   - the pushed document with its version
+	Push(ctx context.Context, req PushRequest) (*Document, error)
+
+	// Rollback pushes the configuration that ran before the one applied on
+	// the edge, as a new version.
+	//
+	// Args:
+	//   - edgeID: the edge to roll back
+	//
+	// Returns:
+	//   - the pushed document
+	Rollback(ctx context.Context, edgeID string) (*Document, error)
+
+	// State returns the desired configuration of an edge along with the last
+	// report of the edge.
+	//
+	// Args:
+	//   - edgeID: the edge
+	//
+	// Returns:
+	//   - the desired version, the reported outcome and whether they match
+	State(ctx context.Context, edgeID string) (*State, error)
+}
+
+func NewService(store Store) Service {
+	return &service{store}
+}
+
+type service struct {
+	store Store
+}
+
+func (svc *service) Push(ctx context.Context, req PushRequest) (*Document, error) {
+	if req.EdgeID == "" {
+		return nil, errs.New(errs.InvalidArgument, "edge id is required")
+	}
+
+	if err := Validate(req.Machines); err != nil {
+		return nil, err
+	}
+
+	doc := &Document{
+		Machines: req.Machines,
+		Comment:  req.Comment,
+	}
+
+	return svc.push(ctx, req.EdgeID, doc)
+}
+
+func (svc *service) push(ctx context.Context, edgeID string, doc *Document) (*Document, error) {
+	docs, err := svc.store.Documents(ctx, edgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest int64
+	if len(docs) > 0 {
+		latest = docs[len(docs)-1].Version
+	}
+
+	doc.Version = latest + 1
+	doc.CreatedTime = time.Now()
+
+	if err := svc.store.Push(ctx, edgeID, doc, latest); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (svc *service) Rollback(ctx context.Context, edgeID string) (*Document, error) {
+	if edgeID == "" {
+		return nil, errs.New(errs.InvalidArgument, "edge id is required")
+	}
+
+	report, err := svc.store.Report(ctx, edgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.PreviousVersion == 0 {
+		return nil, ErrNoPrevious
+	}
+
+	docs, err := svc.store.Documents(ctx, edgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		if doc.Version != report.PreviousVersion {
+			continue
+		}
+
+		rollback := &Document{
+			Machines:   doc.Machines,
+			Comment:    "rollback to version " + strconv.FormatInt(doc.Version, 10),
+			RollbackOf: report.AppliedVersion,
+		}
+
+		return svc.push(ctx, edgeID, rollback)
+	}
+
+	return nil, ErrNoPrevious.WithDetail("version", report.PreviousVersion)
+}
+
+func (svc *service) State(ctx context.Context, edgeID string) (*State, error) {
+	if edgeID == "" {
+		return nil, errs.New(errs.InvalidArgument, "edge id is required")
+	}
+
+	docs, err := svc.store.Documents(ctx, edgeID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &State{
+		EdgeID: edgeID,
+	}
+
+	if len(docs) > 0 {
+		state.Desired = docs[len(docs)-1]
+		state.DesiredVersion = state.Desired.Version
+	}
+
+	report, err := svc.store.Report(ctx, edgeID)
+	if err != nil && errs.CodeOf(err) != errs.NotFound {
+		return nil, err
+	}
+
+	if report != nil {
+		state.Reported = report
+		state.InSync = report.AppliedVersion == state.DesiredVersion
+	}
+
+	return state, nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+// RequestValidator checks the requests of a driver against its schema,
+// leaving the requests of the drivers without a schema to the driver (e.g.,
+// the service of the edge).
+type RequestValidator interface {
+	ValidateRequest(ctx context.Context, driver string, raw json.RawMessage) error
+}
+
+// SchemaValidator checks the read request of every controller against the
+// schema of its driver. The fields failing it are reported by controller in
+// the "errors" detail.
+func SchemaValidator(validator RequestValidator) Validator {
+	return func(ctx context.Context, machines []*machine.Machine) error {
+		problems := make(map[string]any)
+
+		for _, m := range machines {
+			for _, c := range m.Controllers {
+				ref := string(m.MachineID) + "/" + c.ControllerID
+
+				req, err := machine.ReadRequest(c)
+				if err != nil {
+					return errs.Wrap(errs.InvalidArgument, err)
+				}
+
+				err = validator.ValidateRequest(ctx, c.Driver, req)
+				switch {
+				case err == nil:
+
+				case errors.Is(err, tool.ErrInvalidRequest):
+					problems[ref] = errs.From(err).Details["errors"]
+
+				default:
+					return ErrInvalidConfig.
+						WithDetail("controller", ref).
+						WithDetail("driver", c.Driver).
+						WithDetail("reason", err.Error())
+				}
+			}
+		}
+
+		if len(problems) > 0 {
+			return ErrInvalidConfig.WithDetail("errors", problems)
+		}
+
+		return nil
+	}
+}
+
+// ReferenceValidator rejects the machines missing a point referenced by the
+// configurations of the edge that are not pushed with the machines, such as
+// its alarms and production tracking. References without a point only
+// require their machine.
+func ReferenceValidator(refs ...machine.PointRef) Validator {
+	return func(ctx context.Context, machines []*machine.Machine) error {
+		points := make(map[machine.PointRef]struct{})
+		for _, m := range machines {
+			points[machine.PointRef{MachineID: m.MachineID}] = struct{}{}
+
+			for _, c := range m.Controllers {
+				for _, p := range c.Points {
+					ref := machine.PointRef{
+						MachineID:    m.MachineID,
+						ControllerID: c.ControllerID,
+						Point:        p.Name,
+					}
+
+					points[ref] = struct{}{}
+				}
+			}
+		}
+
+		problems := make([]string, 0)
+		for _, ref := range refs {
+			if _, ok := points[ref]; ok {
+				continue
+			}
+
+			if ref.Point == "" {
+				problems = append(problems, "missing referenced machine: "+string(ref.MachineID))
+			} else {
+				problems = append(problems, "missing referenced point: "+ref.String())
+			}
+		}
+
+		if len(problems) > 0 {
+			return ErrInvalidConfig.WithDetail("errors", problems)
+		}
+
+		return nil
+	}
+}
+
+// Validators runs the validators in order, until one rejects the machines.
+func Validators(validators ...Validator) Validator {
+	return func(ctx context.Context, machines []*machine.Machine) error {
+		for _, validate := range validators {
+			if err := validate(ctx, machines); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+func TestReferenceValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	ms := machines("CNC-01", "CNC-02")
+	ms[0].Controllers[0].Points = []*machine.Point{{Name: "temperature"}}
+
+	validate := ReferenceValidator(
+		machine.PointRef{MachineID: "CNC-01", ControllerID: "PLC", Point: "temperature"},
+		machine.PointRef{MachineID: "CNC-02"},
+	)
+
+	ctx := context.Background()
+	assert.NoError(validate(ctx, ms))
+
+	// Removing a point or a machine still referenced is rejected.
+	err := validate(ctx, machines("CNC-01"))
+	assert.ErrorIs(err, ErrInvalidConfig)
+	assert.Equal([]string{
+		"missing referenced point: CNC-01/PLC/temperature",
+		"missing referenced machine: CNC-02",
+	}, errs.From(err).Details["errors"])
+}
+
+// requestValidator refuses the requests of the drivers listed, as invalid
+// or with an error of the driver.
+type requestValidator map[string]error
+
+func (v requestValidator) ValidateRequest(ctx context.Context, driver string, raw json.RawMessage) error {
+	return v[driver]
+}
+
+func TestSchemaValidator(t *testing.T) {
+	assert := assert.New(t)
+
+	fields := []*tool.FieldError{{Field: "unit_id", Type: "required", Description: "unit_id is required"}}
+
+	ms := machines("CNC-01", "CNC-02")
+	ms[1].Controllers[0].Driver = "legacy"
+
+	ctx := context.Background()
+
+	// Drivers without a schema accept every request.
+	validate := SchemaValidator(requestValidator{})
+	assert.NoError(validate(ctx, ms))
+
+	// The fields failing the schema are reported by controller.
+	validate = SchemaValidator(requestValidator{
+		"modbus": tool.ErrInvalidRequest.WithDetail("errors", fields),
+	})
+
+	err := validate(ctx, ms)
+	assert.ErrorIs(err, ErrInvalidConfig)
+	assert.Equal(map[string]any{"CNC-01/PLC": fields}, errs.From(err).Details["errors"])
+
+	// A driver failing to answer rejects the configuration.
+	validate = SchemaValidator(requestValidator{
+		"legacy": errs.New(errs.NotFound, "driver not found"),
+	})
+
+	err = validate(ctx, ms)
+	assert.ErrorIs(err, ErrInvalidConfig)
+	assert.Equal("CNC-02/PLC", errs.From(err).Details["controller"])
+}
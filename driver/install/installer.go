@@ -0,0 +1,244 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+)
+
+var (
+	ErrNoSource           = errs.New(errs.InvalidArgument, "file or object is required")
+	ErrObjectsUnavailable = errs.New(errs.Unavailable, "object store not available")
+	ErrDriverNotInstalled = errs.New(errs.NotFound, "driver not installed")
+	ErrHandshakeFailed    = errs.New(errs.FailedPrecondition, "driver handshake failed, previous version restored")
+)
+
+// HandshakeTimeout bounds the handshake with a driver just installed.
+var HandshakeTimeout = 10 * time.Second
+
+// Request locates a driver package, in a file on the edge or in an object
+// store bucket.
+type Request struct {
+	File   string `json:"file,omitempty"`
+	Bucket string `json:"bucket,omitempty"`
+	Object string `json:"object,omitempty"`
+}
+
+// ObjectStore fetches the packages stored as objects.
+type ObjectStore interface {
+	Get(ctx context.Context, bucket string, name string) (io.ReadCloser, error)
+}
+
+// Client runs the drivers installed.
+type Client interface {
+	tool.Describer
+	tool.Restarter
+}
+
+type Installer interface {
+	// Install installs the driver of the package, or upgrades it. The
+	// running driver is restarted on the new binary; if it fails the
+	// handshake, the previous version is restored. Packages are refused
+	// unless signed by a trusted key, or unsigned ones are allowed.
+	Install(ctx context.Context, req *Request) (*tool.Descriptor, error)
+
+	// Uninstall stops the driver and removes it.
+	Uninstall(ctx context.Context, name string) error
+
+	// SetObjectStore sets the store of the packages requested as objects.
+	SetObjectStore(store ObjectStore)
+}
+
+// NewInstaller returns the installer of the drivers in path, installing
+// the packages the trust accepts.
+func NewInstaller(path string, client Client, trust Trust) Installer {
+	return &installer{
+		path:   path,
+		client: client,
+		trust:  trust,
+	}
+}
+
+type installer struct {
+	path    string
+	client  Client
+	trust   Trust
+	objects ObjectStore
+	sync.Mutex
+}
+
+func (i *installer) SetObjectStore(store ObjectStore) {
+	i.Lock()
+	defer i.Unlock()
+
+	i.objects = store
+}
+
+func (i *installer) open(ctx context.Context, req *Request) (io.ReadCloser, error) {
+	switch {
+	case req.File != "":
+		f, err := os.Open(req.File)
+		if err != nil {
+			return nil, errs.Wrap(errs.NotFound, err)
+		}
+
+		return f, nil
+
+	case req.Bucket != "" && req.Object != "":
+		if i.objects == nil {
+			return nil, ErrObjectsUnavailable
+		}
+
+		return i.objects.Get(ctx, req.Bucket, req.Object)
+
+	default:
+		return nil, ErrNoSource
+	}
+}
+
+func (i *installer) Install(ctx context.Context, req *Request) (*tool.Descriptor, error) {
+	i.Lock()
+	defer i.Unlock()
+
+	r, err := i.open(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(i.path, 0755); err != nil {
+		return nil, err
+	}
+
+	// Stage in the drivers directory, so that the files are moved in place
+	// atomically. Directories are never listed as drivers.
+	staging, err := os.MkdirTemp(i.path, ".install-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	p, err := unpack(r, staging, i.trust)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := filepath.Join(staging, ".backup")
+	if err := os.Mkdir(backup, 0755); err != nil {
+		return nil, err
+	}
+
+	binary := p.binary()
+	manifest := binary + ".json"
+
+	// The running driver keeps its binary once replaced: move the files in
+	// place first, so that it is never started again on the old ones.
+	backedUp, err := move(i.path, backup, binary, manifest)
+	if err != nil {
+		return nil, i.rollback(p.info.Name, backup, backedUp, err)
+	}
+
+	if err := os.Rename(filepath.Join(staging, ManifestFile), filepath.Join(i.path, manifest)); err != nil {
+		return nil, i.rollback(p.info.Name, backup, backedUp, err)
+	}
+
+	if err := os.Rename(filepath.Join(staging, binary), filepath.Join(i.path, binary)); err != nil {
+		return nil, i.rollback(p.info.Name, backup, backedUp, err)
+	}
+
+	// Let the running driver answer its requests, and forget its handshake,
+	// before the new binary is started.
+	if err := i.client.Restart(p.info.Name); err != nil {
+		return nil, i.rollback(p.info.Name, backup, backedUp, err)
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, HandshakeTimeout)
+	defer cancel()
+
+	info, err := i.client.Info(hctx, p.info.Name)
+	if err == nil {
+		err = info.Check()
+	}
+
+	if err == nil && (info.Name != p.info.Name || info.Version != p.info.Version) {
+		err = errors.New("driver reports " + info.Name + " " + info.Version + ", manifest " + p.info.Name + " " + p.info.Version)
+	}
+
+	if err != nil {
+		return nil, i.rollback(p.info.Name, backup, backedUp, err)
+	}
+
+	return &tool.Descriptor{
+		Info:   *info,
+		Source: "handshake",
+	}, nil
+}
+
+// rollback restores the files backed up, and restarts the driver on them.
+func (i *installer) rollback(name string, backup string, files []string, cause error) error {
+	os.Remove(filepath.Join(i.path, name+"_tool"))
+	os.Remove(filepath.Join(i.path, name+"_tool.json"))
+
+	move(backup, i.path, files...)
+
+	i.client.Restart(name)
+
+	return ErrHandshakeFailed.
+		WithDetail("driver", name).
+		WithDetail("reason", cause.Error())
+}
+
+func (i *installer) Uninstall(ctx context.Context, name string) error {
+	if !validName.MatchString(name) {
+		return errs.New(errs.InvalidArgument, "invalid driver name").WithDetail("driver", name)
+	}
+
+	i.Lock()
+	defer i.Unlock()
+
+	binary := filepath.Join(i.path, name+"_tool")
+	if _, err := os.Stat(binary); err != nil {
+		return ErrDriverNotInstalled.WithDetail("driver", name)
+	}
+
+	if err := i.client.Restart(name); err != nil {
+		return err
+	}
+
+	if err := os.Remove(binary); err != nil {
+		return err
+	}
+
+	if err := os.Remove(binary + ".json"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// move moves the files that exist from src to dst, returning the files
+// moved.
+func move(src string, dst string, files ...string) ([]string, error) {
+	moved := make([]string, 0, len(files))
+	for _, name := range files {
+		err := os.Rename(filepath.Join(src, name), filepath.Join(dst, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return moved, err
+		}
+
+		moved = append(moved, name)
+	}
+
+	return moved, nil
+}
@@ -0,0 +1,203 @@
+package install
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+)
+
+// newPackage writes a package of the driver to a file, signed with the key
+// if any.
+func newPackage(t *testing.T, manifest string, binary string, key ed25519.PrivateKey) string {
+	files := map[string][]byte{
+		ManifestFile:  []byte(manifest),
+		"modbus_tool": []byte(binary),
+	}
+
+	sums := new(bytes.Buffer)
+	for _, name := range []string{ManifestFile, "modbus_tool"} {
+		sum := sha256.Sum256(files[name])
+		sums.WriteString(hex.EncodeToString(sum[:]) + "  " + name + "\n")
+	}
+
+	files[ChecksumFile] = sums.Bytes()
+
+	if key != nil {
+		sig := ed25519.Sign(key, sums.Bytes())
+		files[SignatureFile] = []byte(base64.StdEncoding.EncodeToString(sig))
+	}
+
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	for name, data := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(data))})
+		tw.Write(data)
+	}
+
+	tw.Close()
+	gz.Close()
+
+	filename := filepath.Join(t.TempDir(), "modbus.tar.gz")
+	os.WriteFile(filename, buf.Bytes(), 0644)
+
+	return filename
+}
+
+// testClient answers the handshake with the version in the binary. Like
+// the clients running the drivers, it keeps the handshake until restarted;
+// restarted drivers are started again right away, as by the poller.
+type testClient struct {
+	path     string
+	infos    map[string]*tool.Info
+	restarts int
+}
+
+func (c *testClient) Info(ctx context.Context, driver string) (*tool.Info, error) {
+	if info, ok := c.infos[driver]; ok {
+		return info, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.path, driver+"_tool"))
+	if err != nil {
+		return nil, err
+	}
+
+	if string(data) == "broken" {
+		return nil, errs.New(errs.DriverError, "driver crashed")
+	}
+
+	info := &tool.Info{Name: driver, Version: string(data), APIVersion: tool.APIVersion}
+
+	if c.infos == nil {
+		c.infos = make(map[string]*tool.Info)
+	}
+
+	c.infos[driver] = info
+	return info, nil
+}
+
+func (c *testClient) Restart(driver string) error {
+	delete(c.infos, driver)
+	c.restarts++
+
+	c.Info(context.Background(), driver)
+	return nil
+}
+
+func TestInstall(t *testing.T) {
+	assert := assert.New(t)
+
+	path := t.TempDir()
+	client := &testClient{path: path}
+	installer := NewInstaller(path, client, Trust{AllowUnsigned: true})
+	ctx := context.Background()
+
+	manifest := func(version string) string {
+		return `{"name": "modbus", "version": "` + version + `", "operations": ["read"], "api_version": 1}`
+	}
+
+	desc, err := installer.Install(ctx, &Request{File: newPackage(t, manifest("1.0.0"), "1.0.0", nil)})
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal("1.0.0", desc.Version)
+
+	// Upgrades whose driver fails the handshake are rolled back.
+	_, err = installer.Install(ctx, &Request{File: newPackage(t, manifest("1.1.0"), "broken", nil)})
+	assert.ErrorIs(err, ErrHandshakeFailed)
+
+	data, _ := os.ReadFile(filepath.Join(path, "modbus_tool"))
+	assert.Equal("1.0.0", string(data))
+
+	data, _ = os.ReadFile(filepath.Join(path, "modbus_tool.json"))
+	assert.JSONEq(manifest("1.0.0"), string(data))
+
+	// The driver was running the restored version since.
+	info, err := client.Info(ctx, "modbus")
+	if assert.NoError(err) {
+		assert.Equal("1.0.0", info.Version)
+	}
+
+	desc, err = installer.Install(ctx, &Request{File: newPackage(t, manifest("1.1.0"), "1.1.0", nil)})
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal("1.1.0", desc.Version)
+
+	// Only the driver files are left in the directory.
+	entries, _ := os.ReadDir(path)
+	assert.Len(entries, 2)
+
+	assert.NoError(installer.Uninstall(ctx, "modbus"))
+	assert.ErrorIs(installer.Uninstall(ctx, "modbus"), ErrDriverNotInstalled)
+
+	entries, _ = os.ReadDir(path)
+	assert.Empty(entries)
+}
+
+func TestInstallVerifies(t *testing.T) {
+	assert := assert.New(t)
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, other, _ := ed25519.GenerateKey(nil)
+
+	path := t.TempDir()
+	ctx := context.Background()
+
+	manifest := `{"name": "modbus", "version": "1.0.0", "operations": ["read"], "api_version": 1}`
+
+	// Without a trusted key, packages are refused unless unsigned ones are
+	// allowed.
+	installer := NewInstaller(path, &testClient{path: path}, Trust{})
+
+	_, err := installer.Install(ctx, &Request{File: newPackage(t, manifest, "1.0.0", priv)})
+	assert.ErrorIs(err, ErrUntrustedPackage)
+
+	installer = NewInstaller(path, &testClient{path: path}, Trust{
+		Keys:          []ed25519.PublicKey{pub},
+		AllowUnsigned: true,
+	})
+
+	_, err = installer.Install(ctx, &Request{File: newPackage(t, manifest, "1.0.0", nil)})
+	assert.ErrorIs(err, ErrUntrustedPackage)
+
+	_, err = installer.Install(ctx, &Request{File: newPackage(t, manifest, "1.0.0", other)})
+	assert.ErrorIs(err, ErrUntrustedPackage)
+
+	// Drivers of another API version are refused before they are installed.
+	_, err = installer.Install(ctx, &Request{File: newPackage(t, `{"name": "modbus", "api_version": 2}`, "1.0.0", priv)})
+	assert.ErrorIs(err, tool.ErrIncompatibleDriver)
+
+	_, err = installer.Install(ctx, &Request{File: newPackage(t, manifest, "1.0.0", priv)})
+	assert.NoError(err)
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "modbus_tool"), []byte("tampered"), 0755)
+
+	sum := sha256.Sum256([]byte("original"))
+	os.WriteFile(filepath.Join(dir, ChecksumFile), []byte(hex.EncodeToString(sum[:])+" *modbus_tool\n"), 0644)
+
+	err := verify(dir, []string{"modbus_tool"}, Trust{AllowUnsigned: true})
+	assert.ErrorIs(err, ErrChecksumMismatch)
+}
@@ -0,0 +1,270 @@
+// Package install installs, upgrades and removes the driver packages of the
+// edge.
+//
+// A driver package is a gzipped tarball holding, at its root:
+//   - manifest.json: the tool.Info of the driver.
+//   - <name>_tool: the driver binary.
+//   - SHA256SUMS: the checksums of the manifest and the binary, as written by sha256sum.
+//   - SHA256SUMS.sig: the base64 ed25519 signature of SHA256SUMS, by a key the edge trusts.
+//
+// The checksums only prove that the package was not corrupted, not who
+// built it: unsigned packages are refused, unless the edge allows them.
+package install
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+)
+
+const (
+	ManifestFile  = "manifest.json"
+	ChecksumFile  = "SHA256SUMS"
+	SignatureFile = "SHA256SUMS.sig"
+)
+
+// MaxFileSize bounds each file of a package.
+var MaxFileSize int64 = 256 << 20
+
+var (
+	ErrInvalidPackage   = errs.New(errs.InvalidArgument, "invalid driver package")
+	ErrChecksumMismatch = errs.New(errs.InvalidArgument, "driver package checksum mismatch")
+	ErrUntrustedPackage = errs.New(errs.PermissionDenied, "driver package not signed by a trusted key")
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Trust is what the driver packages are checked against before they are
+// installed.
+type Trust struct {
+	// Keys are the keys signing the packages; once set, every package must
+	// be signed by one of them.
+	Keys []ed25519.PublicKey
+
+	// AllowUnsigned installs the packages checked against their checksums
+	// only, when no key is trusted. Otherwise, every package is refused.
+	AllowUnsigned bool
+}
+
+// pkg is a package unpacked in a staging directory.
+type pkg struct {
+	dir  string
+	info *tool.Info
+}
+
+func (p *pkg) binary() string {
+	return p.info.Name + "_tool"
+}
+
+// unpack unpacks the package into the directory, and checks its manifest,
+// checksums and signature.
+func unpack(r io.Reader, dir string, trust Trust) (*pkg, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errs.Wrap(errs.InvalidArgument, err)
+	}
+	defer gz.Close()
+
+	files := make(map[string]bool)
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, errs.Wrap(errs.InvalidArgument, err)
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		// Packages are flat: no links, no paths.
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if hdr.Typeflag != tar.TypeReg || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			return nil, ErrInvalidPackage.WithDetail("file", hdr.Name)
+		}
+
+		if hdr.Size > MaxFileSize {
+			return nil, ErrInvalidPackage.WithDetail("file", hdr.Name).WithDetail("reason", "file too large")
+		}
+
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0755)
+		if err != nil {
+			return nil, ErrInvalidPackage.WithDetail("file", hdr.Name).WithDetail("reason", err.Error())
+		}
+
+		_, err = io.Copy(f, io.LimitReader(tr, MaxFileSize))
+		f.Close()
+
+		if err != nil {
+			return nil, errs.Wrap(errs.InvalidArgument, err)
+		}
+
+		files[name] = true
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, ErrInvalidPackage.WithDetail("reason", "missing "+ManifestFile)
+	}
+
+	var info *tool.Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, ErrInvalidPackage.WithDetail("reason", "invalid manifest: "+err.Error())
+	}
+
+	if !validName.MatchString(info.Name) {
+		return nil, ErrInvalidPackage.WithDetail("reason", "invalid driver name: "+info.Name)
+	}
+
+	p := &pkg{dir, info}
+
+	if !files[p.binary()] {
+		return nil, ErrInvalidPackage.WithDetail("reason", "missing "+p.binary())
+	}
+
+	if err := verify(dir, []string{ManifestFile, p.binary()}, trust); err != nil {
+		return nil, err
+	}
+
+	if err := info.Check(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// verify checks the checksums of the files, and the signature of the
+// checksums against the trusted keys.
+func verify(dir string, files []string, trust Trust) error {
+	sums, err := os.ReadFile(filepath.Join(dir, ChecksumFile))
+	if err != nil {
+		return ErrInvalidPackage.WithDetail("reason", "missing "+ChecksumFile)
+	}
+
+	keys := trust.Keys
+	if len(keys) == 0 && !trust.AllowUnsigned {
+		return ErrUntrustedPackage.WithDetail("reason", "no trusted keys")
+	}
+
+	if len(keys) > 0 {
+		data, err := os.ReadFile(filepath.Join(dir, SignatureFile))
+		if err != nil {
+			return ErrUntrustedPackage.WithDetail("reason", "missing "+SignatureFile)
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return ErrUntrustedPackage.WithDetail("reason", "invalid signature")
+		}
+
+		trusted := false
+		for _, key := range keys {
+			if ed25519.Verify(key, sums, sig) {
+				trusted = true
+				break
+			}
+		}
+
+		if !trusted {
+			return ErrUntrustedPackage
+		}
+	}
+
+	expected := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if !ok {
+			continue
+		}
+
+		// sha256sum marks binary mode with "*".
+		name = strings.TrimPrefix(strings.TrimSpace(name), "*")
+		expected[name] = strings.ToLower(sum)
+	}
+
+	for _, name := range files {
+		sum, ok := expected[name]
+		if !ok {
+			return ErrChecksumMismatch.WithDetail("file", name).WithDetail("reason", "no checksum")
+		}
+
+		actual, err := checksum(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		if actual != sum {
+			return ErrChecksumMismatch.WithDetail("file", name)
+		}
+	}
+
+	return nil
+}
+
+func checksum(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// LoadTrustedKeys loads the base64 ed25519 public keys, one per line, that
+// driver packages must be signed with. Without a key file, no key is
+// trusted.
+func LoadTrustedKeys(filename string) ([]ed25519.PublicKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	keys := make([]ed25519.PublicKey, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid trusted key: " + line)
+		}
+
+		keys = append(keys, ed25519.PublicKey(key))
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,102 @@
+package sandbox
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/flarexio/iiot/driver/tool"
+)
+
+// Table is the nftables table holding the destinations of the drivers.
+const Table = "iiot_sandbox"
+
+// chain is the nftables chain of the driver.
+func chain(driver string) string {
+	return "driver_" + strings.ReplaceAll(driver, "-", "_")
+}
+
+// rules returns the nftables script restricting the connections of the
+// processes of the cgroup, relative to the cgroup v2 root, to the
+// destinations. Host names are resolved once, when the driver starts.
+func rules(driver string, cgroup string, destinations []tool.Destination) (string, error) {
+	level := len(strings.Split(cgroup, "/"))
+	match := "socket cgroupv2 level " + strconv.Itoa(level) + ` "` + cgroup + `"`
+
+	chain := chain(driver)
+
+	var b strings.Builder
+	b.WriteString("add table inet " + Table + "\n")
+	b.WriteString("add chain inet " + Table + " " + chain + " { type filter hook output priority 0 ; policy accept ; }\n")
+	b.WriteString("flush chain inet " + Table + " " + chain + "\n")
+
+	rule := func(r string) {
+		b.WriteString("add rule inet " + Table + " " + chain + " " + match + " " + r + "\n")
+	}
+
+	rule(`oifname "lo" accept`)
+
+	for _, dest := range destinations {
+		if dest.Protocol != "tcp" && dest.Protocol != "udp" {
+			return "", ErrInvalidDestination.WithDetail("protocol", dest.Protocol)
+		}
+
+		if dest.Port < 0 || dest.Port > 65535 {
+			return "", ErrInvalidDestination.WithDetail("port", dest.Port)
+		}
+
+		prefixes, err := resolve(dest.Address)
+		if err != nil {
+			return "", err
+		}
+
+		proto := "meta l4proto " + dest.Protocol
+		if dest.Port > 0 {
+			proto = dest.Protocol + " dport " + strconv.Itoa(dest.Port)
+		}
+
+		for _, prefix := range prefixes {
+			family := "ip"
+			if prefix.Addr().Is6() {
+				family = "ip6"
+			}
+
+			rule(family + " daddr " + prefix.String() + " " + proto + " accept")
+		}
+	}
+
+	rule("drop")
+
+	return b.String(), nil
+}
+
+// resolve returns the prefixes of the address: a CIDR, an IP address or a
+// host name.
+func resolve(address string) ([]netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(address); err == nil {
+		return []netip.Prefix{prefix.Masked()}, nil
+	}
+
+	if addr, err := netip.ParseAddr(address); err == nil {
+		return []netip.Prefix{netip.PrefixFrom(addr, addr.BitLen())}, nil
+	}
+
+	ips, err := net.LookupIP(address)
+	if err != nil {
+		return nil, ErrInvalidDestination.WithDetail("address", address).WithDetail("reason", err.Error())
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(ips))
+	for _, ip := range ips {
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
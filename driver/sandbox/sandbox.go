@@ -0,0 +1,112 @@
+// Package sandbox isolates the driver processes from the host: a dedicated
+// user, cgroup v2 limits, a seccomp filter, a read-only filesystem but for
+// a scratch directory, the network destinations declared in the driver
+// manifest only, and an environment scrubbed of the host secrets.
+//
+// Sandboxed drivers are started through the host binary, which must call
+// Init first thing in main.
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+)
+
+var (
+	ErrUnsupported        = errs.New(errs.FailedPrecondition, "driver sandbox not supported on this platform")
+	ErrInvalidDestination = errs.New(errs.InvalidArgument, "invalid driver destination")
+)
+
+// Config configures the sandbox of the drivers, as read from sandbox.json.
+type Config struct {
+	// UID and GID are the user and group the drivers run as.
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+
+	// Cgroup is the cgroup v2 directory under which each driver gets its
+	// own cgroup (e.g., "/sys/fs/cgroup/iiot"). The limits and network
+	// destinations require it.
+	Cgroup string  `json:"cgroup,omitempty"`
+	CPU    float64 `json:"cpu,omitempty"`    // CPUs per driver
+	Memory int64   `json:"memory,omitempty"` // bytes per driver
+	Pids   int     `json:"pids,omitempty"`   // processes per driver
+
+	// Seccomp denies the drivers the system calls that administer the
+	// host (mount, ptrace, kernel modules, BPF, namespaces, ...).
+	Seccomp bool `json:"seccomp"`
+
+	// ReadOnly mounts the filesystem read-only, but for the scratch
+	// directory of the driver, <scratch_dir>/<driver>.
+	ReadOnly   bool   `json:"read_only"`
+	ScratchDir string `json:"scratch_dir,omitempty"`
+
+	// Env lists the host variables passed to the drivers. Any other is
+	// scrubbed.
+	Env []string `json:"env,omitempty"`
+}
+
+// LoadConfig loads the sandbox configuration. Without a file, drivers run
+// unsandboxed.
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var cfg *Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// env returns the environment of a driver: a minimal one, with the host
+// variables allowed.
+func (cfg *Config) env(scratch string) []string {
+	env := []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + scratch,
+		"TMPDIR=" + scratch,
+	}
+
+	for _, name := range cfg.Env {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+
+	return env
+}
+
+// manifest reads the manifest next to the driver binary; drivers without
+// one declare no destination.
+func manifest(path string, program string) (*tool.Info, error) {
+	name := strings.TrimSuffix(program, "_tool")
+
+	data, err := os.ReadFile(filepath.Join(path, program+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return tool.LegacyInfo(name), nil
+		}
+
+		return nil, err
+	}
+
+	var info *tool.Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, errs.Wrap(errs.InvalidArgument, err).WithDetail("driver", name)
+	}
+
+	return info, nil
+}
@@ -0,0 +1,323 @@
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/flarexio/iiot/driver/tool"
+)
+
+// CgroupRoot is where the cgroup v2 hierarchy is mounted.
+const CgroupRoot = "/sys/fs/cgroup"
+
+// initEnv carries the spec of the driver to the sandbox init.
+const initEnv = "IIOT_SANDBOX_INIT"
+
+// spec is what the sandbox init applies before executing the driver.
+type spec struct {
+	Program  string   `json:"program"`
+	UID      uint32   `json:"uid"`
+	GID      uint32   `json:"gid"`
+	Seccomp  bool     `json:"seccomp"`
+	ReadOnly bool     `json:"read_only"`
+	Scratch  string   `json:"scratch"`
+	Env      []string `json:"env"`
+}
+
+// Command returns the command starting the driver program of the path in
+// the sandbox, and the cleanup to run once it exited.
+func (cfg *Config) Command(path string, program string) (*exec.Cmd, func(), error) {
+	driver := strings.TrimSuffix(program, "_tool")
+
+	info, err := manifest(path, program)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.ScratchDir == "" {
+		return nil, nil, errors.New("sandbox scratch directory is required")
+	}
+
+	scratch := filepath.Join(cfg.ScratchDir, driver)
+	if err := os.MkdirAll(scratch, 0700); err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.Chown(scratch, int(cfg.UID), int(cfg.GID)); err != nil {
+		return nil, nil, err
+	}
+
+	s := &spec{
+		Program:  filepath.Join(path, program),
+		UID:      cfg.UID,
+		GID:      cfg.GID,
+		Seccomp:  cfg.Seccomp,
+		ReadOnly: cfg.ReadOnly,
+		Scratch:  scratch,
+		Env:      cfg.env(scratch),
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attr := &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+
+	// Drivers declaring no destination get a network of their own, with
+	// nothing to reach.
+	if len(info.Destinations) == 0 {
+		attr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	cleanup := func() {}
+
+	if cfg.Cgroup != "" {
+		dir, err := cfg.cgroup(driver)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		f, err := os.Open(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		attr.UseCgroupFD = true
+		attr.CgroupFD = int(f.Fd())
+
+		cleanup = func() {
+			f.Close()
+			os.Remove(dir)
+		}
+
+		if len(info.Destinations) > 0 {
+			rel, err := filepath.Rel(CgroupRoot, dir)
+			if err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+
+			if err := allow(driver, rel, info.Destinations); err != nil {
+				cleanup()
+				return nil, nil, err
+			}
+
+			cleanup = func() {
+				exec.Command("nft", "delete", "chain", "inet", Table, chain(driver)).Run()
+				f.Close()
+				os.Remove(dir)
+			}
+		}
+	} else if len(info.Destinations) > 0 {
+		return nil, nil, errors.New("sandbox cgroup is required to restrict the destinations of " + driver)
+	}
+
+	cmd := exec.Command("/proc/self/exe")
+	cmd.Dir = scratch
+	cmd.Env = append(s.Env, initEnv+"="+string(data))
+	cmd.SysProcAttr = attr
+
+	return cmd, cleanup, nil
+}
+
+// cgroup creates the cgroup of the driver with its limits.
+func (cfg *Config) cgroup(driver string) (string, error) {
+	controllers := "+cpu +memory +pids"
+	if err := os.WriteFile(filepath.Join(cfg.Cgroup, "cgroup.subtree_control"), []byte(controllers), 0644); err != nil {
+		return "", fmt.Errorf("enable cgroup controllers: %w", err)
+	}
+
+	dir := filepath.Join(cfg.Cgroup, driver)
+	if err := os.Mkdir(dir, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", err
+	}
+
+	limits := make(map[string]string)
+
+	if cfg.CPU > 0 {
+		period := 100000
+		limits["cpu.max"] = strconv.Itoa(int(cfg.CPU*float64(period))) + " " + strconv.Itoa(period)
+	}
+
+	if cfg.Memory > 0 {
+		limits["memory.max"] = strconv.FormatInt(cfg.Memory, 10)
+	}
+
+	if cfg.Pids > 0 {
+		limits["pids.max"] = strconv.Itoa(cfg.Pids)
+	}
+
+	for file, value := range limits {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			return "", fmt.Errorf("set %s: %w", file, err)
+		}
+	}
+
+	return dir, nil
+}
+
+// allow restricts the connections of the cgroup to the destinations.
+func allow(driver string, cgroup string, destinations []tool.Destination) error {
+	script, err := rules(driver, cgroup, destinations)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command("nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(script)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("nft: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// Init runs the sandbox init when the host binary is started as one, and
+// returns otherwise. The init applies the sandbox from within the
+// namespaces of the driver, then executes it.
+func Init() {
+	raw, ok := os.LookupEnv(initEnv)
+	if !ok {
+		return
+	}
+
+	if err := launch(raw); err != nil {
+		fmt.Fprintln(os.Stderr, "sandbox:", err)
+		os.Exit(126)
+	}
+}
+
+func launch(raw string) error {
+	// The seccomp filter and credentials must hold on the thread executing
+	// the driver.
+	runtime.LockOSThread()
+
+	var s spec
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return err
+	}
+
+	if s.ReadOnly {
+		if err := readOnly(s.Scratch); err != nil {
+			return err
+		}
+	}
+
+	if err := syscall.Setgroups(nil); err != nil {
+		return os.NewSyscallError("setgroups", err)
+	}
+
+	if err := syscall.Setgid(int(s.GID)); err != nil {
+		return os.NewSyscallError("setgid", err)
+	}
+
+	if err := syscall.Setuid(int(s.UID)); err != nil {
+		return os.NewSyscallError("setuid", err)
+	}
+
+	if s.Seccomp {
+		if err := seccomp(); err != nil {
+			return err
+		}
+	}
+
+	return syscall.Exec(s.Program, []string{s.Program}, s.Env)
+}
+
+// readOnly remounts every mount of the namespace read-only, but the
+// scratch directory.
+func readOnly(scratch string) error {
+	// Keep the remounts in the namespace of the driver.
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return os.NewSyscallError("mount", err)
+	}
+
+	if err := unix.Mount(scratch, scratch, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return os.NewSyscallError("mount", err)
+	}
+
+	mounts, err := mountPoints()
+	if err != nil {
+		return err
+	}
+
+	for _, mount := range mounts {
+		if mount == scratch || strings.HasPrefix(mount, scratch+"/") {
+			continue
+		}
+
+		// The kernel filesystems are read-only to the user of the driver
+		// already, and some refuse to be remounted.
+		if mount == "/proc" || strings.HasPrefix(mount, "/proc/") {
+			continue
+		}
+
+		var stat unix.Statfs_t
+		if err := unix.Statfs(mount, &stat); err != nil {
+			continue
+		}
+
+		// Keep the flags of the mount, which the remount would clear.
+		flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+		for st, ms := range map[int64]uintptr{
+			unix.ST_NOSUID:  unix.MS_NOSUID,
+			unix.ST_NODEV:   unix.MS_NODEV,
+			unix.ST_NOEXEC:  unix.MS_NOEXEC,
+			unix.ST_NOATIME: unix.MS_NOATIME,
+		} {
+			if int64(stat.Flags)&st != 0 {
+				flags |= ms
+			}
+		}
+
+		if err := unix.Mount("", mount, "", flags, ""); err != nil {
+			return fmt.Errorf("remount %s read-only: %w", mount, err)
+		}
+	}
+
+	return nil
+}
+
+// mountPoints lists the mount points of the namespace, parents first.
+func mountPoints() ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mounts := make([]string, 0)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+
+		mount, err := strconv.Unquote(`"` + fields[4] + `"`)
+		if err != nil {
+			mount = fields[4]
+		}
+
+		mounts = append(mounts, mount)
+	}
+
+	return mounts, scanner.Err()
+}
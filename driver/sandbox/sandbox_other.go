@@ -0,0 +1,15 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"os/exec"
+)
+
+// Command fails where the sandbox is not supported.
+func (cfg *Config) Command(path string, program string) (*exec.Cmd, func(), error) {
+	return nil, nil, ErrUnsupported
+}
+
+// Init returns: the sandbox is not supported.
+func Init() {}
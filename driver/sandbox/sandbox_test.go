@@ -0,0 +1,58 @@
+package sandbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+)
+
+func TestRules(t *testing.T) {
+	assert := assert.New(t)
+
+	script, err := rules("modbus-rtu", "iiot/modbus-rtu", []tool.Destination{
+		{Protocol: "tcp", Address: "192.168.1.10", Port: 502},
+		{Protocol: "udp", Address: "10.0.0.0/8"},
+		{Protocol: "tcp", Address: "fd00::1", Port: 4840},
+	})
+
+	if !assert.NoError(err) {
+		return
+	}
+
+	match := `add rule inet iiot_sandbox driver_modbus_rtu socket cgroupv2 level 2 "iiot/modbus-rtu" `
+
+	assert.Equal(""+
+		"add table inet iiot_sandbox\n"+
+		"add chain inet iiot_sandbox driver_modbus_rtu { type filter hook output priority 0 ; policy accept ; }\n"+
+		"flush chain inet iiot_sandbox driver_modbus_rtu\n"+
+		match+`oifname "lo" accept`+"\n"+
+		match+"ip daddr 192.168.1.10/32 tcp dport 502 accept\n"+
+		match+"ip daddr 10.0.0.0/8 meta l4proto udp accept\n"+
+		match+"ip6 daddr fd00::1/128 tcp dport 4840 accept\n"+
+		match+"drop\n",
+		script)
+
+	_, err = rules("modbus", "iiot/modbus", []tool.Destination{
+		{Protocol: "icmp", Address: "192.168.1.10"},
+	})
+
+	assert.ErrorIs(err, ErrInvalidDestination)
+}
+
+func TestEnv(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Setenv("NATS_CREDS", "secret")
+	t.Setenv("TZ", "Asia/Taipei")
+
+	cfg := &Config{Env: []string{"TZ", "LANG_UNSET"}}
+
+	assert.Equal([]string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=/scratch/modbus",
+		"TMPDIR=/scratch/modbus",
+		"TZ=Asia/Taipei",
+	}, cfg.env("/scratch/modbus"))
+}
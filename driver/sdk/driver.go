@@ -0,0 +1,57 @@
+// Package sdk runs drivers as stdio tools, or as gRPC services. A driver
+// implements Driver for its request type, and optionally Writer, Browser,
+// Prober, Subscriber and Schemer; the SDK generates the schema of the
+// request, validates every request against it, serves the methods of the
+// host and reports the operations the driver supports.
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+// Driver reads the points of a device. The request R holds the connection
+// options of the controller and the points to read, with their options, as
+// built by machine.ReadRequest.
+type Driver[R any] interface {
+	// Instruction explains how to configure the driver, for humans and agents.
+	Instruction(ctx context.Context) (string, error)
+
+	// Read reads the points of the request, returning their values in order.
+	Read(ctx context.Context, req *R) ([]any, error)
+}
+
+// Writer is implemented by the drivers that write points. The request has
+// the form of the read request, each point with its value (see
+// machine.WriteRequest).
+type Writer[R any] interface {
+	Write(ctx context.Context, req *R) error
+}
+
+// Browser is implemented by the drivers that discover the points a device
+// offers, with the connection options of the request.
+type Browser[R any] interface {
+	Browse(ctx context.Context, req *R) ([]*machine.Point, error)
+}
+
+// Prober is implemented by the drivers that check that the device of the
+// request answers, returning what it reports about itself (e.g., vendor,
+// model, firmware).
+type Prober[R any] interface {
+	Probe(ctx context.Context, req *R) (map[string]any, error)
+}
+
+// Subscriber is implemented by the drivers that stream the values of the
+// points of the request as they change, until the context is done. Only
+// the transports that stream, such as gRPC, serve subscriptions.
+type Subscriber[R any] interface {
+	Subscribe(ctx context.Context, req *R, send func(results []any) error) error
+}
+
+// Schemer is implemented by the drivers that write their schema by hand,
+// instead of having it generated from the request.
+type Schemer interface {
+	Schema(ctx context.Context) (json.RawMessage, error)
+}
@@ -0,0 +1,32 @@
+package sdk
+
+import (
+	"context"
+	"net"
+	"os/signal"
+	"syscall"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/driver/tool/grpc"
+)
+
+// RunGRPC serves the driver over gRPC at the address until the process is
+// interrupted or terminated, for the drivers running as long-lived
+// services or on another host.
+func RunGRPC[R any](addr string, info tool.Info, driver Driver[R]) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := grpc.NewGRPCServer(lis)
+	if err := Register(server, info, driver); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server.Listen(ctx)
+	return nil
+}
@@ -0,0 +1,246 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion is the JSON Schema draft of the generated schemas.
+const SchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// GenerateSchema generates the JSON schema of the request type R.
+//
+// Properties are named by their json tag, and described by the description
+// tag. The jsonschema tag takes a comma separated list of:
+//   - required: the property must be present.
+//   - minimum=N, maximum=N: bound numbers.
+//   - minLength=N, maxLength=N: bound strings.
+//   - enum=a|b|c: the only values allowed.
+//   - default=V: the value used when absent.
+//   - type=a|b: the JSON types allowed, for any values.
+//
+// Structs do not allow other properties than their fields.
+func GenerateSchema[R any](title string) (json.RawMessage, error) {
+	t := reflect.TypeFor[R]()
+
+	schema, err := reflectSchema(t, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	schema["$schema"] = SchemaVersion
+	if title != "" {
+		schema["title"] = title
+	}
+
+	return json.Marshal(schema)
+}
+
+func reflectSchema(t reflect.Type, visiting map[reflect.Type]bool) (map[string]any, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+
+	case reflect.Interface:
+		return map[string]any{}, nil
+
+	case reflect.Slice, reflect.Array:
+		items, err := reflectSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"type": "array", "items": items}, nil
+
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key: %s", t.Key())
+		}
+
+		values, err := reflectSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+
+	case reflect.Struct:
+		if visiting[t] {
+			return nil, fmt.Errorf("recursive type: %s", t)
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		properties := make(map[string]any)
+		required := make([]string, 0)
+
+		if err := reflectFields(t, properties, &required, visiting); err != nil {
+			return nil, err
+		}
+
+		schema := map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+
+		return schema, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", t)
+	}
+}
+
+func reflectFields(t reflect.Type, properties map[string]any, required *[]string, visiting map[reflect.Type]bool) error {
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		// Embedded structs add their fields, as encoding/json does.
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				if err := reflectFields(ft, properties, required, visiting); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		prop, err := reflectSchema(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+
+		if desc := field.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+
+		isRequired, err := applyTag(prop, field.Tag.Get("jsonschema"))
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+
+		if isRequired {
+			*required = append(*required, name)
+		}
+
+		properties[name] = prop
+	}
+
+	return nil
+}
+
+// applyTag applies the jsonschema tag to the schema of the property, and
+// reports whether the property is required.
+func applyTag(prop map[string]any, tag string) (bool, error) {
+	if tag == "" {
+		return false, nil
+	}
+
+	required := false
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(opt, "=")
+
+		switch key {
+		case "required":
+			required = true
+
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s: %s", key, value)
+			}
+
+			prop[key] = n
+
+		case "minLength", "maxLength":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s: %s", key, value)
+			}
+
+			prop[key] = n
+
+		case "enum":
+			values := make([]any, 0)
+			for _, v := range strings.Split(value, "|") {
+				values = append(values, parseValue(prop["type"], v))
+			}
+
+			prop["enum"] = values
+
+		case "default":
+			prop["default"] = parseValue(prop["type"], value)
+
+		case "type":
+			prop["type"] = strings.Split(value, "|")
+
+		default:
+			return false, fmt.Errorf("unknown jsonschema option: %s", key)
+		}
+	}
+
+	return required, nil
+}
+
+// parseValue parses a tag value as a value of the JSON type.
+func parseValue(typ any, s string) any {
+	switch typ {
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+
+	case "integer":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+
+	case "number":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,141 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/driver/tool/stdio"
+	"github.com/flarexio/iiot/errs"
+)
+
+type testPoint struct {
+	Name     string `json:"name" jsonschema:"required" description:"The name of the point"`
+	Register int    `json:"register" jsonschema:"required,minimum=0,maximum=65535"`
+	Order    string `json:"order,omitempty" jsonschema:"enum=big|little,default=big"`
+}
+
+type testRequest struct {
+	Address string       `json:"address" jsonschema:"required"`
+	Points  []*testPoint `json:"points"`
+	Options map[string]any
+	secret  string
+}
+
+type testDriver struct {
+	read *testRequest
+}
+
+func (d *testDriver) Instruction(ctx context.Context) (string, error) {
+	return "Test instruction", nil
+}
+
+func (d *testDriver) Read(ctx context.Context, req *testRequest) ([]any, error) {
+	d.read = req
+	return []any{1.0}, nil
+}
+
+func (d *testDriver) Probe(ctx context.Context, req *testRequest) (map[string]any, error) {
+	return map[string]any{"vendor": "test"}, nil
+}
+
+func TestGenerateSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	raw, err := GenerateSchema[testRequest]("test")
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	assert.JSONEq(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title": "test",
+		"type": "object",
+		"properties": {
+			"address": {"type": "string"},
+			"points": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"name": {"type": "string", "description": "The name of the point"},
+						"register": {"type": "integer", "minimum": 0, "maximum": 65535},
+						"order": {"type": "string", "enum": ["big", "little"], "default": "big"}
+					},
+					"required": ["name", "register"],
+					"additionalProperties": false
+				}
+			},
+			"Options": {"type": "object", "additionalProperties": {}}
+		},
+		"required": ["address"],
+		"additionalProperties": false
+	}`, string(raw))
+}
+
+// serve serves the driver over pipes, returning a function to call it.
+func serve(t *testing.T, server stdio.StdioServer) func(method string, data string) *stdio.Response {
+	in, w := io.Pipe()
+	r, out := io.Pipe()
+	server.SetIO(in, out)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go server.Listen(ctx)
+
+	decoder := json.NewDecoder(r)
+
+	return func(method string, data string) *stdio.Response {
+		bs, _ := json.Marshal(&stdio.Request{Method: method, Data: []byte(data)})
+		go w.Write(append(bs, '\n'))
+
+		var resp *stdio.Response
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+
+		return resp
+	}
+}
+
+func TestServer(t *testing.T) {
+	assert := assert.New(t)
+
+	driver := new(testDriver)
+
+	server, err := NewServer(tool.Info{Name: "test", Version: "1.2.0"}, driver)
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	call := serve(t, server)
+
+	resp := call("driver.info", "")
+	var info tool.Info
+	json.Unmarshal(resp.Result, &info)
+	assert.Equal("1.2.0", info.Version)
+	assert.Equal([]tool.Operation{tool.OpRead, tool.OpProbe}, info.Operations)
+
+	resp = call("driver.readPoints", `{"address": "127.0.0.1:502", "points": [{"name": "speed", "register": 40001}]}`)
+	assert.NoError(resp.Error)
+	assert.JSONEq(`[1.0]`, string(resp.Result))
+	assert.Equal(40001, driver.read.Points[0].Register)
+
+	// Invalid requests never reach the driver.
+	driver.read = nil
+
+	resp = call("driver.readPoints", `{"points": [{"name": "speed", "register": -1}]}`)
+	assert.Equal(errs.InvalidArgument, errs.CodeOf(resp.Error))
+	assert.Nil(driver.read)
+
+	// Unsupported operations are not served.
+	resp = call("driver.writePoints", `{"address": "127.0.0.1:502"}`)
+	assert.ErrorIs(resp.Error, tool.ErrMethodNotFound)
+}
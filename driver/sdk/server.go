@@ -0,0 +1,189 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os/signal"
+	"syscall"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/driver/tool/stdio"
+	"github.com/flarexio/iiot/errs"
+)
+
+// NewServer returns the stdio server of the driver, with a handler for
+// each operation the driver supports. The operations and the API version
+// of the info are set by the SDK.
+func NewServer[R any](info tool.Info, driver Driver[R]) (stdio.StdioServer, error) {
+	server := stdio.NewStdioServer()
+	if err := Register(server, info, driver); err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+// Register adds a handler to the server for each operation the driver
+// supports. Subscriptions are served by the servers that stream.
+func Register[R any](server tool.Server, info tool.Info, driver Driver[R]) error {
+	var schema json.RawMessage
+	if s, ok := driver.(Schemer); ok {
+		raw, err := s.Schema(context.Background())
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		if err := json.Compact(buf, raw); err != nil {
+			return err
+		}
+
+		schema = buf.Bytes()
+	} else {
+		raw, err := GenerateSchema[R](info.Name)
+		if err != nil {
+			return err
+		}
+
+		schema = raw
+	}
+
+	compiled, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+	if err != nil {
+		return err
+	}
+
+	// Requests are validated before they are decoded, so that the driver
+	// never sees requests the host would reject.
+	decode := func(data []byte) (*R, error) {
+		if err := tool.Validate(compiled, data); err != nil {
+			return nil, err
+		}
+
+		req := new(R)
+		if err := json.Unmarshal(data, req); err != nil {
+			return nil, errs.Wrap(errs.InvalidArgument, err)
+		}
+
+		return req, nil
+	}
+
+	server.AddHandler("driver.schema", func(ctx context.Context, data []byte) ([]byte, error) {
+		return schema, nil
+	})
+	server.AddHandler("driver.instruction", func(ctx context.Context, data []byte) ([]byte, error) {
+		instruction, err := driver.Instruction(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(instruction), nil
+	})
+
+	info.APIVersion = tool.APIVersion
+	info.Operations = []tool.Operation{tool.OpRead}
+	server.AddHandler("driver.readPoints", func(ctx context.Context, data []byte) ([]byte, error) {
+		req, err := decode(data)
+		if err != nil {
+			return nil, err
+		}
+
+		results, err := driver.Read(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(results)
+	})
+
+	if w, ok := driver.(Writer[R]); ok {
+		info.Operations = append(info.Operations, tool.OpWrite)
+		server.AddHandler("driver.writePoints", func(ctx context.Context, data []byte) ([]byte, error) {
+			req, err := decode(data)
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, w.Write(ctx, req)
+		})
+	}
+
+	if b, ok := driver.(Browser[R]); ok {
+		info.Operations = append(info.Operations, tool.OpBrowse)
+		server.AddHandler("driver.browse", func(ctx context.Context, data []byte) ([]byte, error) {
+			req, err := decode(data)
+			if err != nil {
+				return nil, err
+			}
+
+			points, err := b.Browse(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+
+			return json.Marshal(points)
+		})
+	}
+
+	if p, ok := driver.(Prober[R]); ok {
+		info.Operations = append(info.Operations, tool.OpProbe)
+		server.AddHandler("driver.probe", func(ctx context.Context, data []byte) ([]byte, error) {
+			req, err := decode(data)
+			if err != nil {
+				return nil, err
+			}
+
+			device, err := p.Probe(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+
+			return json.Marshal(device)
+		})
+	}
+
+	if s, ok := driver.(Subscriber[R]); ok {
+		if streams, ok := server.(tool.StreamServer); ok {
+			info.Operations = append(info.Operations, tool.OpSubscribe)
+			streams.AddStreamHandler("driver.subscribe", func(ctx context.Context, data []byte, send func([]byte) error) error {
+				req, err := decode(data)
+				if err != nil {
+					return err
+				}
+
+				return s.Subscribe(ctx, req, func(results []any) error {
+					result, err := json.Marshal(results)
+					if err != nil {
+						return err
+					}
+
+					return send(result)
+				})
+			})
+		}
+	}
+
+	server.AddHandler("driver.info", func(ctx context.Context, data []byte) ([]byte, error) {
+		return json.Marshal(&info)
+	})
+
+	return nil
+}
+
+// Run serves the driver over stdin and stdout until the process is
+// interrupted or terminated.
+func Run[R any](info tool.Info, driver Driver[R]) error {
+	server, err := NewServer(info, driver)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	server.Listen(ctx)
+	return nil
+}
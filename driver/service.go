@@ -2,17 +2,18 @@ package driver
 
 import (
 	"context"
-	"errors"
 
+	"github.com/flarexio/iiot/errs"
 	"github.com/flarexio/iiot/machine"
 )
 
 var (
-	ErrControllerNotFound = errors.New("controller not found")
-	ErrPointNotFound      = errors.New("point not found")
+	ErrControllerNotFound = errs.New(errs.NotFound, "controller not found")
+	ErrPointNotFound      = errs.New(errs.NotFound, "point not found")
 )
 
 type Service interface {
 	AddControllers(controllers ...*machine.Controller) error
 	ReadPoints(ctx context.Context, id string, pointNames []string) (points []any, err error)
+	WritePoints(ctx context.Context, id string, values map[string]any) error
 }
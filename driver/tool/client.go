@@ -34,3 +34,31 @@ type Client interface {
 	//   - err: nil if the operation is successful, otherwise an error.
 	ReadPoints(ctx context.Context, driver string, raw json.RawMessage) (results []any, err error)
 }
+
+// Writer is implemented by the clients that can write points through the
+// drivers. It is kept apart from Client, so that writes are only reachable
+// through the guarded service.
+type Writer interface {
+	// WritePoints writes points with the given driver using the provided request.
+	//
+	// Args:
+	//   - driver: The driver to use for writing points.
+	//   - raw: The request in JSON format to be sent to the driver, listing each point with its value.
+	// Returns:
+	//   - err: nil if every point is written, otherwise an error.
+	WritePoints(ctx context.Context, driver string, raw json.RawMessage) (err error)
+}
+
+// Subscriber is implemented by the clients that stream the results of the
+// drivers supporting subscriptions.
+type Subscriber interface {
+	// Subscribe streams the results of the driver for the request.
+	//
+	// Args:
+	//   - driver: The driver to subscribe to.
+	//   - raw: The request in JSON format to be sent to the driver.
+	//   - handler: The handler receiving each result, until the context is done.
+	// Returns:
+	//   - err: nil once the context is done or the driver ends the stream, otherwise an error.
+	Subscribe(ctx context.Context, driver string, raw json.RawMessage, handler func(result any)) (err error)
+}
@@ -0,0 +1,38 @@
+package example
+
+import (
+	"context"
+
+	"github.com/flarexio/iiot/driver"
+	"github.com/flarexio/iiot/driver/sdk"
+	drivertool "github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/driver/tool/inproc"
+)
+
+const Version = "1.0.0"
+
+// init registers the example as a driver compiled into the host.
+func init() {
+	schema, err := sdk.GenerateSchema[Request]("example")
+	if err != nil {
+		panic(err)
+	}
+
+	instruction, err := NewTool().Instruction(context.Background())
+	if err != nil {
+		panic(err)
+	}
+
+	inproc.Register(&inproc.Driver{
+		Info: drivertool.Info{
+			Name:      "example",
+			Version:   Version,
+			Protocols: []string{"example"},
+		},
+		Instruction: instruction,
+		Schema:      schema,
+		New: func() driver.Service {
+			return NewService()
+		},
+	})
+}
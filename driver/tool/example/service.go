@@ -50,6 +50,28 @@ func (svc *service) AddControllers(controllers ...*machine.Controller) error {
 	return nil
 }
 
+func (svc *service) WritePoints(ctx context.Context, id string, values map[string]any) error {
+	svc.Lock()
+	defer svc.Unlock()
+
+	c, ok := svc.controllers[id]
+	if !ok {
+		return driver.ErrControllerNotFound
+	}
+
+	for name := range values {
+		if _, ok := c.Points[name]; !ok {
+			return driver.ErrPointNotFound
+		}
+	}
+
+	for name, value := range values {
+		c.Points[name] = value
+	}
+
+	return nil
+}
+
 func (svc *service) ReadPoints(ctx context.Context, id string, pointNames []string) ([]any, error) {
 	svc.RLock()
 	defer svc.RUnlock()
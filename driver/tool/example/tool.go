@@ -3,40 +3,31 @@ package example
 import (
 	"context"
 
-	"github.com/tdewolff/minify/v2"
-	"github.com/tdewolff/minify/v2/json"
-
 	"github.com/flarexio/iiot/machine"
 )
 
+// Tool is the example driver, served with the driver SDK.
 type Tool interface {
-	Schema(ctx context.Context) ([]byte, error)
 	Instruction(ctx context.Context) (string, error)
-	ReadPoints(ctx context.Context, req *ReadPointsRequest) ([]any, error)
+	Read(ctx context.Context, req *Request) ([]any, error)
+	Write(ctx context.Context, req *Request) error
 }
 
 type Point struct {
-	Name  string
-	Value any
+	Name  string `json:"name" jsonschema:"required" description:"The name of the point"`
+	Value any    `json:"value" jsonschema:"required,type=string|number|boolean" description:"The value of the point, can be string, number or boolean"`
 }
 
-type ReadPointsRequest struct {
-	Points []*Point
+// Request lists the points to read, or to write with their new value.
+type Request struct {
+	Points []*Point `json:"points" description:"List of points to read"`
 }
 
 func NewTool() Tool {
-	m := minify.New()
-	m.AddFunc("application/json", json.Minify)
-	return &tool{m}
-}
-
-type tool struct {
-	m *minify.M
+	return &tool{}
 }
 
-func (t *tool) Schema(ctx context.Context) ([]byte, error) {
-	return t.m.Bytes("application/json", schema)
-}
+type tool struct{}
 
 func (t *tool) Instruction(ctx context.Context) (string, error) {
 	instruction := `This tool reads points from a controller named "TEMP".
@@ -54,12 +45,16 @@ func (t *tool) Instruction(ctx context.Context) (string, error) {
 				"value": 45
 			}
 		]
-	}`
+	}
+
+	Points are written with the same request, giving the new value of each
+	point. The example has no device behind it: writes are checked, then
+	discarded.`
 
 	return instruction, nil
 }
 
-func (t *tool) ReadPoints(ctx context.Context, req *ReadPointsRequest) ([]any, error) {
+func (t *tool) Read(ctx context.Context, req *Request) ([]any, error) {
 	points := make([]*machine.Point, len(req.Points))
 	for i, point := range req.Points {
 		points[i] = &machine.Point{
@@ -86,29 +81,31 @@ func (t *tool) ReadPoints(ctx context.Context, req *ReadPointsRequest) ([]any, e
 	return svc.ReadPoints(ctx, "TEMP", pointNames)
 }
 
-var schema = []byte(`{
-	"$schema": "http://json-schema.org/2020-12/schema",
-	"title": "Example Tool Schema",
-	"type": "object",
-	"properties": {
-		"points": {
-			"type": "array",
-			"items": {
-				"type": "object",
-				"properties": {
-					"name": {
-						"type": "string",
-						"description": "The name of the point"
-					},
-					"value": {
-						"type": ["string", "number", "boolean"],
-						"description": "The value of the point, can be string, number or boolean"
-					}
-				},
-				"required": ["name", "value"],
-				"additionalProperties": false
+// Write writes the points of the "TEMP" controller. The example has
+// no device behind it, so the values are checked and then discarded.
+func (t *tool) Write(ctx context.Context, req *Request) error {
+	points := make([]*machine.Point, len(req.Points))
+	values := make(map[string]any, len(req.Points))
+	for i, point := range req.Points {
+		points[i] = &machine.Point{
+			Name: point.Name,
+			Options: map[string]any{
+				"value": point.Value,
 			},
-			"description": "List of points to read"
 		}
+
+		values[point.Name] = point.Value
+	}
+
+	controller := &machine.Controller{
+		ControllerID: "TEMP",
+		Points:       points,
 	}
-}`)
+
+	svc := NewService()
+	if err := svc.AddControllers(controller); err != nil {
+		return err
+	}
+
+	return svc.WritePoints(ctx, "TEMP", values)
+}
@@ -0,0 +1,309 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	iiotmetadata "github.com/flarexio/iiot/metadata"
+)
+
+type GRPCClient interface {
+	tool.Client
+	tool.Writer
+	tool.Describer
+	tool.Subscriber
+
+	// Drivers lists the drivers of the client.
+	Drivers() []string
+
+	Close() error
+}
+
+// NewGRPCClient returns the client of the drivers served at the targets.
+// Connections are established on the first call.
+func NewGRPCClient(targets map[string]*Target, opts ...grpc.DialOption) (GRPCClient, error) {
+	c := &grpcClient{
+		conns: make(map[string]*grpc.ClientConn),
+		infos: make(map[string]*tool.Info),
+	}
+
+	for driver, target := range targets {
+		if target == nil {
+			c.Close()
+			return nil, errs.New(errs.InvalidArgument, "grpc target is required").WithDetail("driver", driver)
+		}
+
+		creds := insecure.NewCredentials()
+		if target.TLS {
+			creds = credentials.NewTLS(&tls.Config{})
+		}
+
+		dialOpts := append([]grpc.DialOption{
+			grpc.WithTransportCredentials(creds),
+			grpc.WithDefaultCallOptions(grpc.CallContentSubtype(codec{}.Name())),
+		}, opts...)
+
+		conn, err := grpc.NewClient(target.Address, dialOpts...)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+
+		c.conns[driver] = conn
+	}
+
+	return c, nil
+}
+
+type grpcClient struct {
+	conns map[string]*grpc.ClientConn
+	infos map[string]*tool.Info
+	sync.Mutex
+}
+
+func (c *grpcClient) Drivers() []string {
+	drivers := make([]string, 0, len(c.conns))
+	for driver := range c.conns {
+		drivers = append(drivers, driver)
+	}
+
+	return drivers
+}
+
+func (c *grpcClient) conn(ctx context.Context, driver string) (context.Context, *grpc.ClientConn, error) {
+	conn, ok := c.conns[driver]
+	if !ok {
+		return nil, nil, errs.New(errs.NotFound, "driver not found").WithDetail("driver", driver)
+	}
+
+	if traceID := iiotmetadata.TraceID(ctx); traceID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, TraceIDKey, traceID)
+	}
+
+	return ctx, conn, nil
+}
+
+// error decodes the error of a call. Errors the driver did not classify
+// are driver errors.
+func (c *grpcClient) error(driver string, err error) error {
+	e := errs.From(fromStatus(err))
+	if e.Code == errs.Unknown {
+		e = errs.New(errs.DriverError, e.Message)
+	}
+
+	return e.WithDetail("driver", driver)
+}
+
+func (c *grpcClient) call(ctx context.Context, driver string, method string, data []byte) ([]byte, error) {
+	ctx, conn, err := c.conn(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Request{
+		Method: method,
+		Data:   data,
+	}
+
+	resp := new(Response)
+	if err := conn.Invoke(ctx, "/"+ServiceName+"/Call", req, resp); err != nil {
+		return nil, c.error(driver, err)
+	}
+
+	return resp.Result, nil
+}
+
+// Info handshakes with the driver once, and remembers its answer.
+func (c *grpcClient) Info(ctx context.Context, driver string) (*tool.Info, error) {
+	c.Lock()
+	info, ok := c.infos[driver]
+	c.Unlock()
+
+	if ok {
+		return info, nil
+	}
+
+	result, err := c.call(ctx, driver, "driver.info", nil)
+	switch {
+	case errors.Is(err, tool.ErrMethodNotFound):
+		info = tool.LegacyInfo(driver)
+
+	case err != nil:
+		return nil, err
+
+	default:
+		if err := json.Unmarshal(result, &info); err != nil {
+			return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+		}
+	}
+
+	c.Lock()
+	c.infos[driver] = info
+	c.Unlock()
+
+	return info, nil
+}
+
+// check refuses the drivers the host cannot talk to, and the operations
+// they do not support, before calling them.
+func (c *grpcClient) check(ctx context.Context, driver string, op tool.Operation) error {
+	info, err := c.Info(ctx, driver)
+	if err != nil {
+		return err
+	}
+
+	if err := info.Check(); err != nil {
+		return err
+	}
+
+	if op != "" && !info.Supports(op) {
+		return tool.ErrUnsupportedOperation.
+			WithDetail("driver", driver).
+			WithDetail("operation", op)
+	}
+
+	return nil
+}
+
+func (c *grpcClient) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
+	if err := c.check(ctx, driver, ""); err != nil {
+		return nil, err
+	}
+
+	return c.call(ctx, driver, "driver.schema", nil)
+}
+
+func (c *grpcClient) Instruction(ctx context.Context, driver string) (string, error) {
+	if err := c.check(ctx, driver, ""); err != nil {
+		return "", err
+	}
+
+	result, err := c.call(ctx, driver, "driver.instruction", nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(result), nil
+}
+
+func (c *grpcClient) ReadPoints(ctx context.Context, driver string, raw json.RawMessage) ([]any, error) {
+	if err := c.check(ctx, driver, tool.OpRead); err != nil {
+		return nil, err
+	}
+
+	// The results are streamed in chunks, see ReadChunkSize.
+	results := make([]any, 0)
+	err := c.stream(ctx, driver, "driver.readPoints", raw, func(result []byte) error {
+		var chunk []any
+		if err := json.Unmarshal(result, &chunk); err != nil {
+			return errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+		}
+
+		results = append(results, chunk...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (c *grpcClient) WritePoints(ctx context.Context, driver string, raw json.RawMessage) error {
+	if err := c.check(ctx, driver, tool.OpWrite); err != nil {
+		return err
+	}
+
+	_, err := c.call(ctx, driver, "driver.writePoints", raw)
+	return err
+}
+
+func (c *grpcClient) Subscribe(ctx context.Context, driver string, raw json.RawMessage, handler func(result any)) error {
+	if err := c.check(ctx, driver, tool.OpSubscribe); err != nil {
+		return err
+	}
+
+	err := c.stream(ctx, driver, "driver.subscribe", raw, func(result []byte) error {
+		var r any
+		if err := json.Unmarshal(result, &r); err != nil {
+			return errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+		}
+
+		handler(r)
+		return nil
+	})
+
+	// Subscriptions end with the context of the subscriber.
+	if err != nil && ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+// stream calls the streaming method of the driver, calling fn with each
+// result until the driver ends the stream.
+func (c *grpcClient) stream(ctx context.Context, driver string, method string, data []byte, fn func(result []byte) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ctx, conn, err := c.conn(ctx, driver)
+	if err != nil {
+		return err
+	}
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+ServiceName+"/Stream")
+	if err != nil {
+		return c.error(driver, err)
+	}
+
+	req := &Request{
+		Method: method,
+		Data:   data,
+	}
+
+	if err := stream.SendMsg(req); err != nil {
+		return c.error(driver, err)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		return c.error(driver, err)
+	}
+
+	for {
+		resp := new(Response)
+		err := stream.RecvMsg(resp)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return c.error(driver, err)
+		}
+
+		if err := fn(resp.Result); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *grpcClient) Close() error {
+	var err error
+	for _, conn := range c.conns {
+		err = errors.Join(err, conn.Close())
+	}
+
+	return err
+}
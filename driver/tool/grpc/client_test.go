@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/driver/tool/tooltest"
+	"github.com/flarexio/iiot/errs"
+)
+
+// serve serves the handlers of the driver in memory, and returns its
+// client.
+func serve(t *testing.T, driver string, register func(server GRPCServer)) GRPCClient {
+	lis := bufconn.Listen(1 << 20)
+
+	server := NewGRPCServer(lis)
+	register(server)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go server.Listen(ctx)
+
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+
+	targets := map[string]*Target{
+		driver: {Address: "passthrough:///" + driver},
+	}
+
+	client, err := NewGRPCClient(targets, grpc.WithContextDialer(dialer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func TestConformance(t *testing.T) {
+	tooltest.TestTransport(t, func(t *testing.T, driver string, register func(server tool.Server)) tool.Client {
+		return serve(t, driver, func(server GRPCServer) { register(server) })
+	})
+}
+
+func TestClientDriverNotFound(t *testing.T) {
+	client := serve(t, "modbus", func(server GRPCServer) {})
+
+	_, err := client.ReadPoints(context.Background(), "opcua", json.RawMessage(`{}`))
+	assert.Equal(t, errs.NotFound, errs.CodeOf(err))
+}
+
+func TestLoadTargets(t *testing.T) {
+	assert := assert.New(t)
+
+	filename := filepath.Join(t.TempDir(), "grpc_drivers.json")
+
+	os.WriteFile(filename, []byte(`{"modbus": {"address": "localhost:50051"}}`), 0644)
+
+	targets, err := LoadTargets(filename)
+	if assert.NoError(err) {
+		assert.Equal("localhost:50051", targets["modbus"].Address)
+	}
+
+	// Every driver needs its target.
+	os.WriteFile(filename, []byte(`{"modbus": null}`), 0644)
+
+	_, err = LoadTargets(filename)
+	assert.Error(err)
+
+	_, err = NewGRPCClient(map[string]*Target{"modbus": nil})
+	assert.Equal(errs.InvalidArgument, errs.CodeOf(err))
+}
+
+func TestClientSubscribe(t *testing.T) {
+	assert := assert.New(t)
+
+	client := serve(t, "modbus", func(server GRPCServer) {
+		server.AddHandler("driver.info", tooltest.Info("modbus", tool.OpRead, tool.OpSubscribe))
+		server.AddStreamHandler("driver.subscribe", func(ctx context.Context, data []byte, send func([]byte) error) error {
+			for i := range 3 {
+				if err := send([]byte(`[` + string(rune('1'+i)) + `]`)); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	})
+
+	results := make([]any, 0)
+	err := client.Subscribe(context.Background(), "modbus", json.RawMessage(`{}`), func(result any) {
+		results = append(results, result)
+	})
+
+	assert.NoError(err)
+	assert.Equal([]any{[]any{1.0}, []any{2.0}, []any{3.0}}, results)
+}
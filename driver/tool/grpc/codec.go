@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// codec encodes the messages of the driver service as JSON, as the drivers
+// already speak over stdio, so that no code is generated for them. Codecs
+// are registered for the whole process: the name is specific to the driver
+// service, not to replace the codec of other services speaking JSON.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return "iiot-driver-json"
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
@@ -0,0 +1,40 @@
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// Target is the address of a driver served over gRPC, e.g. a long-running
+// service or a container on another host.
+type Target struct {
+	Address string `json:"address"`
+	TLS     bool   `json:"tls,omitempty"`
+}
+
+// LoadTargets loads the drivers served over gRPC, by driver. Without a
+// file, every driver is served over stdio.
+func LoadTargets(filename string) (map[string]*Target, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make(map[string]*Target), nil
+		}
+
+		return nil, err
+	}
+
+	var targets map[string]*Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, err
+	}
+
+	for driver, target := range targets {
+		if target == nil || target.Address == "" {
+			return nil, errors.New("grpc driver " + driver + ": address is required")
+		}
+	}
+
+	return targets, nil
+}
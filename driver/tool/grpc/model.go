@@ -0,0 +1,110 @@
+package grpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+// Request is a call to a driver. The deadline and the cancellation of the
+// caller are carried by gRPC, its trace ID by the metadata.
+type Request struct {
+	Method string `json:"method"`
+	Data   []byte `json:"data,omitempty"`
+}
+
+type Response struct {
+	Result []byte `json:"result,omitempty"`
+}
+
+// TraceIDKey is the metadata key carrying the trace ID of the caller.
+const TraceIDKey = "x-trace-id"
+
+var codeMap = map[errs.Code]codes.Code{
+	errs.Unknown:            codes.Unknown,
+	errs.InvalidArgument:    codes.InvalidArgument,
+	errs.NotFound:           codes.NotFound,
+	errs.FailedPrecondition: codes.FailedPrecondition,
+	errs.PermissionDenied:   codes.PermissionDenied,
+	errs.Unauthenticated:    codes.Unauthenticated,
+	errs.ResourceExhausted:  codes.ResourceExhausted,
+	errs.Unavailable:        codes.Unavailable,
+	errs.Timeout:            codes.DeadlineExceeded,
+	errs.Canceled:           codes.Canceled,
+	errs.DriverError:        codes.Aborted,
+	errs.Internal:           codes.Internal,
+}
+
+// toStatus renders the error as a gRPC status, carrying its code and
+// details so that it is decoded back as the same error.
+func toStatus(err error) error {
+	e := errs.From(err)
+
+	code, ok := codeMap[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	st := status.New(code, e.Message)
+
+	// The details go through JSON to only hold values a struct takes.
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return st.Err()
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return st.Err()
+	}
+
+	detail, err := structpb.NewStruct(fields)
+	if err != nil {
+		return st.Err()
+	}
+
+	if withDetail, err := st.WithDetails(detail); err == nil {
+		st = withDetail
+	}
+
+	return st.Err()
+}
+
+// fromStatus decodes the error of a gRPC call.
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return errs.From(err)
+	}
+
+	for _, detail := range st.Details() {
+		s, ok := detail.(*structpb.Struct)
+		if !ok {
+			continue
+		}
+
+		raw, err := s.MarshalJSON()
+		if err != nil {
+			continue
+		}
+
+		var e *errs.Error
+		if err := json.Unmarshal(raw, &e); err == nil && e.Code != "" {
+			return e
+		}
+	}
+
+	code := errs.Unknown
+	for c, grpcCode := range codeMap {
+		if grpcCode == st.Code() && c != errs.Unknown {
+			code = c
+			break
+		}
+	}
+
+	return errs.New(code, st.Message())
+}
@@ -0,0 +1,231 @@
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	iiotmetadata "github.com/flarexio/iiot/metadata"
+)
+
+// ServiceName is the gRPC service of the drivers. It calls the handlers of
+// the driver by method, as the stdio transport does.
+const ServiceName = "iiot.driver.Driver"
+
+type GRPCServer interface {
+	tool.StreamServer
+}
+
+// NewGRPCServer returns the server of the driver, serving on the listener.
+func NewGRPCServer(lis net.Listener, opts ...grpc.ServerOption) GRPCServer {
+	return &grpcServer{
+		lis:      lis,
+		opts:     opts,
+		handlers: make(map[string]tool.Handler),
+		streams:  make(map[string]tool.StreamHandler),
+	}
+}
+
+type grpcServer struct {
+	lis      net.Listener
+	opts     []grpc.ServerOption
+	handlers map[string]tool.Handler
+	streams  map[string]tool.StreamHandler
+	sync.RWMutex
+}
+
+func (s *grpcServer) AddHandler(method string, handler tool.Handler) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.handlers[method]; ok {
+		return tool.ErrHandlerAlreadyExists
+	}
+
+	s.handlers[method] = handler
+	return nil
+}
+
+func (s *grpcServer) AddStreamHandler(method string, handler tool.StreamHandler) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.streams[method]; ok {
+		return tool.ErrHandlerAlreadyExists
+	}
+
+	s.streams[method] = handler
+	return nil
+}
+
+// Listen serves until the context is done, then stops once the requests in
+// progress are answered.
+func (s *grpcServer) Listen(ctx context.Context) {
+	srv := grpc.NewServer(s.opts...)
+	srv.RegisterService(&serviceDesc, s)
+
+	go func() {
+		<-ctx.Done()
+		srv.GracefulStop()
+	}()
+
+	srv.Serve(s.lis)
+}
+
+// context carries the trace ID of the caller into the context of the
+// handler.
+func (s *grpcServer) context(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	if values := md.Get(TraceIDKey); len(values) > 0 {
+		ctx = iiotmetadata.WithTraceID(ctx, values[0])
+	}
+
+	return ctx
+}
+
+func (s *grpcServer) call(ctx context.Context, req *Request) (*Response, error) {
+	s.RLock()
+	handler, ok := s.handlers[req.Method]
+	s.RUnlock()
+
+	if !ok {
+		return nil, toStatus(tool.ErrMethodNotFound.WithDetail("method", req.Method))
+	}
+
+	result, err := handler(s.context(ctx), req.Data)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &Response{Result: result}, nil
+}
+
+func (s *grpcServer) stream(req *Request, stream grpc.ServerStream) error {
+	s.RLock()
+	handler, ok := s.streams[req.Method]
+	read, readable := s.handlers[req.Method]
+	s.RUnlock()
+
+	// Drivers reading their points at once have their results streamed in
+	// chunks; the others stream them as they are read.
+	if !ok && readable && req.Method == "driver.readPoints" {
+		handler, ok = chunked(read), true
+	}
+
+	if !ok {
+		return toStatus(tool.ErrMethodNotFound.WithDetail("method", req.Method))
+	}
+
+	send := func(result []byte) error {
+		return stream.SendMsg(&Response{Result: result})
+	}
+
+	if err := handler(s.context(stream.Context()), req.Data, send); err != nil {
+		return toStatus(err)
+	}
+
+	return nil
+}
+
+// ReadChunkSize bounds the results of a message of a streamed read, so
+// that reads are not bound by the size of a message.
+var ReadChunkSize = 256
+
+// chunked streams the results of the read handler, ReadChunkSize at a time.
+func chunked(handler tool.Handler) tool.StreamHandler {
+	return func(ctx context.Context, data []byte, send func(result []byte) error) error {
+		result, err := handler(ctx, data)
+		if err != nil {
+			return err
+		}
+
+		var results []json.RawMessage
+		if err := json.Unmarshal(result, &results); err != nil {
+			return errs.Wrap(errs.DriverError, err)
+		}
+
+		// An empty read is answered with an empty chunk.
+		for {
+			n := min(len(results), ReadChunkSize)
+
+			chunk, err := json.Marshal(results[:n])
+			if err != nil {
+				return err
+			}
+
+			if err := send(chunk); err != nil {
+				return err
+			}
+
+			results = results[n:]
+			if len(results) == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+type driverServer interface {
+	call(ctx context.Context, req *Request) (*Response, error)
+	stream(req *Request, stream grpc.ServerStream) error
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*driverServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Call",
+			Handler:    callHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       streamHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+func callHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	req := new(Request)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+
+	s := srv.(driverServer)
+	if interceptor == nil {
+		return s.call(ctx, req)
+	}
+
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/" + ServiceName + "/Call",
+	}
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return s.call(ctx, req.(*Request))
+	}
+
+	return interceptor(ctx, req, info, handler)
+}
+
+func streamHandler(srv any, stream grpc.ServerStream) error {
+	req := new(Request)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+
+	return srv.(driverServer).stream(req, stream)
+}
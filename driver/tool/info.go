@@ -0,0 +1,103 @@
+package tool
+
+import (
+	"context"
+	"slices"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+// APIVersion is the version of the protocol between the host and the
+// drivers. Drivers predating the handshake speak version 1.
+const APIVersion = 1
+
+var (
+	ErrIncompatibleDriver   = errs.New(errs.FailedPrecondition, "incompatible driver")
+	ErrUnsupportedOperation = errs.New(errs.FailedPrecondition, "operation not supported by the driver")
+)
+
+// Operation is an operation a driver supports.
+type Operation string
+
+const (
+	OpRead      Operation = "read"
+	OpWrite     Operation = "write"
+	OpBrowse    Operation = "browse"
+	OpProbe     Operation = "probe"
+	OpSubscribe Operation = "subscribe"
+)
+
+// Info describes a driver, as answered to "driver.info" or written in the
+// manifest next to its binary (e.g., "modbus_tool.json").
+type Info struct {
+	Name       string      `json:"name"`
+	Version    string      `json:"version"`
+	Vendor     string      `json:"vendor,omitempty"`
+	Protocols  []string    `json:"protocols,omitempty"`
+	Operations []Operation `json:"operations"`
+	APIVersion int         `json:"api_version"`
+
+	// Destinations are the network destinations the driver connects to,
+	// the only ones reachable once it is sandboxed.
+	Destinations []Destination `json:"destinations,omitempty"`
+}
+
+// Destination is a network destination of a driver.
+type Destination struct {
+	Protocol string `json:"protocol"` // "tcp" or "udp"
+	Address  string `json:"address"`  // host name, IP address or CIDR
+	Port     int    `json:"port,omitempty"`
+}
+
+// LegacyInfo describes the drivers that do not answer "driver.info".
+func LegacyInfo(name string) *Info {
+	return &Info{
+		Name:       name,
+		Operations: []Operation{OpRead},
+		APIVersion: 1,
+	}
+}
+
+// Supports reports whether the driver supports the operation.
+func (info *Info) Supports(op Operation) bool {
+	return slices.Contains(info.Operations, op)
+}
+
+// Check checks that the host speaks the protocol of the driver.
+func (info *Info) Check() error {
+	if info.APIVersion != APIVersion {
+		return ErrIncompatibleDriver.
+			WithDetail("driver", info.Name).
+			WithDetail("api_version", info.APIVersion).
+			WithDetail("host_api_version", APIVersion)
+	}
+
+	return nil
+}
+
+// Descriptor describes an installed driver. Drivers the host refuses carry
+// the reason.
+type Descriptor struct {
+	Info
+	Source string `json:"source"` // "manifest" or "handshake"
+	Error  string `json:"error,omitempty"`
+}
+
+// Describer is implemented by the clients that handshake with the drivers.
+type Describer interface {
+	// Info describes the driver, as answered to "driver.info".
+	//
+	// Args:
+	//   - driver: The driver to describe.
+	// Returns:
+	//   - info: The name, version, operations and protocol API version of the driver.
+	//   - err: nil if the operation is successful, otherwise an error.
+	Info(ctx context.Context, driver string) (info *Info, err error)
+}
+
+// Restarter is implemented by the clients running the drivers as processes.
+type Restarter interface {
+	// Restart stops the driver once its requests are answered, and forgets
+	// its handshake. The next request starts the driver again.
+	Restart(driver string) error
+}
@@ -0,0 +1,278 @@
+package inproc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"github.com/flarexio/iiot/driver"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var ErrDriverNotRegistered = errs.New(errs.NotFound, "driver not registered")
+
+type InprocClient interface {
+	tool.Client
+	tool.Writer
+	tool.Describer
+	tool.Restarter
+
+	// Drivers lists the drivers registered.
+	Drivers() []string
+}
+
+// NewInprocClient returns the client of the drivers registered. Each
+// driver runs a single service, created on its first request.
+func NewInprocClient() InprocClient {
+	return &inprocClient{
+		instances: make(map[string]*instance),
+	}
+}
+
+type inprocClient struct {
+	instances map[string]*instance
+	sync.Mutex
+}
+
+// instance is the service of a driver, with the controllers added to it.
+type instance struct {
+	svc         driver.Service
+	controllers map[string]*controller
+	sync.Mutex
+}
+
+// controller is a controller added to a service, known by the request
+// it was added with. Its requests are answered one at a time, so that the
+// controller is not replaced while it is read or written.
+type controller struct {
+	request string
+	sync.Mutex
+}
+
+func (c *inprocClient) Drivers() []string {
+	return Drivers()
+}
+
+func (c *inprocClient) driver(name string, op tool.Operation) (*Driver, error) {
+	d, ok := lookup(name)
+	if !ok {
+		return nil, ErrDriverNotRegistered.WithDetail("driver", name)
+	}
+
+	if op != "" && !d.Info.Supports(op) {
+		return nil, tool.ErrUnsupportedOperation.
+			WithDetail("driver", name).
+			WithDetail("operation", op)
+	}
+
+	return d, nil
+}
+
+func (c *inprocClient) Info(ctx context.Context, name string) (*tool.Info, error) {
+	d, err := c.driver(name, "")
+	if err != nil {
+		return nil, err
+	}
+
+	info := d.Info
+	return &info, nil
+}
+
+// Restart drops the service of the driver. The next request creates it
+// again, and adds its controllers.
+func (c *inprocClient) Restart(name string) error {
+	c.Lock()
+	delete(c.instances, name)
+	c.Unlock()
+
+	return nil
+}
+
+func (c *inprocClient) Schema(ctx context.Context, name string) (json.RawMessage, error) {
+	d, err := c.driver(name, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Schema, nil
+}
+
+func (c *inprocClient) Instruction(ctx context.Context, name string) (string, error) {
+	d, err := c.driver(name, "")
+	if err != nil {
+		return "", err
+	}
+
+	return d.Instruction, nil
+}
+
+func (c *inprocClient) ReadPoints(ctx context.Context, name string, raw json.RawMessage) ([]any, error) {
+	d, err := c.driver(name, tool.OpRead)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := parseRequest(raw)
+	if err != nil {
+		return nil, errs.Wrap(errs.InvalidArgument, err).WithDetail("driver", name)
+	}
+
+	ctrl, svc, err := c.controller(d, req)
+	if err != nil {
+		return nil, driverError(name, err)
+	}
+	defer ctrl.Unlock()
+
+	pointNames := make([]string, len(req.Points))
+	for i, point := range req.Points {
+		pointNames[i] = point.Name
+	}
+
+	results, err := svc.ReadPoints(ctx, req.ControllerID, pointNames)
+	if err != nil {
+		return nil, driverError(name, err)
+	}
+
+	return results, nil
+}
+
+func (c *inprocClient) WritePoints(ctx context.Context, name string, raw json.RawMessage) error {
+	d, err := c.driver(name, tool.OpWrite)
+	if err != nil {
+		return err
+	}
+
+	req, err := parseRequest(raw)
+	if err != nil {
+		return errs.Wrap(errs.InvalidArgument, err).WithDetail("driver", name)
+	}
+
+	values := make(map[string]any, len(req.Points))
+	for _, point := range req.Points {
+		value, ok := point.Options["value"]
+		if !ok {
+			return errs.New(errs.InvalidArgument, "point value is required").
+				WithDetail("driver", name).
+				WithDetail("point", point.Name)
+		}
+
+		values[point.Name] = value
+	}
+
+	ctrl, svc, err := c.controller(d, req)
+	if err != nil {
+		return driverError(name, err)
+	}
+	defer ctrl.Unlock()
+
+	if err := svc.WritePoints(ctx, req.ControllerID, values); err != nil {
+		return driverError(name, err)
+	}
+
+	return nil
+}
+
+// controller returns the controller of the request, locked, and the
+// service it is added to. The controller is added again whenever its
+// request changes.
+func (c *inprocClient) controller(d *Driver, req *machine.Controller) (*controller, driver.Service, error) {
+	c.Lock()
+	inst, ok := c.instances[d.Info.Name]
+	if !ok {
+		inst = &instance{
+			svc:         d.New(),
+			controllers: make(map[string]*controller),
+		}
+
+		c.instances[d.Info.Name] = inst
+	}
+	c.Unlock()
+
+	inst.Lock()
+	ctrl, ok := inst.controllers[req.ControllerID]
+	if !ok {
+		ctrl = new(controller)
+		inst.controllers[req.ControllerID] = ctrl
+	}
+	inst.Unlock()
+
+	ctrl.Lock()
+
+	request, _ := json.Marshal(req)
+	if ctrl.request == string(request) {
+		return ctrl, inst.svc, nil
+	}
+
+	if err := inst.svc.AddControllers(req); err != nil {
+		ctrl.request = ""
+		ctrl.Unlock()
+		return nil, nil, err
+	}
+
+	ctrl.request = string(request)
+
+	return ctrl, inst.svc, nil
+}
+
+// parseRequest parses the request built by machine.ReadRequest or
+// machine.WriteRequest back into its controller. Controllers are known by
+// their options, the same options always addressing the same controller.
+func parseRequest(raw json.RawMessage) (*machine.Controller, error) {
+	var req struct {
+		Points []map[string]any `json:"points"`
+	}
+
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return nil, err
+	}
+
+	var options map[string]any
+	if err := json.Unmarshal(raw, &options); err != nil {
+		return nil, err
+	}
+
+	delete(options, "points")
+
+	points := make([]*machine.Point, len(req.Points))
+	for i, p := range req.Points {
+		name, ok := p["name"].(string)
+		if !ok || name == "" {
+			return nil, errs.New(errs.InvalidArgument, "point name is required")
+		}
+
+		delete(p, "name")
+
+		points[i] = &machine.Point{
+			Name:    name,
+			Options: p,
+		}
+	}
+
+	// Map keys are marshaled in order, so equal options hash alike.
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return &machine.Controller{
+		ControllerID: hex.EncodeToString(sum[:8]),
+		Points:       points,
+		Options:      options,
+	}, nil
+}
+
+// driverError classifies the errors the driver did not as driver errors,
+// as the other clients do.
+func driverError(name string, err error) error {
+	if errs.CodeOf(err) == errs.Unknown {
+		return errs.Wrap(errs.DriverError, err).WithDetail("driver", name)
+	}
+
+	return err
+}
@@ -0,0 +1,115 @@
+package inproc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+// testService answers the "value" option of each point, and counts the
+// controllers added.
+type testService struct {
+	points map[string]map[string]any
+	adds   int
+}
+
+func (svc *testService) AddControllers(controllers ...*machine.Controller) error {
+	for _, controller := range controllers {
+		points := make(map[string]any)
+		for _, point := range controller.Points {
+			points[point.Name] = point.Options["value"]
+		}
+
+		svc.points[controller.ControllerID] = points
+		svc.adds++
+	}
+
+	return nil
+}
+
+func (svc *testService) ReadPoints(ctx context.Context, id string, pointNames []string) ([]any, error) {
+	results := make([]any, len(pointNames))
+	for i, name := range pointNames {
+		value, ok := svc.points[id][name]
+		if !ok {
+			return nil, driver.ErrPointNotFound
+		}
+
+		results[i] = value
+	}
+
+	return results, nil
+}
+
+func (svc *testService) WritePoints(ctx context.Context, id string, values map[string]any) error {
+	for name, value := range values {
+		svc.points[id][name] = value
+	}
+
+	return nil
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+
+	svc := &testService{points: make(map[string]map[string]any)}
+
+	Register(&Driver{
+		Info:   tool.Info{Name: "test", Version: "1.0.0"},
+		Schema: []byte(`{"type": "object"}`),
+		New: func() driver.Service {
+			return svc
+		},
+	})
+	defer unregister("test")
+
+	client := NewInprocClient()
+	ctx := context.Background()
+
+	assert.Contains(client.Drivers(), "test")
+
+	info, err := client.Info(ctx, "test")
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.NoError(info.Check())
+	assert.True(info.Supports(tool.OpWrite))
+
+	controller := &machine.Controller{
+		Options: map[string]any{"host": "10.0.0.1"},
+		Points: []*machine.Point{
+			{Name: "temperature", Options: map[string]any{"value": 22.5}},
+		},
+	}
+
+	raw, _ := machine.ReadRequest(controller)
+
+	results, err := client.ReadPoints(ctx, "test", raw)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal([]any{22.5}, results)
+
+	// The controller is added once, until its request changes.
+	client.ReadPoints(ctx, "test", raw)
+	assert.Equal(1, svc.adds)
+
+	raw, _ = machine.WriteRequest(controller, map[string]any{"temperature": 25.0})
+	assert.NoError(client.WritePoints(ctx, "test", raw))
+	assert.Equal(2, svc.adds)
+	assert.Len(svc.points, 1)
+
+	_, err = client.ReadPoints(ctx, "other", raw)
+	assert.ErrorIs(err, ErrDriverNotRegistered)
+
+	_, err = client.ReadPoints(ctx, "test", []byte(`{"points": [{"value": 1}]}`))
+	assert.Equal(errs.InvalidArgument, errs.CodeOf(err))
+}
@@ -0,0 +1,89 @@
+// Package inproc calls the drivers compiled into the host, without a
+// process or a serialization round trip per request.
+//
+// A driver package registers its driver.Service factory in init(), and the
+// host imports the package for its side effects:
+//
+//	import _ "github.com/flarexio/iiot/driver/tool/example"
+package inproc
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/flarexio/iiot/driver"
+	"github.com/flarexio/iiot/driver/tool"
+)
+
+// Factory creates the service of a driver.
+type Factory func() driver.Service
+
+// Driver is a driver compiled into the host.
+type Driver struct {
+	Info        tool.Info
+	Instruction string
+	Schema      json.RawMessage
+	New         Factory
+}
+
+var (
+	registry   = make(map[string]*Driver)
+	registryMu sync.RWMutex
+)
+
+// Register registers the driver under the name of its info. It panics if
+// the driver has no name or factory, or if its name is already registered.
+// Drivers without operations read and write points.
+func Register(d *Driver) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if d == nil || d.New == nil {
+		panic("inproc: Register driver is nil")
+	}
+
+	name := d.Info.Name
+	if name == "" {
+		panic("inproc: Register driver has no name")
+	}
+
+	if _, dup := registry[name]; dup {
+		panic("inproc: Register called twice for driver " + name)
+	}
+
+	if len(d.Info.Operations) == 0 {
+		d.Info.Operations = []tool.Operation{tool.OpRead, tool.OpWrite}
+	}
+
+	if d.Info.APIVersion == 0 {
+		d.Info.APIVersion = tool.APIVersion
+	}
+
+	registry[name] = d
+}
+
+// Drivers lists the names of the drivers registered.
+func Drivers() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return slices.Sorted(maps.Keys(registry))
+}
+
+func lookup(name string) (*Driver, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	d, ok := registry[name]
+	return d, ok
+}
+
+// unregister is used by the tests.
+func unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(registry, name)
+}
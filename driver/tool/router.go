@@ -0,0 +1,99 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+)
+
+// Router is the client of the drivers served over different transports.
+type Router interface {
+	Client
+	Writer
+	Describer
+	Restarter
+	Subscriber
+
+	// Drivers lists the drivers routed to another client than the default
+	// one, which are not found in the drivers directory.
+	Drivers() []string
+}
+
+// NewRouter returns the client calling each driver through the client of
+// its route, and the other drivers through the default client.
+func NewRouter(fallback Client, routes map[string]Client) Router {
+	return &router{
+		fallback: fallback,
+		routes:   routes,
+	}
+}
+
+type router struct {
+	fallback Client
+	routes   map[string]Client
+}
+
+func (r *router) client(driver string) Client {
+	if client, ok := r.routes[driver]; ok {
+		return client
+	}
+
+	return r.fallback
+}
+
+func (r *router) Drivers() []string {
+	return slices.Sorted(maps.Keys(r.routes))
+}
+
+func (r *router) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
+	return r.client(driver).Schema(ctx, driver)
+}
+
+func (r *router) Instruction(ctx context.Context, driver string) (string, error) {
+	return r.client(driver).Instruction(ctx, driver)
+}
+
+func (r *router) ReadPoints(ctx context.Context, driver string, raw json.RawMessage) ([]any, error) {
+	return r.client(driver).ReadPoints(ctx, driver, raw)
+}
+
+func (r *router) WritePoints(ctx context.Context, driver string, raw json.RawMessage) error {
+	writer, ok := r.client(driver).(Writer)
+	if !ok {
+		return ErrUnsupportedOperation.
+			WithDetail("driver", driver).
+			WithDetail("operation", OpWrite)
+	}
+
+	return writer.WritePoints(ctx, driver, raw)
+}
+
+func (r *router) Info(ctx context.Context, driver string) (*Info, error) {
+	describer, ok := r.client(driver).(Describer)
+	if !ok {
+		return LegacyInfo(driver), nil
+	}
+
+	return describer.Info(ctx, driver)
+}
+
+func (r *router) Restart(driver string) error {
+	restarter, ok := r.client(driver).(Restarter)
+	if !ok {
+		return nil
+	}
+
+	return restarter.Restart(driver)
+}
+
+func (r *router) Subscribe(ctx context.Context, driver string, raw json.RawMessage, handler func(result any)) error {
+	subscriber, ok := r.client(driver).(Subscriber)
+	if !ok {
+		return ErrUnsupportedOperation.
+			WithDetail("driver", driver).
+			WithDetail("operation", OpSubscribe)
+	}
+
+	return subscriber.Subscribe(ctx, driver, raw, handler)
+}
@@ -2,12 +2,13 @@ package tool
 
 import (
 	"context"
-	"errors"
+
+	"github.com/flarexio/iiot/errs"
 )
 
 var (
-	ErrHandlerAlreadyExists = errors.New("handler already exists for this method")
-	ErrMethodNotFound       = errors.New("method not found")
+	ErrHandlerAlreadyExists = errs.New(errs.FailedPrecondition, "handler already exists for this method")
+	ErrMethodNotFound       = errs.New(errs.NotFound, "method not found")
 )
 
 type Handler func(ctx context.Context, data []byte) (result []byte, err error)
@@ -16,3 +17,14 @@ type Server interface {
 	AddHandler(method string, handler Handler) error
 	Listen(ctx context.Context)
 }
+
+// StreamHandler answers a request with results sent as they come, until it
+// returns.
+type StreamHandler func(ctx context.Context, data []byte, send func(result []byte) error) error
+
+// StreamServer is implemented by the servers whose transport streams, for
+// the drivers serving subscriptions.
+type StreamServer interface {
+	Server
+	AddStreamHandler(method string, handler StreamHandler) error
+}
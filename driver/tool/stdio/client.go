@@ -5,27 +5,44 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"sync"
 
 	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/metadata"
 )
 
 type StdioClient interface {
 	tool.Client
+	tool.Writer
+	tool.Describer
+	tool.Restarter
 }
 
 func NewStdioClient(executor Executor) StdioClient {
-	return &stdioClient{executor}
+	return &stdioClient{
+		executor: executor,
+		infos:    make(map[string]*tool.Info),
+	}
 }
 
 type stdioClient struct {
 	executor Executor
+	infos    map[string]*tool.Info
+	sync.Mutex
 }
 
 func (c *stdioClient) do(ctx context.Context, program string, req *Request) (*Response, error) {
 	if c.executor == nil {
-		return nil, errors.New("executor is not set")
+		return nil, errs.New(errs.Unavailable, "executor is not set")
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Deadline = &deadline
 	}
 
+	req.TraceID = metadata.TraceID(ctx)
+
 	in := new(bytes.Buffer)
 	out := new(bytes.Buffer)
 
@@ -35,20 +52,116 @@ func (c *stdioClient) do(ctx context.Context, program string, req *Request) (*Re
 	}
 
 	if err := c.executor.Execute(ctx, program, in, out); err != nil {
-		return nil, err
+		if code := errs.CodeOf(err); code == errs.Timeout || code == errs.Canceled {
+			return nil, errs.From(err)
+		}
+
+		return nil, errs.Wrap(errs.Unavailable, err).WithDetail("driver", program)
 	}
 
 	decoder := json.NewDecoder(out)
 
 	var resp *Response
 	if err := decoder.Decode(&resp); err != nil {
-		return nil, err
+		return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", program)
+	}
+
+	// Errors the driver did not classify are driver errors, including the
+	// ones the server of the driver sent as unknown.
+	if resp.Error != nil && errs.CodeOf(resp.Error) == errs.Unknown {
+		e := *errs.From(resp.Error)
+		e.Code = errs.DriverError
+		resp.Error = e.WithDetail("driver", program)
 	}
 
 	return resp, nil
 }
 
+// Info handshakes with the driver once, and remembers its answer.
+func (c *stdioClient) Info(ctx context.Context, driver string) (*tool.Info, error) {
+	c.Lock()
+	info, ok := c.infos[driver]
+	c.Unlock()
+
+	if ok {
+		return info, nil
+	}
+
+	program := driver + "_tool"
+
+	req := &Request{
+		Method: "driver.info",
+	}
+
+	resp, err := c.do(ctx, program, req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case errors.Is(resp.Error, tool.ErrMethodNotFound):
+		info = tool.LegacyInfo(driver)
+
+	case resp.Error != nil:
+		return nil, resp.Error
+
+	default:
+		if err := json.Unmarshal(resp.Result, &info); err != nil {
+			return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+		}
+	}
+
+	c.Lock()
+	c.infos[driver] = info
+	c.Unlock()
+
+	return info, nil
+}
+
+// Restart stops the process of the driver and forgets its handshake, so
+// that the next request starts and handshakes with its current binary.
+func (c *stdioClient) Restart(driver string) error {
+	if c.executor == nil {
+		return errs.New(errs.Unavailable, "executor is not set")
+	}
+
+	if err := c.executor.Stop(driver + "_tool"); err != nil {
+		return err
+	}
+
+	c.Lock()
+	delete(c.infos, driver)
+	c.Unlock()
+
+	return nil
+}
+
+// check refuses the drivers the host cannot talk to, and the operations
+// they do not support, before calling them.
+func (c *stdioClient) check(ctx context.Context, driver string, op tool.Operation) error {
+	info, err := c.Info(ctx, driver)
+	if err != nil {
+		return err
+	}
+
+	if err := info.Check(); err != nil {
+		return err
+	}
+
+	if op != "" && !info.Supports(op) {
+		return tool.ErrUnsupportedOperation.
+			WithDetail("driver", driver).
+			WithDetail("operation", op)
+	}
+
+	return nil
+}
+
 func (c *stdioClient) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
+	if err := c.check(ctx, driver, ""); err != nil {
+		return nil, err
+	}
+
 	program := driver + "_tool"
 
 	req := &Request{
@@ -68,6 +181,10 @@ func (c *stdioClient) Schema(ctx context.Context, driver string) (json.RawMessag
 }
 
 func (c *stdioClient) Instruction(ctx context.Context, driver string) (string, error) {
+	if err := c.check(ctx, driver, ""); err != nil {
+		return "", err
+	}
+
 	program := driver + "_tool"
 
 	req := &Request{
@@ -87,6 +204,10 @@ func (c *stdioClient) Instruction(ctx context.Context, driver string) (string, e
 }
 
 func (c *stdioClient) ReadPoints(ctx context.Context, driver string, raw json.RawMessage) ([]any, error) {
+	if err := c.check(ctx, driver, tool.OpRead); err != nil {
+		return nil, err
+	}
+
 	program := driver + "_tool"
 
 	req := &Request{
@@ -110,3 +231,27 @@ func (c *stdioClient) ReadPoints(ctx context.Context, driver string, raw json.Ra
 
 	return results, nil
 }
+
+func (c *stdioClient) WritePoints(ctx context.Context, driver string, raw json.RawMessage) error {
+	if err := c.check(ctx, driver, tool.OpWrite); err != nil {
+		return err
+	}
+
+	program := driver + "_tool"
+
+	req := &Request{
+		Method: "driver.writePoints",
+		Data:   raw,
+	}
+
+	resp, err := c.do(ctx, program, req)
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package stdio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/driver/tool/tooltest"
+)
+
+// serve runs a server of the driver with the handlers registered, and
+// returns the client executing the driver on it, one request at a time as
+// its process would.
+func serve(t *testing.T, driver string, register func(server tool.Server)) tool.Client {
+	server := NewStdioServer()
+	register(server)
+
+	in, stdin := io.Pipe()
+	stdout, out := io.Pipe()
+	server.SetIO(in, out)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		stdin.Close()
+		stdout.Close()
+	})
+
+	go server.Listen(ctx)
+
+	responses := bufio.NewReader(stdout)
+
+	var mu sync.Mutex
+	executor := NewTestableExecutor(func(ctx context.Context, program string, input io.Reader, output io.Writer) error {
+		if program != driver+"_tool" {
+			return errors.New("executable file not found: " + program)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, err := io.Copy(stdin, input); err != nil {
+			return err
+		}
+
+		line, err := responses.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+
+		_, err = output.Write(line)
+		return err
+	})
+
+	return NewStdioClient(executor)
+}
+
+func TestConformance(t *testing.T) {
+	tooltest.TestTransport(t, serve)
+}
+
+func TestServerHonorsDeadline(t *testing.T) {
+	assert := assert.New(t)
+
+	server := NewStdioServer()
+
+	done := make(chan error, 1)
+	server.AddHandler("driver.slow", func(ctx context.Context, data []byte) ([]byte, error) {
+		<-ctx.Done()
+		done <- ctx.Err()
+		return nil, ctx.Err()
+	})
+
+	deadline := time.Now().Add(50 * time.Millisecond)
+	bs, _ := json.Marshal(&Request{Method: "driver.slow", Deadline: &deadline})
+
+	in, w := io.Pipe()
+	server.SetIO(in, io.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go server.Listen(ctx)
+	go w.Write(append(bs, '\n'))
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		assert.Fail("the deadline of the request was not honored")
+	}
+}
@@ -7,13 +7,27 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/flarexio/iiot/driver/sandbox"
 )
 
 type Executor interface {
 	Execute(ctx context.Context, program string, input io.Reader, output io.Writer) error
+
+	// Stop stops the process of the program once its request in progress
+	// is answered. The next request starts it again.
+	Stop(program string) error
+
 	Close() error
 }
 
+// StopTimeout bounds how long a stopped driver has to exit before it is
+// killed.
+var StopTimeout = 5 * time.Second
+
 func NewCommandExecutor(path string) Executor {
 	return &commandExecutor{
 		path:      path,
@@ -21,93 +35,283 @@ func NewCommandExecutor(path string) Executor {
 	}
 }
 
+// NewSandboxedExecutor returns the executor running the drivers of the
+// path in the sandbox.
+func NewSandboxedExecutor(path string, sandbox *sandbox.Config) Executor {
+	return &commandExecutor{
+		path:      path,
+		sandbox:   sandbox,
+		processes: make(map[string]*managedProcess),
+	}
+}
+
+// managedProcess is the process of a driver. It answers one request at a
+// time: the request in progress holds busy until its response is read.
 type managedProcess struct {
-	cmd    *exec.Cmd
-	stdin  io.WriteCloser
-	stdout io.ReadCloser
-	sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	cleanup func()
+	busy    chan struct{}
+	exited  atomic.Bool
+	once    sync.Once
 }
 
+func (p *managedProcess) acquire(ctx context.Context) error {
+	select {
+	case p.busy <- struct{}{}:
+		return nil
+
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *managedProcess) release() {
+	<-p.busy
+}
+
+// kill kills the process, and releases its resources once.
+func (p *managedProcess) kill() {
+	p.once.Do(func() {
+		p.exited.Store(true)
+		p.stdin.Close()
+		p.stdout.Close()
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+		p.cleanup()
+	})
+}
+
+// terminate asks the process to exit, and kills it after StopTimeout.
+func (p *managedProcess) terminate() {
+	p.once.Do(func() {
+		p.exited.Store(true)
+		p.stdin.Close()
+		p.cmd.Process.Signal(syscall.SIGTERM)
+
+		exited := make(chan struct{})
+		go func() {
+			p.cmd.Wait()
+			close(exited)
+		}()
+
+		select {
+		case <-exited:
+		case <-time.After(StopTimeout):
+			p.cmd.Process.Kill()
+			<-exited
+		}
+
+		p.stdout.Close()
+		p.cleanup()
+	})
+}
+
+// commandExecutor runs each driver in a process of its own. The lock of
+// the executor only guards its processes: requests to different drivers
+// run concurrently.
 type commandExecutor struct {
 	path      string
+	sandbox   *sandbox.Config
 	processes map[string]*managedProcess
 	sync.Mutex
 }
 
-func (e *commandExecutor) startProcess(program string) (*managedProcess, error) {
-	path := filepath.Join(e.path, program)
+func (e *commandExecutor) command(program string) (*exec.Cmd, func(), error) {
+	if e.sandbox != nil {
+		return e.sandbox.Command(e.path, program)
+	}
 
-	cmd := exec.Command(path)
+	cmd := exec.Command(filepath.Join(e.path, program))
 	if err := cmd.Err; err != nil {
+		return nil, nil, err
+	}
+
+	return cmd, func() {}, nil
+}
+
+func (e *commandExecutor) startProcess(program string) (*managedProcess, error) {
+	cmd, cleanup, err := e.command(program)
+	if err != nil {
 		return nil, err
 	}
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
+		cleanup()
 		return nil, err
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		stdin.Close()
+		cleanup()
 		return nil, err
 	}
 
 	if err := cmd.Start(); err != nil {
 		stdin.Close()
 		stdout.Close()
+		cleanup()
 		return nil, err
 	}
 
 	return &managedProcess{
-		cmd:    cmd,
-		stdin:  stdin,
-		stdout: stdout,
+		cmd:     cmd,
+		stdin:   stdin,
+		stdout:  stdout,
+		cleanup: cleanup,
+		busy:    make(chan struct{}, 1),
 	}, nil
 }
 
-func (e *commandExecutor) Execute(ctx context.Context, program string, input io.Reader, output io.Writer) error {
+// process returns the process of the program, started if needed.
+func (e *commandExecutor) process(program string) (*managedProcess, error) {
 	e.Lock()
 	defer e.Unlock()
 
-	proc, ok := e.processes[program]
-	if !ok {
-		p, err := e.startProcess(program)
+	if proc, ok := e.processes[program]; ok {
+		return proc, nil
+	}
+
+	proc, err := e.startProcess(program)
+	if err != nil {
+		return nil, err
+	}
+
+	e.processes[program] = proc
+	return proc, nil
+}
+
+// forget forgets the process of the program, unless it was replaced.
+func (e *commandExecutor) forget(program string, proc *managedProcess) {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.processes[program] == proc {
+		delete(e.processes, program)
+	}
+}
+
+type result struct {
+	line []byte
+	err  error
+}
+
+func (e *commandExecutor) Execute(ctx context.Context, program string, input io.Reader, output io.Writer) error {
+	var proc *managedProcess
+	for {
+		p, err := e.process(program)
 		if err != nil {
 			return err
 		}
 
+		if err := p.acquire(ctx); err != nil {
+			return err
+		}
+
+		// The process was stopped while the request waited for its turn.
+		if p.exited.Load() {
+			p.release()
+			continue
+		}
+
 		proc = p
-		e.processes[program] = proc
+		break
 	}
 
-	proc.Lock()
-	defer proc.Unlock()
+	done := make(chan result, 1)
+	go func() {
+		if _, err := io.Copy(proc.stdin, input); err != nil {
+			done <- result{err: err}
+			return
+		}
+
+		scanner := bufio.NewScanner(proc.stdout)
+		if scanner.Scan() {
+			done <- result{line: scanner.Bytes()}
+			return
+		}
+
+		err := scanner.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+
+		done <- result{err: err}
+	}()
+
+	select {
+	case r := <-done:
+		defer proc.release()
+
+		if r.err != nil {
+			// The pipes of the driver are broken: start it again on the
+			// next request.
+			e.forget(program, proc)
+			proc.kill()
+			return r.err
+		}
 
-	if _, err := io.Copy(proc.stdin, input); err != nil {
+		_, err := output.Write(r.line)
 		return err
+
+	case <-ctx.Done():
+		go e.abandon(program, proc, done)
+		return ctx.Err()
 	}
+}
 
-	scanner := bufio.NewScanner(proc.stdout)
-	if scanner.Scan() {
-		line := scanner.Text()
-		_, err := output.Write([]byte(line))
-		return err
+// abandon discards the response of a request canceled. The driver answers
+// requests in order, so the next request waits for the late response to be
+// read. A driver that does not answer within StopTimeout is killed, and
+// started again by the next request.
+func (e *commandExecutor) abandon(program string, proc *managedProcess, done <-chan result) {
+	defer proc.release()
+
+	select {
+	case r := <-done:
+		if r.err == nil {
+			return
+		}
+
+	case <-time.After(StopTimeout):
 	}
 
-	return scanner.Err()
+	e.forget(program, proc)
+	proc.kill()
+	<-done
 }
 
-func (e *commandExecutor) Close() error {
+func (e *commandExecutor) Stop(program string) error {
 	e.Lock()
-	defer e.Unlock()
+	proc, ok := e.processes[program]
+	delete(e.processes, program)
+	e.Unlock()
 
-	for _, proc := range e.processes {
-		proc.stdin.Close()
-		proc.stdout.Close()
-		proc.cmd.Process.Kill()
+	if !ok {
+		return nil
 	}
 
+	// Wait for the request in progress to be answered.
+	proc.acquire(context.Background())
+	defer proc.release()
+
+	proc.terminate()
+	return nil
+}
+
+func (e *commandExecutor) Close() error {
+	e.Lock()
+	processes := e.processes
 	e.processes = make(map[string]*managedProcess)
+	e.Unlock()
+
+	for _, proc := range processes {
+		proc.kill()
+	}
+
 	return nil
 }
 
@@ -127,6 +331,10 @@ func (e *testableExecutor) Execute(ctx context.Context, program string, input io
 	return e.handler(ctx, program, input, output)
 }
 
+func (e *testableExecutor) Stop(program string) error {
+	return nil
+}
+
 func (e *testableExecutor) Close() error {
 	return nil
 }
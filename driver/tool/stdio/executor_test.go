@@ -2,10 +2,18 @@ package stdio
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestManualExecution(t *testing.T) {
@@ -35,3 +43,68 @@ func TestManualExecution(t *testing.T) {
 		fmt.Println("No response received")
 	}
 }
+
+// echoDriver writes a driver echoing each request, after a second for the
+// slow ones.
+func echoDriver(t *testing.T, path string, program string) {
+	script := "#!/bin/sh\n" +
+		"while read line; do\n" +
+		"  case \"$line\" in slow*) sleep 1;; esac\n" +
+		"  echo \"$line $$\"\n" +
+		"done\n"
+
+	if err := os.WriteFile(filepath.Join(path, program), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecuteLocksPerDriver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+
+	assert := assert.New(t)
+
+	path := t.TempDir()
+	echoDriver(t, path, "a")
+	echoDriver(t, path, "b")
+
+	executor := NewCommandExecutor(path)
+	defer executor.Close()
+
+	execute := func(ctx context.Context, program string, request string) (string, error) {
+		var output bytes.Buffer
+		err := executor.Execute(ctx, program, strings.NewReader(request+"\n"), &output)
+		return output.String(), err
+	}
+
+	first, err := execute(context.Background(), "a", "first")
+	assert.NoError(err)
+
+	pid := strings.TrimPrefix(first, "first ")
+
+	// A slow driver does not stall the others.
+	slow := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := execute(ctx, "a", "slow")
+		slow <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	_, err = execute(context.Background(), "b", "fast")
+	assert.NoError(err)
+	assert.Less(time.Since(start), 500*time.Millisecond)
+
+	assert.ErrorIs(<-slow, context.DeadlineExceeded)
+
+	// The request canceled does not kill the driver, and its late response
+	// is not taken as the answer to the next request.
+	next, err := execute(context.Background(), "a", "next")
+	assert.NoError(err)
+	assert.Equal("next "+pid, next)
+}
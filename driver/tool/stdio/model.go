@@ -3,11 +3,18 @@ package stdio
 import (
 	"encoding/json"
 	"errors"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
 )
 
+// Request is a call to a driver. Deadline and TraceID carry the context of
+// the caller, so the driver stops working on requests that were abandoned.
 type Request struct {
-	Method string
-	Data   []byte
+	Method   string
+	Data     []byte
+	Deadline *time.Time `json:",omitempty"`
+	TraceID  string     `json:",omitempty"`
 }
 
 type Response struct {
@@ -15,10 +22,14 @@ type Response struct {
 	Error  error
 }
 
+// UnmarshalJSON decodes the error of the response as an *errs.Error when
+// the driver sent a code, and as a plain error otherwise.
 func (resp *Response) UnmarshalJSON(data []byte) error {
 	var raw struct {
-		Result []byte
-		Error  string
+		Result  []byte
+		Error   string
+		Code    errs.Code
+		Details map[string]any
 	}
 
 	if err := json.Unmarshal(data, &raw); err != nil {
@@ -30,6 +41,14 @@ func (resp *Response) UnmarshalJSON(data []byte) error {
 	resp.Error = nil
 	if raw.Error != "" {
 		resp.Error = errors.New(raw.Error)
+
+		if raw.Code != "" {
+			resp.Error = &errs.Error{
+				Code:    raw.Code,
+				Message: raw.Error,
+				Details: raw.Details,
+			}
+		}
 	}
 
 	return nil
@@ -37,14 +56,20 @@ func (resp *Response) UnmarshalJSON(data []byte) error {
 
 func (resp *Response) MarshalJSON() ([]byte, error) {
 	raw := struct {
-		Result []byte `json:"result"`
-		Error  string `json:"error,omitempty"`
+		Result  []byte         `json:"result"`
+		Error   string         `json:"error,omitempty"`
+		Code    errs.Code      `json:"code,omitempty"`
+		Details map[string]any `json:"details,omitempty"`
 	}{
 		Result: resp.Result,
 	}
 
 	if resp.Error != nil {
-		raw.Error = resp.Error.Error()
+		err := errs.From(resp.Error)
+
+		raw.Error = err.Message
+		raw.Code = err.Code
+		raw.Details = err.Details
 	}
 
 	return json.Marshal(raw)
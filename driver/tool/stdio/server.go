@@ -10,8 +10,10 @@ import (
 	"time"
 
 	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/metadata"
 )
 
+// DefaultTimeout bounds requests that do not carry a deadline.
 var DefaultTimeout = 5000 * time.Millisecond
 
 type StdioServer interface {
@@ -88,7 +90,16 @@ func (s *stdioServer) handleRequest(ctx context.Context) error {
 	}
 
 	go func() {
-		ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+		if req.TraceID != "" {
+			ctx = metadata.WithTraceID(ctx, req.TraceID)
+		}
+
+		var cancel context.CancelFunc
+		if req.Deadline != nil {
+			ctx, cancel = context.WithDeadline(ctx, *req.Deadline)
+		} else {
+			ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		}
 		defer cancel()
 
 		result, err := handler(ctx, req.Data)
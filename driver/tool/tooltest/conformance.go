@@ -0,0 +1,181 @@
+// Package tooltest checks that the driver transports behave alike, so that
+// drivers work the same whichever transport serves them.
+package tooltest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/metadata"
+)
+
+// ServeFunc serves the handlers registered by register as the driver, and
+// returns the client of the driver over the transport tested.
+type ServeFunc func(t *testing.T, driver string, register func(server tool.Server)) tool.Client
+
+// TestTransport runs the conformance tests of the transport.
+func TestTransport(t *testing.T, serve ServeFunc) {
+	t.Run("PropagatesContext", func(t *testing.T) { testPropagatesContext(t, serve) })
+	t.Run("Handshake", func(t *testing.T) { testHandshake(t, serve) })
+	t.Run("Errors", func(t *testing.T) { testErrors(t, serve) })
+	t.Run("ReadPoints", func(t *testing.T) { testReadPoints(t, serve) })
+}
+
+// Info answers the handshake of the driver with the operations.
+func Info(driver string, operations ...tool.Operation) tool.Handler {
+	return func(ctx context.Context, data []byte) ([]byte, error) {
+		return json.Marshal(&tool.Info{
+			Name:       driver,
+			Operations: operations,
+			APIVersion: tool.APIVersion,
+		})
+	}
+}
+
+func testPropagatesContext(t *testing.T, serve ServeFunc) {
+	assert := assert.New(t)
+
+	var (
+		deadline time.Time
+		traceID  string
+	)
+
+	client := serve(t, "modbus", func(server tool.Server) {
+		server.AddHandler("driver.info", Info("modbus", tool.OpRead))
+		server.AddHandler("driver.schema", func(ctx context.Context, data []byte) ([]byte, error) {
+			deadline, _ = ctx.Deadline()
+			traceID = metadata.TraceID(ctx)
+			return []byte(`{}`), nil
+		})
+	})
+
+	expected := time.Now().Add(time.Second)
+
+	ctx, cancel := context.WithDeadline(context.Background(), expected)
+	defer cancel()
+
+	ctx = metadata.WithTraceID(ctx, "trace-1")
+
+	_, err := client.Schema(ctx, "modbus")
+	if !assert.NoError(err) {
+		return
+	}
+
+	// Some transports send the timeout left, not the deadline.
+	assert.WithinDuration(expected, deadline, 50*time.Millisecond)
+	assert.Equal("trace-1", traceID)
+}
+
+func testHandshake(t *testing.T, serve ServeFunc) {
+	assert := assert.New(t)
+
+	var (
+		methods = make([]string, 0)
+		mu      sync.Mutex
+	)
+
+	record := func(method string, result string) tool.Handler {
+		return func(ctx context.Context, data []byte) ([]byte, error) {
+			mu.Lock()
+			methods = append(methods, method)
+			mu.Unlock()
+
+			return []byte(result), nil
+		}
+	}
+
+	// A driver predating the handshake.
+	client := serve(t, "legacy", func(server tool.Server) {
+		server.AddHandler("driver.readPoints", record("driver.readPoints", `[]`))
+		server.AddHandler("driver.writePoints", record("driver.writePoints", ``))
+	})
+
+	ctx := context.Background()
+
+	describer, ok := client.(tool.Describer)
+	if !assert.True(ok, "client does not handshake") {
+		return
+	}
+
+	info, err := describer.Info(ctx, "legacy")
+	if assert.NoError(err) {
+		assert.Equal(tool.LegacyInfo("legacy"), info)
+	}
+
+	_, err = client.ReadPoints(ctx, "legacy", json.RawMessage(`{}`))
+	assert.NoError(err)
+
+	// Unsupported operations are refused without calling the driver.
+	if writer, ok := client.(tool.Writer); ok {
+		err = writer.WritePoints(ctx, "legacy", json.RawMessage(`{}`))
+		assert.ErrorIs(err, tool.ErrUnsupportedOperation)
+	}
+
+	assert.Equal([]string{"driver.readPoints"}, methods)
+}
+
+func testErrors(t *testing.T, serve ServeFunc) {
+	assert := assert.New(t)
+
+	client := serve(t, "modbus", func(server tool.Server) {
+		server.AddHandler("driver.info", Info("modbus", tool.OpRead, tool.OpWrite))
+		server.AddHandler("driver.readPoints", func(ctx context.Context, data []byte) ([]byte, error) {
+			return nil, tool.ErrInvalidRequest.WithDetail("errors", []tool.FieldError{
+				{Field: "address", Type: "required", Description: "address is required"},
+			})
+		})
+		server.AddHandler("driver.writePoints", func(ctx context.Context, data []byte) ([]byte, error) {
+			return nil, errors.New("device busy")
+		})
+	})
+
+	ctx := context.Background()
+
+	// Errors keep their code and details over the transport.
+	_, err := client.ReadPoints(ctx, "modbus", json.RawMessage(`{}`))
+	if assert.ErrorIs(err, tool.ErrInvalidRequest) {
+		assert.Len(errs.From(err).Details["errors"], 1)
+	}
+
+	// Errors the driver did not classify are driver errors.
+	if writer, ok := client.(tool.Writer); ok {
+		err = writer.WritePoints(ctx, "modbus", json.RawMessage(`{}`))
+		assert.Equal(errs.DriverError, errs.CodeOf(err))
+		assert.ErrorContains(err, "device busy")
+	}
+}
+
+func testReadPoints(t *testing.T, serve ServeFunc) {
+	assert := assert.New(t)
+
+	// Enough results for the transports to split them.
+	expected := make([]any, 1000)
+	for i := range expected {
+		expected[i] = float64(i)
+	}
+
+	var request json.RawMessage
+	client := serve(t, "modbus", func(server tool.Server) {
+		server.AddHandler("driver.info", Info("modbus", tool.OpRead))
+		server.AddHandler("driver.readPoints", func(ctx context.Context, data []byte) ([]byte, error) {
+			request = data
+			return json.Marshal(expected)
+		})
+	})
+
+	results, err := client.ReadPoints(context.Background(), "modbus", json.RawMessage(`{"address":"10.0.0.1:502"}`))
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.JSONEq(`{"address":"10.0.0.1:502"}`, string(request))
+	assert.Equal(expected, results)
+}
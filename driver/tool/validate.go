@@ -0,0 +1,42 @@
+package tool
+
+import (
+	"encoding/json"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+var ErrInvalidRequest = errs.New(errs.InvalidArgument, "request does not match the driver schema")
+
+// FieldError is a field of a driver request failing the driver schema.
+type FieldError struct {
+	Field       string `json:"field"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// Validate checks the request against the schema of the driver, reporting
+// each field that fails it in the "errors" detail.
+func Validate(schema *gojsonschema.Schema, raw json.RawMessage) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return errs.Wrap(errs.InvalidArgument, err)
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	fields := make([]*FieldError, len(result.Errors()))
+	for i, e := range result.Errors() {
+		fields[i] = &FieldError{
+			Field:       e.Field(),
+			Type:        e.Type(),
+			Description: e.Description(),
+		}
+	}
+
+	return ErrInvalidRequest.WithDetail("errors", fields)
+}
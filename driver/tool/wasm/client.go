@@ -0,0 +1,459 @@
+package wasm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+)
+
+var ErrDriverNotFound = errs.New(errs.NotFound, "wasm driver not found")
+
+type WASMClient interface {
+	tool.Client
+	tool.Writer
+	tool.Describer
+	tool.Restarter
+
+	// Drivers lists the drivers of the directory.
+	Drivers() []string
+
+	Close() error
+}
+
+// NewWASMClient returns the client of the drivers of the path, within the
+// limits. Modules are compiled on their first call, and again once their
+// file changes.
+func NewWASMClient(path string, limits *Limits) (WASMClient, error) {
+	if limits == nil {
+		limits = &DefaultLimits
+	}
+
+	ctx := context.Background()
+
+	cfg := wazero.NewRuntimeConfig().
+		WithCloseOnContextDone(true).
+		WithMemoryLimitPages(limits.pages())
+
+	r := wazero.NewRuntimeWithConfig(ctx, cfg)
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	if err := instantiateSockets(ctx, r); err != nil {
+		r.Close(ctx)
+		return nil, err
+	}
+
+	return &wasmClient{
+		path:    path,
+		limits:  limits,
+		runtime: r,
+		modules: make(map[string]*module),
+		loading: make(map[string]*loading),
+	}, nil
+}
+
+type wasmClient struct {
+	path    string
+	limits  *Limits
+	runtime wazero.Runtime
+	modules map[string]*module
+	loading map[string]*loading
+	sync.Mutex
+}
+
+// loading is a driver being compiled, shared by the calls waiting for it.
+type loading struct {
+	done   chan struct{}
+	module *module
+	err    error
+}
+
+// module is a driver compiled, as of the modification of its file.
+type module struct {
+	compiled wazero.CompiledModule
+	modTime  time.Time
+	size     int64
+	info     *tool.Info
+}
+
+func (c *wasmClient) Drivers() []string {
+	entries, err := os.ReadDir(c.path)
+	if err != nil {
+		return nil
+	}
+
+	drivers := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		driver, ok := strings.CutSuffix(entry.Name(), ".wasm")
+		if ok {
+			drivers = append(drivers, driver)
+		}
+	}
+
+	slices.Sort(drivers)
+	return drivers
+}
+
+func (c *wasmClient) Close() error {
+	return c.runtime.Close(context.Background())
+}
+
+// Restart drops the module compiled. The next call compiles the file of
+// the driver again.
+func (c *wasmClient) Restart(driver string) error {
+	c.Lock()
+	m, ok := c.modules[driver]
+	delete(c.modules, driver)
+	c.Unlock()
+
+	if ok {
+		m.compiled.Close(context.Background())
+	}
+
+	return nil
+}
+
+// load returns the module of the driver, compiled from its current file.
+// A driver is compiled and described once for the calls waiting for it,
+// without holding back the calls to the other drivers.
+func (c *wasmClient) load(ctx context.Context, driver string) (*module, error) {
+	if driver == "" || driver != filepath.Base(driver) {
+		return nil, errs.New(errs.InvalidArgument, "invalid driver name").WithDetail("driver", driver)
+	}
+
+	filename := filepath.Join(c.path, driver+".wasm")
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, ErrDriverNotFound.WithDetail("driver", driver)
+	}
+
+	c.Lock()
+
+	if m, ok := c.modules[driver]; ok && m.modTime.Equal(fi.ModTime()) && m.size == fi.Size() {
+		c.Unlock()
+		return m, nil
+	}
+
+	l, ok := c.loading[driver]
+	if !ok {
+		l = &loading{done: make(chan struct{})}
+		c.loading[driver] = l
+
+		// The module is loaded for every call waiting for it, whichever
+		// gives up first.
+		go c.compile(context.WithoutCancel(ctx), driver, filename, fi, l)
+	}
+
+	c.Unlock()
+
+	select {
+	case <-l.done:
+		return l.module, l.err
+
+	case <-ctx.Done():
+		return nil, errs.From(ctx.Err()).WithDetail("driver", driver)
+	}
+}
+
+// compile compiles and describes the file of the driver, and replaces the
+// module of the driver with it.
+func (c *wasmClient) compile(ctx context.Context, driver string, filename string, fi os.FileInfo, l *loading) {
+	defer close(l.done)
+
+	m, err := c.compileModule(ctx, driver, filename, fi)
+
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.loading, driver)
+
+	if err != nil {
+		l.err = err
+		return
+	}
+
+	// Calls in progress keep running on the previous module.
+	if old, ok := c.modules[driver]; ok {
+		old.compiled.Close(ctx)
+	}
+
+	c.modules[driver] = m
+	l.module = m
+}
+
+func (c *wasmClient) compileModule(ctx context.Context, driver string, filename string, fi os.FileInfo) (*module, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errs.Wrap(errs.Unavailable, err).WithDetail("driver", driver)
+	}
+
+	compiled, err := c.runtime.CompileModule(ctx, data)
+	if err != nil {
+		return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+	}
+
+	m := &module{
+		compiled: compiled,
+		modTime:  fi.ModTime(),
+		size:     fi.Size(),
+	}
+
+	info, err := c.describe(ctx, driver, m)
+	if err != nil {
+		compiled.Close(ctx)
+		return nil, err
+	}
+
+	m.info = info
+
+	return m, nil
+}
+
+// describe describes the driver from its manifest, or from its module
+// without one. Only the manifest grants destinations to the driver.
+func (c *wasmClient) describe(ctx context.Context, driver string, m *module) (*tool.Info, error) {
+	var info *tool.Info
+
+	data, err := os.ReadFile(filepath.Join(c.path, driver+".wasm.json"))
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+		}
+
+		return info, nil
+
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	exports := m.compiled.ExportedFunctions()
+
+	if _, ok := exports["iiot_info"]; ok {
+		result, err := c.call(ctx, driver, m, "iiot_info", nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(result, &info); err != nil {
+			return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+		}
+
+		info.Destinations = nil
+
+		return info, nil
+	}
+
+	info = &tool.Info{
+		Name:       driver,
+		Operations: []tool.Operation{tool.OpRead},
+		APIVersion: tool.APIVersion,
+	}
+
+	if _, ok := exports["iiot_write_points"]; ok {
+		info.Operations = append(info.Operations, tool.OpWrite)
+	}
+
+	return info, nil
+}
+
+// call calls the export of the driver in an instance of its own.
+func (c *wasmClient) call(ctx context.Context, driver string, m *module, export string, data []byte) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.limits.timeout())
+	defer cancel()
+
+	var destinations []tool.Destination
+	if m.info != nil {
+		destinations = m.info.Destinations
+	}
+
+	socks := newSockets(destinations)
+	defer socks.Close()
+
+	ctx = context.WithValue(ctx, socketsKey{}, socks)
+
+	cfg := wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStderr(os.Stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+
+	mod, err := c.runtime.InstantiateModule(ctx, m.compiled, cfg)
+	if err != nil {
+		return nil, c.callError(ctx, driver, err)
+	}
+	defer mod.Close(ctx)
+
+	fn := mod.ExportedFunction(export)
+	if fn == nil {
+		return nil, errs.New(errs.DriverError, "export not found").
+			WithDetail("driver", driver).
+			WithDetail("export", export)
+	}
+
+	var params []uint64
+	if data != nil {
+		alloc := mod.ExportedFunction("iiot_alloc")
+		if alloc == nil {
+			return nil, errs.New(errs.DriverError, "export not found").
+				WithDetail("driver", driver).
+				WithDetail("export", "iiot_alloc")
+		}
+
+		results, err := alloc.Call(ctx, uint64(len(data)))
+		if err != nil {
+			return nil, c.callError(ctx, driver, err)
+		}
+
+		ptr := uint32(results[0])
+		if !mod.Memory().Write(ptr, data) {
+			return nil, errs.New(errs.DriverError, "request out of memory range").WithDetail("driver", driver)
+		}
+
+		params = []uint64{uint64(ptr), uint64(len(data))}
+	}
+
+	results, err := fn.Call(ctx, params...)
+	if err != nil {
+		return nil, c.callError(ctx, driver, err)
+	}
+
+	ptr, size := uint32(results[0]>>32), uint32(results[0])
+
+	// The memory is released with the instance.
+	out, ok := mod.Memory().Read(ptr, size)
+	if !ok {
+		return nil, errs.New(errs.DriverError, "response out of memory range").WithDetail("driver", driver)
+	}
+
+	var resp *response
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+	}
+
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	return resp.Result, nil
+}
+
+// callError classifies the errors of a call: the calls closed for running
+// out of time time out, the others are driver errors.
+func (c *wasmClient) callError(ctx context.Context, driver string, err error) error {
+	if ctx.Err() != nil {
+		return errs.From(ctx.Err()).WithDetail("driver", driver)
+	}
+
+	return errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+}
+
+func (c *wasmClient) Info(ctx context.Context, driver string) (*tool.Info, error) {
+	m, err := c.load(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.info, nil
+}
+
+// check refuses the drivers the host cannot talk to, and the operations
+// they do not support, before calling them.
+func (c *wasmClient) check(ctx context.Context, driver string, op tool.Operation) (*module, error) {
+	m, err := c.load(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.info.Check(); err != nil {
+		return nil, err
+	}
+
+	if op != "" && !m.info.Supports(op) {
+		return nil, tool.ErrUnsupportedOperation.
+			WithDetail("driver", driver).
+			WithDetail("operation", op)
+	}
+
+	return m, nil
+}
+
+func (c *wasmClient) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
+	m, err := c.check(ctx, driver, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return c.call(ctx, driver, m, "iiot_schema", nil)
+}
+
+func (c *wasmClient) Instruction(ctx context.Context, driver string) (string, error) {
+	m, err := c.check(ctx, driver, "")
+	if err != nil {
+		return "", err
+	}
+
+	result, err := c.call(ctx, driver, m, "iiot_instruction", nil)
+	if err != nil {
+		return "", err
+	}
+
+	var instruction string
+	if err := json.Unmarshal(result, &instruction); err != nil {
+		return "", errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+	}
+
+	return instruction, nil
+}
+
+func (c *wasmClient) ReadPoints(ctx context.Context, driver string, raw json.RawMessage) ([]any, error) {
+	m, err := c.check(ctx, driver, tool.OpRead)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.call(ctx, driver, m, "iiot_read_points", raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []any
+	if err := json.Unmarshal(result, &results); err != nil {
+		return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+	}
+
+	return results, nil
+}
+
+func (c *wasmClient) WritePoints(ctx context.Context, driver string, raw json.RawMessage) error {
+	m, err := c.check(ctx, driver, tool.OpWrite)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.call(ctx, driver, m, "iiot_write_points", raw)
+	return err
+}
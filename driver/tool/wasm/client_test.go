@@ -0,0 +1,166 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+// buildGuest builds the test driver into the directory.
+func buildGuest(t *testing.T, dir string) {
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", filepath.Join(dir, "echo.wasm"), "./testdata/guest")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("wasip1 toolchain not available: %s", out)
+	}
+}
+
+// echo serves an echo server, answering its address.
+func echo(t *testing.T) *net.TCPAddr {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				buf := make([]byte, 256)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+
+					conn.Write(buf[:n])
+				}
+			}()
+		}
+	}()
+
+	return lis.Addr().(*net.TCPAddr)
+}
+
+func TestClient(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	buildGuest(t, dir)
+
+	addr := echo(t)
+
+	manifest, _ := json.Marshal(&tool.Info{
+		Name:         "echo",
+		Version:      "1.0.0",
+		Operations:   []tool.Operation{tool.OpRead},
+		APIVersion:   tool.APIVersion,
+		Destinations: []tool.Destination{{Protocol: "tcp", Address: "127.0.0.0/8", Port: addr.Port}},
+	})
+
+	os.WriteFile(filepath.Join(dir, "echo.wasm.json"), manifest, 0644)
+
+	// The same driver, without a manifest to grant it destinations.
+	data, _ := os.ReadFile(filepath.Join(dir, "echo.wasm"))
+	os.WriteFile(filepath.Join(dir, "other.wasm"), data, 0644)
+
+	client, err := NewWASMClient(dir, &Limits{Timeout: machine.Duration(time.Second)})
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	assert.Equal([]string{"echo", "other"}, client.Drivers())
+
+	instruction, err := client.Instruction(ctx, "echo")
+	assert.NoError(err)
+	assert.Equal("Reads the points from an echo server.", instruction)
+
+	controller := &machine.Controller{
+		Options: map[string]any{"address": addr.String()},
+		Points:  []*machine.Point{{Name: "temperature"}, {Name: "humidity"}},
+	}
+
+	raw, _ := machine.ReadRequest(controller)
+
+	results, err := client.ReadPoints(ctx, "echo", raw)
+	if assert.NoError(err) {
+		assert.Equal([]any{"temperature", "humidity"}, results)
+	}
+
+	_, err = client.ReadPoints(ctx, "other", raw)
+	assert.Equal(errs.DriverError, errs.CodeOf(err))
+	assert.ErrorContains(err, "dial failed")
+
+	// The echo manifest does not declare writes.
+	raw, _ = machine.WriteRequest(controller, map[string]any{"temperature": 1})
+	assert.ErrorIs(client.WritePoints(ctx, "echo", raw), tool.ErrUnsupportedOperation)
+
+	// Writes never return, and run out of time.
+	err = client.WritePoints(ctx, "other", raw)
+	assert.Equal(errs.Timeout, errs.CodeOf(err))
+
+	_, err = client.Info(ctx, "missing")
+	assert.ErrorIs(err, ErrDriverNotFound)
+}
+
+func TestClientLoad(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+	buildGuest(t, dir)
+
+	client, err := NewWASMClient(dir, nil)
+	if !assert.NoError(err) {
+		return
+	}
+	defer client.Close()
+
+	c := client.(*wasmClient)
+
+	// A call giving up does not fail the load for the others.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.Info(ctx, "echo")
+	assert.Equal(errs.Canceled, errs.CodeOf(err))
+
+	modules := make(chan *module, 8)
+	for range cap(modules) {
+		go func() {
+			m, err := c.load(context.Background(), "echo")
+			assert.NoError(err)
+			modules <- m
+		}()
+	}
+
+	// The driver is compiled once for the calls waiting for it.
+	first := <-modules
+	for range cap(modules) - 1 {
+		assert.Same(first, <-modules)
+	}
+
+	assert.Empty(c.loading)
+}
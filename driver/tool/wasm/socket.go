@@ -0,0 +1,282 @@
+package wasm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+
+	"github.com/flarexio/iiot/driver/tool"
+)
+
+type socketsKey struct{}
+
+// sockets are the sockets of a call, restricted to the destinations of
+// the driver.
+type sockets struct {
+	destinations []tool.Destination
+	conns        map[int32]net.Conn
+	next         int32
+}
+
+func newSockets(destinations []tool.Destination) *sockets {
+	return &sockets{
+		destinations: destinations,
+		conns:        make(map[int32]net.Conn),
+	}
+}
+
+func (s *sockets) Close() {
+	for sock, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, sock)
+	}
+}
+
+// allowed returns the addresses of the host the driver may connect to.
+func (s *sockets) allowed(ctx context.Context, network string, host string, port int) ([]netip.Addr, error) {
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]netip.Addr, 0, len(addrs))
+	for _, addr := range addrs {
+		for _, dest := range s.destinations {
+			if dest.Protocol != network || (dest.Port != 0 && dest.Port != port) {
+				continue
+			}
+
+			if matches(ctx, dest.Address, host, addr) {
+				allowed = append(allowed, addr)
+				break
+			}
+		}
+	}
+
+	return allowed, nil
+}
+
+// matches reports whether the destination address, a CIDR, an IP address
+// or a host name, covers the address of the host.
+func matches(ctx context.Context, address string, host string, addr netip.Addr) bool {
+	if prefix, err := netip.ParsePrefix(address); err == nil {
+		return prefix.Contains(addr)
+	}
+
+	if ip, err := netip.ParseAddr(address); err == nil {
+		return ip.Unmap() == addr
+	}
+
+	if strings.EqualFold(address, host) {
+		return true
+	}
+
+	ips, err := lookup(ctx, address)
+	if err != nil {
+		return false
+	}
+
+	for _, ip := range ips {
+		if ip == addr {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lookup(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr.Unmap()}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, addr := range addrs {
+		addrs[i] = addr.Unmap()
+	}
+
+	return addrs, nil
+}
+
+// dial connects to the first address allowed. The addresses checked are
+// the ones dialed, so that the host name cannot resolve elsewhere.
+func (s *sockets) dial(ctx context.Context, network string, address string) int32 {
+	if network != "tcp" && network != "udp" {
+		return ErrnoInvalid
+	}
+
+	if len(s.conns) >= MaxSockets {
+		return ErrnoInvalid
+	}
+
+	host, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrnoInvalid
+	}
+
+	port, err := strconv.Atoi(p)
+	if err != nil || port <= 0 || port > 65535 {
+		return ErrnoInvalid
+	}
+
+	addrs, err := s.allowed(ctx, network, host, port)
+	if err != nil {
+		return ErrnoIO
+	}
+
+	if len(addrs) == 0 {
+		return ErrnoDenied
+	}
+
+	var dialer net.Dialer
+	for _, addr := range addrs {
+		conn, err := dialer.DialContext(ctx, network, netip.AddrPortFrom(addr, uint16(port)).String())
+		if err != nil {
+			continue
+		}
+
+		s.next++
+		s.conns[s.next] = conn
+
+		return s.next
+	}
+
+	return ErrnoIO
+}
+
+func (s *sockets) send(ctx context.Context, sock int32, data []byte) int32 {
+	conn, ok := s.conns[sock]
+	if !ok {
+		return ErrnoInvalid
+	}
+
+	deadline, _ := ctx.Deadline()
+	conn.SetWriteDeadline(deadline)
+
+	n, err := conn.Write(data)
+	if err != nil {
+		return errno(err)
+	}
+
+	return int32(n)
+}
+
+func (s *sockets) recv(ctx context.Context, sock int32, buf []byte, timeout time.Duration) int32 {
+	conn, ok := s.conns[sock]
+	if !ok {
+		return ErrnoInvalid
+	}
+
+	deadline, ok := ctx.Deadline()
+	if timeout > 0 && (!ok || time.Now().Add(timeout).Before(deadline)) {
+		deadline = time.Now().Add(timeout)
+	}
+
+	conn.SetReadDeadline(deadline)
+
+	// The buffer is a view of the memory of the module, read into in place.
+	n, err := conn.Read(buf)
+	if n > 0 || errors.Is(err, io.EOF) {
+		return int32(n)
+	}
+
+	if err != nil {
+		return errno(err)
+	}
+
+	return 0
+}
+
+func (s *sockets) close(sock int32) int32 {
+	conn, ok := s.conns[sock]
+	if !ok {
+		return ErrnoInvalid
+	}
+
+	delete(s.conns, sock)
+
+	if err := conn.Close(); err != nil {
+		return ErrnoIO
+	}
+
+	return 0
+}
+
+func errno(err error) int32 {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrnoTimeout
+	}
+
+	return ErrnoIO
+}
+
+// instantiateSockets instantiates the host module "iiot", serving the
+// sockets of the call in the context.
+func instantiateSockets(ctx context.Context, r wazero.Runtime) error {
+	socketsOf := func(ctx context.Context) *sockets {
+		s, _ := ctx.Value(socketsKey{}).(*sockets)
+		return s
+	}
+
+	_, err := r.NewHostModuleBuilder("iiot").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, networkPtr, networkLen, addressPtr, addressLen uint32) int32 {
+			s := socketsOf(ctx)
+			network, ok1 := m.Memory().Read(networkPtr, networkLen)
+			address, ok2 := m.Memory().Read(addressPtr, addressLen)
+			if s == nil || !ok1 || !ok2 {
+				return ErrnoInvalid
+			}
+
+			return s.dial(ctx, string(network), string(address))
+		}).
+		Export("sock_dial").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, sock int32, ptr, size uint32) int32 {
+			s := socketsOf(ctx)
+			data, ok := m.Memory().Read(ptr, size)
+			if s == nil || !ok {
+				return ErrnoInvalid
+			}
+
+			return s.send(ctx, sock, data)
+		}).
+		Export("sock_send").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, sock int32, ptr, size uint32, timeoutMs int32) int32 {
+			s := socketsOf(ctx)
+			buf, ok := m.Memory().Read(ptr, size)
+			if s == nil || !ok {
+				return ErrnoInvalid
+			}
+
+			return s.recv(ctx, sock, buf, time.Duration(timeoutMs)*time.Millisecond)
+		}).
+		Export("sock_recv").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, m api.Module, sock int32) int32 {
+			s := socketsOf(ctx)
+			if s == nil {
+				return ErrnoInvalid
+			}
+
+			return s.close(sock)
+		}).
+		Export("sock_close").
+		Instantiate(ctx)
+
+	return err
+}
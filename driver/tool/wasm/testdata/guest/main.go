@@ -0,0 +1,96 @@
+//go:build wasip1
+
+// The test driver: it reads each point from an echo server at the
+// address of the request, and never returns from its writes.
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o echo.wasm
+package main
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+//go:wasmimport iiot sock_dial
+func sockDial(network unsafe.Pointer, networkLen uint32, address unsafe.Pointer, addressLen uint32) int32
+
+//go:wasmimport iiot sock_send
+func sockSend(sock int32, ptr unsafe.Pointer, size uint32) int32
+
+//go:wasmimport iiot sock_recv
+func sockRecv(sock int32, ptr unsafe.Pointer, size uint32, timeoutMs int32) int32
+
+//go:wasmimport iiot sock_close
+func sockClose(sock int32) int32
+
+// buffers keeps the memory handed to the host alive.
+var buffers [][]byte
+
+//go:wasmexport iiot_alloc
+func alloc(size uint32) unsafe.Pointer {
+	buf := make([]byte, size)
+	buffers = append(buffers, buf)
+	return unsafe.Pointer(unsafe.SliceData(buf))
+}
+
+func respond(result any, err string) uint64 {
+	data, _ := json.Marshal(map[string]any{"result": result, "error": err})
+	buffers = append(buffers, data)
+	return uint64(uintptr(unsafe.Pointer(unsafe.SliceData(data))))<<32 | uint64(len(data))
+}
+
+//go:wasmexport iiot_schema
+func schema() uint64 {
+	return respond(map[string]any{"type": "object"}, "")
+}
+
+//go:wasmexport iiot_instruction
+func instruction() uint64 {
+	return respond("Reads the points from an echo server.", "")
+}
+
+//go:wasmexport iiot_read_points
+func readPoints(ptr unsafe.Pointer, size uint32) uint64 {
+	var req struct {
+		Address string `json:"address"`
+		Points  []struct {
+			Name string `json:"name"`
+		} `json:"points"`
+	}
+
+	if err := json.Unmarshal(unsafe.Slice((*byte)(ptr), size), &req); err != nil {
+		return respond(nil, err.Error())
+	}
+
+	network := "tcp"
+	sock := sockDial(unsafe.Pointer(unsafe.StringData(network)), uint32(len(network)),
+		unsafe.Pointer(unsafe.StringData(req.Address)), uint32(len(req.Address)))
+	if sock < 0 {
+		return respond(sock, "dial failed")
+	}
+	defer sockClose(sock)
+
+	results := make([]any, len(req.Points))
+	for i, point := range req.Points {
+		name := []byte(point.Name)
+		sockSend(sock, unsafe.Pointer(unsafe.SliceData(name)), uint32(len(name)))
+
+		buf := make([]byte, 256)
+		n := sockRecv(sock, unsafe.Pointer(unsafe.SliceData(buf)), uint32(len(buf)), 1000)
+		if n < 0 {
+			return respond(n, "recv failed")
+		}
+
+		results[i] = string(buf[:n])
+	}
+
+	return respond(results, "")
+}
+
+//go:wasmexport iiot_write_points
+func writePoints(ptr unsafe.Pointer, size uint32) uint64 {
+	for {
+	}
+}
+
+func main() {}
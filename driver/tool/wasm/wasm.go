@@ -0,0 +1,135 @@
+// Package wasm runs the drivers compiled to WebAssembly, portable across
+// the architectures of the edges and sandboxed by the runtime.
+//
+// A driver is a reactor module (e.g., GOOS=wasip1 -buildmode=c-shared)
+// named <name>.wasm in the drivers directory. Each call runs in a module
+// instance of its own, with the WASI clocks and random source but no
+// filesystem, environment nor arguments. Its manifest, <name>.wasm.json,
+// holds the tool.Info of the driver; its destinations are the only ones
+// the driver may connect to.
+//
+// The module exports:
+//   - memory: the linear memory of the module.
+//   - iiot_alloc(size i32) i32: allocates the memory of a request.
+//   - iiot_schema() i64, iiot_instruction() i64: the schema and instruction.
+//   - iiot_read_points(ptr i32, len i32) i64: reads the points of the request.
+//   - iiot_write_points(ptr i32, len i32) i64: writes them, optional.
+//   - iiot_info() i64: the tool.Info of the driver, without a manifest, optional.
+//
+// Requests are the JSON requests of the other transports. Exports answer
+// the address of their response in the upper 32 bits of the result, and
+// its length in the lower ones. The response is a JSON object holding the
+// "result", or the "error" with its "code" and "details".
+//
+// The host module "iiot" provides the sockets of the drivers, closed at
+// the end of each call:
+//   - sock_dial(network_ptr, network_len, address_ptr, address_len i32) i32: dials "tcp" or "udp" to "host:port", answering the socket.
+//   - sock_send(sock, ptr, len i32) i32: answers the bytes sent.
+//   - sock_recv(sock, ptr, len, timeout_ms i32) i32: answers the bytes received, 0 at the end of the stream.
+//   - sock_close(sock i32) i32.
+//
+// They answer a negative error on failure: ErrnoDenied, ErrnoInvalid,
+// ErrnoIO or ErrnoTimeout.
+package wasm
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+// The errors answered by the host functions.
+const (
+	ErrnoDenied  = -1 // destination not declared in the manifest
+	ErrnoInvalid = -2 // invalid argument or socket
+	ErrnoIO      = -3
+	ErrnoTimeout = -4
+)
+
+// MaxSockets bounds the sockets open in a call.
+var MaxSockets = 16
+
+// Limits bounds each call of the drivers, as read from wasm.json.
+//
+// Memory is bounded, but CPU is not metered in fuel: the runtime counts no
+// instructions. The running time of a call is bounded instead, the
+// instance being closed once it is exceeded, loops included, so a driver
+// may use a whole core until then.
+type Limits struct {
+	Memory  int64            `json:"memory,omitempty"`  // bytes of linear memory per instance
+	Timeout machine.Duration `json:"timeout,omitempty"` // running time per call
+}
+
+// DefaultLimits are the limits of the fields unset.
+var DefaultLimits = Limits{
+	Memory:  128 << 20,
+	Timeout: machine.Duration(10 * time.Second),
+}
+
+// LoadLimits loads the limits of the drivers. Without a file, the default
+// limits apply.
+func LoadLimits(filename string) (*Limits, error) {
+	limits := DefaultLimits
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &limits, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, err
+	}
+
+	return &limits, nil
+}
+
+// pages returns the memory limit in pages of 64 KiB.
+func (l *Limits) pages() uint32 {
+	memory := l.Memory
+	if memory <= 0 {
+		memory = DefaultLimits.Memory
+	}
+
+	return uint32((memory + 65535) / 65536)
+}
+
+func (l *Limits) timeout() time.Duration {
+	if l.Timeout <= 0 {
+		return time.Duration(DefaultLimits.Timeout)
+	}
+
+	return time.Duration(l.Timeout)
+}
+
+// response is the response of an export.
+type response struct {
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Code    errs.Code       `json:"code,omitempty"`
+	Details map[string]any  `json:"details,omitempty"`
+}
+
+func (resp *response) err() error {
+	if resp.Error == "" {
+		return nil
+	}
+
+	code := resp.Code
+	if code == "" {
+		code = errs.DriverError
+	}
+
+	return &errs.Error{
+		Code:    code,
+		Message: resp.Error,
+		Details: resp.Details,
+	}
+}
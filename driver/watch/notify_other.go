@@ -0,0 +1,37 @@
+//go:build !linux
+
+package watch
+
+import (
+	"context"
+	"time"
+)
+
+// PollInterval is how often the directory is scanned where inotify is not
+// available.
+var PollInterval = 2 * time.Second
+
+// notify signals a possible change at every poll interval.
+func notify(ctx context.Context, path string) (<-chan struct{}, error) {
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		ticker := time.NewTicker(PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case <-ticker.C:
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -0,0 +1,189 @@
+// Package watch watches the drivers directory of the edge, so that the
+// drivers copied, replaced or removed there are picked up without
+// restarting the edge.
+package watch
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/driver/tool"
+)
+
+// Settle is how long the directory must stay quiet before it is scanned, so
+// that a binary being copied is only reported once it is complete.
+var Settle = 500 * time.Millisecond
+
+// Op is a change of a driver binary.
+type Op string
+
+const (
+	Added   Op = "added"
+	Changed Op = "changed"
+	Removed Op = "removed"
+)
+
+// Event records a change of a driver binary.
+type Event struct {
+	Driver string    `json:"driver"`
+	Op     Op        `json:"op"`
+	Time   time.Time `json:"time"`
+}
+
+type EventHandler func(event *Event)
+
+type Watcher interface {
+	// Run watches the directory until the context is done. The drivers
+	// whose binary changed are restarted before their event is handled.
+	Run(ctx context.Context) error
+
+	// Subscribe registers a handler that receives every driver event.
+	Subscribe(handler EventHandler)
+}
+
+// NewWatcher returns the watcher of the driver binaries in path, which
+// restarts the changed drivers through the restarter.
+func NewWatcher(path string, restarter tool.Restarter) Watcher {
+	return &watcher{
+		path:      path,
+		restarter: restarter,
+		handlers:  make([]EventHandler, 0),
+	}
+}
+
+type watcher struct {
+	path      string
+	restarter tool.Restarter
+	handlers  []EventHandler
+	sync.RWMutex
+}
+
+// binary identifies a version of a driver binary.
+type binary struct {
+	modTime time.Time
+	size    int64
+}
+
+func (w *watcher) Run(ctx context.Context) error {
+	if err := os.MkdirAll(w.path, 0755); err != nil {
+		return err
+	}
+
+	changes, err := notify(ctx, w.path)
+	if err != nil {
+		return err
+	}
+
+	last, err := scan(w.path)
+	if err != nil {
+		return err
+	}
+
+	settle := time.NewTimer(Settle)
+	settle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			settle.Stop()
+			return nil
+
+		case <-changes:
+			settle.Reset(Settle)
+
+		case <-settle.C:
+			current, err := scan(w.path)
+			if err != nil {
+				zap.L().Error(err.Error(), zap.String("path", w.path))
+				continue
+			}
+
+			for _, event := range diff(last, current) {
+				w.handle(event)
+			}
+
+			last = current
+		}
+	}
+}
+
+func (w *watcher) handle(event *Event) {
+	// Requests in progress are answered by the previous binary, the next
+	// ones by the new one.
+	if err := w.restarter.Restart(event.Driver); err != nil {
+		zap.L().Error(err.Error(), zap.String("driver", event.Driver))
+	}
+
+	w.RLock()
+	handlers := w.handlers
+	w.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (w *watcher) Subscribe(handler EventHandler) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.handlers = append(w.handlers, handler)
+}
+
+// scan lists the driver binaries of the directory.
+func scan(path string) (map[string]binary, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	binaries := make(map[string]binary)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		driver, ok := strings.CutSuffix(entry.Name(), "_tool")
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		binaries[driver] = binary{info.ModTime(), info.Size()}
+	}
+
+	return binaries, nil
+}
+
+func diff(last map[string]binary, current map[string]binary) []*Event {
+	now := time.Now()
+
+	events := make([]*Event, 0)
+	for driver, b := range current {
+		prev, ok := last[driver]
+		switch {
+		case !ok:
+			events = append(events, &Event{driver, Added, now})
+
+		case !prev.modTime.Equal(b.modTime) || prev.size != b.size:
+			events = append(events, &Event{driver, Changed, now})
+		}
+	}
+
+	for driver := range last {
+		if _, ok := current[driver]; !ok {
+			events = append(events, &Event{driver, Removed, now})
+		}
+	}
+
+	return events
+}
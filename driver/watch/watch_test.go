@@ -0,0 +1,83 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRestarter struct {
+	restarted []string
+	sync.Mutex
+}
+
+func (r *testRestarter) Restart(driver string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.restarted = append(r.restarted, driver)
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	assert := assert.New(t)
+
+	Settle = 50 * time.Millisecond
+
+	path := t.TempDir()
+	os.WriteFile(filepath.Join(path, "modbus_tool"), []byte("v1"), 0755)
+
+	restarter := new(testRestarter)
+	w := NewWatcher(path, restarter)
+
+	events := make(chan *Event, 10)
+	w.Subscribe(func(event *Event) {
+		events <- event
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go w.Run(ctx)
+
+	next := func() *Event {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return nil
+		}
+	}
+
+	// Let the watcher take its first scan.
+	time.Sleep(100 * time.Millisecond)
+
+	os.WriteFile(filepath.Join(path, "opcua_tool"), []byte("v1"), 0755)
+	os.WriteFile(filepath.Join(path, "opcua_tool.json"), []byte("{}"), 0644)
+
+	event := next()
+	assert.Equal("opcua", event.Driver)
+	assert.Equal(Added, event.Op)
+
+	os.WriteFile(filepath.Join(path, "modbus_tool"), []byte("v1.1"), 0755)
+
+	event = next()
+	assert.Equal("modbus", event.Driver)
+	assert.Equal(Changed, event.Op)
+
+	os.Remove(filepath.Join(path, "opcua_tool"))
+
+	event = next()
+	assert.Equal("opcua", event.Driver)
+	assert.Equal(Removed, event.Op)
+
+	restarter.Lock()
+	assert.Equal([]string{"opcua", "modbus", "opcua"}, restarter.restarted)
+	restarter.Unlock()
+}
@@ -4,16 +4,36 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
+
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/safety"
 )
 
 type EndpointSet struct {
-	CheckConnection endpoint.Endpoint
-	ListDrivers     endpoint.Endpoint
-	Schema          endpoint.Endpoint
-	Instruction     endpoint.Endpoint
-	ReadPoints      endpoint.Endpoint
+	CheckConnection      endpoint.Endpoint
+	ListDrivers          endpoint.Endpoint
+	InstallDriver        endpoint.Endpoint
+	UninstallDriver      endpoint.Endpoint
+	Schema               endpoint.Endpoint
+	Instruction          endpoint.Endpoint
+	ReadPoints           endpoint.Endpoint
+	ListMachines         endpoint.Endpoint
+	MachineStatus        endpoint.Endpoint
+	MachineStatusHistory endpoint.Endpoint
+	PointValues          endpoint.Endpoint
+	WritePoints          endpoint.Endpoint
+	ListAlarms           endpoint.Endpoint
+	AcknowledgeAlarm     endpoint.Endpoint
+	ShelveAlarm          endpoint.Endpoint
+	ReadHistory          endpoint.Endpoint
+	ProductionReport     endpoint.Endpoint
+	ProductionHistory    endpoint.Endpoint
 }
 
 type CheckConnectionRequest struct {
@@ -39,6 +59,29 @@ func ListDriversEndpoint(svc Service) endpoint.Endpoint {
 	}
 }
 
+func InstallDriverEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(install.Request)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.InstallDriver(ctx, req)
+	}
+}
+
+func UninstallDriverEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		driver, ok := request.(string)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		err := svc.UninstallDriver(ctx, driver)
+		return nil, err
+	}
+}
+
 func SchemaEndpoint(svc Service) endpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
 		driver, ok := request.(string)
@@ -76,3 +119,142 @@ func ReadPointsEndpoint(svc Service) endpoint.Endpoint {
 		return svc.ReadPoints(ctx, req.Driver, req.Raw)
 	}
 }
+
+func ListMachinesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		return svc.ListMachines(ctx)
+	}
+}
+
+func MachineStatusEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		id, ok := request.(machine.MachineID)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.MachineStatus(ctx, id)
+	}
+}
+
+func PointValuesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		id, ok := request.(machine.MachineID)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.PointValues(ctx, id)
+	}
+}
+
+func WritePointsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(safety.Request)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.WritePoints(ctx, &req)
+	}
+}
+
+type MachineStatusHistoryRequest struct {
+	MachineID machine.MachineID `json:"machine_id"`
+	Since     time.Time         `json:"since"`
+}
+
+func MachineStatusHistoryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(MachineStatusHistoryRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.MachineStatusHistory(ctx, req.MachineID, req.Since)
+	}
+}
+
+func ListAlarmsEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		filter, ok := request.(alarm.Filter)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.ListAlarms(ctx, filter)
+	}
+}
+
+type AcknowledgeAlarmRequest struct {
+	ID      string `json:"id"`
+	User    string `json:"user"`
+	Comment string `json:"comment"`
+}
+
+func AcknowledgeAlarmEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(AcknowledgeAlarmRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.AcknowledgeAlarm(ctx, req.ID, req.User, req.Comment)
+	}
+}
+
+type ShelveAlarmRequest struct {
+	ID       string           `json:"id"`
+	User     string           `json:"user"`
+	Comment  string           `json:"comment"`
+	Duration machine.Duration `json:"duration"`
+}
+
+func ShelveAlarmEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(ShelveAlarmRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.ShelveAlarm(ctx, req.ID, req.User, req.Comment, time.Duration(req.Duration))
+	}
+}
+
+func ReadHistoryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		query, ok := request.(historian.Query)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.ReadHistory(ctx, query)
+	}
+}
+
+func ProductionReportEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		id, ok := request.(machine.MachineID)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.ProductionReport(ctx, id)
+	}
+}
+
+type ProductionHistoryRequest struct {
+	MachineID machine.MachineID `json:"machine_id"`
+	Since     time.Time         `json:"since"`
+}
+
+func ProductionHistoryEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(ProductionHistoryRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.ProductionHistory(ctx, req.MachineID, req.Since)
+	}
+}
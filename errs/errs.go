@@ -0,0 +1,194 @@
+// Package errs defines the typed errors shared by the service, the drivers
+// and the transports. Each error carries a code that the transports render
+// as their own status (HTTP status, NATS error code, MCP error result), and
+// that survives the round trip back into an error on the client side.
+package errs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type Code string
+
+const (
+	Unknown            Code = "unknown"
+	InvalidArgument    Code = "invalid_argument"
+	NotFound           Code = "not_found"
+	FailedPrecondition Code = "failed_precondition"
+	PermissionDenied   Code = "permission_denied"
+	Unauthenticated    Code = "unauthenticated"
+	ResourceExhausted  Code = "resource_exhausted"
+	Unavailable        Code = "unavailable"
+	Timeout            Code = "timeout"
+	Canceled           Code = "canceled"
+	DriverError        Code = "driver_error"
+	Internal           Code = "internal"
+)
+
+var statuses = map[Code]int{
+	Unknown:            http.StatusInternalServerError,
+	InvalidArgument:    http.StatusBadRequest,
+	NotFound:           http.StatusNotFound,
+	FailedPrecondition: http.StatusConflict,
+	PermissionDenied:   http.StatusForbidden,
+	Unauthenticated:    http.StatusUnauthorized,
+	ResourceExhausted:  http.StatusTooManyRequests,
+	Unavailable:        http.StatusServiceUnavailable,
+	Timeout:            http.StatusGatewayTimeout,
+	Canceled:           499, // Client Closed Request
+	DriverError:        http.StatusBadGateway,
+	Internal:           http.StatusInternalServerError,
+}
+
+// HTTPStatus returns the HTTP status of the code.
+func (c Code) HTTPStatus() int {
+	status, ok := statuses[c]
+	if !ok {
+		return http.StatusInternalServerError
+	}
+
+	return status
+}
+
+// CodeFromStatus returns the code of an HTTP status, for peers that only
+// send a status (e.g., "417" from older edges).
+func CodeFromStatus(status string) Code {
+	n, err := strconv.Atoi(status)
+	if err != nil {
+		return Unknown
+	}
+
+	// Older edges reported every failure as 417.
+	if n == http.StatusExpectationFailed {
+		return Unknown
+	}
+
+	for code, s := range statuses {
+		if s == n && code != Unknown && code != Internal {
+			return code
+		}
+	}
+
+	if n >= 500 {
+		return Internal
+	}
+
+	return Unknown
+}
+
+// Error is an error with a code and optional details.
+type Error struct {
+	Code    Code           `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+
+	cause error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Wrap returns an error with the code and the message of err, keeping err
+// as its cause. Errors that already carry a code keep it.
+func Wrap(code Code, err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return From(err)
+	}
+
+	return &Error{
+		Code:    code,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+// WithDetail returns a copy of the error with the detail added.
+func (e *Error) WithDetail(key string, value any) *Error {
+	details := make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		details[k] = v
+	}
+
+	details[key] = value
+
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors with the same code and message, so that sentinel
+// errors still match once they have been sent over a transport.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return e.Code == t.Code && e.Message == t.Message
+}
+
+// From converts any error into an Error. The code of the first Error found
+// in the chain is kept along with the full message; context errors map to
+// timeout and canceled, and anything else is unknown.
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		if e == err {
+			return e
+		}
+
+		return &Error{
+			Code:    e.Code,
+			Message: err.Error(),
+			Details: e.Details,
+			cause:   err,
+		}
+	}
+
+	code := Unknown
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		code = Timeout
+	case errors.Is(err, context.Canceled):
+		code = Canceled
+	}
+
+	return &Error{
+		Code:    code,
+		Message: err.Error(),
+		cause:   err,
+	}
+}
+
+// CodeOf returns the code of the error, or Unknown.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	return From(err).Code
+}
@@ -0,0 +1,59 @@
+package errs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errPointNotFound = New(NotFound, "point not found")
+
+func TestRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	err := fmt.Errorf("read points: %w", errPointNotFound.WithDetail("point", "temperature"))
+
+	e := From(err)
+	assert.Equal(NotFound, e.Code)
+	assert.Equal("read points: point not found", e.Message)
+	assert.Equal("temperature", e.Details["point"])
+	assert.Equal(http.StatusNotFound, e.Code.HTTPStatus())
+
+	bs, jsonErr := json.Marshal(e)
+	if !assert.NoError(jsonErr) {
+		return
+	}
+
+	var decoded *Error
+	if !assert.NoError(json.Unmarshal(bs, &decoded)) {
+		return
+	}
+
+	assert.Equal(NotFound, decoded.Code)
+	assert.Equal("temperature", decoded.Details["point"])
+
+	// A sentinel sent as is still matches once decoded.
+	bs, _ = json.Marshal(errPointNotFound)
+	json.Unmarshal(bs, &decoded)
+	assert.ErrorIs(decoded, errPointNotFound)
+}
+
+func TestFrom(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(Timeout, CodeOf(context.DeadlineExceeded))
+	assert.Equal(Canceled, CodeOf(context.Canceled))
+	assert.Equal(Unknown, CodeOf(errors.New("boom")))
+	assert.Equal(Unavailable, CodeOf(Wrap(Unavailable, errors.New("boom"))))
+
+	// Wrapping keeps the code of errors that already have one.
+	assert.Equal(NotFound, CodeOf(Wrap(Internal, errPointNotFound)))
+
+	assert.Equal(NotFound, CodeFromStatus("404"))
+	assert.Equal(Unknown, CodeFromStatus("417"))
+}
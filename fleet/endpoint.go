@@ -0,0 +1,25 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func ListEdgesEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		return svc.ListEdges(ctx)
+	}
+}
+
+func FanOutEndpoint(svc Service) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(FanOutRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		return svc.FanOut(ctx, req)
+	}
+}
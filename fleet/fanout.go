@@ -0,0 +1,205 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/flarexio/core/model"
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrNoEdges          = errs.New(errs.InvalidArgument, "no edges")
+	ErrInvalidOperation = errs.New(errs.InvalidArgument, "invalid operation")
+)
+
+// DefaultEdgeTimeout bounds the call to each edge of a fan-out.
+var DefaultEdgeTimeout = 5 * time.Second
+
+// Operation is a call that can be fanned out across edges.
+type Operation string
+
+const (
+	ReadPoints      Operation = "read_points"
+	CheckConnection Operation = "check_connection"
+	ListDrivers     Operation = "list_drivers"
+)
+
+// FanOutRequest runs an operation against a set of edges. Edges are edge IDs
+// or wildcard patterns (e.g., "*", "line-*") matched against the online
+// edges.
+type FanOutRequest struct {
+	Edges     []string         `json:"edges"`
+	Operation Operation        `json:"operation"`
+	Timeout   machine.Duration `json:"timeout,omitempty"`
+
+	// Driver and Raw are the arguments of read_points.
+	Driver string          `json:"driver,omitempty"`
+	Raw    json.RawMessage `json:"raw,omitempty"`
+
+	// Network and Address are the arguments of check_connection.
+	Network string `json:"network,omitempty"`
+	Address string `json:"address,omitempty"`
+}
+
+func (req *FanOutRequest) Validate() error {
+	if len(req.Edges) == 0 {
+		return ErrNoEdges
+	}
+
+	switch req.Operation {
+	case ReadPoints:
+		if req.Driver == "" {
+			return errs.New(errs.InvalidArgument, "driver is required")
+		}
+	case CheckConnection:
+		if req.Network == "" || req.Address == "" {
+			return errs.New(errs.InvalidArgument, "network and address are required")
+		}
+	case ListDrivers:
+	default:
+		return ErrInvalidOperation
+	}
+
+	if req.Timeout < 0 {
+		return errs.New(errs.InvalidArgument, "timeout must not be negative")
+	}
+
+	return nil
+}
+
+// EdgeResult is the outcome of an operation on one edge: either a result
+// or an error.
+type EdgeResult struct {
+	EdgeID   string           `json:"edge_id"`
+	Result   any              `json:"result,omitempty"`
+	Error    *errs.Error      `json:"error,omitempty"`
+	Duration machine.Duration `json:"duration"`
+}
+
+// FanOutResult holds the result of every edge, keyed by edge ID. A fan-out
+// succeeds as a whole even when some edges fail.
+type FanOutResult struct {
+	Operation Operation              `json:"operation"`
+	Results   map[string]*EdgeResult `json:"results"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+}
+
+// resolve expands the patterns of the request into edge IDs. Patterns
+// without wildcards are taken as is, so that edges not yet discovered can
+// still be called.
+func resolve(patterns []string, edges []*Edge) []string {
+	set := make(map[string]struct{})
+
+	for _, pattern := range patterns {
+		if !isPattern(pattern) {
+			set[pattern] = struct{}{}
+			continue
+		}
+
+		for _, edge := range edges {
+			if ok, _ := path.Match(pattern, edge.EdgeID); ok && edge.Online {
+				set[edge.EdgeID] = struct{}{}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+func isPattern(s string) bool {
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[':
+			return true
+		}
+	}
+
+	return false
+}
+
+// fanOut calls the operation on every edge concurrently, each within its
+// own timeout.
+func fanOut(ctx context.Context, svc iiot.Service, ids []string, req FanOutRequest) *FanOutResult {
+	timeout := time.Duration(req.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultEdgeTimeout
+	}
+
+	results := make([]*EdgeResult, len(ids))
+
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			ctx = context.WithValue(ctx, model.EdgeID, id)
+
+			start := time.Now()
+			result, err := call(ctx, svc, req)
+
+			results[i] = &EdgeResult{
+				EdgeID:   id,
+				Result:   result,
+				Error:    errs.From(err),
+				Duration: machine.Duration(time.Since(start)),
+			}
+		}(i, id)
+	}
+
+	wg.Wait()
+
+	out := &FanOutResult{
+		Operation: req.Operation,
+		Results:   make(map[string]*EdgeResult, len(results)),
+	}
+
+	for _, result := range results {
+		out.Results[result.EdgeID] = result
+
+		if result.Error != nil {
+			out.Failed++
+		} else {
+			out.Succeeded++
+		}
+	}
+
+	return out
+}
+
+func call(ctx context.Context, svc iiot.Service, req FanOutRequest) (any, error) {
+	switch req.Operation {
+	case ReadPoints:
+		return svc.ReadPoints(ctx, req.Driver, req.Raw)
+
+	case CheckConnection:
+		if err := svc.CheckConnection(ctx, req.Network, req.Address); err != nil {
+			return nil, err
+		}
+
+		return "Connection successful", nil
+
+	case ListDrivers:
+		return svc.ListDrivers(ctx)
+
+	default:
+		return nil, ErrInvalidOperation
+	}
+}
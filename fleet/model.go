@@ -0,0 +1,52 @@
+package fleet
+
+import (
+	"context"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrEdgeNotFound = errs.New(errs.NotFound, "edge not found")
+)
+
+// MachineSummary is the status of a machine of an edge.
+type MachineSummary struct {
+	MachineID machine.MachineID     `json:"machine_id"`
+	Status    machine.MachineStatus `json:"status"`
+}
+
+// Heartbeat is published periodically by every edge to announce what it
+// can do.
+type Heartbeat struct {
+	EdgeID   string            `json:"edge_id"`
+	Version  string            `json:"version"`
+	Time     time.Time         `json:"time"`
+	Drivers  []string          `json:"drivers"`
+	Machines []*MachineSummary `json:"machines"`
+}
+
+type HeartbeatHandler func(hb *Heartbeat)
+
+// HeartbeatSource builds the heartbeat of the local edge.
+type HeartbeatSource func(ctx context.Context) (*Heartbeat, error)
+
+// Edge is an edge of the fleet, as known from service discovery and its
+// heartbeats.
+type Edge struct {
+	EdgeID    string            `json:"edge_id"`
+	ServiceID string            `json:"service_id,omitempty"`
+	Version   string            `json:"version"`
+	Online    bool              `json:"online"`
+	LastSeen  time.Time         `json:"last_seen"`
+	Drivers   []string          `json:"drivers"`
+	Machines  []*MachineSummary `json:"machines"`
+	Endpoints []string          `json:"endpoints,omitempty"`
+}
+
+// Discoverer finds the edges currently running the IIoT service.
+type Discoverer interface {
+	Discover(ctx context.Context) ([]*Edge, error)
+}
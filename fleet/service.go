@@ -0,0 +1,189 @@
+package fleet
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/errs"
+)
+
+// DefaultHeartbeatInterval is how often edges publish their heartbeat.
+var DefaultHeartbeatInterval = 30 * time.Second
+
+type Service interface {
+	// ListEdges lists the known edges, discovering the online ones first.
+	ListEdges(ctx context.Context) ([]*Edge, error)
+
+	// Observe records the heartbeat of an edge.
+	Observe(hb *Heartbeat)
+
+	// FanOut runs an operation against a set of edges concurrently.
+	//
+	// Args:
+	//   - req: the edges, by ID or wildcard, the operation and its arguments
+	//
+	// Returns:
+	//   - the result or error of every edge, keyed by edge ID
+	FanOut(ctx context.Context, req FanOutRequest) (*FanOutResult, error)
+}
+
+// NewService returns a fleet service. Edges count as online while they
+// answered the last discovery or sent a heartbeat within the TTL. Fan-outs
+// call the edges through the given service, which resolves the edge from
+// the context (e.g., the proxy over pubsub.MakeEndpoints).
+func NewService(discoverer Discoverer, ttl time.Duration, edges iiot.Service) Service {
+	if ttl <= 0 {
+		ttl = 3 * DefaultHeartbeatInterval
+	}
+
+	return &service{
+		discoverer: discoverer,
+		ttl:        ttl,
+		target:     edges,
+		edges:      make(map[string]*Edge),
+	}
+}
+
+type service struct {
+	discoverer Discoverer
+	ttl        time.Duration
+	target     iiot.Service
+	edges      map[string]*Edge
+	sync.RWMutex
+}
+
+func (svc *service) ListEdges(ctx context.Context) ([]*Edge, error) {
+	if svc.discoverer != nil {
+		discovered, err := svc.discoverer.Discover(ctx)
+		if err != nil {
+			// Fall back to the heartbeats received so far.
+			zap.L().Warn("edge discovery failed", zap.Error(err))
+		}
+
+		now := time.Now()
+
+		svc.Lock()
+		for _, d := range discovered {
+			edge := svc.edge(d.EdgeID)
+			edge.ServiceID = d.ServiceID
+			edge.Version = d.Version
+			edge.Endpoints = d.Endpoints
+			edge.LastSeen = now
+		}
+		svc.Unlock()
+	}
+
+	svc.RLock()
+	defer svc.RUnlock()
+
+	now := time.Now()
+
+	edges := make([]*Edge, 0, len(svc.edges))
+	for _, e := range svc.edges {
+		edge := *e
+		edge.Online = now.Sub(edge.LastSeen) < svc.ttl
+		edges = append(edges, &edge)
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].EdgeID < edges[j].EdgeID
+	})
+
+	return edges, nil
+}
+
+func (svc *service) Observe(hb *Heartbeat) {
+	if hb == nil || hb.EdgeID == "" {
+		return
+	}
+
+	svc.Lock()
+	defer svc.Unlock()
+
+	edge := svc.edge(hb.EdgeID)
+	edge.Drivers = hb.Drivers
+	edge.Machines = hb.Machines
+
+	if hb.Version != "" {
+		edge.Version = hb.Version
+	}
+
+	// Trust the local clock over the clock of the edge.
+	edge.LastSeen = time.Now()
+}
+
+func (svc *service) FanOut(ctx context.Context, req FanOutRequest) (*FanOutResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	if svc.target == nil {
+		return nil, errs.New(errs.Unavailable, "fan-out not available")
+	}
+
+	var edges []*Edge
+	if slices.ContainsFunc(req.Edges, isPattern) {
+		known, err := svc.ListEdges(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		edges = known
+	}
+
+	ids := resolve(req.Edges, edges)
+	if len(ids) == 0 {
+		return nil, ErrNoEdges.WithDetail("edges", req.Edges)
+	}
+
+	return fanOut(ctx, svc.target, ids, req), nil
+}
+
+// edge returns the edge with the ID, adding it if unknown. The caller must
+// hold the lock.
+func (svc *service) edge(id string) *Edge {
+	edge, ok := svc.edges[id]
+	if !ok {
+		edge = &Edge{
+			EdgeID:   id,
+			Drivers:  make([]string, 0),
+			Machines: make([]*MachineSummary, 0),
+		}
+
+		svc.edges[id] = edge
+	}
+
+	return edge
+}
+
+// Beat publishes the heartbeat of the local edge every interval until the
+// context is done.
+func Beat(ctx context.Context, interval time.Duration, source HeartbeatSource, handler HeartbeatHandler) {
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		hb, err := source(ctx)
+		if err != nil {
+			zap.L().Warn("heartbeat failed", zap.Error(err))
+		} else {
+			handler(hb)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
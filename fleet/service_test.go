@@ -0,0 +1,113 @@
+package fleet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/core/model"
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+type fakeDiscoverer []*Edge
+
+func (d fakeDiscoverer) Discover(ctx context.Context) ([]*Edge, error) {
+	return d, nil
+}
+
+func TestListEdges(t *testing.T) {
+	assert := assert.New(t)
+
+	discoverer := fakeDiscoverer{
+		{EdgeID: "edge-b", ServiceID: "svc-b", Version: "1.0.0"},
+	}
+
+	svc := NewService(discoverer, time.Minute, nil)
+
+	svc.Observe(&Heartbeat{
+		EdgeID:   "edge-a",
+		Version:  "0.9.0",
+		Drivers:  []string{"modbus"},
+		Machines: []*MachineSummary{{MachineID: "CNC01", Status: "running"}},
+	})
+
+	edges, err := svc.ListEdges(context.Background())
+	if !assert.NoError(err) || !assert.Len(edges, 2) {
+		return
+	}
+
+	assert.Equal("edge-a", edges[0].EdgeID)
+	assert.True(edges[0].Online)
+	assert.Equal([]string{"modbus"}, edges[0].Drivers)
+
+	assert.Equal("edge-b", edges[1].EdgeID)
+	assert.Equal("svc-b", edges[1].ServiceID)
+	assert.True(edges[1].Online)
+
+	// Edges not seen within the TTL are listed as offline.
+	svc.(*service).edges["edge-a"].LastSeen = time.Now().Add(-2 * time.Minute)
+
+	edges, err = svc.ListEdges(context.Background())
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.False(edges[0].Online)
+	assert.True(edges[1].Online)
+}
+
+type fakeEdges struct {
+	iiot.Service
+}
+
+func (f fakeEdges) ListDrivers(ctx context.Context) ([]*tool.Descriptor, error) {
+	switch ctx.Value(model.EdgeID) {
+	case "line-1":
+		return []*tool.Descriptor{{Info: tool.Info{Name: "modbus"}}}, nil
+	case "line-2":
+		<-ctx.Done()
+		return nil, ctx.Err()
+	default:
+		return nil, errs.New(errs.Unavailable, "edge offline")
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	assert := assert.New(t)
+
+	discoverer := fakeDiscoverer{
+		{EdgeID: "line-1"},
+		{EdgeID: "line-2"},
+		{EdgeID: "lab-1"},
+	}
+
+	svc := NewService(discoverer, time.Minute, fakeEdges{})
+
+	result, err := svc.FanOut(context.Background(), FanOutRequest{
+		Edges:     []string{"line-*", "line-3"},
+		Operation: ListDrivers,
+		Timeout:   machine.Duration(50 * time.Millisecond),
+	})
+	if !assert.NoError(err) || !assert.Len(result.Results, 3) {
+		return
+	}
+
+	assert.Equal(1, result.Succeeded)
+	assert.Equal(2, result.Failed)
+
+	assert.Equal([]*tool.Descriptor{{Info: tool.Info{Name: "modbus"}}}, result.Results["line-1"].Result)
+	assert.Nil(result.Results["line-1"].Error)
+	assert.Equal(errs.Timeout, result.Results["line-2"].Error.Code)
+	assert.Equal(errs.Unavailable, result.Results["line-3"].Error.Code)
+
+	_, err = svc.FanOut(context.Background(), FanOutRequest{
+		Edges:     []string{"cell-*"},
+		Operation: ListDrivers,
+	})
+	assert.ErrorIs(err, ErrNoEdges)
+}
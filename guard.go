@@ -0,0 +1,33 @@
+package iiot
+
+import (
+	"context"
+
+	"github.com/flarexio/iiot/safety"
+)
+
+// WriteGuardMiddleware passes the point writes through the guard, so that
+// they are proposed, confirmed, limited and rate limited by its policy
+// before they reach the drivers.
+func WriteGuardMiddleware(guard safety.Guard) ServiceMiddleware {
+	return func(next Service) Service {
+		return &writeGuardMiddleware{
+			Service: next,
+			guard:   guard,
+		}
+	}
+}
+
+type writeGuardMiddleware struct {
+	Service
+	guard safety.Guard
+}
+
+func (mw *writeGuardMiddleware) WritePoints(ctx context.Context, req *safety.Request) (*safety.Result, error) {
+	return mw.guard.Write(ctx, req, func(ctx context.Context, writes []*safety.Write, dryRun bool) (*safety.Result, error) {
+		return mw.Service.WritePoints(ctx, &safety.Request{
+			Writes: writes,
+			DryRun: dryRun,
+		})
+	})
+}
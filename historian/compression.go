@@ -0,0 +1,174 @@
+package historian
+
+import (
+	"math"
+	"time"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+// Compressor decides which samples of a point are stored.
+type Compressor interface {
+	// Add offers a record and returns the records to store, if any.
+	Add(r *Record) []*Record
+
+	// Flush returns the record held back by the compressor, if any.
+	Flush() []*Record
+}
+
+func NewCompressor(c Compression) Compressor {
+	maxInterval := time.Duration(c.MaxInterval)
+
+	switch c.Method {
+	case Deadband:
+		return &deadband{
+			deviation:   c.Deviation,
+			maxInterval: maxInterval,
+		}
+
+	case SwingingDoor:
+		return &swingingDoor{
+			deviation:   c.Deviation,
+			maxInterval: maxInterval,
+			fallback: &deadband{
+				maxInterval: maxInterval,
+			},
+		}
+
+	default:
+		return new(noCompression)
+	}
+}
+
+type noCompression struct{}
+
+func (c *noCompression) Add(r *Record) []*Record {
+	return []*Record{r}
+}
+
+func (c *noCompression) Flush() []*Record {
+	return nil
+}
+
+// deadband stores a value when it differs from the last stored value by
+// more than the deviation. Non-numeric values are stored when they change.
+type deadband struct {
+	deviation   float64
+	maxInterval time.Duration
+	last        *Record
+}
+
+func (c *deadband) Add(r *Record) []*Record {
+	if c.last == nil || c.exceeded(r) || c.expired(r) {
+		c.last = r
+		return []*Record{r}
+	}
+
+	return nil
+}
+
+func (c *deadband) exceeded(r *Record) bool {
+	v, okV := machine.ToFloat(r.Value)
+	lv, okL := machine.ToFloat(c.last.Value)
+	if !okV || !okL {
+		return !machine.Equal(r.Value, c.last.Value)
+	}
+
+	if c.deviation == 0 {
+		return v != lv
+	}
+
+	return math.Abs(v-lv) > c.deviation
+}
+
+func (c *deadband) expired(r *Record) bool {
+	return c.maxInterval > 0 && r.Time.Sub(c.last.Time) >= c.maxInterval
+}
+
+func (c *deadband) Flush() []*Record {
+	return nil
+}
+
+// swingingDoor implements the swinging door trending algorithm: a value is
+// stored only when a straight line from the last stored value can no longer
+// represent all values received since within the deviation. Non-numeric
+// values fall back to the deadband.
+type swingingDoor struct {
+	deviation   float64
+	maxInterval time.Duration
+	fallback    *deadband
+
+	archived *Record
+	held     *Record
+	upper    float64
+	lower    float64
+}
+
+func (c *swingingDoor) Add(r *Record) []*Record {
+	v, ok := machine.ToFloat(r.Value)
+	if !ok {
+		return c.fallback.Add(r)
+	}
+
+	if c.archived == nil {
+		c.archive(r)
+		return []*Record{r}
+	}
+
+	if c.maxInterval > 0 && r.Time.Sub(c.archived.Time) >= c.maxInterval {
+		// The held value ends the line from the archived value, as when
+		// the door opens.
+		var stored []*Record
+		if c.held != nil {
+			stored = append(stored, c.held)
+		}
+
+		c.archive(r)
+		return append(stored, r)
+	}
+
+	dt := r.Time.Sub(c.archived.Time).Seconds()
+	if dt <= 0 {
+		return nil
+	}
+
+	var stored []*Record
+
+	c.narrow(v, dt)
+	if c.lower > c.upper && c.held != nil {
+		// The door opened: the held value is the last one that fits
+		// the line from the archived value.
+		stored = append(stored, c.held)
+		c.archive(c.held)
+
+		dt = r.Time.Sub(c.archived.Time).Seconds()
+		c.narrow(v, dt)
+	}
+
+	c.held = r
+	return stored
+}
+
+func (c *swingingDoor) narrow(v float64, dt float64) {
+	av, _ := machine.ToFloat(c.archived.Value)
+
+	c.upper = min(c.upper, (v+c.deviation-av)/dt)
+	c.lower = max(c.lower, (v-c.deviation-av)/dt)
+}
+
+func (c *swingingDoor) archive(r *Record) {
+	c.archived = r
+	c.held = nil
+	c.upper = math.Inf(1)
+	c.lower = math.Inf(-1)
+}
+
+func (c *swingingDoor) Flush() []*Record {
+	if c.held == nil {
+		return nil
+	}
+
+	held := c.held
+	c.archive(held)
+	return []*Record{held}
+}
@@ -0,0 +1,89 @@
+package historian
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+func records(start time.Time, values ...any) []*Record {
+	rs := make([]*Record, len(values))
+	for i, v := range values {
+		rs[i] = &Record{
+			Time:  start.Add(time.Duration(i) * time.Second),
+			Value: v,
+		}
+	}
+
+	return rs
+}
+
+func compress(c Compressor, rs []*Record) []any {
+	stored := make([]any, 0)
+	for _, r := range rs {
+		for _, s := range c.Add(r) {
+			stored = append(stored, s.Value)
+		}
+	}
+
+	for _, s := range c.Flush() {
+		stored = append(stored, s.Value)
+	}
+
+	return stored
+}
+
+func TestDeadband(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCompressor(Compression{
+		Method:    Deadband,
+		Deviation: 1,
+	})
+
+	rs := records(time.Now(), 10.0, 10.5, 11.2, 11.0, 9.9, "bad", "bad", "ok")
+	assert.Equal([]any{10.0, 11.2, 9.9, "bad", "ok"}, compress(c, rs))
+}
+
+func TestDeadbandMaxInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCompressor(Compression{
+		Method:      Deadband,
+		MaxInterval: machine.Duration(2 * time.Second),
+	})
+
+	rs := records(time.Now(), 1.0, 1.0, 1.0, 1.0, 1.0)
+	assert.Len(compress(c, rs), 3)
+}
+
+func TestSwingingDoor(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCompressor(Compression{
+		Method:    SwingingDoor,
+		Deviation: 0.5,
+	})
+
+	// A ramp followed by a plateau is stored as its corners.
+	rs := records(time.Now(), 0.0, 1.0, 2.0, 3.0, 4.0, 4.0, 4.0, 4.0)
+	assert.Equal([]any{0.0, 4.0, 4.0}, compress(c, rs))
+}
+
+func TestSwingingDoorMaxInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	c := NewCompressor(Compression{
+		Method:      SwingingDoor,
+		Deviation:   0.5,
+		MaxInterval: machine.Duration(3 * time.Second),
+	})
+
+	// The value held when the interval expires is stored, the line from
+	// the first value to the last missing the peak otherwise.
+	rs := records(time.Now(), 0.0, 1.0, 2.0, 0.0)
+	assert.Equal([]any{0.0, 2.0, 0.0}, compress(c, rs))
+}
@@ -0,0 +1,150 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrQueueFull = errs.New(errs.Unavailable, "forward queue full")
+)
+
+// Record is a point value stored by the historian. Records keep the time
+// the value was sampled, which is preserved when they are forwarded.
+type Record struct {
+	machine.PointRef
+	Time  time.Time `json:"time"`
+	Value any       `json:"value"`
+}
+
+// Rollup aggregates the numeric samples of a point over an interval that
+// starts at Start.
+type Rollup struct {
+	machine.PointRef
+	Interval machine.Duration `json:"interval"`
+	Start    time.Time        `json:"start"`
+	Min      float64          `json:"min"`
+	Max      float64          `json:"max"`
+	Sum      float64          `json:"sum"`
+	Count    int              `json:"count"`
+	First    float64          `json:"first"`
+	Last     float64          `json:"last"`
+}
+
+func (r *Rollup) Avg() float64 {
+	if r.Count == 0 {
+		return 0
+	}
+
+	return r.Sum / float64(r.Count)
+}
+
+func (r *Rollup) add(v float64) {
+	if r.Count == 0 {
+		r.Min = v
+		r.Max = v
+		r.First = v
+	}
+
+	r.Min = min(r.Min, v)
+	r.Max = max(r.Max, v)
+	r.Sum += v
+	r.Last = v
+	r.Count++
+}
+
+type CompressionMethod string
+
+const (
+	NoCompression CompressionMethod = "none"
+	Deadband      CompressionMethod = "deadband"
+	SwingingDoor  CompressionMethod = "swinging_door"
+)
+
+// Compression configures how samples are reduced before being stored.
+// Deviation is the deadband or the swinging door width in the unit of the
+// point, and MaxInterval forces a value to be stored at least that often.
+type Compression struct {
+	Method      CompressionMethod `json:"method"`
+	Deviation   float64           `json:"deviation"`
+	MaxInterval machine.Duration  `json:"max_interval"`
+}
+
+type Config struct {
+	// Compression is the default compression of all points.
+	Compression Compression `json:"compression"`
+
+	// Points overrides the compression of individual points, keyed by the
+	// point reference (e.g., "M1/PLC1/temperature").
+	Points map[string]*Compression `json:"points"`
+
+	// Retention is how long raw values are kept.
+	Retention machine.Duration `json:"retention"`
+
+	// Rollups are the intervals the values are downsampled to.
+	Rollups []machine.Duration `json:"rollups"`
+
+	// RollupRetention is how long rollups are kept.
+	RollupRetention machine.Duration `json:"rollup_retention"`
+
+	// QueueMaxBytes limits the size of the store-and-forward queue.
+	QueueMaxBytes int64 `json:"queue_max_bytes"`
+}
+
+func (cfg *Config) compression(ref machine.PointRef) Compression {
+	if c, ok := cfg.Points[ref.String()]; ok && c != nil {
+		return *c
+	}
+
+	return cfg.Compression
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Compression: Compression{
+			Method:      Deadband,
+			MaxInterval: machine.Duration(10 * time.Minute),
+		},
+		Points:    make(map[string]*Compression),
+		Retention: machine.Duration(30 * 24 * time.Hour),
+		Rollups: []machine.Duration{
+			machine.Duration(time.Minute),
+			machine.Duration(time.Hour),
+		},
+		RollupRetention: machine.Duration(365 * 24 * time.Hour),
+		QueueMaxBytes:   256 << 20,
+	}
+}
+
+// LoadConfig reads the historian configuration from the given JSON file on
+// top of the defaults. A missing file yields the default configuration.
+func LoadConfig(filename string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	f, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(f, cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Publisher forwards records upstream. Publish must only return nil once
+// all records have been accepted, as they are removed from the queue.
+type Publisher interface {
+	Publish(ctx context.Context, records []*Record) error
+}
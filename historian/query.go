@@ -0,0 +1,660 @@
+package historian
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrInvalidQuery     = errs.New(errs.InvalidArgument, "invalid history query")
+	ErrInvalidPageToken = errs.New(errs.InvalidArgument, "invalid page token")
+)
+
+const (
+	DefaultLimit = 1000
+	MaxLimit     = 10000
+)
+
+type ReadMode string
+
+const (
+	Raw          ReadMode = "raw"
+	Interpolated ReadMode = "interpolated"
+	Aggregated   ReadMode = "aggregate"
+)
+
+type Aggregate string
+
+const (
+	Min             Aggregate = "min"
+	Max             Aggregate = "max"
+	Avg             Aggregate = "avg"
+	Count           Aggregate = "count"
+	TimeWeightedAvg Aggregate = "twa"
+	First           Aggregate = "first"
+	Last            Aggregate = "last"
+)
+
+// Query selects the history of a point over [Start, End).
+//
+// Raw returns the stored values, Interpolated returns a value at every
+// Interval interpolated linearly between the stored values (non-numeric
+// values hold the previous value), and Aggregated returns the Aggregates of
+// every Interval. Results are paged by Limit; pass the NextPageToken of a
+// result to read the following page. With AllPages, the following pages are
+// read into the same result, until MaxLimit values are read.
+type Query struct {
+	Point      machine.PointRef `json:"point"`
+	Start      time.Time        `json:"start"`
+	End        time.Time        `json:"end"`
+	Mode       ReadMode         `json:"mode"`
+	Interval   machine.Duration `json:"interval,omitempty"`
+	Aggregates []Aggregate      `json:"aggregates,omitempty"`
+	Limit      int              `json:"limit,omitempty"`
+	PageToken  string           `json:"page_token,omitempty"`
+	AllPages   bool             `json:"all_pages,omitempty"`
+}
+
+func (q *Query) Validate() error {
+	if q.Point.MachineID == "" || q.Point.Point == "" {
+		return errors.Join(ErrInvalidQuery, errors.New("point is required"))
+	}
+
+	if q.Start.IsZero() || q.End.IsZero() || !q.Start.Before(q.End) {
+		return errors.Join(ErrInvalidQuery, errors.New("start must be before end"))
+	}
+
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return errors.Join(ErrInvalidQuery, errors.New("limit out of range"))
+	}
+
+	switch q.Mode {
+	case Raw:
+
+	case Interpolated:
+		if q.Interval <= 0 {
+			return errors.Join(ErrInvalidQuery, errors.New("interval is required"))
+		}
+
+	case Aggregated:
+		if q.Interval <= 0 {
+			return errors.Join(ErrInvalidQuery, errors.New("interval is required"))
+		}
+
+		if len(q.Aggregates) == 0 {
+			return errors.Join(ErrInvalidQuery, errors.New("aggregates are required"))
+		}
+
+		for _, a := range q.Aggregates {
+			switch a {
+			case Min, Max, Avg, Count, TimeWeightedAvg, First, Last:
+			default:
+				return errors.Join(ErrInvalidQuery, errors.New("unsupported aggregate: "+string(a)))
+			}
+		}
+
+	default:
+		return errors.Join(ErrInvalidQuery, errors.New("unsupported mode"))
+	}
+
+	return nil
+}
+
+// Value is a value of a point in a history result. Aggregated results set
+// Aggregates for the interval starting at Time instead of Value.
+type Value struct {
+	Time       time.Time             `json:"time"`
+	Value      any                   `json:"value,omitempty"`
+	Aggregates map[Aggregate]float64 `json:"aggregates,omitempty"`
+}
+
+type Result struct {
+	Point         machine.PointRef `json:"point"`
+	Mode          ReadMode         `json:"mode"`
+	Values        []*Value         `json:"values"`
+	NextPageToken string           `json:"next_page_token,omitempty"`
+}
+
+// ReadPages reads the pages of the query from its page token on, with
+// read, into a single result. It stops once MaxLimit values are read; the
+// NextPageToken of the result resumes after them.
+func ReadPages(q Query, read func(Query) (*Result, error)) (*Result, error) {
+	q.AllPages = false
+
+	var result *Result
+	for {
+		page, err := read(q)
+		if err != nil {
+			return nil, err
+		}
+
+		if result == nil {
+			result = page
+		} else {
+			result.Values = append(result.Values, page.Values...)
+			result.NextPageToken = page.NextPageToken
+		}
+
+		if page.NextPageToken == "" || len(result.Values) >= MaxLimit {
+			return result, nil
+		}
+
+		q.PageToken = page.NextPageToken
+	}
+}
+
+// pageToken marks where the next page starts: at Time, after skipping the
+// first Skip raw values stored at that exact time.
+type pageToken struct {
+	Time time.Time
+	Skip int
+}
+
+func (t pageToken) String() string {
+	s := t.Time.Format(time.RFC3339Nano) + "|" + strconv.Itoa(t.Skip)
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func parsePageToken(s string) (pageToken, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageToken{}, ErrInvalidPageToken
+	}
+
+	ts, skip, ok := strings.Cut(string(bs), "|")
+	if !ok {
+		return pageToken{}, ErrInvalidPageToken
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return pageToken{}, ErrInvalidPageToken
+	}
+
+	n, err := strconv.Atoi(skip)
+	if err != nil || n < 0 {
+		return pageToken{}, ErrInvalidPageToken
+	}
+
+	return pageToken{t, n}, nil
+}
+
+// read runs the query against the store. Only the values already stored
+// are visible; the values held back by the compressors are not.
+func (s *store) read(q Query, rollups []machine.Duration) (*Result, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+
+	token := pageToken{Time: q.Start}
+	if q.PageToken != "" {
+		t, err := parsePageToken(q.PageToken)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Time.Before(q.Start) || !t.Time.Before(q.End) {
+			return nil, ErrInvalidPageToken
+		}
+
+		token = t
+	}
+
+	result := &Result{
+		Point:  q.Point,
+		Mode:   q.Mode,
+		Values: make([]*Value, 0),
+	}
+
+	var err error
+	switch q.Mode {
+	case Raw:
+		err = s.readRaw(q, token, limit, result)
+	case Interpolated:
+		err = s.readInterpolated(q, token.Time, limit, result)
+	case Aggregated:
+		err = s.readAggregated(q, token.Time, limit, rollups, result)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// scanRecords calls fn with the raw records of the point stored on the days
+// overlapping [from, to], in the order they were stored.
+func (s *store) scanRecords(ref machine.PointRef, from, to time.Time, fn func(r *Record) error) error {
+	return s.Scan(rawSeries, from, to, func(line []byte) error {
+		var r *Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil
+		}
+
+		if r.PointRef != ref {
+			return nil
+		}
+
+		return fn(r)
+	})
+}
+
+func (s *store) readRaw(q Query, token pageToken, limit int, result *Result) error {
+	skipped := 0
+	return s.scanRecords(q.Point, token.Time, q.End, func(r *Record) error {
+		if r.Time.Before(token.Time) || !r.Time.Before(q.End) {
+			return nil
+		}
+
+		if r.Time.Equal(token.Time) && skipped < token.Skip {
+			skipped++
+			return nil
+		}
+
+		if len(result.Values) == limit {
+			next := pageToken{Time: r.Time}
+			for _, v := range result.Values {
+				if v.Time.Equal(r.Time) {
+					next.Skip++
+				}
+			}
+
+			if next.Time.Equal(token.Time) {
+				next.Skip += token.Skip
+			}
+
+			result.NextPageToken = next.String()
+			return errStop
+		}
+
+		result.Values = append(result.Values, &Value{
+			Time:  r.Time,
+			Value: r.Value,
+		})
+
+		return nil
+	})
+}
+
+// window loads the records of the point in [from, to), along with the last
+// record before from (looking back up to a day) and the first record at or
+// after to.
+func (s *store) window(ref machine.PointRef, from, to time.Time) (prev *Record, records []*Record, next *Record, err error) {
+	records = make([]*Record, 0)
+
+	err = s.scanRecords(ref, from.Add(-24*time.Hour), to.Add(24*time.Hour), func(r *Record) error {
+		switch {
+		case r.Time.Before(from):
+			prev = r
+		case r.Time.Before(to):
+			records = append(records, r)
+		case next == nil:
+			next = r
+		default:
+			return errStop
+		}
+
+		return nil
+	})
+
+	return prev, records, next, err
+}
+
+func (s *store) readInterpolated(q Query, from time.Time, limit int, result *Result) error {
+	interval := time.Duration(q.Interval)
+
+	to := from.Add(time.Duration(limit) * interval)
+	if to.After(q.End) {
+		to = q.End
+	}
+
+	prev, records, next, err := s.window(q.Point, from, to)
+	if err != nil {
+		return err
+	}
+
+	if next != nil {
+		records = append(records, next)
+	}
+
+	i := 0
+	for t := from; t.Before(to); t = t.Add(interval) {
+		for i < len(records) && !records[i].Time.After(t) {
+			prev = records[i]
+			i++
+		}
+
+		if prev == nil {
+			continue
+		}
+
+		value := prev.Value
+		if i < len(records) {
+			value = interpolate(prev, records[i], t)
+		}
+
+		result.Values = append(result.Values, &Value{
+			Time:  t,
+			Value: value,
+		})
+	}
+
+	if to.Before(q.End) {
+		result.NextPageToken = pageToken{Time: to}.String()
+	}
+
+	return nil
+}
+
+func interpolate(a, b *Record, t time.Time) any {
+	av, okA := machine.ToFloat(a.Value)
+	bv, okB := machine.ToFloat(b.Value)
+	if !okA || !okB {
+		return a.Value
+	}
+
+	if _, ok := a.Value.(bool); ok {
+		return a.Value
+	}
+
+	span := b.Time.Sub(a.Time).Seconds()
+	if span <= 0 {
+		return a.Value
+	}
+
+	ratio := t.Sub(a.Time).Seconds() / span
+	return av + (bv-av)*ratio
+}
+
+func (s *store) readAggregated(q Query, from time.Time, limit int, rollups []machine.Duration, result *Result) error {
+	interval := time.Duration(q.Interval)
+
+	to := from.Add(time.Duration(limit) * interval)
+	if to.After(q.End) {
+		to = q.End
+	}
+
+	// Ranges without any rollup, such as the ones recorded before the
+	// rollup was configured, are aggregated from the stored records.
+	found := false
+	if rollup, ok := selectRollup(q, from, to, rollups); ok {
+		var err error
+		found, err = s.readRollups(q, from, to, rollup, result)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !found {
+		if err := s.readBuckets(q, from, to, result); err != nil {
+			return err
+		}
+	}
+
+	if to.Before(q.End) {
+		result.NextPageToken = pageToken{Time: to}.String()
+	}
+
+	return nil
+}
+
+// selectRollup picks the largest stored rollup that the buckets of the query
+// can be built from. Rollups are only used for complete intervals, and do
+// not support the time-weighted average.
+func selectRollup(q Query, from, to time.Time, rollups []machine.Duration) (time.Duration, bool) {
+	for _, a := range q.Aggregates {
+		if a == TimeWeightedAvg {
+			return 0, false
+		}
+	}
+
+	interval := time.Duration(q.Interval)
+
+	var selected time.Duration
+	for _, r := range rollups {
+		ri := time.Duration(r)
+		if ri <= selected || interval%ri != 0 {
+			continue
+		}
+
+		if !from.Truncate(ri).Equal(from) || to.After(time.Now().Truncate(ri)) {
+			continue
+		}
+
+		selected = ri
+	}
+
+	return selected, selected > 0
+}
+
+// readRollups aggregates the buckets of the query from the rollups stored,
+// reporting whether any was found.
+func (s *store) readRollups(q Query, from, to time.Time, rollup time.Duration, result *Result) (bool, error) {
+	interval := time.Duration(q.Interval)
+
+	buckets := make(map[time.Time]*Rollup)
+	err := s.Scan(rollupSeries(machine.Duration(rollup)), from, to, func(line []byte) error {
+		var r *Rollup
+		if err := json.Unmarshal(line, &r); err != nil {
+			return nil
+		}
+
+		if r.PointRef != q.Point || r.Start.Before(from) || !r.Start.Before(to) {
+			return nil
+		}
+
+		start := from.Add(r.Start.Sub(from) / interval * interval)
+
+		b, ok := buckets[start]
+		if !ok {
+			buckets[start] = r
+			return nil
+		}
+
+		b.Min = min(b.Min, r.Min)
+		b.Max = max(b.Max, r.Max)
+		b.Sum += r.Sum
+		b.Count += r.Count
+		b.Last = r.Last
+
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	if len(buckets) == 0 {
+		return false, nil
+	}
+
+	for t := from; t.Before(to); t = t.Add(interval) {
+		b, ok := buckets[t]
+		if !ok {
+			continue
+		}
+
+		aggregates := make(map[Aggregate]float64)
+		for _, a := range q.Aggregates {
+			switch a {
+			case Min:
+				aggregates[a] = b.Min
+			case Max:
+				aggregates[a] = b.Max
+			case Avg:
+				aggregates[a] = b.Avg()
+			case Count:
+				aggregates[a] = float64(b.Count)
+			case First:
+				aggregates[a] = b.First
+			case Last:
+				aggregates[a] = b.Last
+			}
+		}
+
+		result.Values = append(result.Values, &Value{
+			Time:       t,
+			Aggregates: aggregates,
+		})
+	}
+
+	return true, nil
+}
+
+func (s *store) readBuckets(q Query, from, to time.Time, result *Result) error {
+	interval := time.Duration(q.Interval)
+
+	prev, records, _, err := s.window(q.Point, from, to)
+	if err != nil {
+		return err
+	}
+
+	i := 0
+	for start := from; start.Before(to); start = start.Add(interval) {
+		end := start.Add(interval)
+		if end.After(q.End) {
+			end = q.End
+		}
+
+		if now := time.Now(); end.After(now) {
+			end = now
+		}
+
+		b := &bucket{start: start, end: end, prev: prev}
+		for i < len(records) && records[i].Time.Before(end) {
+			b.add(records[i])
+			prev = records[i]
+			i++
+		}
+
+		aggregates := b.aggregates(q.Aggregates)
+		if aggregates == nil {
+			continue
+		}
+
+		result.Values = append(result.Values, &Value{
+			Time:       start,
+			Aggregates: aggregates,
+		})
+	}
+
+	return nil
+}
+
+// bucket aggregates the numeric values stored within [start, end). The
+// value held before the bucket (prev) only contributes to the time-weighted
+// average.
+type bucket struct {
+	start  time.Time
+	end    time.Time
+	prev   *Record
+	rollup Rollup
+	times  []time.Time
+	values []float64
+}
+
+func (b *bucket) add(r *Record) {
+	v, ok := machine.ToFloat(r.Value)
+	if !ok {
+		return
+	}
+
+	b.rollup.add(v)
+	b.times = append(b.times, r.Time)
+	b.values = append(b.values, v)
+}
+
+func (b *bucket) aggregates(requested []Aggregate) map[Aggregate]float64 {
+	twa, hasTWA := b.timeWeightedAvg()
+	if b.rollup.Count == 0 && !hasTWA {
+		return nil
+	}
+
+	aggregates := make(map[Aggregate]float64)
+	for _, a := range requested {
+		if a == TimeWeightedAvg {
+			if hasTWA {
+				aggregates[a] = twa
+			}
+
+			continue
+		}
+
+		if a == Count {
+			aggregates[a] = float64(b.rollup.Count)
+			continue
+		}
+
+		if b.rollup.Count == 0 {
+			continue
+		}
+
+		switch a {
+		case Min:
+			aggregates[a] = b.rollup.Min
+		case Max:
+			aggregates[a] = b.rollup.Max
+		case Avg:
+			aggregates[a] = b.rollup.Avg()
+		case First:
+			aggregates[a] = b.rollup.First
+		case Last:
+			aggregates[a] = b.rollup.Last
+		}
+	}
+
+	return aggregates
+}
+
+// timeWeightedAvg weights each value by how long it was held within the
+// bucket, carrying the value held before the bucket into its start.
+func (b *bucket) timeWeightedAvg() (float64, bool) {
+	times := b.times
+	values := b.values
+
+	if b.prev != nil {
+		if v, ok := machine.ToFloat(b.prev.Value); ok {
+			times = append([]time.Time{b.start}, times...)
+			values = append([]float64{v}, values...)
+		}
+	}
+
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	var sum, total float64
+	for i, v := range values {
+		end := b.end
+		if i+1 < len(times) {
+			end = times[i+1]
+		}
+
+		d := end.Sub(times[i]).Seconds()
+		if d <= 0 {
+			continue
+		}
+
+		sum += v * d
+		total += d
+	}
+
+	if total == 0 {
+		return values[len(values)-1], true
+	}
+
+	return sum / total, true
+}
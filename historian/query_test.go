@@ -0,0 +1,242 @@
+package historian
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+func TestRead(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := newStore(t.TempDir())
+	if !assert.NoError(err) {
+		return
+	}
+	defer s.Close()
+
+	ref := machine.PointRef{MachineID: "M1", ControllerID: "PLC1", Point: "temperature"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// One value every 10 seconds: 0, 10, 20, ..., 50
+	for i := range 6 {
+		r := &Record{
+			PointRef: ref,
+			Time:     start.Add(time.Duration(i) * 10 * time.Second),
+			Value:    float64(i * 10),
+		}
+
+		assert.NoError(s.Append(rawSeries, r.Time, r))
+	}
+
+	q := Query{
+		Point: ref,
+		Start: start,
+		End:   start.Add(time.Minute),
+		Mode:  Raw,
+		Limit: 4,
+	}
+
+	result, err := s.read(q, nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Len(result.Values, 4)
+	assert.NotEmpty(result.NextPageToken)
+
+	q.PageToken = result.NextPageToken
+
+	result, err = s.read(q, nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	if assert.Len(result.Values, 2) {
+		assert.Equal(40.0, result.Values[0].Value)
+	}
+	assert.Empty(result.NextPageToken)
+
+	// Interpolated halfway between the stored values.
+	q = Query{
+		Point:    ref,
+		Start:    start.Add(5 * time.Second),
+		End:      start.Add(30 * time.Second),
+		Mode:     Interpolated,
+		Interval: machine.Duration(10 * time.Second),
+	}
+
+	result, err = s.read(q, nil)
+	if !assert.NoError(err) || !assert.Len(result.Values, 3) {
+		return
+	}
+
+	assert.Equal(5.0, result.Values[0].Value)
+	assert.Equal(25.0, result.Values[2].Value)
+
+	// Aggregated over 30 seconds.
+	q = Query{
+		Point:      ref,
+		Start:      start,
+		End:        start.Add(time.Minute),
+		Mode:       Aggregated,
+		Interval:   machine.Duration(30 * time.Second),
+		Aggregates: []Aggregate{Min, Max, Avg, Count, TimeWeightedAvg},
+	}
+
+	result, err = s.read(q, nil)
+	if !assert.NoError(err) || !assert.Len(result.Values, 2) {
+		return
+	}
+
+	first := result.Values[0].Aggregates
+	assert.Equal(0.0, first[Min])
+	assert.Equal(20.0, first[Max])
+	assert.Equal(10.0, first[Avg])
+	assert.Equal(3.0, first[Count])
+	assert.Equal(10.0, first[TimeWeightedAvg])
+
+	// The last value is held until the end of the second interval.
+	second := result.Values[1].Aggregates
+	assert.Equal(40.0, second[Avg])
+	assert.InDelta((30.0*10+40.0*10+50.0*10)/30, second[TimeWeightedAvg], 1e-9)
+}
+
+func TestReadRawDuplicateTimes(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := newStore(t.TempDir())
+	if !assert.NoError(err) {
+		return
+	}
+	defer s.Close()
+
+	ref := machine.PointRef{MachineID: "M1", ControllerID: "PLC1", Point: "state"}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, v := range []string{"a", "b", "c"} {
+		assert.NoError(s.Append(rawSeries, now, &Record{ref, now, v}))
+	}
+
+	q := Query{
+		Point: ref,
+		Start: now,
+		End:   now.Add(time.Second),
+		Mode:  Raw,
+		Limit: 1,
+	}
+
+	values := make([]any, 0)
+	for {
+		result, err := s.read(q, nil)
+		if !assert.NoError(err) {
+			return
+		}
+
+		for _, v := range result.Values {
+			values = append(values, v.Value)
+		}
+
+		if result.NextPageToken == "" {
+			break
+		}
+
+		q.PageToken = result.NextPageToken
+	}
+
+	assert.Equal([]any{"a", "b", "c"}, values)
+}
+
+func TestReadAggregatedWithoutRollups(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := newStore(t.TempDir())
+	if !assert.NoError(err) {
+		return
+	}
+	defer s.Close()
+
+	ref := machine.PointRef{MachineID: "M1", ControllerID: "PLC1", Point: "temperature"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Recorded before the rollup was configured: no rollup is stored.
+	for i := range 6 {
+		r := &Record{
+			PointRef: ref,
+			Time:     start.Add(time.Duration(i) * 10 * time.Second),
+			Value:    float64(i * 10),
+		}
+
+		assert.NoError(s.Append(rawSeries, r.Time, r))
+	}
+
+	q := Query{
+		Point:      ref,
+		Start:      start,
+		End:        start.Add(time.Minute),
+		Mode:       Aggregated,
+		Interval:   machine.Duration(time.Minute),
+		Aggregates: []Aggregate{Min, Max, Count},
+	}
+
+	result, err := s.read(q, []machine.Duration{machine.Duration(time.Minute)})
+	if !assert.NoError(err) {
+		return
+	}
+
+	if assert.Len(result.Values, 1) {
+		aggregates := result.Values[0].Aggregates
+		assert.Equal(0.0, aggregates[Min])
+		assert.Equal(50.0, aggregates[Max])
+		assert.Equal(6.0, aggregates[Count])
+	}
+}
+
+func TestReadPages(t *testing.T) {
+	assert := assert.New(t)
+
+	s, err := newStore(t.TempDir())
+	if !assert.NoError(err) {
+		return
+	}
+	defer s.Close()
+
+	ref := machine.PointRef{MachineID: "M1", ControllerID: "PLC1", Point: "temperature"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := range 5 {
+		r := &Record{
+			PointRef: ref,
+			Time:     start.Add(time.Duration(i) * time.Second),
+			Value:    float64(i),
+		}
+
+		assert.NoError(s.Append(rawSeries, r.Time, r))
+	}
+
+	q := Query{
+		Point:    ref,
+		Start:    start,
+		End:      start.Add(time.Minute),
+		Mode:     Raw,
+		Limit:    2,
+		AllPages: true,
+	}
+
+	pages := 0
+	result, err := ReadPages(q, func(q Query) (*Result, error) {
+		pages++
+		return s.read(q, nil)
+	})
+
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(3, pages)
+	assert.Len(result.Values, 5)
+	assert.Empty(result.NextPageToken)
+}
@@ -0,0 +1,252 @@
+package historian
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compactBytes is the size of the forwarded records past which the queue
+// file is rewritten without them.
+var compactBytes int64 = 16 << 20
+
+// queue is a persistent FIFO of records waiting to be forwarded. Records
+// are appended as JSON lines to a single file, and the offset of the first
+// record not yet forwarded is kept beside it, so the queue survives
+// restarts. The file is truncated once every record has been forwarded,
+// and compacted once the records forwarded pass compactBytes, so that it
+// does not grow while forwarding lags behind.
+//
+// The queue has a single consumer: the offsets of Peek are committed
+// before the next Peek.
+type queue struct {
+	filename       string
+	offsetFilename string
+	maxBytes       int64
+	f              *os.File
+	size           int64
+	offset         int64
+	notify         chan struct{}
+	sync.Mutex
+}
+
+func newQueue(dir string, maxBytes int64) (*queue, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	filename := filepath.Join(dir, "queue.jsonl")
+
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	q := &queue{
+		filename:       filename,
+		offsetFilename: filepath.Join(dir, "queue.offset"),
+		maxBytes:       maxBytes,
+		f:              f,
+		size:           info.Size(),
+		notify:         make(chan struct{}, 1),
+	}
+
+	bs, err := os.ReadFile(q.offsetFilename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		f.Close()
+		return nil, err
+	}
+
+	if len(bs) > 0 {
+		offset, err := strconv.ParseInt(strings.TrimSpace(string(bs)), 10, 64)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		q.offset = min(offset, q.size)
+	}
+
+	return q, nil
+}
+
+func (q *queue) Push(records ...*Record) error {
+	buf := make([]byte, 0)
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		buf = append(buf, data...)
+		buf = append(buf, '\n')
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	if q.maxBytes > 0 && q.size-q.offset+int64(len(buf)) > q.maxBytes {
+		return ErrQueueFull
+	}
+
+	n, err := q.f.Write(buf)
+	q.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Peek returns up to max records from the head of the queue, along with
+// the offset to commit once they have been forwarded.
+func (q *queue) Peek(max int) ([]*Record, int64, error) {
+	q.Lock()
+	offset, size := q.offset, q.size
+	if offset >= size {
+		q.Unlock()
+		return nil, offset, nil
+	}
+
+	f, err := os.Open(q.filename)
+	q.Unlock()
+
+	if err != nil {
+		return nil, offset, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(io.NewSectionReader(f, offset, size-offset))
+
+	records := make([]*Record, 0, max)
+	next := offset
+	for len(records) < max {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			// A partial line is still being written.
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, offset, err
+		}
+
+		next += int64(len(line))
+
+		var record *Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			// Skip corrupted entries rather than blocking the queue.
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	return records, next, nil
+}
+
+// Commit marks the records before the offset as forwarded.
+func (q *queue) Commit(offset int64) error {
+	q.Lock()
+	defer q.Unlock()
+
+	switch {
+	case offset >= q.size:
+		if err := q.f.Truncate(0); err != nil {
+			return err
+		}
+
+		q.size = 0
+		offset = 0
+
+	case offset >= compactBytes:
+		if err := q.compact(offset); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	q.offset = offset
+
+	return q.writeOffset(offset)
+}
+
+func (q *queue) writeOffset(offset int64) error {
+	return os.WriteFile(q.offsetFilename, []byte(strconv.FormatInt(offset, 10)), 0o644)
+}
+
+// compact rewrites the queue file from the offset. The offset is reset
+// before the file is replaced: a crash in between forwards the records
+// again, rather than losing them. The caller must hold the lock.
+func (q *queue) compact(offset int64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(q.filename), "queue-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, io.NewSectionReader(q.f, offset, q.size-offset))
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := q.writeOffset(0); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), q.filename); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(q.filename, os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	q.f.Close()
+	q.f = f
+	q.size -= offset
+	q.offset = 0
+
+	return nil
+}
+
+func (q *queue) Len() int64 {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.size - q.offset
+}
+
+func (q *queue) Close() error {
+	q.Lock()
+	defer q.Unlock()
+
+	return q.f.Close()
+}
@@ -0,0 +1,114 @@
+package historian
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueSurvivesRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+
+	q, err := newQueue(dir, 0)
+	if !assert.NoError(err) {
+		return
+	}
+
+	now := time.Now()
+	assert.NoError(q.Push(records(now, 1.0, 2.0, 3.0)...))
+
+	rs, next, err := q.Peek(2)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Len(rs, 2)
+	assert.NoError(q.Commit(next))
+	assert.NoError(q.Close())
+
+	q, err = newQueue(dir, 0)
+	if !assert.NoError(err) {
+		return
+	}
+	defer q.Close()
+
+	rs, next, err = q.Peek(10)
+	if !assert.NoError(err) || !assert.Len(rs, 1) {
+		return
+	}
+
+	assert.Equal(3.0, rs[0].Value)
+	assert.True(rs[0].Time.Equal(now.Add(2 * time.Second)))
+
+	assert.NoError(q.Commit(next))
+	assert.Zero(q.Len())
+}
+
+func TestQueueFull(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := newQueue(t.TempDir(), 100)
+	if !assert.NoError(err) {
+		return
+	}
+	defer q.Close()
+
+	assert.NoError(q.Push(records(time.Now(), 1.0)...))
+	assert.ErrorIs(q.Push(records(time.Now(), 1.0, 2.0, 3.0)...), ErrQueueFull)
+}
+
+func TestQueueCompacts(t *testing.T) {
+	assert := assert.New(t)
+
+	defer func(n int64) { compactBytes = n }(compactBytes)
+	compactBytes = 1
+
+	dir := t.TempDir()
+
+	q, err := newQueue(dir, 0)
+	if !assert.NoError(err) {
+		return
+	}
+
+	now := time.Now()
+	assert.NoError(q.Push(records(now, 1.0, 2.0, 3.0)...))
+
+	_, next, err := q.Peek(2)
+	if !assert.NoError(err) {
+		return
+	}
+
+	before := q.Len()
+	assert.NoError(q.Commit(next))
+
+	// Only the records not forwarded are left in the file.
+	info, err := os.Stat(filepath.Join(dir, "queue.jsonl"))
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(q.Len(), info.Size())
+	assert.Less(q.Len(), before)
+
+	assert.NoError(q.Push(records(now.Add(time.Minute), 4.0)...))
+	assert.NoError(q.Close())
+
+	q, err = newQueue(dir, 0)
+	if !assert.NoError(err) {
+		return
+	}
+	defer q.Close()
+
+	rs, _, err := q.Peek(10)
+	if !assert.NoError(err) || !assert.Len(rs, 2) {
+		return
+	}
+
+	assert.Equal(3.0, rs[0].Value)
+	assert.Equal(4.0, rs[1].Value)
+}
@@ -0,0 +1,357 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+const rawSeries = "raw"
+
+// pendingFile holds the rollups in progress while the service is closed.
+const pendingFile = "rollups.json"
+
+func rollupSeries(interval machine.Duration) string {
+	return "rollup/" + time.Duration(interval).String()
+}
+
+type Service interface {
+	// Record compresses and stores a sampled point value, and queues the
+	// stored values for forwarding.
+	Record(ctx context.Context, sample *machine.Sample)
+
+	// Read queries the stored history of a point.
+	Read(ctx context.Context, q Query) (*Result, error)
+
+	// Forward replays the queued values to the publisher in their original
+	// order until the context is done, retrying while the link is down.
+	Forward(ctx context.Context, publisher Publisher)
+
+	// Run enforces the retention policies until the context is done.
+	Run(ctx context.Context)
+
+	// Close flushes the held values, keeps the rollups in progress until
+	// the service is created again, and closes the storage.
+	Close() error
+}
+
+func NewService(dir string, cfg *Config) (Service, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	store, err := newStore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := newQueue(filepath.Join(dir, "queue"), cfg.QueueMaxBytes)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	svc := &service{
+		dir:         dir,
+		cfg:         cfg,
+		store:       store,
+		queue:       queue,
+		compressors: make(map[machine.PointRef]Compressor),
+		rollups:     make(map[machine.PointRef][]*Rollup),
+	}
+
+	if err := svc.resume(); err != nil {
+		store.Close()
+		queue.Close()
+		return nil, err
+	}
+
+	return svc, nil
+}
+
+type service struct {
+	dir         string
+	cfg         *Config
+	store       *store
+	queue       *queue
+	compressors map[machine.PointRef]Compressor
+	rollups     map[machine.PointRef][]*Rollup
+	sync.Mutex
+}
+
+func (svc *service) Record(ctx context.Context, sample *machine.Sample) {
+	if sample == nil || sample.Value == nil {
+		return
+	}
+
+	record := &Record{
+		PointRef: sample.PointRef,
+		Time:     sample.Value.Time,
+		Value:    sample.Value.Value,
+	}
+
+	svc.Lock()
+
+	c, ok := svc.compressors[record.PointRef]
+	if !ok {
+		c = NewCompressor(svc.cfg.compression(record.PointRef))
+		svc.compressors[record.PointRef] = c
+	}
+
+	records := c.Add(record)
+
+	// Rollups aggregate the stored values, as the buckets read from them do.
+	rollups := make([]*Rollup, 0)
+	for _, r := range records {
+		rollups = append(rollups, svc.aggregate(r)...)
+	}
+
+	svc.Unlock()
+
+	if err := svc.persist(records, rollups); err != nil {
+		zap.L().Error(err.Error(), zap.String("point", record.PointRef.String()))
+	}
+}
+
+func (svc *service) persist(records []*Record, rollups []*Rollup) error {
+	var errs error
+	for _, r := range records {
+		errs = errors.Join(errs, svc.store.Append(rawSeries, r.Time, r))
+	}
+
+	for _, r := range rollups {
+		errs = errors.Join(errs, svc.store.Append(rollupSeries(r.Interval), r.Start, r))
+	}
+
+	if len(records) > 0 {
+		errs = errors.Join(errs, svc.queue.Push(records...))
+	}
+
+	return errs
+}
+
+// aggregate adds the stored record to the rollups of its point and returns
+// the rollups whose interval has ended. The caller must hold the lock.
+func (svc *service) aggregate(record *Record) []*Rollup {
+	v, ok := machine.ToFloat(record.Value)
+	if !ok || len(svc.cfg.Rollups) == 0 {
+		return nil
+	}
+
+	current, ok := svc.rollups[record.PointRef]
+	if !ok {
+		current = make([]*Rollup, len(svc.cfg.Rollups))
+		svc.rollups[record.PointRef] = current
+	}
+
+	completed := make([]*Rollup, 0)
+	for i, interval := range svc.cfg.Rollups {
+		start := record.Time.Truncate(time.Duration(interval))
+
+		r := current[i]
+		if r != nil && !r.Start.Equal(start) {
+			completed = append(completed, r)
+			r = nil
+		}
+
+		if r == nil {
+			r = &Rollup{
+				PointRef: record.PointRef,
+				Interval: interval,
+				Start:    start,
+			}
+
+			current[i] = r
+		}
+
+		r.add(v)
+	}
+
+	return completed
+}
+
+func (svc *service) Read(ctx context.Context, q Query) (*Result, error) {
+	read := func(q Query) (*Result, error) {
+		return svc.store.read(q, svc.cfg.Rollups)
+	}
+
+	if q.AllPages {
+		return ReadPages(q, read)
+	}
+
+	return read(q)
+}
+
+func (svc *service) Forward(ctx context.Context, publisher Publisher) {
+	const batchSize = 100
+
+	backoff := time.Second
+	for {
+		records, next, err := svc.queue.Peek(batchSize)
+		if err != nil {
+			zap.L().Error(err.Error())
+		}
+
+		if len(records) == 0 {
+			if next > 0 {
+				svc.queue.Commit(next)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-svc.queue.notify:
+				continue
+			case <-time.After(time.Minute):
+				continue
+			}
+		}
+
+		if err := publisher.Publish(ctx, records); err != nil {
+			zap.L().Warn("forward failed", zap.Error(err), zap.Int64("queued", svc.queue.Len()))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff = min(2*backoff, time.Minute)
+			continue
+		}
+
+		backoff = time.Second
+
+		if err := svc.queue.Commit(next); err != nil {
+			zap.L().Error(err.Error())
+		}
+	}
+}
+
+func (svc *service) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		svc.purge()
+		svc.store.Sync()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (svc *service) purge() {
+	now := time.Now()
+
+	if retention := time.Duration(svc.cfg.Retention); retention > 0 {
+		if err := svc.store.Purge(rawSeries, now.Add(-retention)); err != nil {
+			zap.L().Error(err.Error())
+		}
+	}
+
+	if retention := time.Duration(svc.cfg.RollupRetention); retention > 0 {
+		for _, interval := range svc.cfg.Rollups {
+			if err := svc.store.Purge(rollupSeries(interval), now.Add(-retention)); err != nil {
+				zap.L().Error(err.Error())
+			}
+		}
+	}
+}
+
+// resume resumes the rollups in progress when the service was closed.
+// Rollups of an interval no longer configured are stored as they are.
+func (svc *service) resume() error {
+	filename := filepath.Join(svc.dir, pendingFile)
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+
+	var pending []*Rollup
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return err
+	}
+
+	completed := make([]*Rollup, 0)
+	for _, r := range pending {
+		i := slices.Index(svc.cfg.Rollups, r.Interval)
+		if i < 0 {
+			completed = append(completed, r)
+			continue
+		}
+
+		current, ok := svc.rollups[r.PointRef]
+		if !ok {
+			current = make([]*Rollup, len(svc.cfg.Rollups))
+			svc.rollups[r.PointRef] = current
+		}
+
+		current[i] = r
+	}
+
+	if err := svc.persist(nil, completed); err != nil {
+		return err
+	}
+
+	return os.Remove(filename)
+}
+
+func (svc *service) Close() error {
+	svc.Lock()
+
+	records := make([]*Record, 0)
+	for _, c := range svc.compressors {
+		records = append(records, c.Flush()...)
+	}
+
+	// The values flushed complete the rollups in progress, kept until the
+	// service resumes.
+	completed := make([]*Rollup, 0)
+	for _, r := range records {
+		completed = append(completed, svc.aggregate(r)...)
+	}
+
+	pending := make([]*Rollup, 0)
+	for _, current := range svc.rollups {
+		for _, r := range current {
+			if r != nil {
+				pending = append(pending, r)
+			}
+		}
+	}
+
+	svc.Unlock()
+
+	errs := svc.persist(records, completed)
+
+	if len(pending) > 0 {
+		data, err := json.Marshal(pending)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(svc.dir, pendingFile), data, 0o644)
+		}
+
+		errs = errors.Join(errs, err)
+	}
+
+	errs = errors.Join(errs, svc.store.Close())
+	errs = errors.Join(errs, svc.queue.Close())
+	return errs
+}
@@ -0,0 +1,128 @@
+package historian
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+func sample(ref machine.PointRef, t time.Time, v float64) *machine.Sample {
+	return &machine.Sample{
+		PointRef: ref,
+		Value:    &machine.Value{Type: machine.FLOAT, Value: v, Time: t},
+	}
+}
+
+func rollups(t *testing.T, svc Service, interval machine.Duration) []*Rollup {
+	rs := make([]*Rollup, 0)
+
+	err := svc.(*service).store.Scan(rollupSeries(interval), time.Time{}, time.Now(), func(line []byte) error {
+		var r *Rollup
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+
+		rs = append(rs, r)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	return rs
+}
+
+func TestRollupsSurviveRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := t.TempDir()
+
+	cfg := DefaultConfig()
+	cfg.Compression = Compression{Method: NoCompression}
+	cfg.Rollups = []machine.Duration{machine.Duration(time.Minute)}
+
+	svc, err := NewService(dir, cfg)
+	if !assert.NoError(err) {
+		return
+	}
+
+	ctx := context.Background()
+	ref := machine.PointRef{MachineID: "M1", ControllerID: "PLC1", Point: "temperature"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	svc.Record(ctx, sample(ref, start, 10))
+	svc.Record(ctx, sample(ref, start.Add(10*time.Second), 20))
+	assert.NoError(svc.Close())
+
+	svc, err = NewService(dir, cfg)
+	if !assert.NoError(err) {
+		return
+	}
+	defer svc.Close()
+
+	svc.Record(ctx, sample(ref, start.Add(20*time.Second), 30))
+
+	// The next minute completes the rollup started before the restart.
+	svc.Record(ctx, sample(ref, start.Add(time.Minute), 40))
+
+	rs := rollups(t, svc, cfg.Rollups[0])
+	if !assert.Len(rs, 1) {
+		return
+	}
+
+	assert.Equal(3, rs[0].Count)
+	assert.Equal(10.0, rs[0].Min)
+	assert.Equal(30.0, rs[0].Max)
+	assert.True(rs[0].Start.Equal(start))
+}
+
+func TestRollupsMatchBuckets(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultConfig()
+	cfg.Compression = Compression{Method: Deadband, Deviation: 5}
+	cfg.Rollups = []machine.Duration{machine.Duration(time.Minute)}
+
+	svc, err := NewService(t.TempDir(), cfg)
+	if !assert.NoError(err) {
+		return
+	}
+	defer svc.Close()
+
+	ctx := context.Background()
+	ref := machine.PointRef{MachineID: "M1", ControllerID: "PLC1", Point: "temperature"}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The deadband drops the values within 5 of the last one stored.
+	for i, v := range []float64{10, 11, 12, 30, 31, 50, 52, 20, 21, 22, 60} {
+		svc.Record(ctx, sample(ref, start.Add(time.Duration(i)*15*time.Second), v))
+	}
+
+	q := Query{
+		Point:      ref,
+		Start:      start,
+		End:        start.Add(2 * time.Minute),
+		Mode:       Aggregated,
+		Interval:   machine.Duration(time.Minute),
+		Aggregates: []Aggregate{Min, Max, Avg, Count},
+	}
+
+	store := svc.(*service).store
+
+	fromRollups, err := store.read(q, cfg.Rollups)
+	if !assert.NoError(err) {
+		return
+	}
+
+	fromBuckets, err := store.read(q, nil)
+	if !assert.NoError(err) {
+		return
+	}
+
+	if assert.Len(fromRollups.Values, 2) {
+		assert.Equal(fromBuckets.Values, fromRollups.Values)
+	}
+}
@@ -0,0 +1,222 @@
+package historian
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dayLayout = "2006-01-02"
+
+// store keeps records as JSON lines in one file per UTC day, grouped by
+// series (e.g., "raw" or "rollup/1m0s").
+type store struct {
+	dir   string
+	files map[string]*os.File
+	sync.Mutex
+}
+
+func newStore(dir string) (*store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &store{
+		dir:   dir,
+		files: make(map[string]*os.File),
+	}, nil
+}
+
+func (s *store) filename(series string, day time.Time) string {
+	return filepath.Join(s.dir, series, day.UTC().Format(dayLayout)+".jsonl")
+}
+
+func (s *store) Append(series string, t time.Time, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	filename := s.filename(series, t)
+
+	f, ok := s.files[filename]
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return err
+		}
+
+		f, err = os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+
+		s.files[filename] = f
+		s.closeStale(series, filename)
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// closeStale closes the files of the series other than the current one.
+// The caller must hold the lock.
+func (s *store) closeStale(series string, current string) {
+	prefix := filepath.Join(s.dir, series) + string(filepath.Separator)
+	for filename, f := range s.files {
+		if filename == current || !strings.HasPrefix(filename, prefix) {
+			continue
+		}
+
+		f.Close()
+		delete(s.files, filename)
+	}
+}
+
+// days lists the days with data of the series, oldest first.
+func (s *store) days(series string) ([]time.Time, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, series))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	days := make([]time.Time, 0, len(entries))
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		day, err := time.Parse(dayLayout, name)
+		if err != nil {
+			continue
+		}
+
+		days = append(days, day)
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Before(days[j])
+	})
+
+	return days, nil
+}
+
+// Scan calls fn with each line of the series stored on the days that
+// overlap [from, to], in the order the lines were written. Returning
+// errStop from fn ends the scan without an error.
+func (s *store) Scan(series string, from, to time.Time, fn func(line []byte) error) error {
+	days, err := s.days(series)
+	if err != nil {
+		return err
+	}
+
+	from = from.UTC().Truncate(24 * time.Hour)
+
+	for _, day := range days {
+		if day.Before(from) || day.After(to) {
+			continue
+		}
+
+		if err := s.scanFile(s.filename(series, day), fn); err != nil {
+			if errors.Is(err, errStop) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+var errStop = errors.New("stop scan")
+
+func (s *store) scanFile(filename string, fn func(line []byte) error) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Purge removes the days of the series that ended before the given time.
+func (s *store) Purge(series string, before time.Time) error {
+	days, err := s.days(series)
+	if err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for _, day := range days {
+		if !day.Add(24 * time.Hour).Before(before) {
+			continue
+		}
+
+		filename := s.filename(series, day)
+		if f, ok := s.files[filename]; ok {
+			f.Close()
+			delete(s.files, filename)
+		}
+
+		if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *store) Sync() error {
+	s.Lock()
+	defer s.Unlock()
+
+	var errs error
+	for _, f := range s.files {
+		errs = errors.Join(errs, f.Sync())
+	}
+
+	return errs
+}
+
+func (s *store) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	var errs error
+	for filename, f := range s.files {
+		errs = errors.Join(errs, f.Close())
+		delete(s.files, filename)
+	}
+
+	return errs
+}
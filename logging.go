@@ -3,8 +3,18 @@ package iiot
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/metadata"
+	"github.com/flarexio/iiot/production"
+	"github.com/flarexio/iiot/safety"
 )
 
 func LoggingMiddleware(log *zap.Logger) ServiceMiddleware {
@@ -44,7 +54,7 @@ func (mw *loggingMiddleware) CheckConnection(ctx context.Context, network string
 	return nil
 }
 
-func (mw *loggingMiddleware) ListDrivers(ctx context.Context) ([]string, error) {
+func (mw *loggingMiddleware) ListDrivers(ctx context.Context) ([]*tool.Descriptor, error) {
 	log := mw.log.With(
 		zap.String("action", "list_drivers"),
 	)
@@ -59,6 +69,46 @@ func (mw *loggingMiddleware) ListDrivers(ctx context.Context) ([]string, error)
 	return drivers, nil
 }
 
+func (mw *loggingMiddleware) InstallDriver(ctx context.Context, req install.Request) (*tool.Descriptor, error) {
+	log := mw.log.With(
+		zap.String("action", "install_driver"),
+		zap.String("caller", metadata.Caller(ctx)),
+		zap.String("file", req.File),
+		zap.String("bucket", req.Bucket),
+		zap.String("object", req.Object),
+	)
+
+	driver, err := mw.next.InstallDriver(ctx, req)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("driver installed",
+		zap.String("driver", driver.Name),
+		zap.String("version", driver.Version),
+	)
+
+	return driver, nil
+}
+
+func (mw *loggingMiddleware) UninstallDriver(ctx context.Context, driver string) error {
+	log := mw.log.With(
+		zap.String("action", "uninstall_driver"),
+		zap.String("caller", metadata.Caller(ctx)),
+		zap.String("driver", driver),
+	)
+
+	err := mw.next.UninstallDriver(ctx, driver)
+	if err != nil {
+		log.Error(err.Error())
+		return err
+	}
+
+	log.Info("driver uninstalled")
+	return nil
+}
+
 func (mw *loggingMiddleware) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
 	log := mw.log.With(
 		zap.String("action", "schema"),
@@ -106,3 +156,203 @@ func (mw *loggingMiddleware) ReadPoints(ctx context.Context, driver string, raw
 	log.Info("Read points successful", zap.Any("points", points))
 	return points, nil
 }
+
+func (mw *loggingMiddleware) ListMachines(ctx context.Context) ([]*machine.Machine, error) {
+	log := mw.log.With(
+		zap.String("action", "list_machines"),
+	)
+
+	machines, err := mw.next.ListMachines(ctx)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("machines retrieved", zap.Int("count", len(machines)))
+	return machines, nil
+}
+
+func (mw *loggingMiddleware) MachineStatus(ctx context.Context, id machine.MachineID) (*machine.StatusInfo, error) {
+	log := mw.log.With(
+		zap.String("action", "machine_status"),
+		zap.String("machine_id", string(id)),
+	)
+
+	status, err := mw.next.MachineStatus(ctx, id)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("machine status retrieved", zap.String("status", string(status.Status)))
+	return status, nil
+}
+
+func (mw *loggingMiddleware) PointValues(ctx context.Context, id machine.MachineID) ([]*machine.Sample, error) {
+	log := mw.log.With(
+		zap.String("action", "point_values"),
+		zap.String("machine_id", string(id)),
+	)
+
+	samples, err := mw.next.PointValues(ctx, id)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("point values retrieved", zap.Int("count", len(samples)))
+	return samples, nil
+}
+
+func (mw *loggingMiddleware) WritePoints(ctx context.Context, req *safety.Request) (*safety.Result, error) {
+	points := make([]string, len(req.Writes))
+	for i, w := range req.Writes {
+		points[i] = w.PointRef.String()
+	}
+
+	log := mw.log.With(
+		zap.String("action", "write_points"),
+		zap.String("caller", metadata.Caller(ctx)),
+		zap.Strings("points", points),
+		zap.Bool("confirm", req.Token != ""),
+		zap.Bool("dry_run", req.DryRun),
+	)
+
+	result, err := mw.next.WritePoints(ctx, req)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("write points handled",
+		zap.String("status", string(result.Status)),
+		zap.Any("writes", result.Writes),
+	)
+
+	return result, nil
+}
+
+func (mw *loggingMiddleware) MachineStatusHistory(ctx context.Context, id machine.MachineID, since time.Time) ([]*machine.StatusEvent, error) {
+	log := mw.log.With(
+		zap.String("action", "machine_status_history"),
+		zap.String("machine_id", string(id)),
+		zap.Time("since", since),
+	)
+
+	events, err := mw.next.MachineStatusHistory(ctx, id, since)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("machine status history retrieved", zap.Int("count", len(events)))
+	return events, nil
+}
+
+func (mw *loggingMiddleware) ListAlarms(ctx context.Context, filter alarm.Filter) ([]*alarm.Alarm, error) {
+	log := mw.log.With(
+		zap.String("action", "list_alarms"),
+		zap.Any("filter", filter),
+	)
+
+	alarms, err := mw.next.ListAlarms(ctx, filter)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("alarms retrieved", zap.Int("count", len(alarms)))
+	return alarms, nil
+}
+
+func (mw *loggingMiddleware) AcknowledgeAlarm(ctx context.Context, id string, user string, comment string) (*alarm.Alarm, error) {
+	log := mw.log.With(
+		zap.String("action", "acknowledge_alarm"),
+		zap.String("id", id),
+		zap.String("user", user),
+	)
+
+	a, err := mw.next.AcknowledgeAlarm(ctx, id, user, comment)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("alarm acknowledged", zap.String("state", string(a.State)))
+	return a, nil
+}
+
+func (mw *loggingMiddleware) ShelveAlarm(ctx context.Context, id string, user string, comment string, duration time.Duration) (*alarm.Alarm, error) {
+	log := mw.log.With(
+		zap.String("action", "shelve_alarm"),
+		zap.String("id", id),
+		zap.String("user", user),
+		zap.Duration("duration", duration),
+	)
+
+	a, err := mw.next.ShelveAlarm(ctx, id, user, comment, duration)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("alarm shelved", zap.Time("until", a.Shelve.Until))
+	return a, nil
+}
+
+func (mw *loggingMiddleware) ReadHistory(ctx context.Context, query historian.Query) (*historian.Result, error) {
+	log := mw.log.With(
+		zap.String("action", "read_history"),
+		zap.String("point", query.Point.String()),
+		zap.String("mode", string(query.Mode)),
+		zap.Time("start", query.Start),
+		zap.Time("end", query.End),
+	)
+
+	result, err := mw.next.ReadHistory(ctx, query)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("history read", zap.Int("values", len(result.Values)))
+	return result, nil
+}
+
+func (mw *loggingMiddleware) ProductionReport(ctx context.Context, id machine.MachineID) (*production.Report, error) {
+	log := mw.log.With(
+		zap.String("action", "production_report"),
+		zap.String("machine_id", string(id)),
+	)
+
+	report, err := mw.next.ProductionReport(ctx, id)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("production report retrieved",
+		zap.String("shift", report.Shift),
+		zap.Int64("total_count", report.TotalCount),
+		zap.Float64("oee", report.OEE),
+	)
+
+	return report, nil
+}
+
+func (mw *loggingMiddleware) ProductionHistory(ctx context.Context, id machine.MachineID, since time.Time) ([]*production.Report, error) {
+	log := mw.log.With(
+		zap.String("action", "production_history"),
+		zap.String("machine_id", string(id)),
+		zap.Time("since", since),
+	)
+
+	reports, err := mw.next.ProductionHistory(ctx, id, since)
+	if err != nil {
+		log.Error(err.Error())
+		return nil, err
+	}
+
+	log.Info("production history retrieved", zap.Int("count", len(reports)))
+	return reports, nil
+}
@@ -0,0 +1,33 @@
+package machine
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// LoadMachines reads the machine definitions from the given JSON file.
+// A missing file yields an empty list.
+func LoadMachines(filename string) ([]*Machine, error) {
+	f, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make([]*Machine, 0), nil
+		}
+
+		return nil, err
+	}
+
+	var machines []*Machine
+	if err := json.Unmarshal(f, &machines); err != nil {
+		return nil, err
+	}
+
+	for _, m := range machines {
+		if m.MachineID == "" {
+			return nil, errors.New("machine id is required")
+		}
+	}
+
+	return machines, nil
+}
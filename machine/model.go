@@ -1,9 +1,12 @@
 package machine
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -28,6 +31,11 @@ type Machine struct {
 	Name        string        `json:"name"`
 	Status      MachineStatus `json:"status"`
 	Controllers []*Controller `json:"controllers"`
+	StatusRules []*StatusRule `json:"status_rules,omitempty"`
+
+	// StrictTransitions rejects the status changes that are not valid
+	// transitions, rather than applying them as unexpected.
+	StrictTransitions bool `json:"strict_transitions,omitempty"`
 }
 
 type ControllerType string
@@ -81,9 +89,13 @@ type Point struct {
 	Options map[string]any `json:"options"`
 
 	value *Value `json:"-"`
+	mu    sync.RWMutex
 }
 
 func (p *Point) Value() *Value {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	if p.value == nil {
 		return nil
 	}
@@ -91,6 +103,13 @@ func (p *Point) Value() *Value {
 	return p.value
 }
 
+func (p *Point) setValue(value *Value) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.value = value
+}
+
 type Value struct {
 	Type  DataType  `json:"type"`
 	Value any       `json:"value"`
@@ -145,3 +164,107 @@ func (v *Value) SetValue(value any) error {
 
 	return nil
 }
+
+// ToFloat converts a numeric or boolean value to a float64.
+func ToFloat(value any) (float64, bool) {
+	switch val := value.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int64:
+		return float64(val), true
+	case int:
+		return float64(val), true
+	case bool:
+		if val {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// Equal reports whether two values are equal, comparing numbers and
+// booleans by their numeric value.
+func Equal(a, b any) bool {
+	fa, okA := ToFloat(a)
+	fb, okB := ToFloat(b)
+	if okA && okB {
+		return fa == fb
+	}
+
+	sa, okA := a.(string)
+	sb, okB := b.(string)
+	return okA && okB && sa == sb
+}
+
+// PointRef identifies a point of a controller on a machine.
+type PointRef struct {
+	MachineID    MachineID `json:"machine_id"`
+	ControllerID string    `json:"controller_id"`
+	Point        string    `json:"point"`
+}
+
+func (ref PointRef) String() string {
+	return string(ref.MachineID) + "/" + ref.ControllerID + "/" + ref.Point
+}
+
+func ParsePointRef(s string) (PointRef, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 {
+		return PointRef{}, errors.New("invalid point reference")
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return PointRef{}, errors.New("invalid point reference")
+		}
+	}
+
+	return PointRef{
+		MachineID:    MachineID(parts[0]),
+		ControllerID: parts[1],
+		Point:        parts[2],
+	}, nil
+}
+
+// Sample is a value read from a point at a given time.
+type Sample struct {
+	PointRef
+	Value *Value `json:"value"`
+}
+
+// Duration is a time.Duration encoded as a string (e.g., "5s", "1h30m") in JSON.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch val := raw.(type) {
+	case string:
+		duration, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+
+		*d = Duration(duration)
+
+	case float64:
+		// Plain numbers are interpreted as seconds.
+		*d = Duration(val * float64(time.Second))
+
+	default:
+		return errors.New("invalid duration")
+	}
+
+	return nil
+}
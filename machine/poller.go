@@ -0,0 +1,229 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/driver/tool"
+)
+
+type SampleHandler func(ctx context.Context, sample *Sample)
+
+// Poller periodically reads the points of every controller through its
+// driver and dispatches the values as samples to the subscribed handlers.
+type Poller interface {
+	Machines() []*Machine
+	SetMachines(machines []*Machine)
+	Subscribe(handler SampleHandler)
+	Poll(ctx context.Context) error
+	Run(ctx context.Context)
+}
+
+func NewPoller(machines []*Machine, client tool.Client, interval time.Duration) Poller {
+	return &poller{
+		machines: machines,
+		client:   client,
+		interval: interval,
+		handlers: make([]SampleHandler, 0),
+	}
+}
+
+type poller struct {
+	machines []*Machine
+	client   tool.Client
+	interval time.Duration
+	handlers []SampleHandler
+	polling  sync.Mutex
+	sync.RWMutex
+}
+
+func (p *poller) Machines() []*Machine {
+	p.RLock()
+	defer p.RUnlock()
+
+	return p.machines
+}
+
+// SetMachines replaces the machines polled from the next poll on.
+func (p *poller) SetMachines(machines []*Machine) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.machines = machines
+}
+
+func (p *poller) Subscribe(handler SampleHandler) {
+	p.Lock()
+	defer p.Unlock()
+
+	p.handlers = append(p.handlers, handler)
+}
+
+// Poll reads every controller once, waiting for the poll running, if any.
+// The error joins the error of each controller failing, prefixed with the
+// controller (e.g., "CNC01/PLC: ...").
+func (p *poller) Poll(ctx context.Context) error {
+	p.polling.Lock()
+	defer p.polling.Unlock()
+
+	var errs error
+	for _, m := range p.Machines() {
+		for _, controller := range m.Controllers {
+			if len(controller.Points) == 0 {
+				continue
+			}
+
+			if err := p.pollController(ctx, m.MachineID, controller); err != nil {
+				err = fmt.Errorf("%s/%s: %w", m.MachineID, controller.ControllerID, err)
+				errs = errors.Join(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
+
+func (p *poller) pollController(ctx context.Context, machineID MachineID, controller *Controller) error {
+	raw, err := ReadRequest(controller)
+	if err != nil {
+		return err
+	}
+
+	results, err := p.client.ReadPoints(ctx, controller.Driver, raw)
+	if err != nil {
+		return err
+	}
+
+	if len(results) != len(controller.Points) {
+		return errors.New("unexpected number of results")
+	}
+
+	p.RLock()
+	handlers := p.handlers
+	p.RUnlock()
+
+	// A point of an invalid value is reported, without holding back the
+	// points after it.
+	var errs error
+
+	now := time.Now()
+	for i, point := range controller.Points {
+		value, err := NewValue(point.Type, results[i], now)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("point %s: %w", point.Name, err))
+			continue
+		}
+
+		point.setValue(value)
+
+		sample := &Sample{
+			PointRef: PointRef{
+				MachineID:    machineID,
+				ControllerID: controller.ControllerID,
+				Point:        point.Name,
+			},
+			Value: value,
+		}
+
+		for _, handler := range handlers {
+			handler(ctx, sample)
+		}
+	}
+
+	return errs
+}
+
+func (p *poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if err := p.Poll(ctx); err != nil {
+				zap.L().Warn("poll failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// ReadRequest builds the driver request used to read all points of the
+// controller. The controller options are placed at the top level, and each
+// point is listed by name together with its own options.
+func ReadRequest(controller *Controller) (json.RawMessage, error) {
+	req := make(map[string]any)
+	for k, v := range controller.Options {
+		req[k] = v
+	}
+
+	points := make([]map[string]any, len(controller.Points))
+	for i, point := range controller.Points {
+		p := make(map[string]any)
+		for k, v := range point.Options {
+			p[k] = v
+		}
+
+		p["name"] = point.Name
+		points[i] = p
+	}
+
+	req["points"] = points
+
+	return json.Marshal(req)
+}
+
+// WriteRequest builds the driver request used to write points of the
+// controller. It has the form of ReadRequest, listing only the points to
+// write, each with its new value.
+func WriteRequest(controller *Controller, values map[string]any) (json.RawMessage, error) {
+	req := make(map[string]any)
+	for k, v := range controller.Options {
+		req[k] = v
+	}
+
+	points := make([]map[string]any, 0, len(values))
+	for _, point := range controller.Points {
+		value, ok := values[point.Name]
+		if !ok {
+			continue
+		}
+
+		p := make(map[string]any)
+		for k, v := range point.Options {
+			p[k] = v
+		}
+
+		p["name"] = point.Name
+		p["value"] = value
+		points = append(points, p)
+	}
+
+	req["points"] = points
+
+	return json.Marshal(req)
+}
+
+// NewValue converts a value returned by a driver into a Value of the given
+// data type. JSON numbers are converted to integers for INT points.
+func NewValue(dataType DataType, raw any, t time.Time) (*Value, error) {
+	if f, ok := raw.(float64); ok && dataType == INT {
+		raw = int64(f)
+	}
+
+	value := new(Value)
+	if err := value.SetValue(raw); err != nil {
+		return nil, err
+	}
+
+	value.Time = t
+	return value, nil
+}
@@ -0,0 +1,48 @@
+package machine
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testClient answers the same results to every read.
+type testClient struct {
+	results []any
+}
+
+func (c *testClient) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
+	return nil, nil
+}
+
+func (c *testClient) Instruction(ctx context.Context, driver string) (string, error) {
+	return "", nil
+}
+
+func (c *testClient) ReadPoints(ctx context.Context, driver string, raw json.RawMessage) ([]any, error) {
+	return c.results, nil
+}
+
+func TestPollInvalidValue(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newTestMachine()
+	client := &testClient{results: []any{false, map[string]any{"invalid": true}, 12.5}}
+	poller := NewPoller([]*Machine{m}, client, 0)
+
+	samples := make([]*Sample, 0)
+	poller.Subscribe(func(ctx context.Context, sample *Sample) {
+		samples = append(samples, sample)
+	})
+
+	err := poller.Poll(context.Background())
+	assert.ErrorContains(err, "cycle_active")
+
+	// The points after the invalid one are still updated.
+	if assert.Len(samples, 2) {
+		assert.Equal("estop", samples[0].Point)
+		assert.Equal("spindle_load", samples[1].Point)
+	}
+}
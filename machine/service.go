@@ -0,0 +1,363 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"go.uber.org/zap"
+)
+
+var ErrMachineNotFound = errs.New(errs.NotFound, "machine not found")
+
+// MaxStatusHistory is the number of status events kept per machine.
+var MaxStatusHistory = 1000
+
+type Service interface {
+	// Machines lists the configured machines with their current status.
+	Machines() []*Machine
+
+	// Status returns the current status of the machine.
+	Status(id MachineID) (*StatusInfo, error)
+
+	// StatusHistory returns the status events of the machine since the given time.
+	StatusHistory(id MachineID, since time.Time) ([]*StatusEvent, error)
+
+	// Values returns the last sampled value of each point of the machine,
+	// in configuration order. Points not sampled yet have no value.
+	Values(id MachineID) ([]*Sample, error)
+
+	// Update applies a sampled point value to the status rules of its machine.
+	Update(ctx context.Context, sample *Sample)
+
+	// Subscribe registers a handler that receives every status event.
+	Subscribe(handler StatusEventHandler)
+
+	// Reload replaces the machines. Machines that remain keep their status
+	// and history. An invalid configuration leaves the service unchanged.
+	Reload(machines []*Machine) error
+}
+
+func NewService(machines []*Machine) (Service, error) {
+	svc := &service{
+		trackers: make(map[MachineID]*tracker),
+		order:    make([]MachineID, 0, len(machines)),
+		handlers: make([]StatusEventHandler, 0),
+	}
+
+	for _, m := range machines {
+		if err := resolveConditions(m); err != nil {
+			return nil, err
+		}
+
+		svc.trackers[m.MachineID] = &tracker{
+			machine: m,
+			values:  make(map[PointRef]*Value),
+			history: make([]*StatusEvent, 0),
+		}
+
+		svc.order = append(svc.order, m.MachineID)
+	}
+
+	return svc, nil
+}
+
+// resolveConditions fills in the controller of conditions that omit it.
+func resolveConditions(m *Machine) error {
+	for _, rule := range m.StatusRules {
+		for _, cond := range rule.Conditions {
+			if cond.ControllerID != "" {
+				continue
+			}
+
+			for _, controller := range m.Controllers {
+				for _, point := range controller.Points {
+					if point.Name != cond.Point {
+						continue
+					}
+
+					if cond.ControllerID != "" {
+						return errors.New("ambiguous point: " + cond.Point)
+					}
+
+					cond.ControllerID = controller.ControllerID
+				}
+			}
+
+			if cond.ControllerID == "" {
+				return errors.Join(ErrPointNotFound, errors.New(cond.Point))
+			}
+		}
+	}
+
+	return nil
+}
+
+type service struct {
+	trackers map[MachineID]*tracker
+	order    []MachineID
+	handlers []StatusEventHandler
+	sync.RWMutex
+}
+
+type tracker struct {
+	machine  *Machine
+	values   map[PointRef]*Value
+	since    time.Time
+	history  []*StatusEvent
+	rejected MachineStatus // the status last rejected, under strict transitions
+}
+
+func (svc *service) Machines() []*Machine {
+	svc.RLock()
+	defer svc.RUnlock()
+
+	machines := make([]*Machine, len(svc.order))
+	for i, id := range svc.order {
+		m := *svc.trackers[id].machine
+		machines[i] = &m
+	}
+
+	return machines
+}
+
+func (svc *service) Status(id MachineID) (*StatusInfo, error) {
+	svc.RLock()
+	defer svc.RUnlock()
+
+	t, ok := svc.trackers[id]
+	if !ok {
+		return nil, ErrMachineNotFound
+	}
+
+	info := &StatusInfo{
+		MachineID: id,
+		Status:    t.machine.Status,
+		Since:     t.since,
+	}
+
+	if !t.since.IsZero() {
+		info.Duration = Duration(time.Since(t.since))
+	}
+
+	return info, nil
+}
+
+func (svc *service) StatusHistory(id MachineID, since time.Time) ([]*StatusEvent, error) {
+	svc.RLock()
+	defer svc.RUnlock()
+
+	t, ok := svc.trackers[id]
+	if !ok {
+		return nil, ErrMachineNotFound
+	}
+
+	events := make([]*StatusEvent, 0)
+	for _, event := range t.history {
+		if event.Time.Before(since) {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (svc *service) Values(id MachineID) ([]*Sample, error) {
+	svc.RLock()
+	defer svc.RUnlock()
+
+	t, ok := svc.trackers[id]
+	if !ok {
+		return nil, ErrMachineNotFound
+	}
+
+	samples := make([]*Sample, 0)
+	for _, controller := range t.machine.Controllers {
+		for _, point := range controller.Points {
+			ref := PointRef{
+				MachineID:    id,
+				ControllerID: controller.ControllerID,
+				Point:        point.Name,
+			}
+
+			samples = append(samples, &Sample{
+				PointRef: ref,
+				Value:    t.values[ref],
+			})
+		}
+	}
+
+	return samples, nil
+}
+
+func (svc *service) Update(ctx context.Context, sample *Sample) {
+	if sample == nil || sample.Value == nil {
+		return
+	}
+
+	svc.Lock()
+
+	t, ok := svc.trackers[sample.MachineID]
+	if !ok {
+		svc.Unlock()
+		return
+	}
+
+	t.values[sample.PointRef] = sample.Value
+
+	event := t.evaluate(sample.Value.Time)
+
+	svc.Unlock()
+
+	if event == nil {
+		return
+	}
+
+	svc.RLock()
+	handlers := svc.handlers
+	svc.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+func (svc *service) Subscribe(handler StatusEventHandler) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	svc.handlers = append(svc.handlers, handler)
+}
+
+func (svc *service) Reload(machines []*Machine) error {
+	for _, m := range machines {
+		if err := resolveConditions(m); err != nil {
+			return err
+		}
+	}
+
+	svc.Lock()
+	defer svc.Unlock()
+
+	trackers := make(map[MachineID]*tracker, len(machines))
+	order := make([]MachineID, 0, len(machines))
+
+	for _, m := range machines {
+		t, ok := svc.trackers[m.MachineID]
+		if ok {
+			m.Status = t.machine.Status
+			t.machine = m
+		} else {
+			t = &tracker{
+				machine: m,
+				values:  make(map[PointRef]*Value),
+				history: make([]*StatusEvent, 0),
+			}
+		}
+
+		trackers[m.MachineID] = t
+		order = append(order, m.MachineID)
+	}
+
+	svc.trackers = trackers
+	svc.order = order
+
+	return nil
+}
+
+// evaluate derives the status of the machine and records the change, if
+// any. The status is observed from the device, so by default a change that
+// is not a valid transition is applied and flagged as unexpected, e.g. a
+// machine seen running again without being seen idle after a fault. The
+// machines with strict transitions reject it instead: they keep their
+// status, and record the rejected change once until the status derived
+// changes again.
+func (t *tracker) evaluate(now time.Time) *StatusEvent {
+	m := t.machine
+	if len(m.StatusRules) == 0 {
+		return nil
+	}
+
+	status := t.derive()
+	if status == m.Status {
+		t.rejected = ""
+		return nil
+	}
+
+	event := &StatusEvent{
+		MachineID: m.MachineID,
+		From:      m.Status,
+		To:        status,
+		Time:      now,
+	}
+
+	if !CanTransition(m.Status, status) {
+		zap.L().Warn(ErrInvalidTransition.Error(),
+			zap.String("machine_id", string(m.MachineID)),
+			zap.String("from", string(m.Status)),
+			zap.String("to", string(status)),
+		)
+
+		if m.StrictTransitions {
+			if status == t.rejected {
+				return nil
+			}
+
+			t.rejected = status
+
+			event.Rejected = true
+			t.record(event)
+
+			return event
+		}
+
+		event.Unexpected = true
+	}
+
+	if !t.since.IsZero() {
+		event.Duration = Duration(now.Sub(t.since))
+	}
+
+	m.Status = status
+	t.since = now
+	t.rejected = ""
+
+	t.record(event)
+
+	return event
+}
+
+func (t *tracker) record(event *StatusEvent) {
+	t.history = append(t.history, event)
+	if len(t.history) > MaxStatusHistory {
+		t.history = t.history[len(t.history)-MaxStatusHistory:]
+	}
+}
+
+func (t *tracker) derive() MachineStatus {
+	for _, rule := range t.machine.StatusRules {
+		matched := true
+		for _, cond := range rule.Conditions {
+			ref := PointRef{
+				MachineID:    t.machine.MachineID,
+				ControllerID: cond.ControllerID,
+				Point:        cond.Point,
+			}
+
+			if !cond.Match(t.values[ref]) {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return rule.Status
+		}
+	}
+
+	return Idle
+}
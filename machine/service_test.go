@@ -0,0 +1,274 @@
+package machine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestMachine() *Machine {
+	return &Machine{
+		MachineID: "CNC01",
+		Controllers: []*Controller{
+			{
+				ControllerID: "NC",
+				Points: []*Point{
+					{Name: "estop", Type: BOOL},
+					{Name: "cycle_active", Type: BOOL},
+					{Name: "spindle_load", Type: FLOAT},
+				},
+			},
+		},
+		StatusRules: []*StatusRule{
+			{
+				Status: EmergencyStop,
+				Conditions: []*StatusCondition{
+					{Point: "estop", Operator: Eq, Value: true},
+				},
+			},
+			{
+				Status: Running,
+				Conditions: []*StatusCondition{
+					{Point: "cycle_active", Operator: Eq, Value: true},
+					{Point: "spindle_load", Operator: Gt, Value: 5},
+				},
+			},
+			{
+				Status: Paused,
+				Conditions: []*StatusCondition{
+					{Point: "cycle_active", Operator: Eq, Value: true},
+				},
+			},
+		},
+	}
+}
+
+func update(svc Service, point string, value any, t time.Time) {
+	svc.Update(context.Background(), &Sample{
+		PointRef: PointRef{
+			MachineID:    "CNC01",
+			ControllerID: "NC",
+			Point:        point,
+		},
+		Value: &Value{Value: value, Time: t},
+	})
+}
+
+func TestStatusRules(t *testing.T) {
+	assert := assert.New(t)
+
+	svc, err := NewService([]*Machine{newTestMachine()})
+	if !assert.NoError(err) {
+		return
+	}
+
+	events := make([]*StatusEvent, 0)
+	svc.Subscribe(func(event *StatusEvent) {
+		events = append(events, event)
+	})
+
+	now := time.Now()
+
+	update(svc, "estop", false, now)
+	update(svc, "spindle_load", 40.0, now.Add(1*time.Minute))
+	update(svc, "cycle_active", true, now.Add(2*time.Minute))
+	update(svc, "spindle_load", 0.0, now.Add(5*time.Minute))
+	update(svc, "estop", true, now.Add(6*time.Minute))
+
+	// An emergency stop released while the cycle is active is not a valid
+	// transition, recorded all the same.
+	update(svc, "estop", false, now.Add(7*time.Minute))
+	update(svc, "spindle_load", 40.0, now.Add(8*time.Minute))
+
+	info, err := svc.Status("CNC01")
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(Running, info.Status)
+
+	if !assert.Len(events, 6) {
+		return
+	}
+
+	assert.Equal(Idle, events[0].To)
+	assert.Equal(Running, events[1].To)
+	assert.Equal(Duration(2*time.Minute), events[1].Duration)
+	assert.Equal(Paused, events[2].To)
+	assert.Equal(Duration(3*time.Minute), events[2].Duration)
+	assert.Equal(EmergencyStop, events[3].To)
+	assert.Equal(Paused, events[4].To)
+	assert.True(events[4].Unexpected)
+	assert.Equal(Running, events[5].To)
+	assert.False(events[5].Unexpected)
+
+	history, err := svc.StatusHistory("CNC01", now.Add(2*time.Minute))
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Len(history, 5)
+
+	_, err = svc.Status("unknown")
+	assert.ErrorIs(err, ErrMachineNotFound)
+}
+
+func TestStatusRecoversFromFault(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newTestMachine()
+	m.Controllers[0].Points = append(m.Controllers[0].Points, &Point{Name: "alarm", Type: BOOL})
+	m.StatusRules = append([]*StatusRule{
+		{
+			Status: Fault,
+			Conditions: []*StatusCondition{
+				{Point: "alarm", Operator: Eq, Value: true},
+			},
+		},
+	}, m.StatusRules...)
+
+	svc, err := NewService([]*Machine{m})
+	if !assert.NoError(err) {
+		return
+	}
+
+	events := make([]*StatusEvent, 0)
+	svc.Subscribe(func(event *StatusEvent) {
+		events = append(events, event)
+	})
+
+	now := time.Now()
+
+	update(svc, "estop", false, now)
+	update(svc, "cycle_active", true, now)
+	update(svc, "spindle_load", 40.0, now)
+	update(svc, "alarm", true, now.Add(1*time.Minute))
+
+	// The fault is cleared while the machine keeps running.
+	update(svc, "alarm", false, now.Add(4*time.Minute))
+
+	info, err := svc.Status("CNC01")
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(Running, info.Status)
+	assert.Equal(now.Add(4*time.Minute), info.Since)
+
+	last := events[len(events)-1]
+	assert.Equal(Fault, last.From)
+	assert.Equal(Running, last.To)
+	assert.Equal(Duration(3*time.Minute), last.Duration)
+	assert.True(last.Unexpected)
+}
+
+func TestStrictTransitions(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newTestMachine()
+	m.StrictTransitions = true
+	m.Controllers[0].Points = append(m.Controllers[0].Points, &Point{Name: "alarm", Type: BOOL})
+	m.StatusRules = append([]*StatusRule{
+		{
+			Status: Fault,
+			Conditions: []*StatusCondition{
+				{Point: "alarm", Operator: Eq, Value: true},
+			},
+		},
+	}, m.StatusRules...)
+
+	svc, err := NewService([]*Machine{m})
+	if !assert.NoError(err) {
+		return
+	}
+
+	events := make([]*StatusEvent, 0)
+	svc.Subscribe(func(event *StatusEvent) {
+		events = append(events, event)
+	})
+
+	now := time.Now()
+
+	update(svc, "estop", false, now)
+	update(svc, "spindle_load", 40.0, now)
+	update(svc, "cycle_active", true, now)
+	update(svc, "alarm", true, now.Add(1*time.Minute))
+
+	// Running is not reachable from a fault: the machine stays faulted, and
+	// the change is recorded once.
+	update(svc, "alarm", false, now.Add(4*time.Minute))
+	update(svc, "spindle_load", 45.0, now.Add(5*time.Minute))
+
+	info, err := svc.Status("CNC01")
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(Fault, info.Status)
+	assert.Equal(now.Add(1*time.Minute), info.Since)
+
+	last := events[len(events)-1]
+	assert.Equal(Fault, last.From)
+	assert.Equal(Running, last.To)
+	assert.True(last.Rejected)
+
+	history, err := svc.StatusHistory("CNC01", time.Time{})
+	if !assert.NoError(err) {
+		return
+	}
+
+	rejected := 0
+	for _, event := range history {
+		if event.Rejected {
+			rejected++
+		}
+	}
+
+	assert.Equal(1, rejected)
+
+	// The machine leaves the fault through a valid transition.
+	update(svc, "cycle_active", false, now.Add(6*time.Minute))
+	update(svc, "spindle_load", 0.0, now.Add(6*time.Minute))
+
+	info, _ = svc.Status("CNC01")
+	assert.Equal(Idle, info.Status)
+}
+
+func TestCanTransition(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(CanTransition("", Running))
+	assert.True(CanTransition(Running, Fault))
+	assert.False(CanTransition(EmergencyStop, Running))
+	assert.False(CanTransition(Maintenance, Running))
+}
+
+func TestValues(t *testing.T) {
+	assert := assert.New(t)
+
+	svc, err := NewService([]*Machine{newTestMachine()})
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	now := time.Now()
+	update(svc, "spindle_load", 12.5, now)
+
+	samples, err := svc.Values("CNC01")
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	assert.Len(samples, 3)
+	assert.Equal("estop", samples[0].Point)
+	assert.Nil(samples[0].Value)
+	assert.Equal("spindle_load", samples[2].Point)
+	assert.Equal(12.5, samples[2].Value.Value)
+
+	_, err = svc.Values("CNC02")
+	assert.ErrorIs(err, ErrMachineNotFound)
+}
@@ -0,0 +1,133 @@
+package machine
+
+import (
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+var (
+	ErrInvalidTransition = errs.New(errs.FailedPrecondition, "invalid status transition")
+	ErrPointNotFound     = errs.New(errs.NotFound, "point not found")
+)
+
+// transitions lists the statuses each status may transition to.
+var transitions = map[MachineStatus][]MachineStatus{
+	Idle:          {Starting, Running, Stopped, Fault, Maintenance, EmergencyStop},
+	Starting:      {Running, Idle, Stopped, Fault, EmergencyStop},
+	Running:       {Paused, Idle, Stopped, Fault, EmergencyStop},
+	Paused:        {Running, Idle, Stopped, Fault, EmergencyStop},
+	Stopped:       {Idle, Starting, Running, Maintenance, Fault, EmergencyStop},
+	Fault:         {Idle, Stopped, Maintenance, EmergencyStop},
+	Maintenance:   {Idle, Stopped, Fault, EmergencyStop},
+	EmergencyStop: {Idle, Stopped, Fault, Maintenance},
+}
+
+// CanTransition reports whether a machine may change from one status to
+// another. Any status may be entered from the unknown (empty) status.
+func CanTransition(from, to MachineStatus) bool {
+	if from == "" {
+		return true
+	}
+
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+type Operator string
+
+const (
+	Eq  Operator = "eq"
+	Ne  Operator = "ne"
+	Gt  Operator = "gt"
+	Gte Operator = "gte"
+	Lt  Operator = "lt"
+	Lte Operator = "lte"
+)
+
+// StatusCondition compares the value of a point of the machine. The
+// controller may be omitted if the point name is unique on the machine.
+type StatusCondition struct {
+	ControllerID string   `json:"controller_id,omitempty"`
+	Point        string   `json:"point"`
+	Operator     Operator `json:"operator"`
+	Value        any      `json:"value"`
+}
+
+func (c *StatusCondition) Match(value *Value) bool {
+	if value == nil {
+		return false
+	}
+
+	switch c.Operator {
+	case Eq:
+		return Equal(value.Value, c.Value)
+
+	case Ne:
+		return !Equal(value.Value, c.Value)
+	}
+
+	v, ok := ToFloat(value.Value)
+	if !ok {
+		return false
+	}
+
+	target, ok := ToFloat(c.Value)
+	if !ok {
+		return false
+	}
+
+	switch c.Operator {
+	case Gt:
+		return v > target
+	case Gte:
+		return v >= target
+	case Lt:
+		return v < target
+	case Lte:
+		return v <= target
+	default:
+		return false
+	}
+}
+
+// StatusRule derives the status of a machine when all its conditions match.
+// Rules are evaluated in order and the first matching rule wins; if no rule
+// matches, the machine is idle.
+type StatusRule struct {
+	Status     MachineStatus      `json:"status"`
+	Conditions []*StatusCondition `json:"conditions"`
+}
+
+// StatusEvent records a status change of a machine, along with how long the
+// machine stayed in the previous status.
+type StatusEvent struct {
+	MachineID MachineID     `json:"machine_id"`
+	From      MachineStatus `json:"from"`
+	To        MachineStatus `json:"to"`
+	Time      time.Time     `json:"time"`
+	Duration  Duration      `json:"duration"`
+
+	// Unexpected flags a change that is not a valid transition. The status
+	// observed is recorded all the same, the machine being in it.
+	Unexpected bool `json:"unexpected,omitempty"`
+
+	// Rejected flags a change that is not a valid transition, refused as
+	// the machine enforces its transitions: it keeps the From status.
+	Rejected bool `json:"rejected,omitempty"`
+}
+
+type StatusEventHandler func(event *StatusEvent)
+
+// StatusInfo describes the current status of a machine.
+type StatusInfo struct {
+	MachineID MachineID     `json:"machine_id"`
+	Status    MachineStatus `json:"status"`
+	Since     time.Time     `json:"since"`
+	Duration  Duration      `json:"duration"`
+}
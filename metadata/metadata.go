@@ -0,0 +1,45 @@
+// Package metadata carries request metadata, such as the trace ID and the
+// identity of the caller, through a context across transports and drivers.
+package metadata
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type contextKey int
+
+const (
+	traceIDKey contextKey = iota
+	callerKey
+)
+
+// WithTraceID returns a context carrying the trace ID.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey, traceID)
+}
+
+// TraceID returns the trace ID carried by the context, if any.
+func TraceID(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey).(string)
+	return traceID
+}
+
+// NewTraceID generates a random 128-bit trace ID.
+func NewTraceID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithCaller returns a context carrying the identity of the caller.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey, caller)
+}
+
+// Caller returns the identity of the caller carried by the context, if any.
+func Caller(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey).(string)
+	return caller
+}
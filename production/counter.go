@@ -0,0 +1,35 @@
+package production
+
+import "math"
+
+// counterState turns the readings of a part counter into part counts.
+type counterState struct {
+	max  float64
+	last *float64
+}
+
+// update returns the number of parts counted since the previous reading.
+// A counter that drops by more than half its range is taken to have
+// wrapped past its maximum; any other drop is taken as a reset to zero.
+func (c *counterState) update(v float64) int64 {
+	last := c.last
+	c.last = &v
+
+	if last == nil {
+		return 0
+	}
+
+	var delta float64
+	switch {
+	case v >= *last:
+		delta = v - *last
+
+	case c.max > 0 && *last-v > c.max/2:
+		delta = c.max - *last + v + 1
+
+	default:
+		delta = v
+	}
+
+	return int64(math.Round(delta))
+}
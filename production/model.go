@@ -0,0 +1,235 @@
+package production
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrMachineNotTracked = errs.New(errs.NotFound, "machine not tracked")
+	ErrInvalidShift      = errs.New(errs.InvalidArgument, "invalid shift")
+	ErrNoActiveShift     = errs.New(errs.FailedPrecondition, "no active shift")
+)
+
+// Shift is a daily production period, in the local time of the edge. A
+// shift whose end is not after its start ends on the following day.
+type Shift struct {
+	Name  string `json:"name"`
+	Start string `json:"start"` // HH:MM
+	End   string `json:"end"`   // HH:MM
+}
+
+func (s *Shift) offsets() (start time.Duration, length time.Duration, err error) {
+	parse := func(v string) (time.Duration, error) {
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			return 0, errors.Join(ErrInvalidShift, err)
+		}
+
+		return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+	}
+
+	start, err = parse(s.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err := parse(s.End)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	length = end - start
+	if length <= 0 {
+		length += 24 * time.Hour
+	}
+
+	return start, length, nil
+}
+
+// Window returns the period of the shift that contains t, if any.
+func (s *Shift) Window(t time.Time) (start time.Time, end time.Time, ok bool) {
+	offset, length, err := s.offsets()
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+
+	// A shift that started yesterday may still be running.
+	for _, day := range []time.Time{midnight.AddDate(0, 0, -1), midnight} {
+		start := day.Add(offset)
+		end := start.Add(length)
+
+		if !t.Before(start) && t.Before(end) {
+			return start, end, true
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+// Next returns the first window of the shift starting at or after t.
+func (s *Shift) Next(t time.Time) (start time.Time, end time.Time, ok bool) {
+	offset, length, err := s.offsets()
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	y, m, d := t.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+
+	for _, day := range []time.Time{midnight, midnight.AddDate(0, 0, 1)} {
+		start := day.Add(offset)
+		if !start.Before(t) {
+			return start, start.Add(length), true
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+// DefaultShifts is used when no shifts are configured: the whole day.
+var DefaultShifts = []*Shift{
+	{Name: "day", Start: "00:00", End: "00:00"},
+}
+
+// Counter is a part counter point of a machine. Max is the value after
+// which the counter wraps to zero; zero means the counter does not wrap.
+type Counter struct {
+	ControllerID string  `json:"controller_id"`
+	Point        string  `json:"point"`
+	Max          float64 `json:"max,omitempty"`
+}
+
+// Machine configures the production tracking of a machine. At least two of
+// the total, good and reject counters are needed to derive the third; with
+// only one, it is taken as the total and all parts as good.
+type Machine struct {
+	MachineID      machine.MachineID `json:"machine_id"`
+	TotalCounter   *Counter          `json:"total_counter,omitempty"`
+	GoodCounter    *Counter          `json:"good_counter,omitempty"`
+	RejectCounter  *Counter          `json:"reject_counter,omitempty"`
+	IdealCycleTime machine.Duration  `json:"ideal_cycle_time"`
+}
+
+type Config struct {
+	Shifts   []*Shift   `json:"shifts"`
+	Machines []*Machine `json:"machines"`
+
+	// PlannedStatuses are the statuses counted as planned downtime, which
+	// is excluded from the planned production time. Every status other
+	// than running counts as unplanned downtime.
+	PlannedStatuses []machine.MachineStatus `json:"planned_statuses"`
+
+	// PublishInterval is how often the reports of the current shifts are
+	// published.
+	PublishInterval machine.Duration `json:"publish_interval"`
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Shifts:          DefaultShifts,
+		Machines:        make([]*Machine, 0),
+		PlannedStatuses: []machine.MachineStatus{machine.Maintenance},
+		PublishInterval: machine.Duration(time.Minute),
+	}
+}
+
+// LoadConfig reads the production configuration from the given JSON file
+// on top of the defaults. A missing file yields the default configuration.
+func LoadConfig(filename string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	f, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(f, cfg); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Shifts) == 0 {
+		cfg.Shifts = DefaultShifts
+	}
+
+	for _, s := range cfg.Shifts {
+		if _, _, err := s.offsets(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, m := range cfg.Machines {
+		if m.MachineID == "" {
+			return nil, errors.New("machine id is required")
+		}
+	}
+
+	return cfg, nil
+}
+
+// Report is the production of a machine over a shift. The report of the
+// current shift covers the shift up to UpdatedTime; Final is set once the
+// shift has ended.
+type Report struct {
+	MachineID   machine.MachineID `json:"machine_id"`
+	Shift       string            `json:"shift"`
+	Start       time.Time         `json:"start"`
+	End         time.Time         `json:"end"`
+	UpdatedTime time.Time         `json:"updated_time"`
+	Final       bool              `json:"final"`
+
+	TotalCount  int64 `json:"total_count"`
+	GoodCount   int64 `json:"good_count"`
+	RejectCount int64 `json:"reject_count"`
+
+	LastCycleTime    machine.Duration `json:"last_cycle_time"`
+	AverageCycleTime machine.Duration `json:"average_cycle_time"`
+	IdealCycleTime   machine.Duration `json:"ideal_cycle_time"`
+
+	RunTime               machine.Duration `json:"run_time"`
+	PlannedDowntime       machine.Duration `json:"planned_downtime"`
+	UnplannedDowntime     machine.Duration `json:"unplanned_downtime"`
+	PlannedProductionTime machine.Duration `json:"planned_production_time"`
+
+	Availability float64 `json:"availability"`
+	Performance  float64 `json:"performance"`
+	Quality      float64 `json:"quality"`
+	OEE          float64 `json:"oee"`
+}
+
+// calculate derives the OEE factors from the counts and times.
+func (r *Report) calculate() {
+	r.Availability, r.Performance, r.Quality = 0, 0, 0
+
+	planned := time.Duration(r.PlannedProductionTime)
+	run := time.Duration(r.RunTime)
+
+	if planned > 0 {
+		r.Availability = run.Seconds() / planned.Seconds()
+	}
+
+	if run > 0 && r.IdealCycleTime > 0 {
+		ideal := time.Duration(r.IdealCycleTime).Seconds() * float64(r.TotalCount)
+		r.Performance = ideal / run.Seconds()
+	}
+
+	if r.TotalCount > 0 {
+		r.Quality = float64(r.GoodCount) / float64(r.TotalCount)
+	}
+
+	r.OEE = r.Availability * r.Performance * r.Quality
+}
+
+type ReportHandler func(report *Report)
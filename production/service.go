@@ -0,0 +1,521 @@
+package production
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+// MaxReports is the number of completed shift reports kept per machine.
+var MaxReports = 1000
+
+type Service interface {
+	// Update counts the parts of a sampled counter value.
+	Update(ctx context.Context, sample *machine.Sample)
+
+	// UpdateStatus tracks the run time and downtime of a machine.
+	UpdateStatus(event *machine.StatusEvent)
+
+	// Report returns the report of the current shift of the machine.
+	Report(id machine.MachineID) (*Report, error)
+
+	// Reports returns the reports of the shifts of the machine that ended
+	// since the given time, oldest first.
+	Reports(id machine.MachineID, since time.Time) ([]*Report, error)
+
+	// Subscribe registers a handler that receives the reports of the
+	// current shifts periodically, and the final report of each shift.
+	Subscribe(handler ReportHandler)
+
+	// Run closes the ended shifts and publishes the reports until the
+	// context is done.
+	Run(ctx context.Context)
+}
+
+func NewService(cfg *Config) Service {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+
+	shifts := cfg.Shifts
+	if len(shifts) == 0 {
+		shifts = DefaultShifts
+	}
+
+	svc := &service{
+		cfg:      cfg,
+		shifts:   shifts,
+		trackers: make(map[machine.MachineID]*tracker),
+		counters: make(map[machine.PointRef]*counterRef),
+		handlers: make([]ReportHandler, 0),
+	}
+
+	for _, m := range cfg.Machines {
+		t := &tracker{
+			machine:   m,
+			durations: make(map[machine.MachineStatus]time.Duration),
+			history:   make([]*Report, 0),
+		}
+
+		svc.trackers[m.MachineID] = t
+
+		kinds := []struct {
+			counter *Counter
+			kind    counterKind
+		}{
+			{m.TotalCounter, totalCounter},
+			{m.GoodCounter, goodCounter},
+			{m.RejectCounter, rejectCounter},
+		}
+
+		for _, k := range kinds {
+			if k.counter == nil {
+				continue
+			}
+
+			ref := machine.PointRef{
+				MachineID:    m.MachineID,
+				ControllerID: k.counter.ControllerID,
+				Point:        k.counter.Point,
+			}
+
+			svc.counters[ref] = &counterRef{
+				tracker: t,
+				kind:    k.kind,
+				state:   &counterState{max: k.counter.Max},
+			}
+		}
+	}
+
+	return svc
+}
+
+type counterKind int
+
+const (
+	totalCounter counterKind = iota
+	goodCounter
+	rejectCounter
+)
+
+type counterRef struct {
+	tracker *tracker
+	kind    counterKind
+	state   *counterState
+}
+
+type service struct {
+	cfg      *Config
+	shifts   []*Shift
+	trackers map[machine.MachineID]*tracker
+	counters map[machine.PointRef]*counterRef
+	handlers []ReportHandler
+	sync.RWMutex
+}
+
+// tracker accumulates the production of a machine over its current shift.
+type tracker struct {
+	machine *Machine
+
+	shift *Shift
+	start time.Time
+	end   time.Time
+
+	// closed is the end of the last shift closed.
+	closed time.Time
+
+	status      machine.MachineStatus
+	statusSince time.Time
+	durations   map[machine.MachineStatus]time.Duration
+
+	counts     [3]int64
+	lastPart   time.Time
+	lastCycle  time.Duration
+	cycleTime  time.Duration
+	cycleParts int64
+
+	history []*Report
+}
+
+func (svc *service) Update(ctx context.Context, sample *machine.Sample) {
+	if sample == nil || sample.Value == nil {
+		return
+	}
+
+	svc.Lock()
+
+	c, ok := svc.counters[sample.PointRef]
+	if !ok {
+		svc.Unlock()
+		return
+	}
+
+	v, ok := machine.ToFloat(sample.Value.Value)
+	if !ok {
+		svc.Unlock()
+		return
+	}
+
+	now := sample.Value.Time
+	t := c.tracker
+
+	reports := svc.advance(t, now)
+
+	if parts := c.state.update(v); parts > 0 && t.shift != nil {
+		t.counts[c.kind] += parts
+
+		if c.kind == totalCounter || t.machine.TotalCounter == nil {
+			t.count(parts, now)
+		}
+	}
+
+	svc.Unlock()
+
+	svc.publish(reports)
+}
+
+// count records the cycle time of the parts made since the last part.
+func (t *tracker) count(parts int64, now time.Time) {
+	if !t.lastPart.IsZero() {
+		elapsed := now.Sub(t.lastPart)
+
+		t.lastCycle = elapsed / time.Duration(parts)
+		t.cycleTime += elapsed
+		t.cycleParts += parts
+	}
+
+	t.lastPart = now
+}
+
+func (svc *service) UpdateStatus(event *machine.StatusEvent) {
+	// Rejected changes leave the machine in its status.
+	if event == nil || event.Rejected {
+		return
+	}
+
+	svc.Lock()
+
+	t, ok := svc.trackers[event.MachineID]
+	if !ok {
+		svc.Unlock()
+		return
+	}
+
+	reports := svc.advance(t, event.Time)
+
+	t.accrue(event.Time)
+	t.status = event.To
+
+	svc.Unlock()
+
+	svc.publish(reports)
+}
+
+// advance closes the shift of the tracker if it has ended by now, reports
+// the shifts that ended since without any sample, and opens the shift
+// containing now. It returns the final reports of the closed shifts, if
+// any. The caller must hold the lock.
+func (svc *service) advance(t *tracker, now time.Time) []*Report {
+	if t.shift != nil && now.Before(t.end) {
+		return nil
+	}
+
+	reports := make([]*Report, 0)
+
+	if t.shift != nil {
+		reports = append(reports, svc.close(t))
+	}
+
+	// The machine stays in its status throughout the shifts without any
+	// sample, reported all the same.
+	if !t.closed.IsZero() {
+		for {
+			shift, start, end, ok := svc.next(t.closed)
+			if !ok || end.After(now) {
+				break
+			}
+
+			t.open(shift, start, end)
+			reports = append(reports, svc.close(t))
+		}
+	}
+
+	// Only the reports kept are published.
+	if len(reports) > MaxReports {
+		reports = reports[len(reports)-MaxReports:]
+	}
+
+	t.shift = nil
+	for _, s := range svc.shifts {
+		if start, end, ok := s.Window(now); ok {
+			t.open(s, start, end)
+			break
+		}
+	}
+
+	return reports
+}
+
+// next returns the first shift starting at or after t.
+func (svc *service) next(t time.Time) (*Shift, time.Time, time.Time, bool) {
+	var (
+		next       *Shift
+		start, end time.Time
+	)
+
+	for _, s := range svc.shifts {
+		sStart, sEnd, ok := s.Next(t)
+		if ok && (next == nil || sStart.Before(start)) {
+			next, start, end = s, sStart, sEnd
+		}
+	}
+
+	return next, start, end, next != nil
+}
+
+// close closes the shift of the tracker, returning its final report. The
+// caller must hold the lock.
+func (svc *service) close(t *tracker) *Report {
+	t.accrue(t.end)
+
+	report := t.report(t.end, svc.cfg.PlannedStatuses)
+	report.Final = true
+
+	t.history = append(t.history, report)
+	if len(t.history) > MaxReports {
+		t.history = t.history[len(t.history)-MaxReports:]
+	}
+
+	t.closed = t.end
+	t.shift = nil
+
+	return report
+}
+
+// open opens the shift. The counters and times of the shift start from
+// scratch, while the machine stays in its current status.
+func (t *tracker) open(shift *Shift, start, end time.Time) {
+	t.shift, t.start, t.end = shift, start, end
+
+	t.durations = make(map[machine.MachineStatus]time.Duration)
+	t.statusSince = t.start
+	t.counts = [3]int64{}
+	t.lastPart = time.Time{}
+	t.lastCycle = 0
+	t.cycleTime = 0
+	t.cycleParts = 0
+}
+
+// accrue adds the time spent in the current status up to now.
+func (t *tracker) accrue(now time.Time) {
+	if t.shift == nil {
+		return
+	}
+
+	from := t.statusSince
+	if from.Before(t.start) {
+		from = t.start
+	}
+
+	to := now
+	if to.After(t.end) {
+		to = t.end
+	}
+
+	if t.status != "" && to.After(from) {
+		t.durations[t.status] += to.Sub(from)
+	}
+
+	if now.After(t.statusSince) {
+		t.statusSince = now
+	}
+}
+
+// report builds the report of the current shift up to now, without
+// accruing the time of the current status.
+func (t *tracker) report(now time.Time, planned []machine.MachineStatus) *Report {
+	m := t.machine
+
+	report := &Report{
+		MachineID:      m.MachineID,
+		Shift:          t.shift.Name,
+		Start:          t.start,
+		End:            t.end,
+		UpdatedTime:    now,
+		IdealCycleTime: m.IdealCycleTime,
+		LastCycleTime:  machine.Duration(t.lastCycle),
+	}
+
+	if t.cycleParts > 0 {
+		report.AverageCycleTime = machine.Duration(t.cycleTime / time.Duration(t.cycleParts))
+	}
+
+	total, good, reject := t.counts[totalCounter], t.counts[goodCounter], t.counts[rejectCounter]
+	hasTotal, hasGood, hasReject := m.TotalCounter != nil, m.GoodCounter != nil, m.RejectCounter != nil
+
+	switch {
+	case hasTotal && hasGood && hasReject:
+	case hasTotal && hasReject:
+		good = total - reject
+	case hasTotal && hasGood:
+		reject = total - good
+	case hasGood && hasReject:
+		total = good + reject
+	case hasTotal:
+		good = total
+	case hasGood:
+		total = good
+	}
+
+	report.TotalCount = total
+	report.GoodCount = max(good, 0)
+	report.RejectCount = max(reject, 0)
+
+	durations := make(map[machine.MachineStatus]time.Duration, len(t.durations)+1)
+	for status, d := range t.durations {
+		durations[status] = d
+	}
+
+	if t.status != "" && now.After(t.statusSince) {
+		to := now
+		if to.After(t.end) {
+			to = t.end
+		}
+
+		if to.After(t.statusSince) {
+			durations[t.status] += to.Sub(t.statusSince)
+		}
+	}
+
+	var run, plannedDowntime, unplannedDowntime time.Duration
+	for status, d := range durations {
+		switch {
+		case status == machine.Running:
+			run += d
+		case slices.Contains(planned, status):
+			plannedDowntime += d
+		default:
+			unplannedDowntime += d
+		}
+	}
+
+	report.RunTime = machine.Duration(run)
+	report.PlannedDowntime = machine.Duration(plannedDowntime)
+	report.UnplannedDowntime = machine.Duration(unplannedDowntime)
+	report.PlannedProductionTime = machine.Duration(run + unplannedDowntime)
+
+	report.calculate()
+
+	return report
+}
+
+func (svc *service) Report(id machine.MachineID) (*Report, error) {
+	svc.Lock()
+
+	t, ok := svc.trackers[id]
+	if !ok {
+		svc.Unlock()
+		return nil, ErrMachineNotTracked
+	}
+
+	now := time.Now()
+
+	reports := svc.advance(t, now)
+
+	var report *Report
+	if t.shift != nil {
+		report = t.report(now, svc.cfg.PlannedStatuses)
+	}
+
+	svc.Unlock()
+
+	svc.publish(reports)
+
+	if report == nil {
+		return nil, ErrNoActiveShift
+	}
+
+	return report, nil
+}
+
+func (svc *service) Reports(id machine.MachineID, since time.Time) ([]*Report, error) {
+	svc.RLock()
+	defer svc.RUnlock()
+
+	t, ok := svc.trackers[id]
+	if !ok {
+		return nil, ErrMachineNotTracked
+	}
+
+	reports := make([]*Report, 0)
+	for _, report := range t.history {
+		if report.End.Before(since) {
+			continue
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (svc *service) Subscribe(handler ReportHandler) {
+	svc.Lock()
+	defer svc.Unlock()
+
+	svc.handlers = append(svc.handlers, handler)
+}
+
+func (svc *service) Run(ctx context.Context) {
+	interval := time.Duration(svc.cfg.PublishInterval)
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+
+		svc.Lock()
+
+		reports := make([]*Report, 0, len(svc.trackers))
+		for _, t := range svc.trackers {
+			reports = append(reports, svc.advance(t, now)...)
+
+			if t.shift != nil {
+				reports = append(reports, t.report(now, svc.cfg.PlannedStatuses))
+			}
+		}
+
+		svc.Unlock()
+
+		svc.publish(reports)
+	}
+}
+
+func (svc *service) publish(reports []*Report) {
+	if len(reports) == 0 {
+		return
+	}
+
+	svc.RLock()
+	handlers := svc.handlers
+	svc.RUnlock()
+
+	for _, report := range reports {
+		for _, handler := range handlers {
+			handler(report)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package production
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/machine"
+)
+
+func TestCounterState(t *testing.T) {
+	assert := assert.New(t)
+
+	c := &counterState{max: 9999}
+
+	assert.Equal(int64(0), c.update(9990))
+	assert.Equal(int64(5), c.update(9995))
+
+	// Wraps past the maximum: 9996..9999, 0..3
+	assert.Equal(int64(8), c.update(3))
+
+	// Reset to zero by the operator, then counts 2 parts.
+	assert.Equal(int64(2), c.update(5))
+	assert.Equal(int64(2), c.update(2))
+}
+
+func TestShiftReport(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultConfig()
+	cfg.Shifts = []*Shift{
+		{Name: "morning", Start: "06:00", End: "14:00"},
+		{Name: "night", Start: "22:00", End: "06:00"},
+	}
+	cfg.Machines = []*Machine{
+		{
+			MachineID:      "CNC01",
+			TotalCounter:   &Counter{ControllerID: "NC", Point: "parts"},
+			RejectCounter:  &Counter{ControllerID: "NC", Point: "rejects"},
+			IdealCycleTime: machine.Duration(time.Minute),
+		},
+	}
+
+	svc := NewService(cfg)
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hour int) time.Time {
+		return day.Add(time.Duration(hour) * time.Hour)
+	}
+
+	count := func(point string, v float64, hour int) {
+		svc.Update(context.Background(), &machine.Sample{
+			PointRef: machine.PointRef{MachineID: "CNC01", ControllerID: "NC", Point: point},
+			Value:    &machine.Value{Value: v, Time: at(hour)},
+		})
+	}
+
+	status := func(from, to machine.MachineStatus, hour int) {
+		svc.UpdateStatus(&machine.StatusEvent{
+			MachineID: "CNC01",
+			From:      from,
+			To:        to,
+			Time:      at(hour),
+		})
+	}
+
+	status("", machine.Running, 6)
+	count("parts", 100, 6)
+	count("rejects", 0, 6)
+
+	count("parts", 160, 7)
+	count("parts", 340, 10)
+	status(machine.Running, machine.Fault, 10)
+	status(machine.Fault, machine.Running, 11)
+	count("parts", 460, 13)
+	count("rejects", 12, 13)
+	status(machine.Running, machine.Maintenance, 13)
+
+	// The first sample after the shift closes it.
+	count("parts", 460, 14)
+
+	reports, err := svc.Reports("CNC01", time.Time{})
+	if !assert.NoError(err) || !assert.Len(reports, 1) {
+		return
+	}
+
+	report := reports[0]
+	assert.True(report.Final)
+	assert.Equal("morning", report.Shift)
+	assert.Equal(int64(360), report.TotalCount)
+	assert.Equal(int64(348), report.GoodCount)
+	assert.Equal(int64(12), report.RejectCount)
+	assert.Equal(machine.Duration(6*time.Hour), report.RunTime)
+	assert.Equal(machine.Duration(time.Hour), report.UnplannedDowntime)
+	assert.Equal(machine.Duration(time.Hour), report.PlannedDowntime)
+	assert.InDelta(6.0/7.0, report.Availability, 1e-9)
+	assert.InDelta(1.0, report.Performance, 1e-9)
+	assert.InDelta(348.0/360.0, report.Quality, 1e-9)
+	assert.InDelta(6.0/7.0*348.0/360.0, report.OEE, 1e-9)
+}
+
+func TestEmptyShiftReports(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultConfig()
+	cfg.Shifts = []*Shift{
+		{Name: "morning", Start: "06:00", End: "14:00"},
+		{Name: "night", Start: "22:00", End: "06:00"},
+	}
+	cfg.Machines = []*Machine{
+		{MachineID: "CNC01", TotalCounter: &Counter{ControllerID: "NC", Point: "parts"}},
+	}
+
+	svc := NewService(cfg)
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	svc.UpdateStatus(&machine.StatusEvent{MachineID: "CNC01", To: machine.Fault, Time: day.Add(6 * time.Hour)})
+
+	// Nothing is sampled until the next morning.
+	svc.UpdateStatus(&machine.StatusEvent{MachineID: "CNC01", From: machine.Fault, To: machine.Running, Time: day.Add(31 * time.Hour)})
+
+	reports, err := svc.Reports("CNC01", time.Time{})
+	if !assert.NoError(err) || !assert.Len(reports, 2) {
+		return
+	}
+
+	assert.Equal("morning", reports[0].Shift)
+
+	night := reports[1]
+	assert.True(night.Final)
+	assert.Equal("night", night.Shift)
+	assert.Equal(day.Add(22*time.Hour), night.Start)
+	assert.Equal(int64(0), night.TotalCount)
+	assert.Equal(machine.Duration(8*time.Hour), night.UnplannedDowntime)
+	assert.Zero(night.RunTime)
+}
+
+func TestShiftWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	night := &Shift{Name: "night", Start: "22:00", End: "06:00"}
+
+	now := time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)
+	start, end, ok := night.Window(now)
+	if !assert.True(ok) {
+		return
+	}
+
+	assert.Equal(time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC), start)
+	assert.Equal(time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC), end)
+
+	_, _, ok = night.Window(now.Add(4 * time.Hour))
+	assert.False(ok)
+}
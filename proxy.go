@@ -4,6 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
+
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/production"
+	"github.com/flarexio/iiot/safety"
 )
 
 func ProxyMiddleware(endpoints *EndpointSet) ServiceMiddleware {
@@ -28,13 +37,13 @@ func (mw *proxyMiddleware) CheckConnection(ctx context.Context, network string,
 	return err
 }
 
-func (mw *proxyMiddleware) ListDrivers(ctx context.Context) ([]string, error) {
+func (mw *proxyMiddleware) ListDrivers(ctx context.Context) ([]*tool.Descriptor, error) {
 	resp, err := mw.endpoints.ListDrivers(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	drivers, ok := resp.([]string)
+	drivers, ok := resp.([]*tool.Descriptor)
 	if !ok {
 		return nil, errors.New("invalid response")
 	}
@@ -42,6 +51,25 @@ func (mw *proxyMiddleware) ListDrivers(ctx context.Context) ([]string, error) {
 	return drivers, nil
 }
 
+func (mw *proxyMiddleware) InstallDriver(ctx context.Context, req install.Request) (*tool.Descriptor, error) {
+	resp, err := mw.endpoints.InstallDriver(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	driver, ok := resp.(*tool.Descriptor)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return driver, nil
+}
+
+func (mw *proxyMiddleware) UninstallDriver(ctx context.Context, driver string) error {
+	_, err := mw.endpoints.UninstallDriver(ctx, driver)
+	return err
+}
+
 func (mw *proxyMiddleware) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
 	resp, err := mw.endpoints.Schema(ctx, driver)
 	if err != nil {
@@ -88,3 +116,180 @@ func (mw *proxyMiddleware) ReadPoints(ctx context.Context, driver string, raw js
 
 	return points, nil
 }
+
+func (mw *proxyMiddleware) ListMachines(ctx context.Context) ([]*machine.Machine, error) {
+	resp, err := mw.endpoints.ListMachines(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	machines, ok := resp.([]*machine.Machine)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return machines, nil
+}
+
+func (mw *proxyMiddleware) MachineStatus(ctx context.Context, id machine.MachineID) (*machine.StatusInfo, error) {
+	resp, err := mw.endpoints.MachineStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	status, ok := resp.(*machine.StatusInfo)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return status, nil
+}
+
+func (mw *proxyMiddleware) PointValues(ctx context.Context, id machine.MachineID) ([]*machine.Sample, error) {
+	resp, err := mw.endpoints.PointValues(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, ok := resp.([]*machine.Sample)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return samples, nil
+}
+
+func (mw *proxyMiddleware) WritePoints(ctx context.Context, req *safety.Request) (*safety.Result, error) {
+	resp, err := mw.endpoints.WritePoints(ctx, *req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := resp.(*safety.Result)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return result, nil
+}
+
+func (mw *proxyMiddleware) MachineStatusHistory(ctx context.Context, id machine.MachineID, since time.Time) ([]*machine.StatusEvent, error) {
+	req := MachineStatusHistoryRequest{
+		MachineID: id,
+		Since:     since,
+	}
+
+	resp, err := mw.endpoints.MachineStatusHistory(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	events, ok := resp.([]*machine.StatusEvent)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return events, nil
+}
+
+func (mw *proxyMiddleware) ListAlarms(ctx context.Context, filter alarm.Filter) ([]*alarm.Alarm, error) {
+	resp, err := mw.endpoints.ListAlarms(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	alarms, ok := resp.([]*alarm.Alarm)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return alarms, nil
+}
+
+func (mw *proxyMiddleware) AcknowledgeAlarm(ctx context.Context, id string, user string, comment string) (*alarm.Alarm, error) {
+	req := AcknowledgeAlarmRequest{
+		ID:      id,
+		User:    user,
+		Comment: comment,
+	}
+
+	resp, err := mw.endpoints.AcknowledgeAlarm(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	a, ok := resp.(*alarm.Alarm)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return a, nil
+}
+
+func (mw *proxyMiddleware) ShelveAlarm(ctx context.Context, id string, user string, comment string, duration time.Duration) (*alarm.Alarm, error) {
+	req := ShelveAlarmRequest{
+		ID:       id,
+		User:     user,
+		Comment:  comment,
+		Duration: machine.Duration(duration),
+	}
+
+	resp, err := mw.endpoints.ShelveAlarm(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	a, ok := resp.(*alarm.Alarm)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return a, nil
+}
+
+func (mw *proxyMiddleware) ReadHistory(ctx context.Context, query historian.Query) (*historian.Result, error) {
+	resp, err := mw.endpoints.ReadHistory(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	result, ok := resp.(*historian.Result)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return result, nil
+}
+
+func (mw *proxyMiddleware) ProductionReport(ctx context.Context, id machine.MachineID) (*production.Report, error) {
+	resp, err := mw.endpoints.ProductionReport(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, ok := resp.(*production.Report)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return report, nil
+}
+
+func (mw *proxyMiddleware) ProductionHistory(ctx context.Context, id machine.MachineID, since time.Time) ([]*production.Report, error) {
+	req := ProductionHistoryRequest{
+		MachineID: id,
+		Since:     since,
+	}
+
+	resp, err := mw.endpoints.ProductionHistory(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	reports, ok := resp.([]*production.Report)
+	if !ok {
+		return nil, errors.New("invalid response")
+	}
+
+	return reports, nil
+}
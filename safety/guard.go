@@ -0,0 +1,260 @@
+package safety
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/metadata"
+)
+
+// MaxProposals bounds the proposals awaiting confirmation.
+var MaxProposals = 100
+
+// WriteFunc writes the points, or only checks them against the machines in
+// a dry run.
+type WriteFunc func(ctx context.Context, writes []*Write, dryRun bool) (*Result, error)
+
+// Guard applies the policy to the write requests.
+type Guard interface {
+	// Write checks the writes of the request against the policy and proposes
+	// them, returning the token that confirms them. Requests with the token
+	// of a proposal write its points with the writer, once. Dry runs and
+	// policies in dry run mode never write.
+	//
+	// The token is the secret of the proposal, answered to its proposer
+	// only. Unless the policy says otherwise, the proposal is confirmed by
+	// an identified caller other than its proposer. The caller is only as
+	// trustworthy as the transport: the MCP HTTP transport authenticates it
+	// with its bearer tokens, while over NATS it is asserted by the client,
+	// trusted as far as the permissions of its user on the subjects of the
+	// edge.
+	Write(ctx context.Context, req *Request, write WriteFunc) (*Result, error)
+}
+
+func NewGuard(policy *Policy) Guard {
+	rules := make(map[string]*Rule, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		rules[rule.Point] = rule
+	}
+
+	maxWrites := policy.MaxWritesPerMinute
+	if maxWrites <= 0 {
+		maxWrites = DefaultMaxWritesPerMinute
+	}
+
+	ttl := time.Duration(policy.ConfirmTTL)
+	if ttl <= 0 {
+		ttl = DefaultConfirmTTL
+	}
+
+	return &guard{
+		rules:            rules,
+		maxWrites:        maxWrites,
+		ttl:              ttl,
+		dryRun:           policy.DryRun,
+		selfConfirm:      policy.SelfConfirm,
+		anonymousConfirm: policy.AnonymousConfirm,
+		proposals:        make(map[string]*proposal),
+		lastWrite:        make(map[machine.PointRef]time.Time),
+		written:          make([]time.Time, 0),
+		now:              time.Now,
+	}
+}
+
+type guard struct {
+	rules            map[string]*Rule
+	maxWrites        int
+	ttl              time.Duration
+	dryRun           bool
+	selfConfirm      bool
+	anonymousConfirm bool
+
+	proposals map[string]*proposal
+	lastWrite map[machine.PointRef]time.Time
+	written   []time.Time // the time of each point written in the last minute
+	now       func() time.Time
+	sync.Mutex
+}
+
+type proposal struct {
+	caller  string // as carried by the context, see Guard
+	writes  []*Write
+	expires time.Time
+}
+
+func (g *guard) Write(ctx context.Context, req *Request, write WriteFunc) (*Result, error) {
+	if req == nil {
+		return nil, ErrNoWrites
+	}
+
+	if req.Token != "" {
+		return g.confirm(ctx, req.Token, write)
+	}
+
+	if len(req.Writes) == 0 {
+		return nil, ErrNoWrites
+	}
+
+	for _, w := range req.Writes {
+		rule, ok := g.rules[w.PointRef.String()]
+		if !ok {
+			return nil, ErrNotAllowed.WithDetail("point", w.PointRef.String())
+		}
+
+		if err := rule.Check(w.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	g.Lock()
+	err := g.checkRate(req.Writes)
+	g.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the writes against the machines, and report their live values.
+	result, err := write(ctx, req.Writes, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.DryRun || g.dryRun {
+		result.Status = DryRun
+		return result, nil
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	now := g.now()
+	for token, p := range g.proposals {
+		if now.After(p.expires) {
+			delete(g.proposals, token)
+		}
+	}
+
+	if len(g.proposals) >= MaxProposals {
+		return nil, ErrWritesPending
+	}
+
+	expires := now.Add(g.ttl)
+	token := metadata.NewTraceID()
+
+	g.proposals[token] = &proposal{
+		caller:  metadata.Caller(ctx),
+		writes:  result.Writes,
+		expires: expires,
+	}
+
+	result.Status = Proposed
+	result.Token = token
+	result.ExpiresTime = &expires
+
+	return result, nil
+}
+
+// confirm writes the proposal of the token. The token is used up, even if
+// the write fails, but not by a caller refused as its confirmer.
+func (g *guard) confirm(ctx context.Context, token string, write WriteFunc) (*Result, error) {
+	g.Lock()
+
+	p, ok := g.proposals[token]
+	if !ok || g.now().After(p.expires) {
+		delete(g.proposals, token)
+		g.Unlock()
+		return nil, ErrInvalidToken
+	}
+
+	if err := g.checkConfirmer(metadata.Caller(ctx), p); err != nil {
+		g.Unlock()
+		return nil, err
+	}
+
+	delete(g.proposals, token)
+
+	if err := g.checkRate(p.writes); err != nil {
+		g.Unlock()
+		return nil, err
+	}
+
+	if g.dryRun {
+		g.Unlock()
+		return &Result{Status: DryRun, Writes: p.writes}, nil
+	}
+
+	g.record(p.writes)
+	g.Unlock()
+
+	result, err := write(ctx, p.writes, false)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Status = Written
+	return result, nil
+}
+
+// checkConfirmer checks that the caller may confirm the proposal.
+func (g *guard) checkConfirmer(caller string, p *proposal) error {
+	switch {
+	case caller == "":
+		if !g.anonymousConfirm {
+			return ErrConfirmerRequired
+		}
+
+	case caller == p.caller:
+		if !g.selfConfirm {
+			return ErrSelfConfirm
+		}
+	}
+
+	return nil
+}
+
+// checkRate checks the minimum interval of the points and the writes per
+// minute of the edge. The caller must hold the lock.
+func (g *guard) checkRate(writes []*Write) error {
+	now := g.now()
+
+	for _, w := range writes {
+		rule := g.rules[w.PointRef.String()]
+		if rule == nil || rule.MinInterval <= 0 {
+			continue
+		}
+
+		last, ok := g.lastWrite[w.PointRef]
+		if ok && now.Sub(last) < time.Duration(rule.MinInterval) {
+			return ErrRateLimited.
+				WithDetail("point", w.PointRef.String()).
+				WithDetail("retry_after", machine.Duration(time.Duration(rule.MinInterval)-now.Sub(last)))
+		}
+	}
+
+	// Forget the writes older than a minute.
+	i := 0
+	for i < len(g.written) && now.Sub(g.written[i]) >= time.Minute {
+		i++
+	}
+	g.written = g.written[i:]
+
+	if len(g.written)+len(writes) > g.maxWrites {
+		return ErrRateLimited.WithDetail("max_writes_per_minute", g.maxWrites)
+	}
+
+	return nil
+}
+
+// record counts the writes against the rate limits. The caller must hold
+// the lock.
+func (g *guard) record(writes []*Write) {
+	now := g.now()
+
+	for _, w := range writes {
+		g.lastWrite[w.PointRef] = now
+		g.written = append(g.written, now)
+	}
+}
@@ -0,0 +1,204 @@
+package safety
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/metadata"
+)
+
+func newTestGuard(now *time.Time) *guard {
+	min, max := 50.0, 120.0
+
+	g := NewGuard(&Policy{
+		Rules: []*Rule{
+			{
+				Point:       "CNC01/PLC/spindle_override",
+				Min:         &min,
+				Max:         &max,
+				MinInterval: machine.Duration(10 * time.Second),
+			},
+			{
+				Point:  "CNC01/PLC/mode",
+				Values: []any{"auto", "manual"},
+			},
+		},
+		MaxWritesPerMinute: 3,
+	}).(*guard)
+
+	g.now = func() time.Time { return *now }
+	return g
+}
+
+func newWrite(point string, value any) *Write {
+	return &Write{
+		PointRef: machine.PointRef{MachineID: "CNC01", ControllerID: "PLC", Point: point},
+		Value:    value,
+	}
+}
+
+type testWriter struct {
+	written [][]*Write
+}
+
+func (w *testWriter) write(ctx context.Context, writes []*Write, dryRun bool) (*Result, error) {
+	if !dryRun {
+		w.written = append(w.written, writes)
+	}
+
+	return &Result{Writes: writes}, nil
+}
+
+func TestGuardPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	g := newTestGuard(&now)
+	w := new(testWriter)
+	ctx := context.Background()
+
+	_, err := g.Write(ctx, &Request{}, w.write)
+	assert.ErrorIs(err, ErrNoWrites)
+
+	_, err = g.Write(ctx, &Request{Writes: []*Write{newWrite("feed_override", 100.0)}}, w.write)
+	assert.ErrorIs(err, ErrNotAllowed)
+
+	_, err = g.Write(ctx, &Request{Writes: []*Write{newWrite("spindle_override", 150.0)}}, w.write)
+	assert.ErrorIs(err, ErrOutOfRange)
+
+	_, err = g.Write(ctx, &Request{Writes: []*Write{newWrite("mode", "mdi")}}, w.write)
+	assert.ErrorIs(err, ErrOutOfRange)
+
+	result, err := g.Write(ctx, &Request{Writes: []*Write{newWrite("mode", "auto")}, DryRun: true}, w.write)
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	assert.Equal(DryRun, result.Status)
+	assert.Empty(result.Token)
+	assert.Empty(w.written)
+}
+
+func TestGuardConfirm(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	g := newTestGuard(&now)
+	w := new(testWriter)
+	ctx := metadata.WithCaller(context.Background(), "alice")
+
+	result, err := g.Write(ctx, &Request{Writes: []*Write{newWrite("spindle_override", 110.0)}}, w.write)
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	assert.Equal(Proposed, result.Status)
+	assert.NotEmpty(result.Token)
+	assert.Empty(w.written)
+
+	// The proposer does not confirm its own proposal, nor does a caller
+	// without identity; neither uses up the token.
+	_, err = g.Write(ctx, &Request{Token: result.Token}, w.write)
+	assert.ErrorIs(err, ErrSelfConfirm)
+
+	_, err = g.Write(context.Background(), &Request{Token: result.Token}, w.write)
+	assert.ErrorIs(err, ErrConfirmerRequired)
+
+	operator := metadata.WithCaller(context.Background(), "bob")
+	confirmed, err := g.Write(operator, &Request{Token: result.Token}, w.write)
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	assert.Equal(Written, confirmed.Status)
+	assert.Len(w.written, 1)
+
+	// Tokens confirm once.
+	_, err = g.Write(operator, &Request{Token: result.Token}, w.write)
+	assert.ErrorIs(err, ErrInvalidToken)
+
+	// Proposals expire.
+	result, _ = g.Write(ctx, &Request{Writes: []*Write{newWrite("mode", "auto")}}, w.write)
+	now = now.Add(DefaultConfirmTTL + time.Second)
+
+	_, err = g.Write(operator, &Request{Token: result.Token}, w.write)
+	assert.ErrorIs(err, ErrInvalidToken)
+	assert.Len(w.written, 1)
+}
+
+func TestGuardConfirmPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	w := new(testWriter)
+	rules := []*Rule{{Point: "CNC01/PLC/mode", Values: []any{"auto", "manual"}}}
+
+	propose := func(g Guard, ctx context.Context) string {
+		result, err := g.Write(ctx, &Request{Writes: []*Write{newWrite("mode", "auto")}}, w.write)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return result.Token
+	}
+
+	// The policy lets the proposer confirm.
+	g := NewGuard(&Policy{Rules: rules, SelfConfirm: true})
+	ctx := metadata.WithCaller(context.Background(), "alice")
+
+	_, err := g.Write(ctx, &Request{Token: propose(g, ctx)}, w.write)
+	assert.NoError(err)
+
+	// The policy accepts the confirmations without a caller, e.g. over stdio.
+	g = NewGuard(&Policy{Rules: rules, AnonymousConfirm: true})
+	ctx = context.Background()
+
+	_, err = g.Write(ctx, &Request{Token: propose(g, ctx)}, w.write)
+	assert.NoError(err)
+	assert.Len(w.written, 2)
+}
+
+func TestGuardRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	g := newTestGuard(&now)
+	w := new(testWriter)
+	agent := metadata.WithCaller(context.Background(), "agent")
+	operator := metadata.WithCaller(context.Background(), "operator")
+
+	write := func(writes ...*Write) error {
+		result, err := g.Write(agent, &Request{Writes: writes}, w.write)
+		if err != nil {
+			return err
+		}
+
+		_, err = g.Write(operator, &Request{Token: result.Token}, w.write)
+		return err
+	}
+
+	assert.NoError(write(newWrite("spindle_override", 100.0)))
+
+	// The point waits for its minimum interval.
+	err := write(newWrite("spindle_override", 105.0))
+	assert.ErrorIs(err, ErrRateLimited)
+	assert.Equal(errs.ResourceExhausted, errs.CodeOf(err))
+
+	now = now.Add(10 * time.Second)
+	assert.NoError(write(newWrite("spindle_override", 105.0)))
+
+	// The edge writes at most 3 points per minute.
+	assert.NoError(write(newWrite("mode", "auto")))
+	assert.ErrorIs(write(newWrite("mode", "manual")), ErrRateLimited)
+
+	now = now.Add(time.Minute)
+	assert.NoError(write(newWrite("mode", "manual")))
+	assert.Len(w.written, 4)
+}
@@ -0,0 +1,175 @@
+// Package safety guards the writes to the devices: only the points allowed
+// by the policy are written, within their limits and rates, and only once
+// the write proposed is confirmed.
+package safety
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+var (
+	ErrNoWrites      = errs.New(errs.InvalidArgument, "no writes")
+	ErrNotAllowed    = errs.New(errs.PermissionDenied, "write not allowed")
+	ErrOutOfRange    = errs.New(errs.InvalidArgument, "value out of range")
+	ErrRateLimited   = errs.New(errs.ResourceExhausted, "write rate limit exceeded")
+	ErrInvalidToken  = errs.New(errs.FailedPrecondition, "invalid or expired confirmation token")
+	ErrWritesPending = errs.New(errs.FailedPrecondition, "too many writes awaiting confirmation")
+
+	ErrConfirmerRequired = errs.New(errs.Unauthenticated, "confirmation requires an identified caller")
+	ErrSelfConfirm       = errs.New(errs.PermissionDenied, "proposal must be confirmed by another caller")
+)
+
+// Write is a value to write to a point.
+type Write struct {
+	machine.PointRef
+	Value any `json:"value"`
+
+	// Previous is the live value of the point when the write was checked.
+	Previous *machine.Value `json:"previous,omitempty"`
+}
+
+// Request requests writes. Without a token, the writes are checked and
+// proposed; the token of the proposal confirms them. A dry run only checks
+// the writes.
+type Request struct {
+	Writes []*Write `json:"writes,omitempty"`
+	Token  string   `json:"token,omitempty"`
+	DryRun bool     `json:"dry_run,omitempty"`
+}
+
+// Status is the outcome of a write request.
+type Status string
+
+const (
+	// DryRun: the writes are allowed, but nothing was written.
+	DryRun Status = "dry_run"
+
+	// Proposed: the writes are allowed, and wait for confirmation.
+	Proposed Status = "proposed"
+
+	// Written: the writes were confirmed and written.
+	Written Status = "written"
+)
+
+type Result struct {
+	Status      Status     `json:"status"`
+	Writes      []*Write   `json:"writes"`
+	Token       string     `json:"token,omitempty"`
+	ExpiresTime *time.Time `json:"expires_time,omitempty"`
+}
+
+// Rule allows writes to a point (e.g., "CNC01/PLC/spindle_override").
+type Rule struct {
+	Point string `json:"point"`
+
+	// Min and Max bound numeric values.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+
+	// Values lists the only values allowed, if any.
+	Values []any `json:"values,omitempty"`
+
+	// MinInterval is the minimum time between two writes of the point.
+	MinInterval machine.Duration `json:"min_interval,omitempty"`
+}
+
+// Check checks the value against the limits of the rule.
+func (r *Rule) Check(value any) error {
+	if len(r.Values) > 0 {
+		for _, allowed := range r.Values {
+			if machine.Equal(allowed, value) {
+				return nil
+			}
+		}
+
+		return ErrOutOfRange.WithDetail("point", r.Point).WithDetail("values", r.Values)
+	}
+
+	if r.Min == nil && r.Max == nil {
+		return nil
+	}
+
+	f, ok := machine.ToFloat(value)
+	if !ok {
+		return ErrOutOfRange.WithDetail("point", r.Point).WithDetail("reason", "value is not numeric")
+	}
+
+	if r.Min != nil && f < *r.Min {
+		return ErrOutOfRange.WithDetail("point", r.Point).WithDetail("min", *r.Min)
+	}
+
+	if r.Max != nil && f > *r.Max {
+		return ErrOutOfRange.WithDetail("point", r.Point).WithDetail("max", *r.Max)
+	}
+
+	return nil
+}
+
+// Policy lists the points that may be written. Points without a rule are
+// never written.
+type Policy struct {
+	Rules []*Rule `json:"rules"`
+
+	// MaxWritesPerMinute bounds the points written per minute on the edge.
+	MaxWritesPerMinute int `json:"max_writes_per_minute,omitempty"`
+
+	// ConfirmTTL is how long a proposal waits for confirmation.
+	ConfirmTTL machine.Duration `json:"confirm_ttl,omitempty"`
+
+	// SelfConfirm lets the proposer confirm its own proposals. Otherwise,
+	// another caller confirms them, e.g., the operator approving the writes
+	// proposed by an agent.
+	SelfConfirm bool `json:"self_confirm,omitempty"`
+
+	// AnonymousConfirm accepts the confirmations without a caller, such as
+	// over the MCP stdio transport, where the proposer and the confirmer
+	// cannot be told apart.
+	AnonymousConfirm bool `json:"anonymous_confirm,omitempty"`
+
+	// DryRun checks every write without ever writing.
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+var (
+	DefaultMaxWritesPerMinute = 10
+	DefaultConfirmTTL         = 2 * time.Minute
+)
+
+// LoadPolicy loads the write policy. Without a policy file, every write
+// is denied.
+func LoadPolicy(filename string) (*Policy, error) {
+	policy := &Policy{
+		Rules: make([]*Rule, 0),
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return policy, nil
+		}
+
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range policy.Rules {
+		if _, err := machine.ParsePointRef(rule.Point); err != nil {
+			return nil, errors.Join(err, errors.New(rule.Point))
+		}
+
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return nil, errors.New("min greater than max: " + rule.Point)
+		}
+	}
+
+	return policy, nil
+}
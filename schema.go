@@ -0,0 +1,137 @@
+package iiot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+)
+
+// schemaCache caches the schemas of the drivers, compiled for validation.
+// The schema of a driver is fetched again once its revision changes: the
+// version it describes itself with and, for the drivers run from a binary,
+// the binary. Invalidate forgets it explicitly.
+type schemaCache struct {
+	path    string
+	client  tool.Client
+	schemas map[string]*driverSchema
+	sync.Mutex
+}
+
+type driverSchema struct {
+	raw    json.RawMessage
+	schema *gojsonschema.Schema // nil for the drivers without a schema
+
+	revision revision
+}
+
+// revision identifies the driver a schema was fetched from.
+type revision struct {
+	version string
+	modTime time.Time
+	size    int64
+}
+
+func newSchemaCache(path string, client tool.Client) *schemaCache {
+	return &schemaCache{
+		path:    path,
+		client:  client,
+		schemas: make(map[string]*driverSchema),
+	}
+}
+
+// revision returns the revision of the driver. The version of a driver
+// failing to describe itself is unknown.
+func (c *schemaCache) revision(ctx context.Context, driver string) (revision, bool) {
+	var rev revision
+
+	if describer, ok := c.client.(tool.Describer); ok {
+		info, err := describer.Info(ctx, driver)
+		if err != nil {
+			return rev, false
+		}
+
+		rev.version = info.Version
+	}
+
+	if fi, err := os.Stat(filepath.Join(c.path, driver+"_tool")); err == nil {
+		rev.modTime = fi.ModTime()
+		rev.size = fi.Size()
+	}
+
+	return rev, true
+}
+
+// Schema returns the schema of the driver, fetching it if the driver
+// changed since it was cached.
+func (c *schemaCache) Schema(ctx context.Context, driver string) (*driverSchema, error) {
+	rev, known := c.revision(ctx, driver)
+
+	c.Lock()
+	s, ok := c.schemas[driver]
+	c.Unlock()
+
+	if ok && (!known || s.revision == rev) {
+		return s, nil
+	}
+
+	raw, err := c.client.Schema(ctx, driver)
+	switch {
+	case errors.Is(err, tool.ErrMethodNotFound):
+		raw = nil
+
+	case err != nil:
+		return nil, err
+	}
+
+	s = &driverSchema{
+		revision: rev,
+	}
+
+	if len(raw) > 0 && string(raw) != "null" {
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+		if err != nil {
+			return nil, errs.Wrap(errs.DriverError, err).WithDetail("driver", driver)
+		}
+
+		s.raw = raw
+		s.schema = schema
+	}
+
+	if !known {
+		return s, nil
+	}
+
+	c.Lock()
+	c.schemas[driver] = s
+	c.Unlock()
+
+	return s, nil
+}
+
+// Validate checks the request against the schema, reporting each field
+// that fails it. Without a schema, the request is left to the driver.
+func (s *driverSchema) Validate(raw json.RawMessage) error {
+	if s.schema == nil {
+		return nil
+	}
+
+	return tool.Validate(s.schema, raw)
+}
+
+// Invalidate forgets the schema of the driver, once it is upgraded or
+// removed.
+func (c *schemaCache) Invalidate(driver string) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.schemas, driver)
+}
@@ -0,0 +1,209 @@
+package iiot
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+)
+
+type testClient struct {
+	schemas int
+	reads   int
+}
+
+func (c *testClient) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
+	c.schemas++
+
+	return json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"points": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {"address": {"type": "integer"}},
+					"required": ["address"]
+				}
+			}
+		},
+		"required": ["points"]
+	}`), nil
+}
+
+func (c *testClient) Instruction(ctx context.Context, driver string) (string, error) {
+	return "", nil
+}
+
+func (c *testClient) ReadPoints(ctx context.Context, driver string, raw json.RawMessage) ([]any, error) {
+	c.reads++
+	return []any{}, nil
+}
+
+func TestReadPointsValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	path := t.TempDir()
+	binary := filepath.Join(path, "drivers", "modbus_tool")
+
+	os.MkdirAll(filepath.Dir(binary), 0755)
+	os.WriteFile(binary, []byte("v1"), 0755)
+
+	client := new(testClient)
+	svc := NewService(path, client, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	_, err := svc.ReadPoints(ctx, "modbus", json.RawMessage(`{"points": [{"address": 40001}]}`))
+	assert.NoError(err)
+	assert.Equal(1, client.reads)
+
+	_, err = svc.ReadPoints(ctx, "modbus", json.RawMessage(`{"points": [{"address": "40001"}, {}]}`))
+	assert.ErrorIs(err, tool.ErrInvalidRequest)
+	assert.Equal(1, client.reads)
+
+	e := errs.From(err)
+	assert.Equal("modbus", e.Details["driver"])
+
+	fields := e.Details["errors"].([]*tool.FieldError)
+	if assert.Len(fields, 2) {
+		assert.Equal("points.0.address", fields[0].Field)
+		assert.Equal("points.1", fields[1].Field)
+	}
+
+	// The schema is cached until the driver binary changes.
+	assert.Equal(1, client.schemas)
+
+	os.WriteFile(binary, []byte("v2"), 0755)
+	os.Chtimes(binary, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+
+	_, err = svc.Schema(ctx, "modbus")
+	assert.NoError(err)
+	assert.Equal(2, client.schemas)
+}
+
+// versionedClient serves drivers without a binary, such as the ones
+// served over gRPC or compiled in.
+type versionedClient struct {
+	testClient
+	version string
+}
+
+func (c *versionedClient) Info(ctx context.Context, driver string) (*tool.Info, error) {
+	return &tool.Info{Name: driver, Version: c.version, APIVersion: tool.APIVersion}, nil
+}
+
+func TestSchemaCacheRevision(t *testing.T) {
+	assert := assert.New(t)
+
+	client := &versionedClient{version: "1.0.0"}
+	svc := NewService(t.TempDir(), client, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	request := json.RawMessage(`{"points": [{"address": 40001}]}`)
+
+	_, err := svc.ReadPoints(ctx, "modbus", request)
+	assert.NoError(err)
+
+	_, err = svc.ReadPoints(ctx, "modbus", request)
+	assert.NoError(err)
+	assert.Equal(1, client.schemas)
+
+	// The schema is fetched again once the driver is upgraded.
+	client.version = "1.1.0"
+
+	_, err = svc.ReadPoints(ctx, "modbus", request)
+	assert.NoError(err)
+	assert.Equal(2, client.schemas)
+
+	// Or once it is invalidated.
+	svc.(*service).schemas.Invalidate("modbus")
+
+	_, err = svc.Schema(ctx, "modbus")
+	assert.NoError(err)
+	assert.Equal(3, client.schemas)
+}
+
+// schemalessClient serves a driver that has no schema.
+type schemalessClient struct {
+	testClient
+}
+
+func (c *schemalessClient) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
+	return nil, tool.ErrMethodNotFound
+}
+
+func TestReadPointsWithoutSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	client := new(schemalessClient)
+	svc := NewService(t.TempDir(), client, nil, nil, nil, nil, nil)
+	ctx := context.Background()
+
+	// The request is left to the driver.
+	_, err := svc.ReadPoints(ctx, "legacy", json.RawMessage(`{"anything": true}`))
+	assert.NoError(err)
+	assert.Equal(1, client.reads)
+
+	_, err = svc.Schema(ctx, "legacy")
+	assert.Equal(errs.NotFound, errs.CodeOf(err))
+}
+
+type describingClient struct {
+	testClient
+}
+
+func (c *describingClient) Info(ctx context.Context, driver string) (*tool.Info, error) {
+	switch driver {
+	case "modbus":
+		return &tool.Info{Name: "modbus", Version: "1.0.0", Operations: []tool.Operation{tool.OpRead}, APIVersion: tool.APIVersion}, nil
+	default:
+		return &tool.Info{Name: driver, APIVersion: tool.APIVersion + 1}, nil
+	}
+}
+
+func TestListDrivers(t *testing.T) {
+	assert := assert.New(t)
+
+	path := t.TempDir()
+	drivers := filepath.Join(path, "drivers")
+
+	os.MkdirAll(drivers, 0755)
+	os.WriteFile(filepath.Join(drivers, "modbus_tool"), nil, 0755)
+	os.WriteFile(filepath.Join(drivers, "opcua_tool"), nil, 0755)
+	os.WriteFile(filepath.Join(drivers, "s7_tool"), nil, 0755)
+	os.WriteFile(filepath.Join(drivers, "s7_tool.json"), []byte(`{
+		"name": "s7",
+		"version": "2.1.0",
+		"vendor": "ACME",
+		"protocols": ["s7comm"],
+		"operations": ["read", "write"],
+		"api_version": 1
+	}`), 0644)
+
+	svc := NewService(path, new(describingClient), nil, nil, nil, nil, nil)
+
+	descs, err := svc.ListDrivers(context.Background())
+	if !assert.NoError(err) || !assert.Len(descs, 3) {
+		return
+	}
+
+	assert.Equal("modbus", descs[0].Name)
+	assert.Equal("handshake", descs[0].Source)
+	assert.Empty(descs[0].Error)
+
+	// Drivers of another API version are listed, but refused.
+	assert.Equal("opcua", descs[1].Name)
+	assert.Equal(tool.ErrIncompatibleDriver.Error(), descs[1].Error)
+
+	assert.Equal("s7", descs[2].Name)
+	assert.Equal("manifest", descs[2].Source)
+	assert.Equal("ACME", descs[2].Vendor)
+	assert.True(descs[2].Supports(tool.OpWrite))
+}
@@ -4,12 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/install"
 	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/production"
+	"github.com/flarexio/iiot/safety"
 )
 
 type Service interface {
@@ -22,25 +32,169 @@ type Service interface {
 	//   - error: nil if the connection is successful, otherwise an error.
 	CheckConnection(ctx context.Context, network string, address string) error
 
-	// ListDrivers retrieves a list of available drivers.
+	// ListDrivers retrieves the available drivers.
 	//
 	// Returns:
-	//   - drivers: A slice of driver names.
+	//   - drivers: A slice of drivers with their version, vendor, protocols, operations and API version; drivers the host refuses carry the reason.
 	//   - error: nil if the operation is successful, otherwise an error.
-	ListDrivers(ctx context.Context) (drivers []string, err error)
+	ListDrivers(ctx context.Context) (drivers []*tool.Descriptor, err error)
+
+	// InstallDriver installs or upgrades a driver from a package, rolling back on a failed handshake.
+	//
+	// Args:
+	//   - req: The package, in a file on the edge or an object of the object store.
+	// Returns:
+	//   - driver: The driver installed, as described by its handshake.
+	//   - error: nil if the operation is successful, otherwise an error.
+	InstallDriver(ctx context.Context, req install.Request) (driver *tool.Descriptor, err error)
+
+	// UninstallDriver stops a driver and removes it.
+	//
+	// Args:
+	//   - driver: The name of the driver to uninstall.
+	// Returns:
+	//   - error: nil if the operation is successful, otherwise an error.
+	UninstallDriver(ctx context.Context, driver string) error
+
+	// ListMachines retrieves the configured machines with their current status.
+	//
+	// Returns:
+	//   - machines: A slice of machines.
+	//   - error: nil if the operation is successful, otherwise an error.
+	ListMachines(ctx context.Context) (machines []*machine.Machine, err error)
+
+	// MachineStatus retrieves the current status of a machine.
+	//
+	// Args:
+	//   - id: The ID of the machine.
+	// Returns:
+	//   - status: The current status and since when the machine has been in it.
+	//   - error: nil if the operation is successful, otherwise an error.
+	MachineStatus(ctx context.Context, id machine.MachineID) (status *machine.StatusInfo, err error)
+
+	// MachineStatusHistory retrieves the status changes of a machine.
+	//
+	// Args:
+	//   - id: The ID of the machine.
+	//   - since: Only status changes at or after this time are returned.
+	// Returns:
+	//   - events: A slice of status events, oldest first.
+	//   - error: nil if the operation is successful, otherwise an error.
+	MachineStatusHistory(ctx context.Context, id machine.MachineID, since time.Time) (events []*machine.StatusEvent, err error)
+
+	// PointValues retrieves the live values of the points of a machine.
+	//
+	// Args:
+	//   - id: The ID of the machine.
+	// Returns:
+	//   - samples: The last sampled value of each point; points not sampled yet have no value.
+	//   - error: nil if the operation is successful, otherwise an error.
+	PointValues(ctx context.Context, id machine.MachineID) (samples []*machine.Sample, err error)
+
+	// WritePoints writes values to the points of the machines through their drivers.
+	//
+	// Args:
+	//   - req: The writes, the token confirming a proposal, or a dry run.
+	// Returns:
+	//   - result: The writes checked, proposed or written, with the live values they replace.
+	//   - error: nil if the operation is successful, otherwise an error.
+	WritePoints(ctx context.Context, req *safety.Request) (result *safety.Result, err error)
+
+	// ListAlarms lists the alarms matching the given filter.
+	//
+	// Args:
+	//   - filter: The machine, state and severity to filter by; empty fields match all.
+	// Returns:
+	//   - alarms: A slice of alarms, oldest first.
+	//   - error: nil if the operation is successful, otherwise an error.
+	ListAlarms(ctx context.Context, filter alarm.Filter) (alarms []*alarm.Alarm, err error)
+
+	// AcknowledgeAlarm acknowledges an alarm, recording the user and comment.
+	//
+	// Args:
+	//   - id: The ID of the alarm to acknowledge.
+	//   - user: The user acknowledging the alarm.
+	//   - comment: An optional comment.
+	// Returns:
+	//   - alarm: The acknowledged alarm.
+	//   - error: nil if the operation is successful, otherwise an error.
+	AcknowledgeAlarm(ctx context.Context, id string, user string, comment string) (alarm *alarm.Alarm, err error)
+
+	// ShelveAlarm suppresses an alarm for the given duration.
+	//
+	// Args:
+	//   - id: The ID of the alarm to shelve.
+	//   - user: The user shelving the alarm.
+	//   - comment: The reason for shelving.
+	//   - duration: How long the alarm stays shelved.
+	// Returns:
+	//   - alarm: The shelved alarm.
+	//   - error: nil if the operation is successful, otherwise an error.
+	ShelveAlarm(ctx context.Context, id string, user string, comment string, duration time.Duration) (alarm *alarm.Alarm, err error)
+
+	// ReadHistory reads the stored history of a point.
+	//
+	// Args:
+	//   - query: The point, time range, read mode and page to read.
+	// Returns:
+	//   - result: The values of the page and the token of the next page, if any.
+	//   - error: nil if the operation is successful, otherwise an error.
+	ReadHistory(ctx context.Context, query historian.Query) (result *historian.Result, err error)
+
+	// ProductionReport retrieves the production and OEE of a machine over its current shift.
+	//
+	// Args:
+	//   - id: The ID of the machine.
+	// Returns:
+	//   - report: The counts, cycle times, downtime and OEE of the shift so far.
+	//   - error: nil if the operation is successful, otherwise an error.
+	ProductionReport(ctx context.Context, id machine.MachineID) (report *production.Report, err error)
+
+	// ProductionHistory retrieves the production reports of the ended shifts of a machine.
+	//
+	// Args:
+	//   - id: The ID of the machine.
+	//   - since: Only shifts ending at or after this time are returned.
+	// Returns:
+	//   - reports: A slice of shift reports, oldest first.
+	//   - error: nil if the operation is successful, otherwise an error.
+	ProductionHistory(ctx context.Context, id machine.MachineID, since time.Time) (reports []*production.Report, err error)
 
 	tool.Client
 }
 
 type ServiceMiddleware func(Service) Service
 
-func NewService(path string, tool tool.Client) Service {
-	return &service{path, tool}
+var (
+	ErrMachinesNotAvailable   = errs.New(errs.Unavailable, "machines not available")
+	ErrAlarmsNotAvailable     = errs.New(errs.Unavailable, "alarms not available")
+	ErrHistoryNotAvailable    = errs.New(errs.Unavailable, "history not available")
+	ErrProductionNotAvailable = errs.New(errs.Unavailable, "production not available")
+	ErrDriversNotManageable   = errs.New(errs.Unavailable, "driver installation not available")
+)
+
+func NewService(path string, tool tool.Client, machines machine.Service, alarms alarm.Service, history historian.Service, production production.Service, installer install.Installer) Service {
+	return &service{
+		path:       path,
+		tool:       tool,
+		schemas:    newSchemaCache(filepath.Join(path, "drivers"), tool),
+		machines:   machines,
+		alarms:     alarms,
+		history:    history,
+		production: production,
+		installer:  installer,
+	}
 }
 
 type service struct {
-	path string
-	tool tool.Client
+	path       string
+	tool       tool.Client
+	schemas    *schemaCache
+	machines   machine.Service
+	alarms     alarm.Service
+	history    historian.Service
+	production production.Service
+	installer  install.Installer
 }
 
 func (svc *service) CheckConnection(ctx context.Context, network string, address string) error {
@@ -55,7 +209,7 @@ func (svc *service) CheckConnection(ctx context.Context, network string, address
 	return nil
 }
 
-func (svc *service) ListDrivers(ctx context.Context) ([]string, error) {
+func (svc *service) ListDrivers(ctx context.Context) ([]*tool.Descriptor, error) {
 	driverPath := filepath.Join(svc.path, "drivers")
 
 	entries, err := os.ReadDir(driverPath)
@@ -63,9 +217,10 @@ func (svc *service) ListDrivers(ctx context.Context) ([]string, error) {
 		return nil, err
 	}
 
-	drivers := make([]string, 0)
+	drivers := make([]*tool.Descriptor, 0)
 	for _, entry := range entries {
-		if entry.IsDir() {
+		// Manifests describe the binaries they sit next to.
+		if entry.IsDir() || filepath.Ext(entry.Name()) == ".json" {
 			continue
 		}
 
@@ -76,27 +231,133 @@ func (svc *service) ListDrivers(ctx context.Context) ([]string, error) {
 			continue
 		}
 
-		drivers = append(drivers, driver)
+		drivers = append(drivers, svc.describe(ctx, driverPath, driver))
+	}
+
+	// Drivers served over other transports, or compiled in, have no binary.
+	if router, ok := svc.tool.(tool.Router); ok {
+		for _, driver := range router.Drivers() {
+			found := slices.ContainsFunc(drivers, func(desc *tool.Descriptor) bool {
+				return desc.Name == driver
+			})
+
+			if !found {
+				drivers = append(drivers, svc.describe(ctx, driverPath, driver))
+			}
+		}
 	}
 
 	if len(drivers) == 0 {
-		return nil, errors.New("no drivers available")
+		return nil, errs.New(errs.NotFound, "no drivers available")
 	}
 
 	return drivers, nil
 }
 
+// describe describes the driver from its manifest, or from its handshake
+// without one. Drivers the host cannot use are listed with the reason.
+func (svc *service) describe(ctx context.Context, path string, driver string) *tool.Descriptor {
+	desc := &tool.Descriptor{
+		Info: tool.Info{Name: driver},
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, driver+"_tool.json"))
+	switch {
+	case err == nil:
+		desc.Source = "manifest"
+
+		if err := json.Unmarshal(data, &desc.Info); err != nil {
+			desc.Error = "invalid manifest: " + err.Error()
+			return desc
+		}
+
+		if desc.Name != driver {
+			desc.Error = "manifest of driver " + desc.Name
+			return desc
+		}
+
+	case errors.Is(err, os.ErrNotExist):
+		describer, ok := svc.tool.(tool.Describer)
+		if !ok {
+			desc.Info = *tool.LegacyInfo(driver)
+			return desc
+		}
+
+		desc.Source = "handshake"
+
+		info, err := describer.Info(ctx, driver)
+		if err != nil {
+			desc.Error = err.Error()
+			return desc
+		}
+
+		desc.Info = *info
+
+	default:
+		desc.Error = err.Error()
+		return desc
+	}
+
+	if err := desc.Check(); err != nil {
+		desc.Error = err.Error()
+	}
+
+	return desc
+}
+
+func (svc *service) InstallDriver(ctx context.Context, req install.Request) (*tool.Descriptor, error) {
+	if svc.installer == nil {
+		return nil, ErrDriversNotManageable
+	}
+
+	driver, err := svc.installer.Install(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	svc.schemas.Invalidate(driver.Name)
+
+	return driver, nil
+}
+
+func (svc *service) UninstallDriver(ctx context.Context, driver string) error {
+	if driver == "" {
+		return errs.New(errs.InvalidArgument, "driver parameter is required")
+	}
+
+	if svc.installer == nil {
+		return ErrDriversNotManageable
+	}
+
+	if err := svc.installer.Uninstall(ctx, driver); err != nil {
+		return err
+	}
+
+	svc.schemas.Invalidate(driver)
+
+	return nil
+}
+
 func (svc *service) Schema(ctx context.Context, driver string) (json.RawMessage, error) {
 	if driver == "" {
-		return nil, errors.New("driver parameter is required")
+		return nil, errs.New(errs.InvalidArgument, "driver parameter is required")
 	}
 
-	return svc.tool.Schema(ctx, driver)
+	schema, err := svc.schemas.Schema(ctx, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	if schema.raw == nil {
+		return nil, errs.New(errs.NotFound, "no schema available for the specified driver")
+	}
+
+	return schema.raw, nil
 }
 
 func (svc *service) Instruction(ctx context.Context, driver string) (string, error) {
 	if driver == "" {
-		return "", errors.New("driver parameter is required")
+		return "", errs.New(errs.InvalidArgument, "driver parameter is required")
 	}
 
 	instruction, err := svc.tool.Instruction(ctx, driver)
@@ -105,7 +366,7 @@ func (svc *service) Instruction(ctx context.Context, driver string) (string, err
 	}
 
 	if instruction == "" {
-		return "", errors.New("no instruction available for the specified driver")
+		return "", errs.New(errs.NotFound, "no instruction available for the specified driver")
 	}
 
 	return instruction, nil
@@ -113,8 +374,264 @@ func (svc *service) Instruction(ctx context.Context, driver string) (string, err
 
 func (svc *service) ReadPoints(ctx context.Context, driver string, raw json.RawMessage) ([]any, error) {
 	if driver == "" {
-		return nil, errors.New("driver parameter is required")
+		return nil, errs.New(errs.InvalidArgument, "driver parameter is required")
+	}
+
+	// Reject the requests the driver would reject, without reaching the
+	// device.
+	if err := svc.ValidateRequest(ctx, driver, raw); err != nil {
+		return nil, errs.From(err).WithDetail("driver", driver)
 	}
 
 	return svc.tool.ReadPoints(ctx, driver, raw)
 }
+
+// ValidateRequest checks the request against the schema of the driver,
+// reporting each field that fails it. Drivers without a schema check their
+// requests themselves.
+func (svc *service) ValidateRequest(ctx context.Context, driver string, raw json.RawMessage) error {
+	schema, err := svc.schemas.Schema(ctx, driver)
+	if err != nil {
+		return err
+	}
+
+	return schema.Validate(raw)
+}
+
+func (svc *service) ListMachines(ctx context.Context) ([]*machine.Machine, error) {
+	if svc.machines == nil {
+		return nil, ErrMachinesNotAvailable
+	}
+
+	return svc.machines.Machines(), nil
+}
+
+func (svc *service) MachineStatus(ctx context.Context, id machine.MachineID) (*machine.StatusInfo, error) {
+	if svc.machines == nil {
+		return nil, ErrMachinesNotAvailable
+	}
+
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+	}
+
+	return svc.machines.Status(id)
+}
+
+func (svc *service) MachineStatusHistory(ctx context.Context, id machine.MachineID, since time.Time) ([]*machine.StatusEvent, error) {
+	if svc.machines == nil {
+		return nil, ErrMachinesNotAvailable
+	}
+
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+	}
+
+	return svc.machines.StatusHistory(id, since)
+}
+
+func (svc *service) PointValues(ctx context.Context, id machine.MachineID) ([]*machine.Sample, error) {
+	if svc.machines == nil {
+		return nil, ErrMachinesNotAvailable
+	}
+
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+	}
+
+	return svc.machines.Values(id)
+}
+
+func (svc *service) WritePoints(ctx context.Context, req *safety.Request) (*safety.Result, error) {
+	if svc.machines == nil {
+		return nil, ErrMachinesNotAvailable
+	}
+
+	if req == nil || len(req.Writes) == 0 {
+		return nil, safety.ErrNoWrites
+	}
+
+	machines := make(map[machine.MachineID]*machine.Machine)
+	for _, m := range svc.machines.Machines() {
+		machines[m.MachineID] = m
+	}
+
+	controllers := make([]*machine.Controller, 0)
+	values := make(map[*machine.Controller]map[string]any)
+	writes := make([]*safety.Write, len(req.Writes))
+
+	for i, w := range req.Writes {
+		m, ok := machines[w.MachineID]
+		if !ok {
+			return nil, machine.ErrMachineNotFound.WithDetail("machine_id", w.MachineID)
+		}
+
+		var controller *machine.Controller
+		for _, c := range m.Controllers {
+			if c.ControllerID == w.ControllerID {
+				controller = c
+				break
+			}
+		}
+
+		if controller == nil {
+			return nil, errs.New(errs.NotFound, "controller not found").
+				WithDetail("controller_id", w.ControllerID)
+		}
+
+		var point *machine.Point
+		for _, p := range controller.Points {
+			if p.Name == w.Point {
+				point = p
+				break
+			}
+		}
+
+		if point == nil {
+			return nil, machine.ErrPointNotFound.WithDetail("point", w.PointRef.String())
+		}
+
+		if point.Access == machine.ReadOnly {
+			return nil, safety.ErrNotAllowed.
+				WithDetail("point", w.PointRef.String()).
+				WithDetail("reason", "read-only point")
+		}
+
+		if f, ok := w.Value.(float64); ok && point.Type == machine.INT && f != math.Trunc(f) {
+			return nil, errs.New(errs.InvalidArgument, "value does not match the point type").
+				WithDetail("point", w.PointRef.String()).
+				WithDetail("type", point.Type)
+		}
+
+		value, err := machine.NewValue(point.Type, w.Value, time.Now())
+		if err != nil || value.Type != point.Type {
+			return nil, errs.New(errs.InvalidArgument, "value does not match the point type").
+				WithDetail("point", w.PointRef.String()).
+				WithDetail("type", point.Type)
+		}
+
+		if _, ok := values[controller]; !ok {
+			controllers = append(controllers, controller)
+			values[controller] = make(map[string]any)
+		}
+
+		values[controller][point.Name] = value.Value
+
+		writes[i] = &safety.Write{
+			PointRef: w.PointRef,
+			Value:    value.Value,
+		}
+	}
+
+	// Report the live values replaced by the writes.
+	for _, w := range writes {
+		samples, err := svc.machines.Values(w.MachineID)
+		if err != nil {
+			continue
+		}
+
+		for _, sample := range samples {
+			if sample.PointRef == w.PointRef {
+				w.Previous = sample.Value
+			}
+		}
+	}
+
+	if req.DryRun {
+		return &safety.Result{Status: safety.DryRun, Writes: writes}, nil
+	}
+
+	writer, ok := svc.tool.(tool.Writer)
+	if !ok {
+		return nil, errs.New(errs.Unavailable, "point writes not supported")
+	}
+
+	for _, controller := range controllers {
+		raw, err := machine.WriteRequest(controller, values[controller])
+		if err != nil {
+			return nil, err
+		}
+
+		if err := writer.WritePoints(ctx, controller.Driver, raw); err != nil {
+			return nil, errs.Wrap(errs.DriverError, err).WithDetail("controller_id", controller.ControllerID)
+		}
+	}
+
+	return &safety.Result{Status: safety.Written, Writes: writes}, nil
+}
+
+func (svc *service) ListAlarms(ctx context.Context, filter alarm.Filter) ([]*alarm.Alarm, error) {
+	if svc.alarms == nil {
+		return nil, ErrAlarmsNotAvailable
+	}
+
+	return svc.alarms.Alarms(filter), nil
+}
+
+func (svc *service) AcknowledgeAlarm(ctx context.Context, id string, user string, comment string) (*alarm.Alarm, error) {
+	if svc.alarms == nil {
+		return nil, ErrAlarmsNotAvailable
+	}
+
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+	}
+
+	if user == "" {
+		return nil, errs.New(errs.InvalidArgument, "user parameter is required")
+	}
+
+	return svc.alarms.Acknowledge(id, user, comment)
+}
+
+func (svc *service) ShelveAlarm(ctx context.Context, id string, user string, comment string, duration time.Duration) (*alarm.Alarm, error) {
+	if svc.alarms == nil {
+		return nil, ErrAlarmsNotAvailable
+	}
+
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+	}
+
+	if user == "" {
+		return nil, errs.New(errs.InvalidArgument, "user parameter is required")
+	}
+
+	if duration <= 0 {
+		return nil, errs.New(errs.InvalidArgument, "duration must be positive")
+	}
+
+	return svc.alarms.Shelve(id, user, comment, duration)
+}
+
+func (svc *service) ReadHistory(ctx context.Context, query historian.Query) (*historian.Result, error) {
+	if svc.history == nil {
+		return nil, ErrHistoryNotAvailable
+	}
+
+	return svc.history.Read(ctx, query)
+}
+
+func (svc *service) ProductionReport(ctx context.Context, id machine.MachineID) (*production.Report, error) {
+	if svc.production == nil {
+		return nil, ErrProductionNotAvailable
+	}
+
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+	}
+
+	return svc.production.Report(id)
+}
+
+func (svc *service) ProductionHistory(ctx context.Context, id machine.MachineID, since time.Time) ([]*production.Report, error) {
+	if svc.production == nil {
+		return nil, ErrProductionNotAvailable
+	}
+
+	if id == "" {
+		return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+	}
+
+	return svc.production.Reports(id, since)
+}
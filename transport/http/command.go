@@ -0,0 +1,68 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/flarexio/iiot/command"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+func AddCommandRouters(r *gin.Engine, svc command.Service) {
+	r.POST("/fleet/edges/:id/commands", SubmitCommandHandler(command.SubmitEndpoint(svc)))
+	r.GET("/fleet/commands/:id", CommandResultHandler(command.ResultEndpoint(svc)))
+}
+
+func SubmitCommandHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req command.SubmitRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		req.EdgeID = c.Param("id")
+
+		ctx := c.Request.Context()
+		result, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, result)
+	}
+}
+
+// CommandResultHandler returns the result of a command, waiting for its
+// completion up to the duration of the wait query (e.g., ?wait=30s).
+func CommandResultHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := command.ResultRequest{
+			ID: c.Param("id"),
+		}
+
+		if wait := c.Query("wait"); wait != "" {
+			d, err := time.ParseDuration(wait)
+			if err != nil {
+				Error(c, errs.Wrap(errs.InvalidArgument, err))
+				return
+			}
+
+			req.Wait = machine.Duration(d)
+		}
+
+		ctx := c.Request.Context()
+		result, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
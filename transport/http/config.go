@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/flarexio/iiot/config"
+	"github.com/flarexio/iiot/errs"
+)
+
+func AddConfigRouters(r *gin.Engine, svc config.Service) {
+	r.GET("/fleet/edges/:id/config", ConfigStateHandler(config.StateEndpoint(svc)))
+	r.PUT("/fleet/edges/:id/config", PushConfigHandler(config.PushEndpoint(svc)))
+	r.POST("/fleet/edges/:id/config/rollback", RollbackConfigHandler(config.RollbackEndpoint(svc)))
+}
+
+func PushConfigHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req config.PushRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		req.EdgeID = c.Param("id")
+
+		ctx := c.Request.Context()
+		doc, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, doc)
+	}
+}
+
+func RollbackConfigHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		doc, err := endpoint(ctx, c.Param("id"))
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, doc)
+	}
+}
+
+func ConfigStateHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		state, err := endpoint(ctx, c.Param("id"))
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, state)
+	}
+}
@@ -0,0 +1,16 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+// Error aborts the request with the HTTP status of the error code, and the
+// error (code, message and details) as the JSON body.
+func Error(c *gin.Context, err error) {
+	e := errs.From(err)
+
+	c.AbortWithStatusJSON(e.Code.HTTPStatus(), e)
+	c.Error(err)
+}
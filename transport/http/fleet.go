@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/fleet"
+)
+
+func AddFleetRouters(r *gin.Engine, svc fleet.Service) {
+	r.GET("/fleet/edges", ListEdgesHandler(fleet.ListEdgesEndpoint(svc)))
+	r.POST("/fleet/fan_out", FanOutHandler(fleet.FanOutEndpoint(svc)))
+}
+
+func ListEdgesHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		edges, err := endpoint(ctx, nil)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, edges)
+	}
+}
+
+func FanOutHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req fleet.FanOutRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		result, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
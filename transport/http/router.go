@@ -9,7 +9,20 @@ import (
 func AddRouters(r *gin.Engine, endpoints iiot.EndpointSet) {
 	r.POST("/iiot/check_connection", CheckConnectionHandler(endpoints.CheckConnection))
 	r.GET("/iiot/drivers", ListDriversHandler(endpoints.ListDrivers))
+	r.POST("/iiot/drivers", InstallDriverHandler(endpoints.InstallDriver))
+	r.DELETE("/iiot/drivers/:driver", UninstallDriverHandler(endpoints.UninstallDriver))
 	r.GET("/iiot/drivers/:driver/schema", SchemaHandler(endpoints.Schema))
 	r.GET("/iiot/drivers/:driver/instruction", InstructionHandler(endpoints.Instruction))
 	r.POST("/iiot/drivers/:driver/read_points", ReadPointsHandler(endpoints.ReadPoints))
+	r.GET("/iiot/machines", ListMachinesHandler(endpoints.ListMachines))
+	r.GET("/iiot/machines/:id/status", MachineStatusHandler(endpoints.MachineStatus))
+	r.GET("/iiot/machines/:id/status/history", MachineStatusHistoryHandler(endpoints.MachineStatusHistory))
+	r.GET("/iiot/machines/:id/points", PointValuesHandler(endpoints.PointValues))
+	r.POST("/iiot/points/write", WritePointsHandler(endpoints.WritePoints))
+	r.GET("/iiot/machines/:id/production", ProductionReportHandler(endpoints.ProductionReport))
+	r.GET("/iiot/machines/:id/production/history", ProductionHistoryHandler(endpoints.ProductionHistory))
+	r.GET("/iiot/alarms", ListAlarmsHandler(endpoints.ListAlarms))
+	r.POST("/iiot/alarms/:id/acknowledge", AcknowledgeAlarmHandler(endpoints.AcknowledgeAlarm))
+	r.POST("/iiot/alarms/:id/shelve", ShelveAlarmHandler(endpoints.ShelveAlarm))
+	r.POST("/iiot/history", ReadHistoryHandler(endpoints.ReadHistory))
 }
@@ -2,31 +2,33 @@ package http
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-kit/kit/endpoint"
 
 	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/safety"
 )
 
 func CheckConnectionHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req iiot.CheckConnectionRequest
 		if err := c.ShouldBind(&req); err != nil {
-			c.String(http.StatusBadRequest, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
 			return
 		}
 
 		ctx := c.Request.Context()
 		_, err := endpoint(ctx, req)
 		if err != nil {
-			c.String(http.StatusExpectationFailed, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, err)
 			return
 		}
 
@@ -39,9 +41,7 @@ func ListDriversHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
 		ctx := c.Request.Context()
 		drivers, err := endpoint(ctx, nil)
 		if err != nil {
-			c.String(http.StatusExpectationFailed, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, err)
 			return
 		}
 
@@ -49,23 +49,56 @@ func ListDriversHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
 	}
 }
 
+func InstallDriverHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req install.Request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		driver, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, driver)
+	}
+}
+
+func UninstallDriverHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		driver := c.Param("driver")
+		if driver == "" {
+			Error(c, errs.New(errs.InvalidArgument, "driver parameter is required"))
+			return
+		}
+
+		ctx := c.Request.Context()
+		_, err := endpoint(ctx, driver)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.String(http.StatusOK, "Driver uninstalled")
+	}
+}
+
 func SchemaHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		driver := c.Param("driver")
 		if driver == "" {
-			err := errors.New("driver parameter is required")
-			c.String(http.StatusBadRequest, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, errs.New(errs.InvalidArgument, "driver parameter is required"))
 			return
 		}
 
 		ctx := c.Request.Context()
 		schema, err := endpoint(ctx, driver)
 		if err != nil {
-			c.String(http.StatusExpectationFailed, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, err)
 			return
 		}
 
@@ -77,28 +110,20 @@ func InstructionHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		driver := c.Param("driver")
 		if driver == "" {
-			err := errors.New("driver parameter is required")
-			c.String(http.StatusBadRequest, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, errs.New(errs.InvalidArgument, "driver parameter is required"))
 			return
 		}
 
 		ctx := c.Request.Context()
 		resp, err := endpoint(ctx, driver)
 		if err != nil {
-			c.String(http.StatusExpectationFailed, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, err)
 			return
 		}
 
 		instruction, ok := resp.(string)
 		if !ok {
-			err := errors.New("invalid instruction response type")
-			c.String(http.StatusInternalServerError, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, errs.New(errs.Internal, "invalid instruction response type"))
 			return
 		}
 
@@ -110,18 +135,13 @@ func ReadPointsHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		driver := c.Param("driver")
 		if driver == "" {
-			err := errors.New("driver parameter is required")
-			c.String(http.StatusBadRequest, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, errs.New(errs.InvalidArgument, "driver parameter is required"))
 			return
 		}
 
 		var raw json.RawMessage
 		if err := c.ShouldBind(&raw); err != nil {
-			c.String(http.StatusBadRequest, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
 			return
 		}
 
@@ -133,12 +153,260 @@ func ReadPointsHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
 		ctx := c.Request.Context()
 		points, err := endpoint(ctx, req)
 		if err != nil {
-			c.String(http.StatusExpectationFailed, err.Error())
-			c.Error(err)
-			c.Abort()
+			Error(c, err)
 			return
 		}
 
 		c.JSON(http.StatusOK, points)
 	}
 }
+
+func ListMachinesHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		machines, err := endpoint(ctx, nil)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, machines)
+	}
+}
+
+func MachineStatusHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := machine.MachineID(c.Param("id"))
+
+		ctx := c.Request.Context()
+		status, err := endpoint(ctx, id)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+func PointValuesHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := machine.MachineID(c.Param("id"))
+
+		ctx := c.Request.Context()
+		samples, err := endpoint(ctx, id)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, samples)
+	}
+}
+
+func MachineStatusHistoryHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := iiot.MachineStatusHistoryRequest{
+			MachineID: machine.MachineID(c.Param("id")),
+		}
+
+		if since := c.Query("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				Error(c, errs.Wrap(errs.InvalidArgument, err))
+				return
+			}
+
+			req.Since = t
+		}
+
+		ctx := c.Request.Context()
+		events, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, events)
+	}
+}
+
+func ListAlarmsHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter alarm.Filter
+		if err := c.ShouldBindQuery(&filter); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		alarms, err := endpoint(ctx, filter)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, alarms)
+	}
+}
+
+func WritePointsHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req safety.Request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		result, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func AcknowledgeAlarmHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req iiot.AcknowledgeAlarmRequest
+		if err := c.ShouldBind(&req); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		req.ID = c.Param("id")
+
+		ctx := c.Request.Context()
+		alarm, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, alarm)
+	}
+}
+
+func ShelveAlarmHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req iiot.ShelveAlarmRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		req.ID = c.Param("id")
+
+		ctx := c.Request.Context()
+		alarm, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, alarm)
+	}
+}
+
+// ReadHistoryHandler reads a page of history. With ?stream=true, all pages
+// from the requested one on are written as newline-delimited JSON results
+// as they are read, so large ranges do not have to be paged by the client.
+func ReadHistoryHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var query historian.Query
+		if err := c.ShouldBindJSON(&query); err != nil {
+			Error(c, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx := c.Request.Context()
+		resp, err := endpoint(ctx, query)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		if c.Query("stream") != "true" {
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+
+		enc := json.NewEncoder(c.Writer)
+		for {
+			result, ok := resp.(*historian.Result)
+			if !ok {
+				c.Error(errs.New(errs.Internal, "invalid response"))
+				return
+			}
+
+			if err := enc.Encode(result); err != nil {
+				c.Error(err)
+				return
+			}
+
+			c.Writer.Flush()
+
+			if result.NextPageToken == "" {
+				return
+			}
+
+			query.PageToken = result.NextPageToken
+
+			resp, err = endpoint(ctx, query)
+			if err != nil {
+				// The status is already sent; end the stream with the error.
+				enc.Encode(gin.H{"error": errs.From(err)})
+				c.Error(err)
+				return
+			}
+		}
+	}
+}
+
+func ProductionReportHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := machine.MachineID(c.Param("id"))
+
+		ctx := c.Request.Context()
+		report, err := endpoint(ctx, id)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func ProductionHistoryHandler(endpoint endpoint.Endpoint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := iiot.ProductionHistoryRequest{
+			MachineID: machine.MachineID(c.Param("id")),
+		}
+
+		if since := c.Query("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				Error(c, errs.Wrap(errs.InvalidArgument, err))
+				return
+			}
+
+			req.Since = t
+		}
+
+		ctx := c.Request.Context()
+		reports, err := endpoint(ctx, req)
+		if err != nil {
+			Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, reports)
+	}
+}
@@ -0,0 +1,100 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/flarexio/iiot/command"
+	"github.com/flarexio/iiot/errs"
+)
+
+func SubmitCommandTool(name ...string) mcp.Tool {
+	toolName := "SubmitCommand"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Queue a command for an edge, which runs it once online, even if it is offline now. Returns the pending result; use CommandResult with its id to poll or await completion. Submitting the same id again does not run the command twice."),
+		mcp.WithString("edge_id",
+			mcp.Required(),
+			mcp.Description("The edge ID to run the command on"),
+		),
+		mcp.WithString("endpoint",
+			mcp.Required(),
+			mcp.Description("The endpoint of the edge, such as 'alarms.acknowledge'"),
+		),
+		mcp.WithObject("payload",
+			mcp.Description("The request of the endpoint"),
+		),
+		mcp.WithString("id",
+			mcp.Description("An optional unique ID that deduplicates the command; generated when empty"),
+		),
+		mcp.WithString("ttl",
+			mcp.Description("How long the command may wait for the edge (e.g., 1h); it expires afterwards"),
+		),
+	)
+}
+
+func SubmitCommandHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req command.SubmitRequest
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		return commandResult(endpoint(ctx, req))
+	}
+}
+
+func CommandResultTool(name ...string) mcp.Tool {
+	toolName := "CommandResult"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Get the status of a queued command (pending, succeeded, failed or expired) and, once run, the response or error of the edge."),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("The command ID returned by SubmitCommand"),
+		),
+		mcp.WithString("wait",
+			mcp.Description("How long to wait for the command to complete (e.g., 30s); returns at once when empty"),
+		),
+	)
+}
+
+func CommandResultHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req command.ResultRequest
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		return commandResult(endpoint(ctx, req))
+	}
+}
+
+func commandResult(resp any, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		return NewToolResultError(err), nil
+	}
+
+	result, ok := resp.(*command.Result)
+	if !ok {
+		err := errs.New(errs.Internal, "invalid response type")
+		return NewToolResultError(err), nil
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+	}
+
+	return mcp.NewToolResultText(string(bs)), nil
+}
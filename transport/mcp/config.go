@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/flarexio/iiot/config"
+	"github.com/flarexio/iiot/errs"
+)
+
+func PushConfigTool(name ...string) mcp.Tool {
+	toolName := "PushConfig"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Push a new version of the machine and driver configuration of an edge. The edge validates it against the driver schemas, applies it, and rolls back if it fails; use ConfigState to see the outcome. The machines replace the whole configuration, so include the unchanged ones."),
+		mcp.WithString("edge_id",
+			mcp.Required(),
+			mcp.Description("The edge to configure"),
+		),
+		mcp.WithArray("machines",
+			mcp.Required(),
+			mcp.Description("The machines with their controllers (driver, address, options) and points, as listed by ListMachines"),
+			mcp.Items(map[string]any{
+				"type": "object",
+			}),
+		),
+		mcp.WithString("comment",
+			mcp.Description("What the change is about"),
+		),
+	)
+}
+
+func PushConfigHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req config.PushRequest
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		return configResult[*config.Document](endpoint(ctx, req))
+	}
+}
+
+func RollbackConfigTool(name ...string) mcp.Tool {
+	toolName := "RollbackConfig"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Roll an edge back to the configuration that ran before the current one, pushed as a new version."),
+		mcp.WithString("edge_id",
+			mcp.Required(),
+			mcp.Description("The edge to roll back"),
+		),
+	)
+}
+
+func RollbackConfigHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		edgeID, err := request.RequireString("edge_id")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		return configResult[*config.Document](endpoint(ctx, edgeID))
+	}
+}
+
+func ConfigStateTool(name ...string) mcp.Tool {
+	toolName := "ConfigState"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Get the configuration state of an edge: the desired version, and the version it applied with the outcome (applied, rejected, rolled_back or failed), the changed machines and any error."),
+		mcp.WithString("edge_id",
+			mcp.Required(),
+			mcp.Description("The edge"),
+		),
+	)
+}
+
+func ConfigStateHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		edgeID, err := request.RequireString("edge_id")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		return configResult[*config.State](endpoint(ctx, edgeID))
+	}
+}
+
+func configResult[T any](resp any, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		return NewToolResultError(err), nil
+	}
+
+	result, ok := resp.(T)
+	if !ok {
+		err := errs.New(errs.Internal, "invalid response type")
+		return NewToolResultError(err), nil
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+	}
+
+	return mcp.NewToolResultText(string(bs)), nil
+}
@@ -0,0 +1,28 @@
+package mcp
+
+import (
+	"encoding/json"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+// NewToolResultError returns an error result whose text is the error (code,
+// message and details) as JSON. The error is also attached to the metadata
+// of the result, so clients can read it without parsing the text.
+func NewToolResultError(err error) *mcp.CallToolResult {
+	e := errs.From(err)
+
+	bs, jsonErr := json.Marshal(e)
+	if jsonErr != nil {
+		return mcp.NewToolResultError(e.Message)
+	}
+
+	result := mcp.NewToolResultError(string(bs))
+	result.Meta = map[string]any{
+		"error": e,
+	}
+
+	return result
+}
@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/fleet"
+)
+
+func ListEdgesTool(name ...string) mcp.Tool {
+	toolName := "ListEdges"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("List the edges of the fleet: whether each is online, its version, drivers and machines with their status. Use the edge_id of an online edge as the context of the other tools."),
+	)
+}
+
+func ListEdgesHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		resp, err := endpoint(ctx, nil)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		edges, ok := resp.([]*fleet.Edge)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(&edges)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func FanOutTool(name ...string) mcp.Tool {
+	toolName := "FanOut"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Run ReadPoints, CheckConnection or ListDrivers on many edges at once, e.g., the same point on every identical line. Returns the result or error of each edge, keyed by edge ID; some edges may fail while others succeed."),
+		mcp.WithArray("edges",
+			mcp.Required(),
+			mcp.Description("Edge IDs or wildcard patterns matched against the online edges (e.g., '*', 'line-*'). Use ListEdges to find them."),
+			mcp.Items(map[string]any{
+				"type": "string",
+			}),
+		),
+		mcp.WithString("operation",
+			mcp.Required(),
+			mcp.Description("The operation to run on every edge"),
+			mcp.Enum(string(fleet.ReadPoints), string(fleet.CheckConnection), string(fleet.ListDrivers)),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("The timeout of each edge (e.g., 5s, default 5s)"),
+		),
+		mcp.WithString("driver",
+			mcp.Description("The driver of read_points, such as 'modbus', 'opcua', etc."),
+		),
+		mcp.WithObject("raw",
+			mcp.Description("The driver-specific configuration of read_points. Use Schema to get the required format."),
+		),
+		mcp.WithString("network",
+			mcp.Description("The network of check_connection, such as 'tcp' or 'udp'"),
+		),
+		mcp.WithString("address",
+			mcp.Description("The address of check_connection, such as 'localhost:502'"),
+		),
+	)
+}
+
+func FanOutHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req fleet.FanOutRequest
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		resp, err := endpoint(ctx, req)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		result, ok := resp.(*fleet.FanOutResult)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(result)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
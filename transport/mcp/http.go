@@ -0,0 +1,399 @@
+package mcp
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/flarexio/iiot/metadata"
+)
+
+// HTTPConfig configures the HTTP transports of the MCP server.
+type HTTPConfig struct {
+	// Tokens maps the bearer tokens accepted to the name of their caller.
+	// Without tokens, every request is accepted, so the server only listens
+	// on the loopback interface (see ListenAddr).
+	Tokens map[string]string
+
+	// AllowedOrigins lists the origins allowed to call the server from a
+	// browser, or "*" for any. Without origins, only local origins are
+	// allowed. Requests without an Origin header are not checked.
+	AllowedOrigins []string
+
+	// SessionTTL is how long an idle session is kept.
+	SessionTTL time.Duration
+}
+
+// ParseTokens parses "name:token" entries into the tokens of HTTPConfig.
+// Entries without a name are named after their position.
+func ParseTokens(entries []string) map[string]string {
+	tokens := make(map[string]string, len(entries))
+	for i, entry := range entries {
+		name, token, ok := strings.Cut(entry, ":")
+		if !ok {
+			name, token = "token-"+strconv.Itoa(i+1), entry
+		}
+
+		if token != "" {
+			tokens[token] = name
+		}
+	}
+
+	return tokens
+}
+
+// ListenAddr returns the address to serve the port on: every interface
+// once requests are authenticated, the loopback interface otherwise.
+func (cfg HTTPConfig) ListenAddr(port int) string {
+	if len(cfg.Tokens) == 0 {
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	}
+
+	return ":" + strconv.Itoa(port)
+}
+
+// DefaultSessionTTL is the session TTL when none is configured.
+var DefaultSessionTTL = 30 * time.Minute
+
+// NewHTTPHandler serves the MCP server over the streamable HTTP transport
+// on /mcp and over the SSE transport on /sse and /message. Each request is
+// checked for its origin and bearer token, and sessions of both transports
+// are bound to the caller that created them.
+func NewHTTPHandler(s *server.MCPServer, cfg HTTPConfig) http.Handler {
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = DefaultSessionTTL
+	}
+
+	sessions := newSessionManager(cfg.SessionTTL)
+
+	streamable := server.NewStreamableHTTPServer(s,
+		server.WithSessionIdManager(sessions),
+		server.WithHTTPContextFunc(callerContext),
+		server.WithHeartbeatInterval(30*time.Second),
+	)
+
+	sse := server.NewSSEServer(s,
+		server.WithSSEContextFunc(callerContext),
+		server.WithDynamicBasePath(sessions.claimStream),
+		server.WithKeepAlive(true),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", sessions.bind(streamable))
+	mux.Handle("/sse", sessions.bindStream(sse.SSEHandler()))
+	mux.Handle("/message", sessions.bindMessage(sse.MessageHandler()))
+
+	return guard(cfg, mux)
+}
+
+// ServeHTTP serves the MCP server on the address until the context is done.
+func ServeHTTP(ctx context.Context, addr string, s *server.MCPServer, cfg HTTPConfig) error {
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: NewHTTPHandler(s, cfg),
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+
+	case <-ctx.Done():
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+
+		return srv.Shutdown(ctx)
+	}
+}
+
+// callerContext passes the caller authenticated by guard to the tools.
+func callerContext(ctx context.Context, r *http.Request) context.Context {
+	if caller := metadata.Caller(r.Context()); caller != "" {
+		ctx = metadata.WithCaller(ctx, caller)
+	}
+
+	return ctx
+}
+
+// guard rejects requests from origins that are not allowed, against DNS
+// rebinding, and requests without a valid bearer token.
+func guard(cfg HTTPConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !originAllowed(origin, cfg.AllowedOrigins) {
+			http.Error(w, "Origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		if len(cfg.Tokens) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="iiot"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		caller, ok := authenticate(token, cfg.Tokens)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="iiot", error="invalid_token"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := metadata.WithCaller(r.Context(), caller)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate compares the token with every known token in constant time.
+func authenticate(token string, tokens map[string]string) (string, bool) {
+	var caller string
+	var found bool
+
+	for known, name := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+			caller, found = name, true
+		}
+	}
+
+	return caller, found
+}
+
+func originAllowed(origin string, allowed []string) bool {
+	if len(allowed) == 0 {
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+
+		switch host := u.Hostname(); host {
+		case "localhost", "127.0.0.1", "::1":
+			return true
+		default:
+			ip := net.ParseIP(host)
+			return ip != nil && ip.IsLoopback()
+		}
+	}
+
+	return slices.Contains(allowed, "*") || slices.Contains(allowed, strings.TrimSuffix(origin, "/"))
+}
+
+var errUnknownSession = errors.New("unknown session")
+
+// sessionManager issues random session IDs, expires idle sessions, and
+// remembers the caller owning each session. The SSE sessions, issued by
+// the SSE server, live as long as their stream.
+type sessionManager struct {
+	ttl      time.Duration
+	sessions map[string]*session
+	streams  map[string]string
+	sync.Mutex
+}
+
+type session struct {
+	owner      string
+	lastSeen   time.Time
+	terminated bool
+}
+
+func newSessionManager(ttl time.Duration) *sessionManager {
+	return &sessionManager{
+		ttl:      ttl,
+		sessions: make(map[string]*session),
+		streams:  make(map[string]string),
+	}
+}
+
+func (m *sessionManager) Generate() string {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+
+	// Forget the sessions idle for long.
+	for id, s := range m.sessions {
+		if now.Sub(s.lastSeen) > 2*m.ttl {
+			delete(m.sessions, id)
+		}
+	}
+
+	id := metadata.NewTraceID()
+	m.sessions[id] = &session{lastSeen: now}
+	return id
+}
+
+// Validate reports expired sessions as terminated, so that the client
+// initializes a new one.
+func (m *sessionManager) Validate(sessionID string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return false, errUnknownSession
+	}
+
+	now := time.Now()
+	if now.Sub(s.lastSeen) > m.ttl {
+		s.terminated = true
+	}
+
+	if s.terminated {
+		return true, nil
+	}
+
+	s.lastSeen = now
+	return false, nil
+}
+
+func (m *sessionManager) Terminate(sessionID string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return false, errUnknownSession
+	}
+
+	s.terminated = true
+	return false, nil
+}
+
+// bind rejects requests on a session owned by another caller, and records
+// the owner of the sessions created by initialize requests.
+func (m *sessionManager) bind(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := metadata.Caller(r.Context())
+
+		if id := r.Header.Get("Mcp-Session-Id"); id != "" {
+			m.Lock()
+			s, ok := m.sessions[id]
+			foreign := ok && s.owner != caller
+			m.Unlock()
+
+			if foreign {
+				http.Error(w, "Session not found", http.StatusNotFound)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(&sessionWriter{
+			ResponseWriter: w,
+			claim: func(id string) {
+				m.Lock()
+				defer m.Unlock()
+
+				if s, ok := m.sessions[id]; ok && s.owner == "" {
+					s.owner = caller
+				}
+			},
+		}, r)
+	})
+}
+
+// sessionWriter claims the session ID set on the response.
+type sessionWriter struct {
+	http.ResponseWriter
+	claim   func(id string)
+	written bool
+}
+
+func (w *sessionWriter) WriteHeader(status int) {
+	if !w.written {
+		w.written = true
+
+		if id := w.Header().Get("Mcp-Session-Id"); id != "" {
+			w.claim(id)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *sessionWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+type streamKey struct{}
+
+// stream is the SSE session of a stream, claimed once the SSE server
+// issues it.
+type stream struct {
+	id string
+}
+
+// bindStream forgets the SSE session once its stream ends.
+func (m *sessionManager) bindStream(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := new(stream)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), streamKey{}, st)))
+
+		m.Lock()
+		defer m.Unlock()
+
+		if st.id != "" {
+			delete(m.streams, st.id)
+		}
+	})
+}
+
+// claimStream records the caller opening the stream as the owner of its
+// SSE session. It is called by the SSE server when it announces the message
+// endpoint of the session, whose base path it returns.
+func (m *sessionManager) claimStream(r *http.Request, sessionID string) string {
+	if st, ok := r.Context().Value(streamKey{}).(*stream); ok {
+		m.Lock()
+		defer m.Unlock()
+
+		st.id = sessionID
+		m.streams[sessionID] = metadata.Caller(r.Context())
+	}
+
+	return "/"
+}
+
+// bindMessage rejects messages to the SSE sessions owned by another caller.
+func (m *sessionManager) bindMessage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		owner, ok := m.streams[r.URL.Query().Get("sessionId")]
+		m.Unlock()
+
+		if ok && owner != metadata.Caller(r.Context()) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	s := server.NewMCPServer("IIoT Service", "1.0.0")
+
+	handler := NewHTTPHandler(s, HTTPConfig{
+		Tokens: map[string]string{
+			"secret-a": "agent-a",
+			"secret-b": "agent-b",
+		},
+	})
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	post := func(token string, origin string, session string) *http.Response {
+		body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`
+		if session != "" {
+			body = `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+		}
+
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+
+		if session != "" {
+			req.Header.Set("Mcp-Session-Id", session)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp
+	}
+
+	assert.Equal(http.StatusUnauthorized, post("", "", "").StatusCode)
+	assert.Equal(http.StatusUnauthorized, post("wrong", "", "").StatusCode)
+	assert.Equal(http.StatusForbidden, post("secret-a", "http://evil.example.com", "").StatusCode)
+
+	resp := post("secret-a", "http://localhost:3000", "")
+	if !assert.Equal(http.StatusOK, resp.StatusCode) {
+		return
+	}
+
+	session := resp.Header.Get("Mcp-Session-Id")
+	if !assert.NotEmpty(session) {
+		return
+	}
+
+	assert.Equal(http.StatusOK, post("secret-a", "", session).StatusCode)
+
+	// Sessions cannot be used by another caller.
+	assert.Equal(http.StatusNotFound, post("secret-b", "", session).StatusCode)
+
+	assert.Equal(http.StatusBadRequest, post("secret-a", "", "unknown").StatusCode)
+}
+
+func TestHTTPHandlerSSE(t *testing.T) {
+	assert := assert.New(t)
+
+	s := server.NewMCPServer("IIoT Service", "1.0.0")
+
+	handler := NewHTTPHandler(s, HTTPConfig{
+		Tokens: map[string]string{
+			"secret-a": "agent-a",
+			"secret-b": "agent-b",
+		},
+	})
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/sse", nil)
+	req.Header.Set("Authorization", "Bearer secret-a")
+
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+
+	// The stream announces the message endpoint of its session.
+	var endpoint string
+	scanner := bufio.NewScanner(stream.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			endpoint = strings.TrimSpace(data)
+			break
+		}
+	}
+
+	if !assert.True(strings.HasPrefix(endpoint, "/message?sessionId=")) {
+		return
+	}
+
+	post := func(token string) int {
+		body := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+endpoint, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(http.StatusAccepted, post("secret-a"))
+
+	// Sessions cannot be used by another caller.
+	assert.Equal(http.StatusNotFound, post("secret-b"))
+}
+
+func TestListenAddr(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("127.0.0.1:8080", HTTPConfig{}.ListenAddr(8080))
+	assert.Equal(":8080", HTTPConfig{Tokens: map[string]string{"secret": "agent"}}.ListenAddr(8080))
+}
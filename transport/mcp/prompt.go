@@ -0,0 +1,383 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/flarexio/core/model"
+
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/production"
+)
+
+// WithoutEdge removes the edge argument of the prompt, for prompts served
+// by the edge itself.
+func WithoutEdge(prompt mcp.Prompt) mcp.Prompt {
+	prompt.Arguments = slices.DeleteFunc(slices.Clone(prompt.Arguments), func(arg mcp.PromptArgument) bool {
+		return arg.Name == "edge_id"
+	})
+
+	return prompt
+}
+
+// AddPrompts adds the guided workflows of the IIoT service to the server.
+// The prompts of a service local to the edge take no edge argument.
+func AddPrompts(s *server.MCPServer, endpoints iiot.EndpointSet, local bool) {
+	prompts := []struct {
+		Prompt  mcp.Prompt
+		Handler server.PromptHandlerFunc
+	}{
+		{Prompt: ConfigureDevicePrompt(), Handler: ConfigureDeviceHandler(endpoints)},
+		{Prompt: DiagnosePointPrompt(), Handler: DiagnosePointHandler(endpoints)},
+		{Prompt: ShiftSummaryPrompt(), Handler: ShiftSummaryHandler(endpoints)},
+	}
+
+	for _, p := range prompts {
+		if local {
+			p.Prompt = WithoutEdge(p.Prompt)
+		}
+
+		s.AddPrompt(p.Prompt, p.Handler)
+	}
+}
+
+func withEdgeArgument() mcp.PromptOption {
+	return mcp.WithArgument("edge_id",
+		mcp.ArgumentDescription("The edge ID of the device or machine"),
+		mcp.RequiredArgument(),
+	)
+}
+
+// promptContext scopes the requests of the prompt to its edge, if any.
+func promptContext(ctx context.Context, request mcp.GetPromptRequest) (context.Context, string) {
+	edgeID := request.Params.Arguments["edge_id"]
+	if edgeID != "" {
+		ctx = context.WithValue(ctx, model.EdgeID, edgeID)
+	}
+
+	return ctx, edgeID
+}
+
+// promptMessages collects the messages of a prompt: the request, followed
+// by the context it needs.
+type promptMessages []mcp.PromptMessage
+
+func (m *promptMessages) text(format string, args ...any) {
+	content := mcp.NewTextContent(fmt.Sprintf(format, args...))
+	*m = append(*m, mcp.NewPromptMessage(mcp.RoleUser, content))
+}
+
+func (m *promptMessages) resource(uri string, mimeType string, text string) {
+	content := mcp.NewEmbeddedResource(mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: mimeType,
+		Text:     text,
+	})
+
+	*m = append(*m, mcp.NewPromptMessage(mcp.RoleUser, content))
+}
+
+func (m *promptMessages) json(uri string, v any) {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		m.unavailable(uri, err)
+		return
+	}
+
+	m.resource(uri, "application/json", string(bs))
+}
+
+// data adds context that has no resource of its own.
+func (m *promptMessages) data(title string, v any) {
+	bs, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		m.unavailable(title, err)
+		return
+	}
+
+	m.text("%s:\n```json\n%s\n```", title, bs)
+}
+
+// unavailable notes context that could not be retrieved, so that the
+// prompt still guides the workflow.
+func (m *promptMessages) unavailable(what string, err error) {
+	m.text("%s is unavailable: %s", what, err.Error())
+}
+
+func ConfigureDevicePrompt(name ...string) mcp.Prompt {
+	promptName := "configure_device"
+	if len(name) > 0 {
+		promptName = name[0]
+	}
+
+	return mcp.NewPrompt(promptName,
+		mcp.WithPromptDescription("Configure a new device with a driver (e.g., a Modbus PLC): check the connection, build the driver configuration from its schema, and verify it by reading points."),
+		withEdgeArgument(),
+		mcp.WithArgument("driver",
+			mcp.ArgumentDescription("The name of the driver, such as 'modbus', 'opcua', etc."),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("address",
+			mcp.ArgumentDescription("The address of the device (e.g., 192.168.1.100:502)"),
+		),
+		mcp.WithArgument("machine_id",
+			mcp.ArgumentDescription("The machine the device belongs to"),
+		),
+	)
+}
+
+func ConfigureDeviceHandler(endpoints iiot.EndpointSet) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		driver := request.Params.Arguments["driver"]
+		if driver == "" {
+			return nil, errs.New(errs.InvalidArgument, "driver argument is required")
+		}
+
+		ctx, edgeID := promptContext(ctx, request)
+
+		address := request.Params.Arguments["address"]
+		if address == "" {
+			address = "(ask me for it)"
+		}
+
+		machineID := request.Params.Arguments["machine_id"]
+		if machineID == "" {
+			machineID = "(ask me for it)"
+		}
+
+		messages := make(promptMessages, 0)
+		messages.text(`Help me configure a new device with the %[1]s driver.
+
+Device address: %[2]s
+Machine: %[3]s
+
+Follow these steps, and stop to ask me when something is missing or fails:
+1. Check that the device is reachable with CheckConnection on its address.
+2. Build the driver configuration following the schema below; the instruction explains the options and how points are addressed.
+3. Verify the configuration with ReadPoints on the %[1]s driver, reading a few points first.
+4. Propose the controller of the machine (controller_id, driver, address, options and points) for the machine configuration, and wait for my approval before applying it.`,
+			driver, address, machineID)
+
+		if resp, err := endpoints.Schema(ctx, driver); err != nil {
+			messages.unavailable("The schema of the "+driver+" driver", err)
+		} else if schema, ok := resp.(json.RawMessage); ok {
+			messages.resource(SchemaURI(edgeID, driver), "application/schema+json", string(schema))
+		}
+
+		if resp, err := endpoints.Instruction(ctx, driver); err != nil {
+			messages.unavailable("The instruction of the "+driver+" driver", err)
+		} else if instruction, ok := resp.(string); ok {
+			messages.resource(InstructionURI(edgeID, driver), "text/markdown", instruction)
+		}
+
+		return mcp.NewGetPromptResult("Configure a new "+driver+" device", messages), nil
+	}
+}
+
+func DiagnosePointPrompt(name ...string) mcp.Prompt {
+	promptName := "diagnose_point"
+	if len(name) > 0 {
+		promptName = name[0]
+	}
+
+	return mcp.NewPrompt(promptName,
+		mcp.WithPromptDescription("Diagnose why a point of a machine reads bad: no value, a stale value, or an implausible value."),
+		withEdgeArgument(),
+		mcp.WithArgument("machine_id",
+			mcp.ArgumentDescription("The ID of the machine"),
+			mcp.RequiredArgument(),
+		),
+		mcp.WithArgument("point",
+			mcp.ArgumentDescription("The name of the point"),
+			mcp.RequiredArgument(),
+		),
+	)
+}
+
+func DiagnosePointHandler(endpoints iiot.EndpointSet) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		id := machine.MachineID(request.Params.Arguments["machine_id"])
+		point := request.Params.Arguments["point"]
+		if id == "" || point == "" {
+			return nil, errs.New(errs.InvalidArgument, "machine_id and point arguments are required")
+		}
+
+		ctx, edgeID := promptContext(ctx, request)
+
+		m, controller, err := findPoint(ctx, endpoints, id, point)
+		if err != nil {
+			return nil, err
+		}
+
+		messages := make(promptMessages, 0)
+		messages.text(`Diagnose why the point %[1]q of machine %[2]s reads bad.
+
+The point is read by controller %[3]s with the %[4]s driver at %[5]s. A bad read is a missing value, a value that stopped updating, or a value that is implausible for its type and unit.
+
+Follow these steps and report the likely cause with a fix:
+1. Look at the live value below: is there a value, and is its time recent compared to the poll interval?
+2. Check that the controller is reachable with CheckConnection on %[5]s.
+3. Read the point directly with ReadPoints on the %[4]s driver, using the options of the controller, to see the error of the driver.
+4. Compare the options of the point (address, data type, scaling, byte order) with the driver instruction.
+5. Check the alarms of the machine for related faults.`,
+			point, id, controller.ControllerID, controller.Driver, controller.Address)
+
+		messages.json(MachineURI(edgeID, id), m)
+
+		if resp, err := endpoints.PointValues(ctx, id); err != nil {
+			messages.unavailable("The live value of the point", err)
+		} else if samples, ok := resp.([]*machine.Sample); ok {
+			for _, sample := range samples {
+				if sample.ControllerID == controller.ControllerID && sample.Point == point {
+					messages.json(PointURI(edgeID, sample.PointRef), sample)
+				}
+			}
+		}
+
+		if resp, err := endpoints.Instruction(ctx, controller.Driver); err != nil {
+			messages.unavailable("The instruction of the "+controller.Driver+" driver", err)
+		} else if instruction, ok := resp.(string); ok {
+			messages.resource(InstructionURI(edgeID, controller.Driver), "text/markdown", instruction)
+		}
+
+		filter := alarm.Filter{MachineID: id}
+		if resp, err := endpoints.ListAlarms(ctx, filter); err != nil {
+			messages.unavailable("The alarms of the machine", err)
+		} else {
+			messages.data("The alarms of the machine", resp)
+		}
+
+		return mcp.NewGetPromptResult("Diagnose point "+point+" of machine "+string(id), messages), nil
+	}
+}
+
+// findPoint returns the machine and the controller of the first point with
+// the name, in configuration order.
+func findPoint(ctx context.Context, endpoints iiot.EndpointSet, id machine.MachineID, point string) (*machine.Machine, *machine.Controller, error) {
+	resp, err := endpoints.ListMachines(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	machines, ok := resp.([]*machine.Machine)
+	if !ok {
+		return nil, nil, errs.New(errs.Internal, "invalid response type")
+	}
+
+	for _, m := range machines {
+		if m.MachineID != id {
+			continue
+		}
+
+		for _, c := range m.Controllers {
+			for _, p := range c.Points {
+				if p.Name == point {
+					return m, c, nil
+				}
+			}
+		}
+
+		return nil, nil, machine.ErrPointNotFound.WithDetail("point", point)
+	}
+
+	return nil, nil, machine.ErrMachineNotFound.WithDetail("machine_id", string(id))
+}
+
+func ShiftSummaryPrompt(name ...string) mcp.Prompt {
+	promptName := "shift_summary"
+	if len(name) > 0 {
+		promptName = name[0]
+	}
+
+	return mcp.NewPrompt(promptName,
+		mcp.WithPromptDescription("Summarize the status, production, OEE and alarms of a machine over its current shift."),
+		withEdgeArgument(),
+		mcp.WithArgument("machine_id",
+			mcp.ArgumentDescription("The ID of the machine"),
+			mcp.RequiredArgument(),
+		),
+	)
+}
+
+// DefaultShiftWindow is the period summarized when the production of the
+// machine is not tracked, and so its shift is unknown.
+var DefaultShiftWindow = 8 * time.Hour
+
+func ShiftSummaryHandler(endpoints iiot.EndpointSet) server.PromptHandlerFunc {
+	return func(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		id := machine.MachineID(request.Params.Arguments["machine_id"])
+		if id == "" {
+			return nil, errs.New(errs.InvalidArgument, "machine_id argument is required")
+		}
+
+		ctx, edgeID := promptContext(ctx, request)
+
+		since := time.Now().Add(-DefaultShiftWindow)
+		period := "the last " + DefaultShiftWindow.String()
+
+		var report *production.Report
+		if resp, err := endpoints.ProductionReport(ctx, id); err == nil {
+			report, _ = resp.(*production.Report)
+		}
+
+		if report != nil {
+			since = report.Start
+			period = "shift " + report.Shift + ", since " + report.Start.Format(time.RFC3339)
+		}
+
+		messages := make(promptMessages, 0)
+		messages.text(`Summarize %[1]s of machine %[2]s for a shift handover.
+
+Cover, in this order:
+1. The current status of the machine, and how long it has been in it.
+2. The time spent in each status, and the longest stops with their likely cause.
+3. The production counts, cycle times and OEE (availability, performance, quality), if tracked.
+4. The alarms raised, still active, or shelved, and who acknowledged them.
+5. Anything the next shift should follow up on.
+
+Keep it short, and flag the numbers that look abnormal.`,
+			period, id)
+
+		if resp, err := endpoints.MachineStatus(ctx, id); err != nil {
+			messages.unavailable("The status of the machine", err)
+		} else {
+			messages.data("The current status of the machine", resp)
+		}
+
+		req := iiot.MachineStatusHistoryRequest{MachineID: id, Since: since}
+		if resp, err := endpoints.MachineStatusHistory(ctx, req); err != nil {
+			messages.unavailable("The status history of the machine", err)
+		} else {
+			messages.data("The status changes of the machine since "+since.Format(time.RFC3339), resp)
+		}
+
+		if report != nil {
+			messages.data("The production report of the shift", report)
+		} else {
+			messages.text("The production of the machine is not tracked.")
+		}
+
+		filter := alarm.Filter{MachineID: id}
+		if resp, err := endpoints.ListAlarms(ctx, filter); err != nil {
+			messages.unavailable("The alarms of the machine", err)
+		} else {
+			messages.data("The alarms of the machine", resp)
+		}
+
+		title := "Shift summary of machine " + string(id)
+		if edgeID != "" {
+			title += " on edge " + edgeID
+		}
+
+		return mcp.NewGetPromptResult(title, messages), nil
+	}
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/core/model"
+
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+func TestDiagnosePoint(t *testing.T) {
+	assert := assert.New(t)
+
+	var edgeID string
+	endpoints := iiot.EndpointSet{
+		ListMachines: func(ctx context.Context, request any) (any, error) {
+			edgeID, _ = ctx.Value(model.EdgeID).(string)
+
+			return []*machine.Machine{
+				{
+					MachineID: "CNC01",
+					Controllers: []*machine.Controller{
+						{
+							ControllerID: "PLC",
+							Driver:       "modbus",
+							Address:      "192.168.1.100:502",
+							Points:       []*machine.Point{{Name: "spindle_load"}},
+						},
+					},
+				},
+			}, nil
+		},
+		PointValues: func(ctx context.Context, request any) (any, error) {
+			return []*machine.Sample{
+				{PointRef: machine.PointRef{MachineID: "CNC01", ControllerID: "PLC", Point: "spindle_load"}},
+			}, nil
+		},
+		Instruction: func(ctx context.Context, request any) (any, error) {
+			return "Modbus instruction", nil
+		},
+		ListAlarms: func(ctx context.Context, request any) (any, error) {
+			return nil, errs.New(errs.Unavailable, "alarms not available")
+		},
+	}
+
+	handler := DiagnosePointHandler(endpoints)
+
+	var request mcp.GetPromptRequest
+	request.Params.Arguments = map[string]string{
+		"edge_id":    "edge-1",
+		"machine_id": "CNC01",
+		"point":      "spindle_load",
+	}
+
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	assert.Equal("edge-1", edgeID)
+
+	uris := make([]string, 0)
+	for _, msg := range result.Messages {
+		if res, ok := msg.Content.(mcp.EmbeddedResource); ok {
+			uris = append(uris, res.Resource.(mcp.TextResourceContents).URI)
+		}
+	}
+
+	assert.Equal([]string{
+		"iiot://edges/edge-1/machines/CNC01",
+		"iiot://edges/edge-1/machines/CNC01/points/spindle_load",
+		"iiot://edges/edge-1/drivers/modbus/instruction",
+	}, uris)
+
+	// Unavailable context is noted, not fatal.
+	last := result.Messages[len(result.Messages)-1].Content.(mcp.TextContent)
+	assert.Contains(last.Text, "alarms not available")
+
+	// Unknown points fail the prompt.
+	request.Params.Arguments["point"] = "unknown"
+
+	_, err = handler(context.Background(), request)
+	assert.Equal(errs.NotFound, errs.CodeOf(err))
+}
+
+func TestWithoutEdge(t *testing.T) {
+	assert := assert.New(t)
+
+	prompt := WithoutEdge(ShiftSummaryPrompt())
+	assert.Len(prompt.Arguments, 1)
+	assert.Equal("machine_id", prompt.Arguments[0].Name)
+}
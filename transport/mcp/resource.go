@@ -0,0 +1,457 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+
+	"github.com/flarexio/core/model"
+
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/machine"
+)
+
+// Resources are addressed by URIs under iiot://edges/{edge_id} on the cloud
+// (e.g., iiot://edges/{edge_id}/machines/{id}/points/{point}), and directly
+// under iiot:// on the edge itself (e.g., iiot://machines/{id}).
+const resourceScheme = "iiot://"
+
+func resourcePrefix(edgeID string) string {
+	if edgeID == "" {
+		return resourceScheme
+	}
+
+	return resourceScheme + "edges/" + url.PathEscape(edgeID) + "/"
+}
+
+// MachineURI returns the URI of the machine resource, on the edge itself
+// when the edge ID is empty.
+func MachineURI(edgeID string, id machine.MachineID) string {
+	return resourcePrefix(edgeID) + "machines/" + url.PathEscape(string(id))
+}
+
+// PointsURI returns the URI of the live point values of the machine.
+func PointsURI(edgeID string, id machine.MachineID) string {
+	return MachineURI(edgeID, id) + "/points"
+}
+
+// PointURI returns the URI of the live value of the point.
+func PointURI(edgeID string, ref machine.PointRef) string {
+	return PointsURI(edgeID, ref.MachineID) + "/" + url.PathEscape(ref.Point)
+}
+
+// SchemaURI returns the URI of the configuration schema of the driver.
+func SchemaURI(edgeID string, driver string) string {
+	return resourcePrefix(edgeID) + "drivers/" + url.PathEscape(driver) + "/schema"
+}
+
+// InstructionURI returns the URI of the instruction of the driver.
+func InstructionURI(edgeID string, driver string) string {
+	return resourcePrefix(edgeID) + "drivers/" + url.PathEscape(driver) + "/instruction"
+}
+
+// AddResources adds the driver, machine and point resources of the IIoT
+// service to the server. The resources of a service local to the edge are
+// not scoped by edge.
+func AddResources(s *server.MCPServer, endpoints iiot.EndpointSet, local bool) {
+	prefix := resourceScheme + "edges/{edge_id}/"
+	if local {
+		prefix = resourceScheme
+	}
+
+	resources := []struct {
+		uri     string
+		name    string
+		desc    string
+		mime    string
+		handler server.ResourceTemplateHandlerFunc
+	}{
+		{
+			uri:     prefix + "machines",
+			name:    "Machines",
+			desc:    "The machines of the edge with their controllers, points and current status.",
+			mime:    "application/json",
+			handler: MachinesResourceHandler(endpoints.ListMachines),
+		},
+		{
+			uri:     prefix + "machines/{id}",
+			name:    "Machine",
+			desc:    "The definition and current status of a machine. Updated when its status changes.",
+			mime:    "application/json",
+			handler: MachineResourceHandler(endpoints.ListMachines),
+		},
+		{
+			uri:     prefix + "machines/{id}/points",
+			name:    "Point values",
+			desc:    "The live value of every point of a machine. Updated when a point is sampled.",
+			mime:    "application/json",
+			handler: PointValuesResourceHandler(endpoints.PointValues),
+		},
+		{
+			uri:     prefix + "machines/{id}/points/{point}",
+			name:    "Point value",
+			desc:    "The live value of a point of a machine, by point name. Updated when the point is sampled.",
+			mime:    "application/json",
+			handler: PointValueResourceHandler(endpoints.PointValues),
+		},
+		{
+			uri:     prefix + "drivers/{driver}/schema",
+			name:    "Driver schema",
+			desc:    "The JSON schema of the configuration of a driver.",
+			mime:    "application/schema+json",
+			handler: SchemaResourceHandler(endpoints.Schema),
+		},
+		{
+			uri:     prefix + "drivers/{driver}/instruction",
+			name:    "Driver instruction",
+			desc:    "How to configure and use a driver.",
+			mime:    "text/markdown",
+			handler: InstructionResourceHandler(endpoints.Instruction),
+		},
+	}
+
+	for _, r := range resources {
+		if !strings.Contains(r.uri, "{") {
+			resource := mcp.NewResource(r.uri, r.name,
+				mcp.WithResourceDescription(r.desc),
+				mcp.WithMIMEType(r.mime),
+			)
+
+			s.AddResource(resource, server.ResourceHandlerFunc(r.handler))
+			continue
+		}
+
+		template := mcp.NewResourceTemplate(r.uri, r.name,
+			mcp.WithTemplateDescription(r.desc),
+			mcp.WithTemplateMIMEType(r.mime),
+		)
+
+		s.AddResourceTemplate(template, r.handler)
+	}
+}
+
+// resourceArgument returns the value of a variable of the URI template.
+func resourceArgument(request mcp.ReadResourceRequest, name string) string {
+	switch v := request.Params.Arguments[name].(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+
+	return ""
+}
+
+// resourceContext scopes the request to the edge of the resource, if any.
+func resourceContext(ctx context.Context, request mcp.ReadResourceRequest) context.Context {
+	if edgeID := resourceArgument(request, "edge_id"); edgeID != "" {
+		ctx = context.WithValue(ctx, model.EdgeID, edgeID)
+	}
+
+	return ctx
+}
+
+func jsonResource(request mcp.ReadResourceRequest, mimeType string, v any) ([]mcp.ResourceContents, error) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return nil, errs.Wrap(errs.Internal, err)
+	}
+
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      request.Params.URI,
+			MIMEType: mimeType,
+			Text:     string(bs),
+		},
+	}, nil
+}
+
+func MachinesResourceHandler(endpoint endpoint.Endpoint) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		resp, err := endpoint(resourceContext(ctx, request), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		machines, ok := resp.([]*machine.Machine)
+		if !ok {
+			return nil, errs.New(errs.Internal, "invalid response type")
+		}
+
+		return jsonResource(request, "application/json", &machines)
+	}
+}
+
+func MachineResourceHandler(endpoint endpoint.Endpoint) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		id := machine.MachineID(resourceArgument(request, "id"))
+		if id == "" {
+			return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+		}
+
+		resp, err := endpoint(resourceContext(ctx, request), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		machines, ok := resp.([]*machine.Machine)
+		if !ok {
+			return nil, errs.New(errs.Internal, "invalid response type")
+		}
+
+		for _, m := range machines {
+			if m.MachineID == id {
+				return jsonResource(request, "application/json", m)
+			}
+		}
+
+		return nil, machine.ErrMachineNotFound
+	}
+}
+
+func PointValuesResourceHandler(endpoint endpoint.Endpoint) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		id := machine.MachineID(resourceArgument(request, "id"))
+		if id == "" {
+			return nil, errs.New(errs.InvalidArgument, "id parameter is required")
+		}
+
+		resp, err := endpoint(resourceContext(ctx, request), id)
+		if err != nil {
+			return nil, err
+		}
+
+		samples, ok := resp.([]*machine.Sample)
+		if !ok {
+			return nil, errs.New(errs.Internal, "invalid response type")
+		}
+
+		return jsonResource(request, "application/json", &samples)
+	}
+}
+
+// PointValueResourceHandler returns the value of the first point with the
+// name, in configuration order.
+func PointValueResourceHandler(endpoint endpoint.Endpoint) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		id := machine.MachineID(resourceArgument(request, "id"))
+		point := resourceArgument(request, "point")
+		if id == "" || point == "" {
+			return nil, errs.New(errs.InvalidArgument, "id and point parameters are required")
+		}
+
+		resp, err := endpoint(resourceContext(ctx, request), id)
+		if err != nil {
+			return nil, err
+		}
+
+		samples, ok := resp.([]*machine.Sample)
+		if !ok {
+			return nil, errs.New(errs.Internal, "invalid response type")
+		}
+
+		for _, sample := range samples {
+			if sample.Point == point {
+				return jsonResource(request, "application/json", sample)
+			}
+		}
+
+		return nil, machine.ErrPointNotFound
+	}
+}
+
+func SchemaResourceHandler(endpoint endpoint.Endpoint) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		driver := resourceArgument(request, "driver")
+		if driver == "" {
+			return nil, errs.New(errs.InvalidArgument, "driver parameter is required")
+		}
+
+		resp, err := endpoint(resourceContext(ctx, request), driver)
+		if err != nil {
+			return nil, err
+		}
+
+		schema, ok := resp.(json.RawMessage)
+		if !ok {
+			return nil, errs.New(errs.Internal, "invalid response type")
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "application/schema+json",
+				Text:     string(schema),
+			},
+		}, nil
+	}
+}
+
+func InstructionResourceHandler(endpoint endpoint.Endpoint) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		driver := resourceArgument(request, "driver")
+		if driver == "" {
+			return nil, errs.New(errs.InvalidArgument, "driver parameter is required")
+		}
+
+		resp, err := endpoint(resourceContext(ctx, request), driver)
+		if err != nil {
+			return nil, err
+		}
+
+		instruction, ok := resp.(string)
+		if !ok {
+			return nil, errs.New(errs.Internal, "invalid response type")
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      request.Params.URI,
+				MIMEType: "text/markdown",
+				Text:     instruction,
+			},
+		}, nil
+	}
+}
+
+// Subscriptions notifies sessions of the updates of the resources they
+// subscribed to. mcp-go does not route resources/subscribe yet, so reading
+// a resource subscribes the session to it until the session ends.
+type Subscriptions struct {
+	server   *server.MCPServer
+	sessions map[string]map[string]struct{} // session ID -> URIs
+	sync.Mutex
+}
+
+func NewSubscriptions() *Subscriptions {
+	return &Subscriptions{
+		sessions: make(map[string]map[string]struct{}),
+	}
+}
+
+// Register adds the hooks tracking the subscriptions of the sessions. Pass
+// the hooks to the server with server.WithHooks.
+func (subs *Subscriptions) Register(hooks *server.Hooks) {
+	hooks.AddAfterReadResource(func(ctx context.Context, id any, message *mcp.ReadResourceRequest, result *mcp.ReadResourceResult) {
+		session := server.ClientSessionFromContext(ctx)
+		if session == nil {
+			return
+		}
+
+		subs.Subscribe(server.ServerFromContext(ctx), session.SessionID(), message.Params.URI)
+	})
+
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		subs.Unsubscribe(session.SessionID(), "")
+	})
+}
+
+// Subscribe subscribes the session to the updates of the resource.
+func (subs *Subscriptions) Subscribe(s *server.MCPServer, sessionID string, uri string) {
+	subs.Lock()
+	defer subs.Unlock()
+
+	if s != nil {
+		subs.server = s
+	}
+
+	uris, ok := subs.sessions[sessionID]
+	if !ok {
+		uris = make(map[string]struct{})
+		subs.sessions[sessionID] = uris
+	}
+
+	uris[uri] = struct{}{}
+}
+
+// Unsubscribe unsubscribes the session from the resource, or from every
+// resource when the URI is empty.
+func (subs *Subscriptions) Unsubscribe(sessionID string, uri string) {
+	subs.Lock()
+	defer subs.Unlock()
+
+	if uri == "" {
+		delete(subs.sessions, sessionID)
+		return
+	}
+
+	delete(subs.sessions[sessionID], uri)
+}
+
+// Subscribers returns the sessions subscribed to the resource.
+func (subs *Subscriptions) Subscribers(uri string) []string {
+	subs.Lock()
+	defer subs.Unlock()
+
+	sessions := make([]string, 0)
+	for sessionID, uris := range subs.sessions {
+		if _, ok := uris[uri]; ok {
+			sessions = append(sessions, sessionID)
+		}
+	}
+
+	return sessions
+}
+
+// Notify sends notifications/resources/updated to the sessions subscribed
+// to the resources. Sessions that are gone are unsubscribed.
+func (subs *Subscriptions) Notify(uris ...string) {
+	subs.Lock()
+	s := subs.server
+	subs.Unlock()
+
+	if s == nil {
+		return
+	}
+
+	for _, uri := range uris {
+		for _, sessionID := range subs.Subscribers(uri) {
+			params := map[string]any{"uri": uri}
+
+			err := s.SendNotificationToSpecificClient(sessionID, mcp.MethodNotificationResourceUpdated, params)
+			if err == nil {
+				continue
+			}
+
+			if errors.Is(err, server.ErrSessionNotFound) {
+				subs.Unsubscribe(sessionID, "")
+				continue
+			}
+
+			zap.L().Debug("resource update dropped",
+				zap.Error(err),
+				zap.String("session_id", sessionID),
+				zap.String("uri", uri),
+			)
+		}
+	}
+}
+
+// NotifySample notifies the subscribers of the point and of the points of
+// its machine.
+func (subs *Subscriptions) NotifySample(edgeID string, sample *machine.Sample) {
+	subs.Notify(
+		PointURI(edgeID, sample.PointRef),
+		PointsURI(edgeID, sample.MachineID),
+	)
+}
+
+// NotifyStatus notifies the subscribers of the machine and of the machines
+// of its edge.
+func (subs *Subscriptions) NotifyStatus(edgeID string, event *machine.StatusEvent) {
+	subs.Notify(
+		MachineURI(edgeID, event.MachineID),
+		resourcePrefix(edgeID)+"machines",
+	)
+}
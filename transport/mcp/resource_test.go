@@ -0,0 +1,126 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/core/model"
+
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/machine"
+)
+
+func readResource(s *server.MCPServer, ctx context.Context, uri string) mcp.JSONRPCMessage {
+	req := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "resources/read",
+		"params":  map[string]any{"uri": uri},
+	}
+
+	data, _ := json.Marshal(req)
+	return s.HandleMessage(ctx, data)
+}
+
+type testSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func (s *testSession) Initialize()       {}
+func (s *testSession) Initialized() bool { return true }
+func (s *testSession) SessionID() string { return s.id }
+
+func (s *testSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+func TestResources(t *testing.T) {
+	assert := assert.New(t)
+
+	var edges []string
+	pointValues := func(ctx context.Context, request any) (any, error) {
+		edgeID, _ := ctx.Value(model.EdgeID).(string)
+		edges = append(edges, edgeID)
+
+		id := request.(machine.MachineID)
+		return []*machine.Sample{
+			{PointRef: machine.PointRef{MachineID: id, ControllerID: "NC", Point: "estop"}},
+			{
+				PointRef: machine.PointRef{MachineID: id, ControllerID: "NC", Point: "spindle load"},
+				Value:    &machine.Value{Type: machine.FLOAT, Value: 12.5},
+			},
+		}, nil
+	}
+
+	subs := NewSubscriptions()
+
+	hooks := new(server.Hooks)
+	subs.Register(hooks)
+
+	s := server.NewMCPServer("test", "1.0.0",
+		server.WithResourceCapabilities(false, false),
+		server.WithHooks(hooks),
+	)
+
+	AddResources(s, iiot.EndpointSet{PointValues: pointValues}, false)
+
+	session := &testSession{"session-1", make(chan mcp.JSONRPCNotification, 1)}
+	if err := s.RegisterSession(context.Background(), session); err != nil {
+		assert.Fail(err.Error())
+		return
+	}
+
+	ctx := s.WithContext(context.Background(), session)
+
+	uri := PointURI("edge-1", machine.PointRef{MachineID: "CNC01", Point: "spindle load"})
+	assert.Equal("iiot://edges/edge-1/machines/CNC01/points/spindle%20load", uri)
+
+	resp, ok := readResource(s, ctx, uri).(mcp.JSONRPCResponse)
+	if !assert.True(ok) {
+		return
+	}
+
+	result := resp.Result.(mcp.ReadResourceResult)
+	content := result.Contents[0].(mcp.TextResourceContents)
+
+	var sample *machine.Sample
+	json.Unmarshal([]byte(content.Text), &sample)
+
+	assert.Equal("spindle load", sample.Point)
+	assert.Equal(12.5, sample.Value.Value)
+	assert.Equal([]string{"edge-1"}, edges)
+
+	// The points of the machine are not mistaken for one of its points.
+	resp, ok = readResource(s, ctx, PointsURI("edge-1", "CNC01")).(mcp.JSONRPCResponse)
+	if assert.True(ok) {
+		result := resp.Result.(mcp.ReadResourceResult)
+		content := result.Contents[0].(mcp.TextResourceContents)
+
+		var samples []*machine.Sample
+		json.Unmarshal([]byte(content.Text), &samples)
+		assert.Len(samples, 2)
+	}
+
+	// Reading a resource subscribes the session to it.
+	assert.Equal([]string{"session-1"}, subs.Subscribers(uri))
+
+	subs.NotifySample("edge-1", &machine.Sample{
+		PointRef: machine.PointRef{MachineID: "CNC01", ControllerID: "NC", Point: "spindle load"},
+	})
+
+	notification := <-session.notifications
+	assert.Equal(mcp.MethodNotificationResourceUpdated, notification.Method)
+	assert.Equal(uri, notification.Params.AdditionalFields["uri"])
+
+	_, ok = readResource(s, ctx, PointURI("edge-1", machine.PointRef{MachineID: "CNC01", Point: "unknown"})).(mcp.JSONRPCError)
+	assert.True(ok)
+
+	subs.Unsubscribe("session-1", "")
+	assert.Empty(subs.Subscribers(uri))
+}
@@ -0,0 +1,60 @@
+package mcp
+
+import (
+	"slices"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/flarexio/iiot"
+)
+
+// WithoutContext removes the edge context argument of the tool, for tools
+// served by the edge itself.
+func WithoutContext(tool mcp.Tool) mcp.Tool {
+	properties := make(map[string]any, len(tool.InputSchema.Properties))
+	for name, prop := range tool.InputSchema.Properties {
+		if name != "ctx" {
+			properties[name] = prop
+		}
+	}
+
+	tool.InputSchema.Properties = properties
+	tool.InputSchema.Required = slices.DeleteFunc(slices.Clone(tool.InputSchema.Required), func(name string) bool {
+		return name == "ctx"
+	})
+
+	return tool
+}
+
+// AddTools adds the tools of the IIoT service to the server. The tools of
+// a service local to the edge take no edge context.
+func AddTools(s *server.MCPServer, endpoints iiot.EndpointSet, local bool) {
+	tools := []server.ServerTool{
+		{Tool: CheckConnectionTool(), Handler: CheckConnectionHandler(endpoints.CheckConnection)},
+		{Tool: ListDriversTool(), Handler: ListDriversHandler(endpoints.ListDrivers)},
+		{Tool: SchemaTool(), Handler: SchemaHandler(endpoints.Schema)},
+		{Tool: InstructionTool(), Handler: InstructionHandler(endpoints.Instruction)},
+		{Tool: ReadPointsTool(), Handler: ReadPointsHandler(endpoints.ReadPoints)},
+		{Tool: ListMachinesTool(), Handler: ListMachinesHandler(endpoints.ListMachines)},
+		{Tool: MachineStatusTool(), Handler: MachineStatusHandler(endpoints.MachineStatus)},
+		{Tool: PointValuesTool(), Handler: PointValuesHandler(endpoints.PointValues)},
+		{Tool: WritePointsTool(), Handler: WritePointsHandler(endpoints.WritePoints)},
+		{Tool: ConfirmWriteTool(), Handler: ConfirmWriteHandler(endpoints.WritePoints)},
+		{Tool: MachineStatusHistoryTool(), Handler: MachineStatusHistoryHandler(endpoints.MachineStatusHistory)},
+		{Tool: ListAlarmsTool(), Handler: ListAlarmsHandler(endpoints.ListAlarms)},
+		{Tool: AcknowledgeAlarmTool(), Handler: AcknowledgeAlarmHandler(endpoints.AcknowledgeAlarm)},
+		{Tool: ShelveAlarmTool(), Handler: ShelveAlarmHandler(endpoints.ShelveAlarm)},
+		{Tool: ProductionReportTool(), Handler: ProductionReportHandler(endpoints.ProductionReport)},
+		{Tool: ProductionHistoryTool(), Handler: ProductionHistoryHandler(endpoints.ProductionHistory)},
+		{Tool: ReadHistoryTool(), Handler: ReadHistoryHandler(endpoints.ReadHistory)},
+	}
+
+	for _, t := range tools {
+		if local {
+			t.Tool = WithoutContext(t.Tool)
+		}
+
+		s.AddTool(t.Tool, t.Handler)
+	}
+}
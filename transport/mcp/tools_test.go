@@ -0,0 +1,22 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithoutContext(t *testing.T) {
+	assert := assert.New(t)
+
+	tool := ReadPointsTool()
+	assert.Contains(tool.InputSchema.Properties, "ctx")
+
+	local := WithoutContext(tool)
+	assert.NotContains(local.InputSchema.Properties, "ctx")
+	assert.Contains(local.InputSchema.Properties, "driver")
+	assert.ElementsMatch([]string{"driver", "raw"}, local.InputSchema.Required)
+
+	// The original tool is unchanged.
+	assert.Contains(tool.InputSchema.Properties, "ctx")
+}
@@ -3,13 +3,19 @@ package mcp
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"time"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/production"
 )
 
 func CheckConnectionTool(name ...string) mcp.Tool {
@@ -39,11 +45,11 @@ func CheckConnectionHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		var req iiot.CheckConnectionRequest
 		if err := request.BindArguments(&req); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
 		}
 
 		if _, err := endpoint(ctx, req); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(err), nil
 		}
 
 		return mcp.NewToolResultText("Connection successful"), nil
@@ -57,7 +63,7 @@ func ListDriversTool(name ...string) mcp.Tool {
 	}
 
 	return mcp.NewTool(toolName,
-		mcp.WithDescription("List all available drivers for IIoT protocols."),
+		mcp.WithDescription("List all available drivers for IIoT protocols, with their version, vendor, protocols and supported operations (read, write, browse, probe, subscribe). Drivers the edge refuses, e.g. built for another API version, carry the reason in error."),
 		WithContext("ctx", "Context for the request",
 			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
 		),
@@ -68,18 +74,18 @@ func ListDriversHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		resp, err := endpoint(ctx, nil)
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(err), nil
 		}
 
-		drivers, ok := resp.([]string)
+		drivers, ok := resp.([]*tool.Descriptor)
 		if !ok {
-			err = errors.New("invalid response type")
-			return mcp.NewToolResultError(err.Error()), nil
+			err = errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
 		}
 
 		bs, err := json.Marshal(&drivers)
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
 		}
 
 		return mcp.NewToolResultText(string(bs)), nil
@@ -108,18 +114,18 @@ func SchemaHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		driver, err := request.RequireString("driver")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
 		}
 
 		resp, err := endpoint(ctx, driver)
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(err), nil
 		}
 
 		schema, ok := resp.(json.RawMessage)
 		if !ok {
-			err := errors.New("invalid response type")
-			return mcp.NewToolResultError(err.Error()), nil
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
 		}
 
 		return mcp.NewToolResultText(string(schema)), nil
@@ -148,18 +154,18 @@ func InstructionHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		driver, err := request.RequireString("driver")
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
 		}
 
 		resp, err := endpoint(ctx, driver)
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(err), nil
 		}
 
 		instruction, ok := resp.(string)
 		if !ok {
-			err := errors.New("invalid response type")
-			return mcp.NewToolResultError(err.Error()), nil
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
 		}
 
 		return mcp.NewToolResultText(string(instruction)), nil
@@ -192,23 +198,582 @@ func ReadPointsHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		var req iiot.ReadPointsRequest
 		if err := request.BindArguments(&req); err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
 		}
 
 		resp, err := endpoint(ctx, req)
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(err), nil
 		}
 
 		points, ok := resp.([]any)
 		if !ok {
-			err := errors.New("invalid response type")
-			return mcp.NewToolResultError(err.Error()), nil
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
 		}
 
 		bs, err := json.Marshal(&points)
 		if err != nil {
-			return mcp.NewToolResultError(err.Error()), nil
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func ListMachinesTool(name ...string) mcp.Tool {
+	toolName := "ListMachines"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("List the machines of an edge with their controllers, points and current status."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+	)
+}
+
+func ListMachinesHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		resp, err := endpoint(ctx, nil)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		machines, ok := resp.([]*machine.Machine)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(&machines)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func MachineStatusTool(name ...string) mcp.Tool {
+	toolName := "MachineStatus"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Get the current status of a machine (idle, starting, running, paused, stopped, fault, maintenance or emergency_stop) and how long it has been in it."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("machine_id",
+			mcp.Required(),
+			mcp.Description("The ID of the machine"),
+		),
+	)
+}
+
+func MachineStatusHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("machine_id")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		resp, err := endpoint(ctx, machine.MachineID(id))
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		status, ok := resp.(*machine.StatusInfo)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(status)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func PointValuesTool(name ...string) mcp.Tool {
+	toolName := "PointValues"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Get the live value of every point of a machine, as last sampled by the edge. Points not sampled yet have no value."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("machine_id",
+			mcp.Required(),
+			mcp.Description("The ID of the machine"),
+		),
+	)
+}
+
+func PointValuesHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("machine_id")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		resp, err := endpoint(ctx, machine.MachineID(id))
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		samples, ok := resp.([]*machine.Sample)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(&samples)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func MachineStatusHistoryTool(name ...string) mcp.Tool {
+	toolName := "MachineStatusHistory"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Get the status changes of a machine, including how long it stayed in each status."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("machine_id",
+			mcp.Required(),
+			mcp.Description("The ID of the machine"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only return changes at or after this time (RFC 3339, e.g., 2025-01-01T08:00:00Z)"),
+		),
+	)
+}
+
+func MachineStatusHistoryHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("machine_id")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		req := iiot.MachineStatusHistoryRequest{
+			MachineID: machine.MachineID(id),
+		}
+
+		if since := request.GetString("since", ""); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+			}
+
+			req.Since = t
+		}
+
+		resp, err := endpoint(ctx, req)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		events, ok := resp.([]*machine.StatusEvent)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(&events)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func ListAlarmsTool(name ...string) mcp.Tool {
+	toolName := "ListAlarms"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("List the current alarms of an edge, optionally filtered by machine, state or severity."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("machine_id",
+			mcp.Description("Only list alarms of this machine"),
+		),
+		mcp.WithString("state",
+			mcp.Description("Only list alarms in this state"),
+			mcp.Enum("active", "acknowledged", "cleared", "shelved"),
+		),
+		mcp.WithString("severity",
+			mcp.Description("Only list alarms of this severity"),
+			mcp.Enum("low", "medium", "high", "critical"),
+		),
+	)
+}
+
+func ListAlarmsHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var filter alarm.Filter
+		if err := request.BindArguments(&filter); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		resp, err := endpoint(ctx, filter)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		alarms, ok := resp.([]*alarm.Alarm)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(&alarms)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func AcknowledgeAlarmTool(name ...string) mcp.Tool {
+	toolName := "AcknowledgeAlarm"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Acknowledge an alarm. The user and comment are recorded with the alarm."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("The ID of the alarm to acknowledge"),
+		),
+		mcp.WithString("user",
+			mcp.Required(),
+			mcp.Description("The user acknowledging the alarm"),
+		),
+		mcp.WithString("comment",
+			mcp.Description("A comment about the acknowledgement"),
+		),
+	)
+}
+
+func AcknowledgeAlarmHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req iiot.AcknowledgeAlarmRequest
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		resp, err := endpoint(ctx, req)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		a, ok := resp.(*alarm.Alarm)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(a)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func ShelveAlarmTool(name ...string) mcp.Tool {
+	toolName := "ShelveAlarm"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Shelve an alarm to suppress it temporarily, e.g. while a sensor is under repair."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("The ID of the alarm to shelve"),
+		),
+		mcp.WithString("user",
+			mcp.Required(),
+			mcp.Description("The user shelving the alarm"),
+		),
+		mcp.WithString("comment",
+			mcp.Required(),
+			mcp.Description("The reason for shelving the alarm"),
+		),
+		mcp.WithString("duration",
+			mcp.Required(),
+			mcp.Description("How long the alarm stays shelved (e.g., 30m, 8h)"),
+		),
+	)
+}
+
+func ShelveAlarmHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req iiot.ShelveAlarmRequest
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		resp, err := endpoint(ctx, req)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		a, ok := resp.(*alarm.Alarm)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(a)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func ReadHistoryTool(name ...string) mcp.Tool {
+	toolName := "ReadHistory"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Read the stored history of a point over a time range, as raw values, values interpolated at an interval, or aggregates per interval. Large ranges are paged; pass the returned next_page_token to read the next page, or set all_pages to read the pages in a single result."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("point",
+			mcp.Required(),
+			mcp.Description("The point to read, as machine_id/controller_id/point"),
+		),
+		mcp.WithString("start",
+			mcp.Required(),
+			mcp.Description("The start of the time range in RFC3339 format (inclusive)"),
+		),
+		mcp.WithString("end",
+			mcp.Required(),
+			mcp.Description("The end of the time range in RFC3339 format (exclusive)"),
+		),
+		mcp.WithString("mode",
+			mcp.Required(),
+			mcp.Description("The read mode"),
+			mcp.Enum("raw", "interpolated", "aggregate"),
+		),
+		mcp.WithString("interval",
+			mcp.Description("The interval of interpolated or aggregated values (e.g., 1m, 1h)"),
+		),
+		mcp.WithArray("aggregates",
+			mcp.Description("The aggregates to compute in aggregate mode"),
+			mcp.Items(map[string]any{
+				"type": "string",
+				"enum": []string{"min", "max", "avg", "count", "twa", "first", "last"},
+			}),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("The maximum number of values per page (default 1000)"),
+		),
+		mcp.WithString("page_token",
+			mcp.Description("The token of the page to read, from a previous result"),
+		),
+		mcp.WithBoolean("all_pages",
+			mcp.Description("Read the following pages into the same result, up to 10000 values; the returned next_page_token resumes after them"),
+		),
+	)
+}
+
+func ReadHistoryHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req struct {
+			Point      string                `json:"point"`
+			Start      time.Time             `json:"start"`
+			End        time.Time             `json:"end"`
+			Mode       historian.ReadMode    `json:"mode"`
+			Interval   machine.Duration      `json:"interval"`
+			Aggregates []historian.Aggregate `json:"aggregates"`
+			Limit      int                   `json:"limit"`
+			PageToken  string                `json:"page_token"`
+			AllPages   bool                  `json:"all_pages"`
+		}
+
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		ref, err := machine.ParsePointRef(req.Point)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		query := historian.Query{
+			Point:      ref,
+			Start:      req.Start,
+			End:        req.End,
+			Mode:       req.Mode,
+			Interval:   req.Interval,
+			Aggregates: req.Aggregates,
+			Limit:      req.Limit,
+			PageToken:  req.PageToken,
+			AllPages:   req.AllPages,
+		}
+
+		resp, err := endpoint(ctx, query)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		result, ok := resp.(*historian.Result)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(result)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func ProductionReportTool(name ...string) mcp.Tool {
+	toolName := "ProductionReport"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Get the production of a machine over its current shift: part counts, cycle times, planned and unplanned downtime, and availability, performance, quality and OEE."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("machine_id",
+			mcp.Required(),
+			mcp.Description("The ID of the machine"),
+		),
+	)
+}
+
+func ProductionReportHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("machine_id")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		resp, err := endpoint(ctx, machine.MachineID(id))
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		report, ok := resp.(*production.Report)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(report)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+		}
+
+		return mcp.NewToolResultText(string(bs)), nil
+	}
+}
+
+func ProductionHistoryTool(name ...string) mcp.Tool {
+	toolName := "ProductionHistory"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Get the final production reports of the ended shifts of a machine, including OEE."),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("machine_id",
+			mcp.Required(),
+			mcp.Description("The ID of the machine"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Only return shifts ending at or after this time (RFC 3339, e.g., 2025-01-01T08:00:00Z)"),
+		),
+	)
+}
+
+func ProductionHistoryHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := request.RequireString("machine_id")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		req := iiot.ProductionHistoryRequest{
+			MachineID: machine.MachineID(id),
+		}
+
+		if since := request.GetString("since", ""); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+			}
+
+			req.Since = t
+		}
+
+		resp, err := endpoint(ctx, req)
+		if err != nil {
+			return NewToolResultError(err), nil
+		}
+
+		reports, ok := resp.([]*production.Report)
+		if !ok {
+			err := errs.New(errs.Internal, "invalid response type")
+			return NewToolResultError(err), nil
+		}
+
+		bs, err := json.Marshal(&reports)
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
 		}
 
 		return mcp.NewToolResultText(string(bs)), nil
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/safety"
+)
+
+// Writes are proposed by WritePoints and written by ConfirmWrite with the
+// token of the proposal. MCP elicitation would let the server ask the user
+// itself, but mcp-go does not support it yet: instead, the write policy has
+// the proposal confirmed by another caller than its proposer, e.g., the
+// operator to whom the agent shows the proposal, and refuses the callers
+// without identity, such as over stdio. Over NATS, the caller is the one
+// the client asserts, not an authenticated identity.
+
+func WritePointsTool(name ...string) mcp.Tool {
+	toolName := "WritePoints"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Propose writing values to the points of machines. Nothing is written: the writes are checked against the write policy of the edge (allowed points, value limits, rate limits) and returned with the current values and a confirmation token. Show the proposal to the user and call ConfirmWrite with the token only once they approve it. With dry_run, only check the writes."),
+		mcp.WithDestructiveHintAnnotation(false),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithArray("writes",
+			mcp.Required(),
+			mcp.Description("The values to write"),
+			mcp.Items(map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"machine_id":    map[string]any{"type": "string"},
+					"controller_id": map[string]any{"type": "string"},
+					"point":         map[string]any{"type": "string"},
+					"value":         map[string]any{"description": "The value, of the type of the point"},
+				},
+				"required": []string{"machine_id", "controller_id", "point", "value"},
+			}),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Only check the writes, without proposing them"),
+		),
+	)
+}
+
+func WritePointsHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		var req safety.Request
+		if err := request.BindArguments(&req); err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		// Confirmation goes through ConfirmWrite only.
+		req.Token = ""
+
+		return writeResult(endpoint(ctx, req))
+	}
+}
+
+func ConfirmWriteTool(name ...string) mcp.Tool {
+	toolName := "ConfirmWrite"
+	if len(name) > 0 {
+		toolName = name[0]
+	}
+
+	return mcp.NewTool(toolName,
+		mcp.WithDescription("Write the values proposed by WritePoints to the devices. Call it only after the user has explicitly approved the proposal. Unless the write policy lets the proposer confirm, the proposal must be confirmed by another caller than the one that proposed it, such as the user approving it. Each token confirms its proposal once, before it expires."),
+		mcp.WithDestructiveHintAnnotation(true),
+		WithContext("ctx", "Context for the request",
+			NewProperty("edge_id", "string", mcp.Description("The edge ID for the request")),
+		),
+		mcp.WithString("token",
+			mcp.Required(),
+			mcp.Description("The confirmation token returned by WritePoints"),
+		),
+	)
+}
+
+func ConfirmWriteHandler(endpoint endpoint.Endpoint) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		token, err := request.RequireString("token")
+		if err != nil {
+			return NewToolResultError(errs.Wrap(errs.InvalidArgument, err)), nil
+		}
+
+		return writeResult(endpoint(ctx, safety.Request{Token: token}))
+	}
+}
+
+// writeResult answers the result of a write request.
+func writeResult(resp any, err error) (*mcp.CallToolResult, error) {
+	if err != nil {
+		return NewToolResultError(err), nil
+	}
+
+	result, ok := resp.(*safety.Result)
+	if !ok {
+		err := errs.New(errs.Internal, "invalid response type")
+		return NewToolResultError(err), nil
+	}
+
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return NewToolResultError(errs.Wrap(errs.Internal, err)), nil
+	}
+
+	return mcp.NewToolResultText(string(bs)), nil
+}
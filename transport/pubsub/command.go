@@ -0,0 +1,320 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/command"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/metadata"
+)
+
+// JetStream resources of the durable command queue: a work queue stream per
+// edge holding its commands, and a key-value bucket holding the results.
+const (
+	CommandStreamPrefix = "IIOT_COMMANDS_"
+	CommandResultBucket = "IIOT_COMMAND_RESULTS"
+	CommandConsumer     = "iiot"
+)
+
+var (
+	// CommandRetention is how long commands wait for their edge, how long
+	// their IDs are deduplicated, and how long their results are kept.
+	CommandRetention = 24 * time.Hour
+
+	// CommandTimeout bounds the run of a command on the edge.
+	CommandTimeout = 30 * time.Second
+
+	// CommandRetryDelay is the delay before a command whose endpoint was
+	// unavailable runs again, up to CommandMaxDeliver times.
+	CommandRetryDelay = 5 * time.Second
+	CommandMaxDeliver = 10
+)
+
+// CommandSubject returns the subject of the commands of an edge to an
+// endpoint (e.g., edges.<edge_id>.commands.alarms.acknowledge).
+func CommandSubject(edgeID string, endpoint string) string {
+	return "edges." + edgeID + ".commands." + endpoint
+}
+
+func commandStream(edgeID string) jetstream.StreamConfig {
+	return jetstream.StreamConfig{
+		Name:       CommandStreamPrefix + edgeID,
+		Subjects:   []string{CommandSubject(edgeID, ">")},
+		Retention:  jetstream.WorkQueuePolicy,
+		MaxAge:     CommandRetention,
+		Duplicates: CommandRetention,
+	}
+}
+
+func commandResults() jetstream.KeyValueConfig {
+	return jetstream.KeyValueConfig{
+		Bucket: CommandResultBucket,
+		TTL:    CommandRetention,
+	}
+}
+
+// NewCommandQueue returns the cloud side of the durable command queue.
+func NewCommandQueue(ctx context.Context, js jetstream.JetStream) (command.Queue, error) {
+	kv, err := js.CreateOrUpdateKeyValue(ctx, commandResults())
+	if err != nil {
+		return nil, err
+	}
+
+	return &commandQueue{js: js, kv: kv}, nil
+}
+
+type commandQueue struct {
+	js      jetstream.JetStream
+	kv      jetstream.KeyValue
+	streams sync.Map // edge ID -> struct{}
+}
+
+func (q *commandQueue) Enqueue(ctx context.Context, cmd *command.Command) (*command.Result, error) {
+	if _, ok := q.streams.Load(cmd.EdgeID); !ok {
+		if _, err := q.js.CreateOrUpdateStream(ctx, commandStream(cmd.EdgeID)); err != nil {
+			return nil, errs.Wrap(errs.Unavailable, err)
+		}
+
+		q.streams.Store(cmd.EdgeID, struct{}{})
+	}
+
+	result := command.NewResult(cmd)
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := q.kv.Create(ctx, cmd.ID, data); err != nil {
+		if errors.Is(err, jetstream.ErrKeyExists) {
+			return q.Result(ctx, cmd.ID)
+		}
+
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(CommandSubject(cmd.EdgeID, cmd.Endpoint))
+	msg.Data = payload
+	msg.Header.Set(jetstream.MsgIDHeader, cmd.ID)
+
+	if _, err := q.js.PublishMsg(ctx, msg); err != nil {
+		// Let the command be submitted again.
+		q.kv.Delete(ctx, cmd.ID)
+
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	return result, nil
+}
+
+func (q *commandQueue) Result(ctx context.Context, id string) (*command.Result, error) {
+	entry, err := q.kv.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, command.ErrCommandNotFound
+		}
+
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	var result *command.Result
+	if err := json.Unmarshal(entry.Value(), &result); err != nil {
+		return nil, errs.Wrap(errs.Internal, err)
+	}
+
+	return result, nil
+}
+
+func (q *commandQueue) Watch(ctx context.Context, id string) (<-chan *command.Result, error) {
+	watcher, err := q.kv.Watch(ctx, id)
+	if err != nil {
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	results := make(chan *command.Result)
+
+	go func() {
+		defer close(results)
+		defer watcher.Stop()
+
+		for {
+			var entry jetstream.KeyValueEntry
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-watcher.Updates():
+				if !ok {
+					return
+				}
+
+				entry = e
+			}
+
+			// A nil entry marks the end of the initial values.
+			if entry == nil || entry.Operation() != jetstream.KeyValuePut {
+				continue
+			}
+
+			var result *command.Result
+			if err := json.Unmarshal(entry.Value(), &result); err != nil {
+				zap.L().Warn("invalid command result", zap.Error(err), zap.String("id", id))
+				continue
+			}
+
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return results, nil
+}
+
+// ConsumeCommands runs the commands queued for the edge with the executor,
+// in order. A command is acknowledged once its result is stored, so one
+// interrupted by a restart runs again; commands that already completed are
+// skipped. Stop the returned context to stop consuming.
+func ConsumeCommands(ctx context.Context, js jetstream.JetStream, edgeID string, executor command.Executor) (jetstream.ConsumeContext, error) {
+	stream, err := js.CreateOrUpdateStream(ctx, commandStream(edgeID))
+	if err != nil {
+		return nil, err
+	}
+
+	kv, err := js.CreateOrUpdateKeyValue(ctx, commandResults())
+	if err != nil {
+		return nil, err
+	}
+
+	consumer, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:    CommandConsumer,
+		AckPolicy:  jetstream.AckExplicitPolicy,
+		AckWait:    CommandTimeout + 10*time.Second,
+		MaxDeliver: CommandMaxDeliver,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return consumer.Consume(func(msg jetstream.Msg) {
+		runCommand(kv, msg, executor)
+	})
+}
+
+func runCommand(kv jetstream.KeyValue, msg jetstream.Msg, executor command.Executor) {
+	log := zap.L().With(zap.String("subject", msg.Subject()))
+
+	var cmd *command.Command
+	if err := json.Unmarshal(msg.Data(), &cmd); err != nil {
+		log.Error("invalid command", zap.Error(err))
+		msg.Term()
+		return
+	}
+
+	log = log.With(zap.String("id", cmd.ID))
+
+	ctx, cancel := context.WithTimeout(context.Background(), CommandTimeout)
+	defer cancel()
+
+	result := command.NewResult(cmd)
+	if entry, err := kv.Get(ctx, cmd.ID); err == nil {
+		if err := json.Unmarshal(entry.Value(), &result); err == nil && result.Status.Done() {
+			msg.Ack()
+			return
+		}
+	}
+
+	attempts := 1
+	if meta, err := msg.Metadata(); err == nil {
+		attempts = int(meta.NumDelivered)
+	}
+
+	result.Attempts = attempts
+
+	now := time.Now()
+	if cmd.Expired(now) {
+		result.Status = command.Expired
+		result.CompletedTime = &now
+
+		storeResult(ctx, kv, result, msg, log)
+		return
+	}
+
+	if cmd.TraceID != "" {
+		ctx = metadata.WithTraceID(ctx, cmd.TraceID)
+	}
+
+	resp, err := executor(ctx, cmd)
+	if err != nil && errs.CodeOf(err) == errs.Unavailable && attempts < CommandMaxDeliver {
+		// The endpoint is not ready yet, e.g. right after a restart.
+		result.Error = errs.From(err)
+
+		data, _ := json.Marshal(result)
+		kv.Put(ctx, cmd.ID, data)
+
+		msg.NakWithDelay(CommandRetryDelay)
+		return
+	}
+
+	result.Complete(resp, err, time.Now())
+
+	storeResult(ctx, kv, result, msg, log)
+}
+
+func storeResult(ctx context.Context, kv jetstream.KeyValue, result *command.Result, msg jetstream.Msg, log *zap.Logger) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err.Error())
+		msg.Term()
+		return
+	}
+
+	if _, err := kv.Put(context.WithoutCancel(ctx), result.ID, data); err != nil {
+		log.Error("failed to store command result", zap.Error(err))
+		msg.NakWithDelay(CommandRetryDelay)
+		return
+	}
+
+	msg.Ack()
+}
+
+// CommandExecutor runs commands as requests to the endpoints of the local
+// service under the topic (e.g., edges.<edge_id>.iiot).
+func CommandExecutor(nc *nats.Conn, topic string) command.Executor {
+	return func(ctx context.Context, cmd *command.Command) (json.RawMessage, error) {
+		msg, err := Request(ctx, nc, topic+"."+cmd.Endpoint, cmd.Payload, CommandTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		if len(msg.Data) == 0 {
+			return nil, nil
+		}
+
+		if json.Valid(msg.Data) {
+			return msg.Data, nil
+		}
+
+		// Some endpoints reply with plain text.
+		return json.Marshal(string(msg.Data))
+	}
+}
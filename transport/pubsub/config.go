@@ -0,0 +1,193 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/config"
+	"github.com/flarexio/iiot/errs"
+)
+
+// ConfigBucket is the key-value bucket holding the configuration of every
+// edge: the documents pushed to it under <edge_id>.desired, and its last
+// report under <edge_id>.reported.
+const ConfigBucket = "IIOT_CONFIG"
+
+// ConfigHistory is the number of documents kept per edge, which bounds how
+// far back a rollback can go.
+var ConfigHistory uint8 = 16
+
+func configBucket() jetstream.KeyValueConfig {
+	return jetstream.KeyValueConfig{
+		Bucket:  ConfigBucket,
+		History: ConfigHistory,
+	}
+}
+
+func desiredKey(edgeID string) string {
+	return edgeID + ".desired"
+}
+
+func reportedKey(edgeID string) string {
+	return edgeID + ".reported"
+}
+
+// NewConfigStore returns the cloud side of the configuration distribution.
+func NewConfigStore(ctx context.Context, js jetstream.JetStream) (config.Store, error) {
+	kv, err := js.CreateOrUpdateKeyValue(ctx, configBucket())
+	if err != nil {
+		return nil, err
+	}
+
+	return &configStore{kv}, nil
+}
+
+type configStore struct {
+	kv jetstream.KeyValue
+}
+
+func (s *configStore) Documents(ctx context.Context, edgeID string) ([]*config.Document, error) {
+	entries, err := s.kv.History(ctx, desiredKey(edgeID))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return make([]*config.Document, 0), nil
+		}
+
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	docs := make([]*config.Document, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Operation() != jetstream.KeyValuePut {
+			continue
+		}
+
+		var doc *config.Document
+		if err := json.Unmarshal(entry.Value(), &doc); err != nil {
+			return nil, errs.Wrap(errs.Internal, err)
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+func (s *configStore) Push(ctx context.Context, edgeID string, doc *config.Document, since int64) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	key := desiredKey(edgeID)
+
+	entry, err := s.kv.Get(ctx, key)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		if since != 0 {
+			return config.ErrVersionConflict
+		}
+
+		_, err = s.kv.Create(ctx, key, data)
+
+	case err != nil:
+		return errs.Wrap(errs.Unavailable, err)
+
+	default:
+		var latest *config.Document
+		if err := json.Unmarshal(entry.Value(), &latest); err != nil {
+			return errs.Wrap(errs.Internal, err)
+		}
+
+		if latest.Version != since {
+			return config.ErrVersionConflict
+		}
+
+		_, err = s.kv.Update(ctx, key, data, entry.Revision())
+	}
+
+	if err != nil {
+		var apiErr *jetstream.APIError
+		if errors.Is(err, jetstream.ErrKeyExists) ||
+			errors.As(err, &apiErr) && apiErr.ErrorCode == jetstream.JSErrCodeStreamWrongLastSequence {
+			return config.ErrVersionConflict
+		}
+
+		return errs.Wrap(errs.Unavailable, err)
+	}
+
+	return nil
+}
+
+func (s *configStore) Report(ctx context.Context, edgeID string) (*config.Report, error) {
+	entry, err := s.kv.Get(ctx, reportedKey(edgeID))
+	if err != nil {
+		if errors.Is(err, jetstream.ErrKeyNotFound) {
+			return nil, config.ErrNoConfig.WithDetail("edge_id", edgeID)
+		}
+
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	var report *config.Report
+	if err := json.Unmarshal(entry.Value(), &report); err != nil {
+		return nil, errs.Wrap(errs.Internal, err)
+	}
+
+	return report, nil
+}
+
+// WatchConfig applies every document pushed to the edge with the agent,
+// including the latest one pushed while the edge was offline, and reports
+// the outcome. Stop the returned watcher to stop applying.
+func WatchConfig(ctx context.Context, js jetstream.JetStream, edgeID string, agent config.Agent) (jetstream.KeyWatcher, error) {
+	kv, err := js.CreateOrUpdateKeyValue(ctx, configBucket())
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := kv.Watch(ctx, desiredKey(edgeID))
+	if err != nil {
+		return nil, err
+	}
+
+	log := zap.L().With(zap.String("edge_id", edgeID))
+
+	go func() {
+		for entry := range watcher.Updates() {
+			// A nil entry marks the end of the initial values.
+			if entry == nil || entry.Operation() != jetstream.KeyValuePut {
+				continue
+			}
+
+			var doc *config.Document
+			if err := json.Unmarshal(entry.Value(), &doc); err != nil {
+				log.Error("invalid configuration", zap.Error(err))
+				continue
+			}
+
+			report := agent.Apply(ctx, doc)
+
+			log.Info("configuration received",
+				zap.Int64("version", doc.Version),
+				zap.String("status", string(report.Status)),
+			)
+
+			data, err := json.Marshal(report)
+			if err != nil {
+				log.Error(err.Error())
+				continue
+			}
+
+			if _, err := kv.Put(ctx, reportedKey(edgeID), data); err != nil {
+				log.Error("failed to report configuration", zap.Error(err))
+			}
+		}
+	}()
+
+	return watcher, nil
+}
@@ -0,0 +1,133 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+
+	"github.com/flarexio/core/model"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/metadata"
+)
+
+// Headers carrying the request context from the client to the handler.
+// The deadline is carried as the time remaining, so that it holds across
+// hosts whose clocks differ.
+const (
+	TimeoutHeader = "Iiot-Timeout"
+	TraceIDHeader = "Iiot-Trace-Id"
+	CallerHeader  = "Iiot-Caller"
+	EdgeIDHeader  = "Iiot-Edge-Id"
+)
+
+// DefaultTimeout bounds requests whose context has no deadline.
+var DefaultTimeout = nats.DefaultTimeout
+
+// Topic resolves the ":edge_id" placeholder of the topic with the edge ID
+// carried by the context.
+func Topic(ctx context.Context, topic string) (string, error) {
+	if !strings.Contains(topic, ":edge_id") {
+		return topic, nil
+	}
+
+	edgeID, ok := ctx.Value(model.EdgeID).(string)
+	if !ok {
+		return "", errors.New("invalid edge id")
+	}
+
+	return strings.Replace(topic, ":edge_id", edgeID, 1), nil
+}
+
+// NewRequestMsg builds a request to the topic, carrying the time remaining
+// before the deadline, the trace ID, caller and edge ID of the context as
+// headers.
+func NewRequestMsg(ctx context.Context, nc *nats.Conn, topic string, data []byte) (*nats.Msg, error) {
+	subject, err := Topic(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+
+	if deadline, ok := ctx.Deadline(); ok {
+		msg.Header.Set(TimeoutHeader, time.Until(deadline).String())
+	}
+
+	traceID := metadata.TraceID(ctx)
+	if traceID == "" {
+		traceID = metadata.NewTraceID()
+	}
+
+	msg.Header.Set(TraceIDHeader, traceID)
+
+	caller := metadata.Caller(ctx)
+	if caller == "" {
+		caller = nc.Opts.Name
+	}
+
+	if caller != "" {
+		msg.Header.Set(CallerHeader, caller)
+	}
+
+	if edgeID, ok := ctx.Value(model.EdgeID).(string); ok {
+		msg.Header.Set(EdgeIDHeader, edgeID)
+	}
+
+	return msg, nil
+}
+
+// Request sends a request carrying the context, and waits for the reply
+// until the context is done. A context without a deadline is bounded by
+// the given timeout.
+func Request(ctx context.Context, nc *nats.Conn, topic string, data []byte, timeout time.Duration) (*nats.Msg, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	msg, err := NewRequestMsg(ctx, nc, topic, data)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := nc.RequestMsgWithContext(ctx, msg)
+	if errors.Is(err, nats.ErrNoResponders) {
+		// Nobody listens on the topic: the edge is offline.
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	return resp, err
+}
+
+// Context rebuilds the context of a request from its headers. The context
+// is canceled once the time remaining to the caller has passed, counted
+// from the reception of the request.
+func Context(r micro.Request) (context.Context, context.CancelFunc) {
+	ctx := context.Background()
+
+	headers := r.Headers()
+
+	if traceID := headers.Get(TraceIDHeader); traceID != "" {
+		ctx = metadata.WithTraceID(ctx, traceID)
+	}
+
+	if caller := headers.Get(CallerHeader); caller != "" {
+		ctx = metadata.WithCaller(ctx, caller)
+	}
+
+	if edgeID := headers.Get(EdgeIDHeader); edgeID != "" {
+		ctx = context.WithValue(ctx, model.EdgeID, edgeID)
+	}
+
+	if timeout, err := time.ParseDuration(headers.Get(TimeoutHeader)); err == nil {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
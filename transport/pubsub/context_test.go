@@ -0,0 +1,187 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/micro"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/core/model"
+	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/metadata"
+)
+
+func TestRequestContext(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := runServer(t)
+
+	type result struct {
+		TraceID  string
+		Caller   string
+		EdgeID   string
+		Deadline bool
+		Timeout  time.Duration
+	}
+
+	results := make(chan result, 1)
+	serve(t, nc, "edge.context", func(r micro.Request) {
+		ctx, cancel := Context(r)
+		defer cancel()
+
+		edgeID, _ := ctx.Value(model.EdgeID).(string)
+		deadline, ok := ctx.Deadline()
+
+		results <- result{
+			TraceID:  metadata.TraceID(ctx),
+			Caller:   metadata.Caller(ctx),
+			EdgeID:   edgeID,
+			Deadline: ok,
+			Timeout:  time.Until(deadline),
+		}
+
+		r.Respond(nil)
+	})
+
+	ctx := metadata.WithTraceID(context.Background(), "trace")
+	ctx = metadata.WithCaller(ctx, "operator")
+	ctx = context.WithValue(ctx, model.EdgeID, "edge")
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := Request(ctx, nc, "edge.context", nil, DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-results
+	assert.Equal("trace", r.TraceID)
+	assert.Equal("operator", r.Caller)
+	assert.Equal("edge", r.EdgeID)
+	assert.True(r.Deadline)
+	assert.InDelta(5*time.Second, r.Timeout, float64(time.Second))
+
+	// Without a deadline, the request is bounded by the timeout given, and
+	// a trace ID is generated.
+	_, err = Request(context.Background(), nc, "edge.context", nil, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r = <-results
+	assert.NotEmpty(r.TraceID)
+	assert.True(r.Deadline)
+	assert.InDelta(2*time.Second, r.Timeout, float64(time.Second))
+}
+
+func TestRequestTopic(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := runServer(t)
+
+	serve(t, nc, "edges.edge.check_connection", func(r micro.Request) {
+		r.Respond([]byte("ok"))
+	})
+
+	ctx := context.WithValue(context.Background(), model.EdgeID, "edge")
+
+	msg, err := Request(ctx, nc, "edges.:edge_id.check_connection", nil, DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal("ok", string(msg.Data))
+
+	// The edge ID is required by the placeholder.
+	_, err = Request(context.Background(), nc, "edges.:edge_id.check_connection", nil, DefaultTimeout)
+	assert.Error(err)
+}
+
+func TestRequestNoResponders(t *testing.T) {
+	nc := runServer(t)
+
+	_, err := Request(context.Background(), nc, "edge.offline", nil, DefaultTimeout)
+	assert.Equal(t, errs.Unavailable, errs.CodeOf(err))
+}
+
+func TestRequestError(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := runServer(t)
+
+	serve(t, nc, "edge.read_points", ReadPointsHandler(func(ctx context.Context, request any) (any, error) {
+		req := request.(iiot.ReadPointsRequest)
+
+		return nil, errs.New(errs.NotFound, "driver not found\nsee the logs").
+			WithDetail("driver", req.Driver)
+	}))
+
+	endpoint := ReadPointsEndpoint(nc, "edge.read_points")
+
+	_, err := endpoint(context.Background(), iiot.ReadPointsRequest{
+		Driver: "modbus",
+		Raw:    json.RawMessage(`{}`),
+	})
+
+	e, ok := err.(*errs.Error)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	assert.Equal(errs.NotFound, e.Code)
+	assert.Equal("driver not found\nsee the logs", e.Message)
+	assert.Equal(map[string]any{"driver": "modbus"}, e.Details)
+
+	// Invalid requests are refused by the handler.
+	msg, err := Request(context.Background(), nc, "edge.read_points", []byte("{"), DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(errs.InvalidArgument, errs.CodeOf(Error(msg)))
+}
+
+func TestRequestErrorWithoutBody(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := runServer(t)
+
+	// Handlers of other services answer only the error headers.
+	serve(t, nc, "edge.legacy", func(r micro.Request) {
+		r.Error("404", "not found", nil)
+	})
+
+	msg, err := Request(context.Background(), nc, "edge.legacy", nil, DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Error(msg)
+	assert.Equal(errs.NotFound, errs.CodeOf(err))
+	assert.EqualError(err, "not found")
+}
+
+func TestRequestSuccess(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := runServer(t)
+
+	serve(t, nc, "edge.read_points", ReadPointsHandler(func(ctx context.Context, request any) (any, error) {
+		return []any{1.0, "on"}, nil
+	}))
+
+	endpoint := ReadPointsEndpoint(nc, "edge.read_points")
+
+	points, err := endpoint(context.Background(), iiot.ReadPointsRequest{
+		Driver: "modbus",
+		Raw:    json.RawMessage(`{}`),
+	})
+
+	assert.NoError(err)
+	assert.Equal([]any{1.0, "on"}, points)
+}
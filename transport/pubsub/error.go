@@ -0,0 +1,28 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go/micro"
+
+	"github.com/flarexio/iiot/errs"
+)
+
+// RespondError replies with the error: the HTTP status of its code as the
+// error code header, its message as the error header, and the error (code,
+// message and details) as JSON, which Error decodes on the client side.
+func RespondError(r micro.Request, err error) {
+	e := errs.From(err)
+
+	data, _ := json.Marshal(e)
+
+	// Header values must fit on a single line.
+	description := strings.ReplaceAll(e.Message, "\n", "; ")
+	if description == "" {
+		description = string(e.Code)
+	}
+
+	r.Error(strconv.Itoa(e.Code.HTTPStatus()), description, data)
+}
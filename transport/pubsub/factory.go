@@ -4,39 +4,51 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"strings"
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/micro"
 
-	"github.com/flarexio/core/model"
 	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/driver/tool"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/production"
+	"github.com/flarexio/iiot/safety"
 )
 
 func MakeEndpoints(nc *nats.Conn, prefix string) *iiot.EndpointSet {
 	return &iiot.EndpointSet{
 		CheckConnection: CheckConnectionEndpoint(nc, prefix+".check_connection"),
 		ListDrivers:     ListDriversEndpoint(nc, prefix+".drivers"),
+		InstallDriver:   InstallDriverEndpoint(nc, prefix+".drivers.install"),
+		UninstallDriver: UninstallDriverEndpoint(nc, prefix+".drivers.uninstall"),
 		Schema:          SchemaEndpoint(nc, prefix+".schema"),
 		Instruction:     InstructionEndpoint(nc, prefix+".instruction"),
 		ReadPoints:      ReadPointsEndpoint(nc, prefix+".read_points"),
+
+		ListMachines:         ListMachinesEndpoint(nc, prefix+".machines"),
+		MachineStatus:        MachineStatusEndpoint(nc, prefix+".machines.status"),
+		MachineStatusHistory: MachineStatusHistoryEndpoint(nc, prefix+".machines.status.history"),
+		PointValues:          PointValuesEndpoint(nc, prefix+".machines.points"),
+		WritePoints:          WritePointsEndpoint(nc, prefix+".machines.points.write"),
+		ProductionReport:     ProductionReportEndpoint(nc, prefix+".machines.production"),
+		ProductionHistory:    ProductionHistoryEndpoint(nc, prefix+".machines.production.history"),
+
+		ListAlarms:       ListAlarmsEndpoint(nc, prefix+".alarms"),
+		AcknowledgeAlarm: AcknowledgeAlarmEndpoint(nc, prefix+".alarms.acknowledge"),
+		ShelveAlarm:      ShelveAlarmEndpoint(nc, prefix+".alarms.shelve"),
+
+		ReadHistory: ReadHistoryEndpoint(nc, prefix+".history.read"),
 	}
 }
 
 func CheckConnectionEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
-		pubTopic := topic
-		if strings.Contains(pubTopic, ":edge_id") {
-			edgeID, ok := ctx.Value(model.EdgeID).(string)
-			if !ok {
-				return nil, errors.New("invalid edge id")
-			}
-
-			pubTopic = strings.Replace(pubTopic, ":edge_id", edgeID, 1)
-		}
-
 		req, ok := request.(iiot.CheckConnectionRequest)
 		if !ok {
 			return nil, errors.New("invalid request")
@@ -47,7 +59,7 @@ func CheckConnectionEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 			return nil, err
 		}
 
-		msg, err := nc.Request(pubTopic, data, 10*time.Second)
+		msg, err := Request(ctx, nc, topic, data, 10*time.Second)
 		if err != nil {
 			return nil, err
 		}
@@ -62,17 +74,54 @@ func CheckConnectionEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 
 func ListDriversEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
-		pubTopic := topic
-		if strings.Contains(pubTopic, ":edge_id") {
-			edgeID, ok := ctx.Value(model.EdgeID).(string)
-			if !ok {
-				return nil, errors.New("invalid edge id")
+		msg, err := Request(ctx, nc, topic, nil, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var drivers []*tool.Descriptor
+		if err := json.Unmarshal(msg.Data, &drivers); err == nil {
+			return drivers, nil
+		}
+
+		// Older edges only list the names of their drivers.
+		var names []string
+		if err := json.Unmarshal(msg.Data, &names); err != nil {
+			return nil, err
+		}
+
+		drivers = make([]*tool.Descriptor, len(names))
+		for i, name := range names {
+			drivers[i] = &tool.Descriptor{
+				Info: *tool.LegacyInfo(name),
 			}
+		}
 
-			pubTopic = strings.Replace(pubTopic, ":edge_id", edgeID, 1)
+		return drivers, nil
+	}
+}
+
+// InstallTimeout bounds the installation of a driver, which downloads its
+// package and handshakes with it.
+var InstallTimeout = 2 * time.Minute
+
+func InstallDriverEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(install.Request)
+		if !ok {
+			return nil, errors.New("invalid request")
 		}
 
-		msg, err := nc.Request(pubTopic, nil, nats.DefaultTimeout)
+		data, err := json.Marshal(&req)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, InstallTimeout)
 		if err != nil {
 			return nil, err
 		}
@@ -81,33 +130,43 @@ func ListDriversEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 			return nil, err
 		}
 
-		var drivers []string
-		if err := json.Unmarshal(msg.Data, &drivers); err != nil {
+		var driver *tool.Descriptor
+		if err := json.Unmarshal(msg.Data, &driver); err != nil {
 			return nil, err
 		}
 
-		return drivers, nil
+		return driver, nil
 	}
 }
 
-func SchemaEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+func UninstallDriverEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
-		pubTopic := topic
-		if strings.Contains(pubTopic, ":edge_id") {
-			edgeID, ok := ctx.Value(model.EdgeID).(string)
-			if !ok {
-				return nil, errors.New("invalid edge id")
-			}
+		driver, ok := request.(string)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		msg, err := Request(ctx, nc, topic, []byte(driver), DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
 
-			pubTopic = strings.Replace(pubTopic, ":edge_id", edgeID, 1)
+		if err := Error(msg); err != nil {
+			return nil, err
 		}
 
+		return nil, nil
+	}
+}
+
+func SchemaEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
 		driver, ok := request.(string)
 		if !ok {
 			return nil, errors.New("invalid request")
 		}
 
-		msg, err := nc.Request(pubTopic, []byte(driver), nats.DefaultTimeout)
+		msg, err := Request(ctx, nc, topic, []byte(driver), DefaultTimeout)
 		if err != nil {
 			return nil, err
 		}
@@ -128,22 +187,12 @@ func SchemaEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 
 func InstructionEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
-		pubTopic := topic
-		if strings.Contains(pubTopic, ":edge_id") {
-			edgeID, ok := ctx.Value(model.EdgeID).(string)
-			if !ok {
-				return nil, errors.New("invalid edge id")
-			}
-
-			pubTopic = strings.Replace(pubTopic, ":edge_id", edgeID, 1)
-		}
-
 		driver, ok := request.(string)
 		if !ok {
 			return nil, errors.New("invalid request")
 		}
 
-		msg, err := nc.Request(pubTopic, []byte(driver), nats.DefaultTimeout)
+		msg, err := Request(ctx, nc, topic, []byte(driver), DefaultTimeout)
 		if err != nil {
 			return nil, err
 		}
@@ -158,16 +207,6 @@ func InstructionEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 
 func ReadPointsEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 	return func(ctx context.Context, request any) (any, error) {
-		pubTopic := topic
-		if strings.Contains(pubTopic, ":edge_id") {
-			edgeID, ok := ctx.Value(model.EdgeID).(string)
-			if !ok {
-				return nil, errors.New("invalid edge id")
-			}
-
-			pubTopic = strings.Replace(pubTopic, ":edge_id", edgeID, 1)
-		}
-
 		req, ok := request.(iiot.ReadPointsRequest)
 		if !ok {
 			return nil, errors.New("invalid request")
@@ -178,7 +217,7 @@ func ReadPointsEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 			return nil, err
 		}
 
-		msg, err := nc.Request(pubTopic, data, nats.DefaultTimeout)
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
 		if err != nil {
 			return nil, err
 		}
@@ -197,20 +236,435 @@ func ReadPointsEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
 	}
 }
 
+func ListMachinesEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		msg, err := Request(ctx, nc, topic, nil, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var machines []*machine.Machine
+		if err := json.Unmarshal(msg.Data, &machines); err != nil {
+			return nil, err
+		}
+
+		return machines, nil
+	}
+}
+
+func MachineStatusEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		id, ok := request.(machine.MachineID)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		msg, err := Request(ctx, nc, topic, []byte(id), DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var status *machine.StatusInfo
+		if err := json.Unmarshal(msg.Data, &status); err != nil {
+			return nil, err
+		}
+
+		return status, nil
+	}
+}
+
+func PointValuesEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		id, ok := request.(machine.MachineID)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		msg, err := Request(ctx, nc, topic, []byte(id), DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var samples []*machine.Sample
+		if err := json.Unmarshal(msg.Data, &samples); err != nil {
+			return nil, err
+		}
+
+		return samples, nil
+	}
+}
+
+func MachineStatusHistoryEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(iiot.MachineStatusHistoryRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		data, err := json.Marshal(&req)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var events []*machine.StatusEvent
+		if err := json.Unmarshal(msg.Data, &events); err != nil {
+			return nil, err
+		}
+
+		return events, nil
+	}
+}
+
+func ListAlarmsEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		filter, ok := request.(alarm.Filter)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		data, err := json.Marshal(&filter)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var alarms []*alarm.Alarm
+		if err := json.Unmarshal(msg.Data, &alarms); err != nil {
+			return nil, err
+		}
+
+		return alarms, nil
+	}
+}
+
+func WritePointsEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(safety.Request)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		data, err := json.Marshal(&req)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var result *safety.Result
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+}
+
+func AcknowledgeAlarmEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(iiot.AcknowledgeAlarmRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		data, err := json.Marshal(&req)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var a *alarm.Alarm
+		if err := json.Unmarshal(msg.Data, &a); err != nil {
+			return nil, err
+		}
+
+		return a, nil
+	}
+}
+
+func ShelveAlarmEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(iiot.ShelveAlarmRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		data, err := json.Marshal(&req)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var a *alarm.Alarm
+		if err := json.Unmarshal(msg.Data, &a); err != nil {
+			return nil, err
+		}
+
+		return a, nil
+	}
+}
+
+func ReadHistoryEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		query, ok := request.(historian.Query)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		if query.AllPages {
+			return readHistoryPages(ctx, nc, topic, query)
+		}
+
+		data, err := json.Marshal(&query)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var result *historian.Result
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+}
+
+func ProductionReportEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		id, ok := request.(machine.MachineID)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		msg, err := Request(ctx, nc, topic, []byte(id), DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var report *production.Report
+		if err := json.Unmarshal(msg.Data, &report); err != nil {
+			return nil, err
+		}
+
+		return report, nil
+	}
+}
+
+func ProductionHistoryEndpoint(nc *nats.Conn, topic string) endpoint.Endpoint {
+	return func(ctx context.Context, request any) (any, error) {
+		req, ok := request.(iiot.ProductionHistoryRequest)
+		if !ok {
+			return nil, errors.New("invalid request")
+		}
+
+		data, err := json.Marshal(&req)
+		if err != nil {
+			return nil, err
+		}
+
+		msg, err := Request(ctx, nc, topic, data, DefaultTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := Error(msg); err != nil {
+			return nil, err
+		}
+
+		var reports []*production.Report
+		if err := json.Unmarshal(msg.Data, &reports); err != nil {
+			return nil, err
+		}
+
+		return reports, nil
+	}
+}
+
+// ReadHistoryStream reads every page of the query in a single request,
+// calling fn with each page as it arrives. The pages are streamed by the
+// edge without waiting for the client to ask for the next one.
+func ReadHistoryStream(ctx context.Context, nc *nats.Conn, topic string, query historian.Query, fn func(*historian.Result) error) error {
+	data, err := json.Marshal(&query)
+	if err != nil {
+		return err
+	}
+
+	req, err := NewRequestMsg(ctx, nc, topic, data)
+	if err != nil {
+		return err
+	}
+
+	inbox := nc.NewInbox()
+
+	sub, err := nc.SubscribeSync(inbox)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	req.Reply = inbox
+	req.Header.Set(StreamHeader, "true")
+
+	if err := nc.PublishMsg(req); err != nil {
+		return err
+	}
+
+	for {
+		// Without a deadline, give up once the edge stops sending pages.
+		pageCtx, cancel := ctx, context.CancelFunc(func() {})
+		if _, ok := ctx.Deadline(); !ok {
+			pageCtx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		}
+
+		msg, err := sub.NextMsgWithContext(pageCtx)
+		cancel()
+
+		if err != nil {
+			return err
+		}
+
+		if err := Error(msg); err != nil {
+			return err
+		}
+
+		// An empty message ends the stream.
+		if len(msg.Data) == 0 {
+			return nil
+		}
+
+		var result *historian.Result
+		if err := json.Unmarshal(msg.Data, &result); err != nil {
+			return err
+		}
+
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+}
+
+// errEnoughPages stops the stream of readHistoryPages.
+var errEnoughPages = errors.New("enough pages")
+
+// readHistoryPages reads the pages of the query with ReadHistoryStream into
+// a single result, until historian.MaxLimit values are read.
+func readHistoryPages(ctx context.Context, nc *nats.Conn, topic string, query historian.Query) (*historian.Result, error) {
+	query.AllPages = false
+
+	var result *historian.Result
+	err := ReadHistoryStream(ctx, nc, topic, query, func(page *historian.Result) error {
+		if result == nil {
+			result = page
+		} else {
+			result.Values = append(result.Values, page.Values...)
+			result.NextPageToken = page.NextPageToken
+		}
+
+		if len(result.Values) >= historian.MaxLimit {
+			return errEnoughPages
+		}
+
+		return nil
+	})
+
+	if err != nil && !errors.Is(err, errEnoughPages) {
+		return nil, err
+	}
+
+	if result == nil {
+		return nil, errs.New(errs.Internal, "empty history stream")
+	}
+
+	return result, nil
+}
+
+// Error returns the error of a reply, if any. Replies from handlers carry
+// the error as JSON, which is decoded into an *errs.Error; otherwise the
+// error code header is mapped to a code.
 func Error(msg *nats.Msg) error {
 	if msg == nil {
 		return errors.New("nil message")
 	}
 
-	code := msg.Header.Get(micro.ErrorCodeHeader)
-	if code == "" {
+	status := msg.Header.Get(micro.ErrorCodeHeader)
+	if status == "" {
 		return nil
 	}
 
+	var e *errs.Error
+	if err := json.Unmarshal(msg.Data, &e); err == nil && e != nil && e.Code != "" {
+		return e
+	}
+
 	description := msg.Header.Get(micro.ErrorHeader)
 	if description == "" {
 		description = "unknown error"
 	}
 
-	return errors.New(code + ": " + description)
+	return errs.New(errs.CodeFromStatus(status), description)
 }
@@ -0,0 +1,77 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/historian"
+)
+
+func TestReadHistoryStream(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := runServer(t)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Three pages, of a value each.
+	serve(t, nc, "edge.history.read", ReadHistoryHandler(func(ctx context.Context, request any) (any, error) {
+		query := request.(historian.Query)
+
+		page := 0
+		switch query.PageToken {
+		case "":
+		case "1":
+			page = 1
+		case "2":
+			page = 2
+		default:
+			return nil, errs.New(errs.InvalidArgument, "invalid page token")
+		}
+
+		result := &historian.Result{
+			Values: []*historian.Value{{Time: start.Add(time.Duration(page) * time.Minute), Value: float64(page)}},
+		}
+
+		if page < 2 {
+			result.NextPageToken = []string{"1", "2"}[page]
+		}
+
+		return result, nil
+	}))
+
+	ctx := context.Background()
+
+	values := make([]any, 0)
+	err := ReadHistoryStream(ctx, nc, "edge.history.read", historian.Query{}, func(result *historian.Result) error {
+		for _, v := range result.Values {
+			values = append(values, v.Value)
+		}
+
+		return nil
+	})
+
+	assert.NoError(err)
+	assert.Equal([]any{0.0, 1.0, 2.0}, values)
+
+	// The endpoint reads every page into a single result.
+	endpoint := ReadHistoryEndpoint(nc, "edge.history.read")
+
+	result, err := endpoint(ctx, historian.Query{PageToken: "1", AllPages: true})
+	if assert.NoError(err) {
+		page := result.(*historian.Result)
+		assert.Len(page.Values, 2)
+		assert.Empty(page.NextPageToken)
+	}
+
+	// Errors end the stream.
+	err = ReadHistoryStream(ctx, nc, "edge.history.read", historian.Query{PageToken: "invalid"}, func(result *historian.Result) error {
+		return nil
+	})
+
+	assert.ErrorIs(err, errs.New(errs.InvalidArgument, "invalid page token"))
+}
@@ -0,0 +1,134 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/fleet"
+)
+
+// EdgeIDMetadata is the service metadata key carrying the edge ID.
+const EdgeIDMetadata = "edge_id"
+
+// DefaultDiscoveryWindow is how long discovery waits for edges to answer.
+var DefaultDiscoveryWindow = 500 * time.Millisecond
+
+// NewDiscoverer returns a discoverer that finds the instances of the named
+// NATS micro service through $SRV.INFO, collecting the answers received
+// within the window.
+func NewDiscoverer(nc *nats.Conn, service string, window time.Duration) fleet.Discoverer {
+	if window <= 0 {
+		window = DefaultDiscoveryWindow
+	}
+
+	return &discoverer{nc, service, window}
+}
+
+type discoverer struct {
+	nc      *nats.Conn
+	service string
+	window  time.Duration
+}
+
+func (d *discoverer) Discover(ctx context.Context) ([]*fleet.Edge, error) {
+	subject, err := micro.ControlSubject(micro.InfoVerb, d.service, "")
+	if err != nil {
+		return nil, err
+	}
+
+	inbox := d.nc.NewInbox()
+
+	sub, err := d.nc.SubscribeSync(inbox)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
+	if err := d.nc.PublishRequest(subject, inbox, nil); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.window)
+	defer cancel()
+
+	edges := make([]*fleet.Edge, 0)
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return edges, nil
+			}
+
+			return edges, err
+		}
+
+		var info micro.Info
+		if err := json.Unmarshal(msg.Data, &info); err != nil {
+			zap.L().Warn("invalid service info", zap.Error(err))
+			continue
+		}
+
+		edge := &fleet.Edge{
+			EdgeID:    info.Metadata[EdgeIDMetadata],
+			ServiceID: info.ID,
+			Version:   info.Version,
+			Endpoints: make([]string, 0, len(info.Endpoints)),
+		}
+
+		for _, endpoint := range info.Endpoints {
+			edge.Endpoints = append(edge.Endpoints, endpoint.Subject)
+
+			// Older edges do not set the metadata: edges.<edge_id>.iiot.<endpoint>
+			if edge.EdgeID == "" {
+				parts := strings.Split(endpoint.Subject, ".")
+				if len(parts) > 2 && parts[0] == "edges" {
+					edge.EdgeID = parts[1]
+				}
+			}
+		}
+
+		if edge.EdgeID == "" {
+			continue
+		}
+
+		edges = append(edges, edge)
+	}
+}
+
+// SubscribeHeartbeats calls the handler with every heartbeat published on
+// the subject (e.g., edges.*.iiot.heartbeat). The edge is the one of the
+// subject, which the NATS permissions of the edge restrict; heartbeats
+// naming another edge are dropped.
+func SubscribeHeartbeats(nc *nats.Conn, subject string, handler fleet.HeartbeatHandler) (*nats.Subscription, error) {
+	return nc.Subscribe(subject, func(msg *nats.Msg) {
+		var hb *fleet.Heartbeat
+		if err := json.Unmarshal(msg.Data, &hb); err != nil || hb == nil {
+			zap.L().Warn("invalid heartbeat", zap.Error(err), zap.String("subject", msg.Subject))
+			return
+		}
+
+		// edges.<edge_id>.iiot.heartbeat
+		parts := strings.Split(msg.Subject, ".")
+		if len(parts) > 2 && parts[0] == "edges" {
+			if hb.EdgeID != "" && hb.EdgeID != parts[1] {
+				zap.L().Warn("heartbeat of another edge",
+					zap.String("subject", msg.Subject),
+					zap.String("edge_id", hb.EdgeID),
+				)
+
+				return
+			}
+
+			hb.EdgeID = parts[1]
+		}
+
+		handler(hb)
+	})
+}
@@ -0,0 +1,64 @@
+package pubsub
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/flarexio/iiot/fleet"
+)
+
+func TestSubscribeHeartbeats(t *testing.T) {
+	assert := assert.New(t)
+
+	nc := runServer(t)
+
+	heartbeats := make(chan *fleet.Heartbeat, 3)
+	sub, err := SubscribeHeartbeats(nc, "edges.*.iiot.heartbeat", func(hb *fleet.Heartbeat) {
+		heartbeats <- hb
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	publish := func(subject string, hb *fleet.Heartbeat) {
+		data, err := json.Marshal(hb)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := nc.Publish(subject, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The edge of the subject is the one observed.
+	publish("edges.edge1.iiot.heartbeat", &fleet.Heartbeat{})
+
+	// Heartbeats naming another edge are dropped.
+	publish("edges.edge1.iiot.heartbeat", &fleet.Heartbeat{EdgeID: "edge2"})
+	publish("edges.edge2.iiot.heartbeat", &fleet.Heartbeat{EdgeID: "edge2"})
+
+	assert.NoError(nc.Flush())
+
+	var ids []string
+	for range 2 {
+		select {
+		case hb := <-heartbeats:
+			ids = append(ids, hb.EdgeID)
+		case <-time.After(5 * time.Second):
+			t.Fatal("heartbeat not received")
+		}
+	}
+
+	assert.Equal([]string{"edge1", "edge2"}, ids)
+
+	select {
+	case hb := <-heartbeats:
+		t.Fatalf("unexpected heartbeat: %+v", hb)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,44 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/errs"
+)
+
+// NewObjectStore returns the store of the driver packages uploaded to
+// JetStream object store buckets.
+func NewObjectStore(js jetstream.JetStream) install.ObjectStore {
+	return &objectStore{js}
+}
+
+type objectStore struct {
+	js jetstream.JetStream
+}
+
+func (s *objectStore) Get(ctx context.Context, bucket string, name string) (io.ReadCloser, error) {
+	obs, err := s.js.ObjectStore(ctx, bucket)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrBucketNotFound) {
+			return nil, errs.Wrap(errs.NotFound, err).WithDetail("bucket", bucket)
+		}
+
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	obj, err := obs.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, jetstream.ErrObjectNotFound) {
+			return nil, errs.Wrap(errs.NotFound, err).WithDetail("object", name)
+		}
+
+		return nil, errs.Wrap(errs.Unavailable, err)
+	}
+
+	return obj, nil
+}
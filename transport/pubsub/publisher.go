@@ -0,0 +1,202 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/watch"
+	"github.com/flarexio/iiot/fleet"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/production"
+)
+
+// AlarmEventHandler publishes alarm events to the given topic, suffixed
+// with the event type (e.g., edges.<edge_id>.iiot.alarms.events.raised).
+func AlarmEventHandler(nc *nats.Conn, topic string) alarm.EventHandler {
+	return func(event *alarm.Event) {
+		data, err := json.Marshal(event)
+		if err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+			return
+		}
+
+		if err := nc.Publish(topic+"."+string(event.Type), data); err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+		}
+	}
+}
+
+// DriverEventHandler publishes the changes of the driver binaries to the
+// given topic, suffixed with the driver (e.g., edges.<edge_id>.iiot.drivers.events.modbus).
+func DriverEventHandler(nc *nats.Conn, topic string) watch.EventHandler {
+	return func(event *watch.Event) {
+		data, err := json.Marshal(event)
+		if err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+			return
+		}
+
+		if err := nc.Publish(topic+"."+event.Driver, data); err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+		}
+	}
+}
+
+// StatusEventHandler publishes machine status events to the given topic,
+// suffixed with the machine ID (e.g., edges.<edge_id>.iiot.machines.events.<machine_id>).
+func StatusEventHandler(nc *nats.Conn, topic string) machine.StatusEventHandler {
+	return func(event *machine.StatusEvent) {
+		data, err := json.Marshal(event)
+		if err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+			return
+		}
+
+		if err := nc.Publish(topic+"."+string(event.MachineID), data); err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+		}
+	}
+}
+
+// SamplePublisher publishes the samples whose value changed to the given
+// topic, suffixed with the machine ID (e.g., edges.<edge_id>.iiot.machines.samples.<machine_id>).
+func SamplePublisher(nc *nats.Conn, topic string) machine.SampleHandler {
+	var mu sync.Mutex
+	last := make(map[machine.PointRef]any)
+
+	return func(ctx context.Context, sample *machine.Sample) {
+		if sample == nil || sample.Value == nil {
+			return
+		}
+
+		mu.Lock()
+		prev, ok := last[sample.PointRef]
+		last[sample.PointRef] = sample.Value.Value
+		mu.Unlock()
+
+		if ok && machine.Equal(prev, sample.Value.Value) {
+			return
+		}
+
+		data, err := json.Marshal(sample)
+		if err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+			return
+		}
+
+		if err := nc.Publish(topic+"."+string(sample.MachineID), data); err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+		}
+	}
+}
+
+// SubscribeSamples calls the handler with the edge ID and every sample
+// published on the subject (e.g., edges.*.iiot.machines.samples.*).
+func SubscribeSamples(nc *nats.Conn, subject string, handler func(edgeID string, sample *machine.Sample)) (*nats.Subscription, error) {
+	return nc.Subscribe(subject, func(msg *nats.Msg) {
+		var sample *machine.Sample
+		if err := json.Unmarshal(msg.Data, &sample); err != nil {
+			zap.L().Warn("invalid sample", zap.Error(err), zap.String("subject", msg.Subject))
+			return
+		}
+
+		handler(subjectEdgeID(msg.Subject), sample)
+	})
+}
+
+// SubscribeStatusEvents calls the handler with the edge ID and every machine
+// status event published on the subject (e.g., edges.*.iiot.machines.events.*).
+func SubscribeStatusEvents(nc *nats.Conn, subject string, handler func(edgeID string, event *machine.StatusEvent)) (*nats.Subscription, error) {
+	return nc.Subscribe(subject, func(msg *nats.Msg) {
+		var event *machine.StatusEvent
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			zap.L().Warn("invalid status event", zap.Error(err), zap.String("subject", msg.Subject))
+			return
+		}
+
+		handler(subjectEdgeID(msg.Subject), event)
+	})
+}
+
+// subjectEdgeID returns the edge ID of a subject under edges.<edge_id>.
+func subjectEdgeID(subject string) string {
+	parts := strings.SplitN(subject, ".", 3)
+	if len(parts) < 3 || parts[0] != "edges" {
+		return ""
+	}
+
+	return parts[1]
+}
+
+// ProductionReportPublisher publishes production reports to the given topic,
+// suffixed with the machine ID (e.g., edges.<edge_id>.iiot.production.reports.<machine_id>).
+func ProductionReportPublisher(nc *nats.Conn, topic string) production.ReportHandler {
+	return func(report *production.Report) {
+		data, err := json.Marshal(report)
+		if err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+			return
+		}
+
+		if err := nc.Publish(topic+"."+string(report.MachineID), data); err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+		}
+	}
+}
+
+// HeartbeatPublisher publishes the heartbeat of the edge to the given topic
+// (e.g., edges.<edge_id>.iiot.heartbeat).
+func HeartbeatPublisher(nc *nats.Conn, topic string) fleet.HeartbeatHandler {
+	return func(hb *fleet.Heartbeat) {
+		data, err := json.Marshal(hb)
+		if err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+			return
+		}
+
+		if err := nc.Publish(topic, data); err != nil {
+			zap.L().Error(err.Error(), zap.String("topic", topic))
+		}
+	}
+}
+
+// HistoryPublisher forwards historian records to JetStream, publishing each
+// record to the given topic suffixed with its machine ID and waiting for the
+// stream to acknowledge it. The message ID lets the stream drop records that
+// are replayed after a partially forwarded batch.
+func HistoryPublisher(js jetstream.JetStream, topic string) historian.Publisher {
+	return &historyPublisher{js, topic}
+}
+
+type historyPublisher struct {
+	js    jetstream.JetStream
+	topic string
+}
+
+func (p *historyPublisher) Publish(ctx context.Context, records []*historian.Record) error {
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+
+		msg := nats.NewMsg(p.topic + "." + string(r.MachineID))
+		msg.Data = data
+		msg.Header.Set(jetstream.MsgIDHeader, r.PointRef.String()+"@"+r.Time.Format(time.RFC3339Nano))
+
+		if _, err := p.js.PublishMsg(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/micro"
+)
+
+// runServer runs an in-process NATS server, and connects to it.
+func runServer(t *testing.T) *nats.Conn {
+	s, err := server.NewServer(&server.Options{
+		Host:   "127.0.0.1",
+		Port:   -1,
+		NoLog:  true,
+		NoSigs: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server not ready")
+	}
+
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(nc.Close)
+
+	return nc
+}
+
+// serve serves the handler at the subject.
+func serve(t *testing.T, nc *nats.Conn, subject string, handler micro.HandlerFunc) {
+	svc, err := micro.AddService(nc, micro.Config{
+		Name:    "iiot_test",
+		Version: "1.0.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { svc.Stop() })
+
+	if err := svc.AddEndpoint("test", handler, micro.WithEndpointSubject(subject)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,7 +9,31 @@ import (
 func AddEndpoints(group micro.Group, endpoints iiot.EndpointSet) {
 	group.AddEndpoint("check_connection", CheckConnectionHandler(endpoints.CheckConnection))
 	group.AddEndpoint("drivers", ListDriversHandler(endpoints.ListDrivers))
+	group.AddEndpoint("drivers_install", InstallDriverHandler(endpoints.InstallDriver),
+		micro.WithEndpointSubject("drivers.install"))
+	group.AddEndpoint("drivers_uninstall", UninstallDriverHandler(endpoints.UninstallDriver),
+		micro.WithEndpointSubject("drivers.uninstall"))
 	group.AddEndpoint("schema", SchemaHandler(endpoints.Schema))
 	group.AddEndpoint("instruction", InstructionHandler(endpoints.Instruction))
 	group.AddEndpoint("read_points", ReadPointsHandler(endpoints.ReadPoints))
+	group.AddEndpoint("machines", ListMachinesHandler(endpoints.ListMachines))
+	group.AddEndpoint("machines_status", MachineStatusHandler(endpoints.MachineStatus),
+		micro.WithEndpointSubject("machines.status"))
+	group.AddEndpoint("machines_status_history", MachineStatusHistoryHandler(endpoints.MachineStatusHistory),
+		micro.WithEndpointSubject("machines.status.history"))
+	group.AddEndpoint("machines_points", PointValuesHandler(endpoints.PointValues),
+		micro.WithEndpointSubject("machines.points"))
+	group.AddEndpoint("machines_points_write", WritePointsHandler(endpoints.WritePoints),
+		micro.WithEndpointSubject("machines.points.write"))
+	group.AddEndpoint("machines_production", ProductionReportHandler(endpoints.ProductionReport),
+		micro.WithEndpointSubject("machines.production"))
+	group.AddEndpoint("machines_production_history", ProductionHistoryHandler(endpoints.ProductionHistory),
+		micro.WithEndpointSubject("machines.production.history"))
+	group.AddEndpoint("alarms", ListAlarmsHandler(endpoints.ListAlarms))
+	group.AddEndpoint("alarms_acknowledge", AcknowledgeAlarmHandler(endpoints.AcknowledgeAlarm),
+		micro.WithEndpointSubject("alarms.acknowledge"))
+	group.AddEndpoint("alarms_shelve", ShelveAlarmHandler(endpoints.ShelveAlarm),
+		micro.WithEndpointSubject("alarms.shelve"))
+	group.AddEndpoint("history_read", ReadHistoryHandler(endpoints.ReadHistory),
+		micro.WithEndpointSubject("history.read"))
 }
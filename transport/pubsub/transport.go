@@ -1,27 +1,33 @@
 package pubsub
 
 import (
-	"context"
 	"encoding/json"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/nats-io/nats.go/micro"
 
 	"github.com/flarexio/iiot"
+	"github.com/flarexio/iiot/alarm"
+	"github.com/flarexio/iiot/driver/install"
+	"github.com/flarexio/iiot/errs"
+	"github.com/flarexio/iiot/historian"
+	"github.com/flarexio/iiot/machine"
+	"github.com/flarexio/iiot/safety"
 )
 
 func CheckConnectionHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
 	return func(r micro.Request) {
 		var req iiot.CheckConnectionRequest
 		if err := json.Unmarshal(r.Data(), &req); err != nil {
-			r.Error("400", err.Error(), nil)
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
 			return
 		}
 
-		ctx := context.Background()
+		ctx, cancel := Context(r)
+		defer cancel()
 		_, err := endpoint(ctx, req)
 		if err != nil {
-			r.Error("417", err.Error(), nil)
+			RespondError(r, err)
 			return
 		}
 
@@ -31,10 +37,11 @@ func CheckConnectionHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
 
 func ListDriversHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
 	return func(r micro.Request) {
-		ctx := context.Background()
+		ctx, cancel := Context(r)
+		defer cancel()
 		drivers, err := endpoint(ctx, nil)
 		if err != nil {
-			r.Error("417", err.Error(), nil)
+			RespondError(r, err)
 			return
 		}
 
@@ -42,18 +49,59 @@ func ListDriversHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
 	}
 }
 
+func InstallDriverHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var req install.Request
+		if err := json.Unmarshal(r.Data(), &req); err != nil {
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		driver, err := endpoint(ctx, req)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&driver)
+	}
+}
+
+func UninstallDriverHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		driver := string(r.Data())
+		if driver == "" {
+			RespondError(r, errs.New(errs.InvalidArgument, "driver parameter is required"))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		_, err := endpoint(ctx, driver)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.Respond([]byte("Driver uninstalled"))
+	}
+}
+
 func SchemaHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
 	return func(r micro.Request) {
 		driver := string(r.Data())
 		if driver == "" {
-			r.Error("400", "driver parameter is required", nil)
+			RespondError(r, errs.New(errs.InvalidArgument, "driver parameter is required"))
 			return
 		}
 
-		ctx := context.Background()
+		ctx, cancel := Context(r)
+		defer cancel()
 		schema, err := endpoint(ctx, driver)
 		if err != nil {
-			r.Error("417", err.Error(), nil)
+			RespondError(r, err)
 			return
 		}
 
@@ -65,14 +113,15 @@ func InstructionHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
 	return func(r micro.Request) {
 		driver := string(r.Data())
 		if driver == "" {
-			r.Error("400", "driver parameter is required", nil)
+			RespondError(r, errs.New(errs.InvalidArgument, "driver parameter is required"))
 			return
 		}
 
-		ctx := context.Background()
+		ctx, cancel := Context(r)
+		defer cancel()
 		instruction, err := endpoint(ctx, driver)
 		if err != nil {
-			r.Error("417", err.Error(), nil)
+			RespondError(r, err)
 			return
 		}
 
@@ -84,17 +133,267 @@ func ReadPointsHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
 	return func(r micro.Request) {
 		var req iiot.ReadPointsRequest
 		if err := json.Unmarshal(r.Data(), &req); err != nil {
-			r.Error("400", err.Error(), nil)
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
 			return
 		}
 
-		ctx := context.Background()
+		ctx, cancel := Context(r)
+		defer cancel()
 		points, err := endpoint(ctx, req)
 		if err != nil {
-			r.Error("417", err.Error(), nil)
+			RespondError(r, err)
 			return
 		}
 
 		r.RespondJSON(&points)
 	}
 }
+
+func ListMachinesHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		ctx, cancel := Context(r)
+		defer cancel()
+		machines, err := endpoint(ctx, nil)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&machines)
+	}
+}
+
+func MachineStatusHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		id := machine.MachineID(r.Data())
+		if id == "" {
+			RespondError(r, errs.New(errs.InvalidArgument, "id parameter is required"))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		status, err := endpoint(ctx, id)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&status)
+	}
+}
+
+func PointValuesHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		id := machine.MachineID(r.Data())
+		if id == "" {
+			RespondError(r, errs.New(errs.InvalidArgument, "id parameter is required"))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		samples, err := endpoint(ctx, id)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&samples)
+	}
+}
+
+func MachineStatusHistoryHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var req iiot.MachineStatusHistoryRequest
+		if err := json.Unmarshal(r.Data(), &req); err != nil {
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		events, err := endpoint(ctx, req)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&events)
+	}
+}
+
+func ListAlarmsHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var filter alarm.Filter
+		if len(r.Data()) > 0 {
+			if err := json.Unmarshal(r.Data(), &filter); err != nil {
+				RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+				return
+			}
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		alarms, err := endpoint(ctx, filter)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&alarms)
+	}
+}
+
+func WritePointsHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var req safety.Request
+		if err := json.Unmarshal(r.Data(), &req); err != nil {
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		result, err := endpoint(ctx, req)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&result)
+	}
+}
+
+func AcknowledgeAlarmHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var req iiot.AcknowledgeAlarmRequest
+		if err := json.Unmarshal(r.Data(), &req); err != nil {
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		alarm, err := endpoint(ctx, req)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&alarm)
+	}
+}
+
+func ShelveAlarmHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var req iiot.ShelveAlarmRequest
+		if err := json.Unmarshal(r.Data(), &req); err != nil {
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		alarm, err := endpoint(ctx, req)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&alarm)
+	}
+}
+
+// StreamHeader asks ReadHistoryHandler to respond with every page from the
+// requested one on, one message per page, followed by an empty message.
+const StreamHeader = "Iiot-Stream"
+
+func ReadHistoryHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var query historian.Query
+		if err := json.Unmarshal(r.Data(), &query); err != nil {
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		stream := r.Headers().Get(StreamHeader) == "true"
+		if stream {
+			query.AllPages = false
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		result, err := endpoint(ctx, query)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		if !stream {
+			r.RespondJSON(&result)
+			return
+		}
+
+		for {
+			if err := r.RespondJSON(&result); err != nil {
+				return
+			}
+
+			page, ok := result.(*historian.Result)
+			if !ok || page.NextPageToken == "" {
+				break
+			}
+
+			query.PageToken = page.NextPageToken
+
+			result, err = endpoint(ctx, query)
+			if err != nil {
+				RespondError(r, err)
+				return
+			}
+		}
+
+		r.Respond(nil)
+	}
+}
+
+func ProductionReportHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		id := machine.MachineID(r.Data())
+		if id == "" {
+			RespondError(r, errs.New(errs.InvalidArgument, "id parameter is required"))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		report, err := endpoint(ctx, id)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&report)
+	}
+}
+
+func ProductionHistoryHandler(endpoint endpoint.Endpoint) micro.HandlerFunc {
+	return func(r micro.Request) {
+		var req iiot.ProductionHistoryRequest
+		if err := json.Unmarshal(r.Data(), &req); err != nil {
+			RespondError(r, errs.Wrap(errs.InvalidArgument, err))
+			return
+		}
+
+		ctx, cancel := Context(r)
+		defer cancel()
+		reports, err := endpoint(ctx, req)
+		if err != nil {
+			RespondError(r, err)
+			return
+		}
+
+		r.RespondJSON(&reports)
+	}
+}